@@ -4,72 +4,40 @@ import (
 	"context"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
-	"k8s.io/client-go/kubernetes/scheme"
-	"k8s.io/client-go/rest"
+	"k8s.io/client-go/gentype"
 
 	"github.com/mirantiscontainers/blueprint-operator/api/v1alpha1"
 )
 
 // AddonInterface is an interface containing the operations that can be done on Addons
 type AddonInterface interface {
-	List(opts metav1.ListOptions) (*v1alpha1.AddonList, error)
-	Get(name string, options metav1.GetOptions) (*v1alpha1.Addon, error)
-	Create(addon *v1alpha1.Addon) (*v1alpha1.Addon, error)
-	Watch(opts metav1.ListOptions) (watch.Interface, error)
+	Create(ctx context.Context, addon *v1alpha1.Addon, opts metav1.CreateOptions) (*v1alpha1.Addon, error)
+	Update(ctx context.Context, addon *v1alpha1.Addon, opts metav1.UpdateOptions) (*v1alpha1.Addon, error)
+	UpdateStatus(ctx context.Context, addon *v1alpha1.Addon, opts metav1.UpdateOptions) (*v1alpha1.Addon, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1alpha1.Addon, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*v1alpha1.AddonList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*v1alpha1.Addon, error)
 }
 
-type addonClient struct {
-	restClient rest.Interface
-	namespace  string
+// addons implements AddonInterface
+type addons struct {
+	*gentype.ClientWithList[*v1alpha1.Addon, *v1alpha1.AddonList]
 }
 
-func (c *addonClient) List(opts metav1.ListOptions) (*v1alpha1.AddonList, error) {
-	result := v1alpha1.AddonList{}
-	err := c.restClient.
-		Get().
-		Namespace(c.namespace).
-		Resource("addons").
-		VersionedParams(&opts, scheme.ParameterCodec).
-		Do(context.TODO()).
-		Into(&result)
-
-	return &result, err
-}
-
-func (c *addonClient) Get(name string, opts metav1.GetOptions) (*v1alpha1.Addon, error) {
-	result := v1alpha1.Addon{}
-	err := c.restClient.
-		Get().
-		Namespace(c.namespace).
-		Resource("addons").
-		Name(name).
-		VersionedParams(&opts, scheme.ParameterCodec).
-		Do(context.TODO()).
-		Into(&result)
-
-	return &result, err
-}
-
-func (c *addonClient) Create(addon *v1alpha1.Addon) (*v1alpha1.Addon, error) {
-	result := v1alpha1.Addon{}
-	err := c.restClient.
-		Post().
-		Namespace(c.namespace).
-		Resource("addons").
-		Body(addon).
-		Do(context.TODO()).
-		Into(&result)
-
-	return &result, err
-}
-
-func (c *addonClient) Watch(opts metav1.ListOptions) (watch.Interface, error) {
-	opts.Watch = true
-	return c.restClient.
-		Get().
-		Namespace(c.namespace).
-		Resource("addons").
-		VersionedParams(&opts, scheme.ParameterCodec).
-		Watch(context.TODO())
+func newAddons(c *BoundlessV1Alpha1Client, namespace string) *addons {
+	return &addons{
+		gentype.NewClientWithList[*v1alpha1.Addon, *v1alpha1.AddonList](
+			"addons",
+			c.RESTClient(),
+			ParameterCodec,
+			namespace,
+			func() *v1alpha1.Addon { return &v1alpha1.Addon{} },
+			func() *v1alpha1.AddonList { return &v1alpha1.AddonList{} },
+		),
+	}
 }
@@ -0,0 +1,43 @@
+package boundlessclientset
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/gentype"
+
+	"github.com/mirantiscontainers/blueprint-operator/api/v1alpha1"
+)
+
+// BlueprintInterface is an interface containing the operations that can be done on Blueprints
+type BlueprintInterface interface {
+	Create(ctx context.Context, blueprint *v1alpha1.Blueprint, opts metav1.CreateOptions) (*v1alpha1.Blueprint, error)
+	Update(ctx context.Context, blueprint *v1alpha1.Blueprint, opts metav1.UpdateOptions) (*v1alpha1.Blueprint, error)
+	UpdateStatus(ctx context.Context, blueprint *v1alpha1.Blueprint, opts metav1.UpdateOptions) (*v1alpha1.Blueprint, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1alpha1.Blueprint, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*v1alpha1.BlueprintList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*v1alpha1.Blueprint, error)
+}
+
+// blueprints implements BlueprintInterface
+type blueprints struct {
+	*gentype.ClientWithList[*v1alpha1.Blueprint, *v1alpha1.BlueprintList]
+}
+
+func newBlueprints(c *BoundlessV1Alpha1Client, namespace string) *blueprints {
+	return &blueprints{
+		gentype.NewClientWithList[*v1alpha1.Blueprint, *v1alpha1.BlueprintList](
+			"blueprints",
+			c.RESTClient(),
+			ParameterCodec,
+			namespace,
+			func() *v1alpha1.Blueprint { return &v1alpha1.Blueprint{} },
+			func() *v1alpha1.BlueprintList { return &v1alpha1.BlueprintList{} },
+		),
+	}
+}
@@ -1,46 +1,92 @@
 package boundlessclientset
 
 import (
-	"k8s.io/client-go/kubernetes/scheme"
+	"net/http"
+
 	"k8s.io/client-go/rest"
 
 	"github.com/mirantiscontainers/blueprint-operator/api/v1alpha1"
 )
 
-// TODO: generate the client code instead or use a dynamic client
+// BoundlessV1Alpha1Interface gives access to the clients of the Blueprint Operator v1alpha1 API
 type BoundlessV1Alpha1Interface interface {
-	Addons(namespace string) v1alpha1.Addon
+	RESTClient() rest.Interface
+	Blueprints(namespace string) BlueprintInterface
+	Addons(namespace string) AddonInterface
+	Manifests(namespace string) ManifestInterface
+	Installations(namespace string) InstallationInterface
 }
 
+// BoundlessV1Alpha1Client is used to interact with the Blueprint Operator v1alpha1 API
 type BoundlessV1Alpha1Client struct {
 	restClient rest.Interface
 }
 
+var _ BoundlessV1Alpha1Interface = &BoundlessV1Alpha1Client{}
+
+// NewForConfig creates a new BoundlessV1Alpha1Client for the given config
 func NewForConfig(c *rest.Config) (*BoundlessV1Alpha1Client, error) {
 	config := *c
-	config.ContentConfig.GroupVersion = &v1alpha1.GroupVersion
-	config.APIPath = "/apis"
-	config.NegotiatedSerializer = scheme.Codecs.WithoutConversion()
-	config.UserAgent = rest.DefaultKubernetesUserAgent()
+	setConfigDefaults(&config)
 
-	client, err := rest.RESTClientFor(&config)
+	httpClient, err := rest.HTTPClientFor(&config)
 	if err != nil {
 		return nil, err
 	}
+	return NewForConfigAndClient(&config, httpClient)
+}
+
+// NewForConfigAndClient creates a new BoundlessV1Alpha1Client for the given config and http client
+func NewForConfigAndClient(c *rest.Config, h *http.Client) (*BoundlessV1Alpha1Client, error) {
+	config := *c
+	setConfigDefaults(&config)
 
+	client, err := rest.RESTClientForConfigAndClient(&config, h)
+	if err != nil {
+		return nil, err
+	}
 	return &BoundlessV1Alpha1Client{restClient: client}, nil
 }
 
-func (c *BoundlessV1Alpha1Client) Addons(namespace string) AddonInterface {
-	return &addonClient{
-		restClient: c.restClient,
-		namespace:  namespace,
+// New creates a new BoundlessV1Alpha1Client for the given RESTClient
+func New(c rest.Interface) *BoundlessV1Alpha1Client {
+	return &BoundlessV1Alpha1Client{restClient: c}
+}
+
+func setConfigDefaults(config *rest.Config) {
+	gv := v1alpha1.GroupVersion
+	config.GroupVersion = &gv
+	config.APIPath = "/apis"
+	config.NegotiatedSerializer = Codecs.WithoutConversion()
+	if config.UserAgent == "" {
+		config.UserAgent = rest.DefaultKubernetesUserAgent()
 	}
 }
 
-func (c *BoundlessV1Alpha1Client) Manifests(namespace string) ManifestInterface {
-	return &manifestClient{
-		restClient: c.restClient,
-		namespace:  namespace,
+// RESTClient returns the RESTClient used to communicate with the API server
+func (c *BoundlessV1Alpha1Client) RESTClient() rest.Interface {
+	if c == nil {
+		return nil
 	}
+	return c.restClient
+}
+
+// Blueprints returns the client for the Blueprints of the namespace
+func (c *BoundlessV1Alpha1Client) Blueprints(namespace string) BlueprintInterface {
+	return newBlueprints(c, namespace)
+}
+
+// Addons returns the client for the Addons of the namespace
+func (c *BoundlessV1Alpha1Client) Addons(namespace string) AddonInterface {
+	return newAddons(c, namespace)
+}
+
+// Manifests returns the client for the Manifests of the namespace
+func (c *BoundlessV1Alpha1Client) Manifests(namespace string) ManifestInterface {
+	return newManifests(c, namespace)
+}
+
+// Installations returns the client for the Installations of the namespace
+func (c *BoundlessV1Alpha1Client) Installations(namespace string) InstallationInterface {
+	return newInstallations(c, namespace)
 }
@@ -0,0 +1,86 @@
+package fake
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/testing"
+
+	"github.com/mirantiscontainers/blueprint-operator/api/v1alpha1"
+
+	"github.com/mirantiscontainers/blueprint-cli/boundlessclientset"
+)
+
+// Clientset is a fake BoundlessV1Alpha1Interface backed by an in-memory object tracker
+// Actions can be inspected and reactors prepended through the embedded testing.Fake
+type Clientset struct {
+	testing.Fake
+	tracker testing.ObjectTracker
+}
+
+var _ boundlessclientset.BoundlessV1Alpha1Interface = &Clientset{}
+
+// NewSimpleClientset returns a fake client that serves the given objects
+func NewSimpleClientset(objects ...runtime.Object) *Clientset {
+	o := testing.NewObjectTracker(boundlessclientset.Scheme, boundlessclientset.Codecs.UniversalDecoder())
+	for _, obj := range objects {
+		if err := o.Add(obj); err != nil {
+			panic(err)
+		}
+	}
+
+	cs := &Clientset{tracker: o}
+	cs.AddReactor("*", "*", testing.ObjectReaction(o))
+	cs.AddWatchReactor("*", func(action testing.Action) (bool, watch.Interface, error) {
+		w, err := o.Watch(action.GetResource(), action.GetNamespace())
+		if err != nil {
+			return false, nil, err
+		}
+		return true, w, nil
+	})
+
+	return cs
+}
+
+// Tracker returns the object tracker of the fake client
+func (c *Clientset) Tracker() testing.ObjectTracker {
+	return c.tracker
+}
+
+// RESTClient returns nil, as the fake client does not talk to an API server
+func (c *Clientset) RESTClient() rest.Interface {
+	var ret *rest.RESTClient
+	return ret
+}
+
+// Blueprints returns the fake client for the Blueprints of the namespace
+func (c *Clientset) Blueprints(namespace string) boundlessclientset.BlueprintInterface {
+	return newFakeClient(&c.Fake, "blueprints", "Blueprint", namespace,
+		func() *v1alpha1.Blueprint { return &v1alpha1.Blueprint{} },
+		func() *v1alpha1.BlueprintList { return &v1alpha1.BlueprintList{} },
+	)
+}
+
+// Addons returns the fake client for the Addons of the namespace
+func (c *Clientset) Addons(namespace string) boundlessclientset.AddonInterface {
+	return newFakeClient(&c.Fake, "addons", "Addon", namespace,
+		func() *v1alpha1.Addon { return &v1alpha1.Addon{} },
+		func() *v1alpha1.AddonList { return &v1alpha1.AddonList{} },
+	)
+}
+
+// Manifests returns the fake client for the Manifests of the namespace
+func (c *Clientset) Manifests(namespace string) boundlessclientset.ManifestInterface {
+	return newFakeClient(&c.Fake, "manifests", "Manifest", namespace,
+		func() *v1alpha1.Manifest { return &v1alpha1.Manifest{} },
+		func() *v1alpha1.ManifestList { return &v1alpha1.ManifestList{} },
+	)
+}
+
+// Installations returns the fake client for the Installations of the namespace
+func (c *Clientset) Installations(namespace string) boundlessclientset.InstallationInterface {
+	return newFakeClient(&c.Fake, "installations", "Installation", namespace,
+		func() *v1alpha1.Installation { return &v1alpha1.Installation{} },
+		func() *v1alpha1.InstallationList { return &v1alpha1.InstallationList{} },
+	)
+}
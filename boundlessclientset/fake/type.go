@@ -0,0 +1,119 @@
+package fake
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/testing"
+
+	"github.com/mirantiscontainers/blueprint-operator/api/v1alpha1"
+)
+
+type object interface {
+	runtime.Object
+	metav1.Object
+}
+
+// fakeClient implements the typed client of a kind by invoking the actions on a testing.Fake
+type fakeClient[T object, L runtime.Object] struct {
+	fake      *testing.Fake
+	resource  schema.GroupVersionResource
+	kind      schema.GroupVersionKind
+	namespace string
+	newObject func() T
+	newList   func() L
+}
+
+func newFakeClient[T object, L runtime.Object](fake *testing.Fake, resource string, kind string, namespace string, newObject func() T, newList func() L) *fakeClient[T, L] {
+	return &fakeClient[T, L]{
+		fake:      fake,
+		resource:  v1alpha1.GroupVersion.WithResource(resource),
+		kind:      v1alpha1.GroupVersion.WithKind(kind),
+		namespace: namespace,
+		newObject: newObject,
+		newList:   newList,
+	}
+}
+
+func (c *fakeClient[T, L]) Create(ctx context.Context, obj T, opts metav1.CreateOptions) (T, error) {
+	return c.invoke(testing.NewCreateActionWithOptions(c.resource, c.namespace, obj, opts))
+}
+
+func (c *fakeClient[T, L]) Update(ctx context.Context, obj T, opts metav1.UpdateOptions) (T, error) {
+	return c.invoke(testing.NewUpdateActionWithOptions(c.resource, c.namespace, obj, opts))
+}
+
+func (c *fakeClient[T, L]) UpdateStatus(ctx context.Context, obj T, opts metav1.UpdateOptions) (T, error) {
+	return c.invoke(testing.NewUpdateSubresourceActionWithOptions(c.resource, "status", c.namespace, obj, opts))
+}
+
+func (c *fakeClient[T, L]) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	_, err := c.fake.Invokes(testing.NewDeleteActionWithOptions(c.resource, c.namespace, name, opts), c.newObject())
+	return err
+}
+
+func (c *fakeClient[T, L]) DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error {
+	_, err := c.fake.Invokes(testing.NewDeleteCollectionActionWithOptions(c.resource, c.namespace, opts, listOpts), c.newList())
+	return err
+}
+
+func (c *fakeClient[T, L]) Get(ctx context.Context, name string, opts metav1.GetOptions) (T, error) {
+	return c.invoke(testing.NewGetActionWithOptions(c.resource, c.namespace, name, opts))
+}
+
+// List returns the objects of the namespace that match the label selector of the options
+func (c *fakeClient[T, L]) List(ctx context.Context, opts metav1.ListOptions) (L, error) {
+	var empty L
+	obj, err := c.fake.Invokes(testing.NewListActionWithOptions(c.resource, c.kind, c.namespace, opts), c.newList())
+	if obj == nil {
+		return empty, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+
+	items, err := meta.ExtractList(obj)
+	if err != nil {
+		return empty, err
+	}
+	var matching []runtime.Object
+	for _, item := range items {
+		accessor, err := meta.Accessor(item)
+		if err != nil {
+			return empty, err
+		}
+		if label.Matches(labels.Set(accessor.GetLabels())) {
+			matching = append(matching, item)
+		}
+	}
+	if err = meta.SetList(obj, matching); err != nil {
+		return empty, err
+	}
+
+	return obj.(L), nil
+}
+
+func (c *fakeClient[T, L]) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	return c.fake.InvokesWatch(testing.NewWatchActionWithOptions(c.resource, c.namespace, opts))
+}
+
+func (c *fakeClient[T, L]) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (T, error) {
+	return c.invoke(testing.NewPatchSubresourceActionWithOptions(c.resource, c.namespace, name, pt, data, opts, subresources...))
+}
+
+func (c *fakeClient[T, L]) invoke(action testing.Action) (T, error) {
+	var empty T
+	obj, err := c.fake.Invokes(action, c.newObject())
+	if obj == nil {
+		return empty, err
+	}
+	return obj.(T), err
+}
@@ -0,0 +1,55 @@
+package boundlessclientset
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/mirantiscontainers/blueprint-operator/api/v1alpha1"
+)
+
+// listWatcher is the part of the typed clients used by the informers
+type listWatcher[L runtime.Object] interface {
+	List(ctx context.Context, opts metav1.ListOptions) (L, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+}
+
+func newInformer[L runtime.Object](client listWatcher[L], obj runtime.Object, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return client.List(context.TODO(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return client.Watch(context.TODO(), options)
+			},
+		},
+		obj,
+		resyncPeriod,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)
+}
+
+// NewBlueprintInformer returns an informer for the Blueprints of the namespace; use metav1.NamespaceAll for all namespaces
+func NewBlueprintInformer(client BoundlessV1Alpha1Interface, namespace string, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return newInformer[*v1alpha1.BlueprintList](client.Blueprints(namespace), &v1alpha1.Blueprint{}, resyncPeriod)
+}
+
+// NewAddonInformer returns an informer for the Addons of the namespace; use metav1.NamespaceAll for all namespaces
+func NewAddonInformer(client BoundlessV1Alpha1Interface, namespace string, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return newInformer[*v1alpha1.AddonList](client.Addons(namespace), &v1alpha1.Addon{}, resyncPeriod)
+}
+
+// NewManifestInformer returns an informer for the Manifests of the namespace; use metav1.NamespaceAll for all namespaces
+func NewManifestInformer(client BoundlessV1Alpha1Interface, namespace string, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return newInformer[*v1alpha1.ManifestList](client.Manifests(namespace), &v1alpha1.Manifest{}, resyncPeriod)
+}
+
+// NewInstallationInformer returns an informer for the Installations of the namespace; use metav1.NamespaceAll for all namespaces
+func NewInstallationInformer(client BoundlessV1Alpha1Interface, namespace string, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return newInformer[*v1alpha1.InstallationList](client.Installations(namespace), &v1alpha1.Installation{}, resyncPeriod)
+}
@@ -0,0 +1,43 @@
+package boundlessclientset
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/gentype"
+
+	"github.com/mirantiscontainers/blueprint-operator/api/v1alpha1"
+)
+
+// InstallationInterface is an interface containing the operations that can be done on Installations
+type InstallationInterface interface {
+	Create(ctx context.Context, installation *v1alpha1.Installation, opts metav1.CreateOptions) (*v1alpha1.Installation, error)
+	Update(ctx context.Context, installation *v1alpha1.Installation, opts metav1.UpdateOptions) (*v1alpha1.Installation, error)
+	UpdateStatus(ctx context.Context, installation *v1alpha1.Installation, opts metav1.UpdateOptions) (*v1alpha1.Installation, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1alpha1.Installation, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*v1alpha1.InstallationList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*v1alpha1.Installation, error)
+}
+
+// installations implements InstallationInterface
+type installations struct {
+	*gentype.ClientWithList[*v1alpha1.Installation, *v1alpha1.InstallationList]
+}
+
+func newInstallations(c *BoundlessV1Alpha1Client, namespace string) *installations {
+	return &installations{
+		gentype.NewClientWithList[*v1alpha1.Installation, *v1alpha1.InstallationList](
+			"installations",
+			c.RESTClient(),
+			ParameterCodec,
+			namespace,
+			func() *v1alpha1.Installation { return &v1alpha1.Installation{} },
+			func() *v1alpha1.InstallationList { return &v1alpha1.InstallationList{} },
+		),
+	}
+}
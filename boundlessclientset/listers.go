@@ -0,0 +1,63 @@
+package boundlessclientset
+
+import (
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/listers"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/mirantiscontainers/blueprint-operator/api/v1alpha1"
+)
+
+// Lister lists objects from the indexer of an informer
+type Lister[T runtime.Object] interface {
+	// List lists all objects in the indexer
+	List(selector labels.Selector) ([]T, error)
+	// Namespace returns a lister for the objects of the namespace
+	Namespace(namespace string) NamespaceLister[T]
+}
+
+// NamespaceLister lists and gets the objects of a namespace from the indexer of an informer
+type NamespaceLister[T runtime.Object] interface {
+	List(selector labels.Selector) ([]T, error)
+	Get(name string) (T, error)
+}
+
+type (
+	BlueprintLister    = Lister[*v1alpha1.Blueprint]
+	AddonLister        = Lister[*v1alpha1.Addon]
+	ManifestLister     = Lister[*v1alpha1.Manifest]
+	InstallationLister = Lister[*v1alpha1.Installation]
+)
+
+type lister[T runtime.Object] struct {
+	listers.ResourceIndexer[T]
+}
+
+func (l lister[T]) Namespace(namespace string) NamespaceLister[T] {
+	return listers.NewNamespaced(l.ResourceIndexer, namespace)
+}
+
+func newLister[T runtime.Object](indexer cache.Indexer, resource string) Lister[T] {
+	return lister[T]{listers.New[T](indexer, v1alpha1.GroupVersion.WithResource(resource).GroupResource())}
+}
+
+// NewBlueprintLister returns a lister for the Blueprints of the indexer
+func NewBlueprintLister(indexer cache.Indexer) BlueprintLister {
+	return newLister[*v1alpha1.Blueprint](indexer, "blueprints")
+}
+
+// NewAddonLister returns a lister for the Addons of the indexer
+func NewAddonLister(indexer cache.Indexer) AddonLister {
+	return newLister[*v1alpha1.Addon](indexer, "addons")
+}
+
+// NewManifestLister returns a lister for the Manifests of the indexer
+func NewManifestLister(indexer cache.Indexer) ManifestLister {
+	return newLister[*v1alpha1.Manifest](indexer, "manifests")
+}
+
+// NewInstallationLister returns a lister for the Installations of the indexer
+func NewInstallationLister(indexer cache.Indexer) InstallationLister {
+	return newLister[*v1alpha1.Installation](indexer, "installations")
+}
@@ -4,72 +4,40 @@ import (
 	"context"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
-	"k8s.io/client-go/kubernetes/scheme"
-	"k8s.io/client-go/rest"
+	"k8s.io/client-go/gentype"
 
 	"github.com/mirantiscontainers/blueprint-operator/api/v1alpha1"
 )
 
 // ManifestInterface is an interface containing the operations that can be done on Manifests
 type ManifestInterface interface {
-	List(opts metav1.ListOptions) (*v1alpha1.ManifestList, error)
-	Get(name string, options metav1.GetOptions) (*v1alpha1.Manifest, error)
-	Create(addon *v1alpha1.Manifest) (*v1alpha1.Manifest, error)
-	Watch(opts metav1.ListOptions) (watch.Interface, error)
+	Create(ctx context.Context, manifest *v1alpha1.Manifest, opts metav1.CreateOptions) (*v1alpha1.Manifest, error)
+	Update(ctx context.Context, manifest *v1alpha1.Manifest, opts metav1.UpdateOptions) (*v1alpha1.Manifest, error)
+	UpdateStatus(ctx context.Context, manifest *v1alpha1.Manifest, opts metav1.UpdateOptions) (*v1alpha1.Manifest, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1alpha1.Manifest, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*v1alpha1.ManifestList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*v1alpha1.Manifest, error)
 }
 
-type manifestClient struct {
-	restClient rest.Interface
-	namespace  string
+// manifests implements ManifestInterface
+type manifests struct {
+	*gentype.ClientWithList[*v1alpha1.Manifest, *v1alpha1.ManifestList]
 }
 
-func (c *manifestClient) List(opts metav1.ListOptions) (*v1alpha1.ManifestList, error) {
-	result := v1alpha1.ManifestList{}
-	err := c.restClient.
-		Get().
-		Namespace(c.namespace).
-		Resource("manifests").
-		VersionedParams(&opts, scheme.ParameterCodec).
-		Do(context.TODO()).
-		Into(&result)
-
-	return &result, err
-}
-
-func (c *manifestClient) Get(name string, opts metav1.GetOptions) (*v1alpha1.Manifest, error) {
-	result := v1alpha1.Manifest{}
-	err := c.restClient.
-		Get().
-		Namespace(c.namespace).
-		Resource("manifests").
-		Name(name).
-		VersionedParams(&opts, scheme.ParameterCodec).
-		Do(context.TODO()).
-		Into(&result)
-
-	return &result, err
-}
-
-func (c *manifestClient) Create(addon *v1alpha1.Manifest) (*v1alpha1.Manifest, error) {
-	result := v1alpha1.Manifest{}
-	err := c.restClient.
-		Post().
-		Namespace(c.namespace).
-		Resource("manifests").
-		Body(addon).
-		Do(context.TODO()).
-		Into(&result)
-
-	return &result, err
-}
-
-func (c *manifestClient) Watch(opts metav1.ListOptions) (watch.Interface, error) {
-	opts.Watch = true
-	return c.restClient.
-		Get().
-		Namespace(c.namespace).
-		Resource("manifests").
-		VersionedParams(&opts, scheme.ParameterCodec).
-		Watch(context.TODO())
+func newManifests(c *BoundlessV1Alpha1Client, namespace string) *manifests {
+	return &manifests{
+		gentype.NewClientWithList[*v1alpha1.Manifest, *v1alpha1.ManifestList](
+			"manifests",
+			c.RESTClient(),
+			ParameterCodec,
+			namespace,
+			func() *v1alpha1.Manifest { return &v1alpha1.Manifest{} },
+			func() *v1alpha1.ManifestList { return &v1alpha1.ManifestList{} },
+		),
+	}
 }
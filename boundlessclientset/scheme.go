@@ -0,0 +1,24 @@
+package boundlessclientset
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+
+	"github.com/mirantiscontainers/blueprint-operator/api/v1alpha1"
+)
+
+var (
+	// Scheme contains the Blueprint Operator types
+	Scheme = runtime.NewScheme()
+	// Codecs serializes the Blueprint Operator types
+	Codecs = serializer.NewCodecFactory(Scheme)
+	// ParameterCodec encodes the query parameters of the requests
+	ParameterCodec = runtime.NewParameterCodec(Scheme)
+)
+
+func init() {
+	metav1.AddToGroupVersion(Scheme, v1alpha1.GroupVersion)
+	utilruntime.Must(v1alpha1.AddToScheme(Scheme))
+}
@@ -7,21 +7,27 @@ import (
 )
 
 func applyCmd() *cobra.Command {
+	inv := newInvocation()
+
 	cmd := &cobra.Command{
 		Use:     "apply",
 		Short:   "Apply the blueprint to the cluster",
 		Args:    cobra.NoArgs,
-		PreRunE: actions(loadBlueprint, loadKubeConfig),
+		PreRunE: actions(inv.loadBlueprint, inv.loadRegistryCredentials, inv.loadKubeConfig),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			log.Info().Msgf("Applying blueprint at %s", blueprintFlag)
-			return commands.Apply(&blueprint, kubeConfig, false, imageRegistry)
+			log.Info().Msgf("Applying blueprint at %s", inv.blueprintFile)
+			return commands.Apply(&inv.blueprint, inv.kubeConfig, false, inv.imageRegistry, inv.skipPreflight, inv.pruneNodes, inv.force)
 		},
 	}
 
 	flags := cmd.Flags()
-	addBlueprintFileFlags(flags)
-	addKubeFlags(flags)
-	addImageRegistryFlag(flags)
+	inv.addBlueprintFileFlags(flags)
+	inv.addKubeFlags(flags)
+	inv.addImageRegistryFlag(flags)
+	inv.addRegistryCredentialsFlags(flags)
+	inv.addForceFlag(flags)
+	inv.addPruneNodesFlag(flags)
+	flags.BoolVarP(&inv.skipPreflight, "skip-preflight", "", false, "Do not check that the hosts and the cluster are ready for the blueprint before applying it")
 
 	return cmd
 }
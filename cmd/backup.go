@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/commands"
+)
+
+func backupCmd() *cobra.Command {
+	inv := newInvocation()
+	var opts commands.BackupOptions
+
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Back up a k0s cluster",
+		Long: `
+Back up a k0s cluster.
+
+A k0s backup is taken on a controller and downloaded into an archive, together with the blueprint file and the
+Blueprint Operator objects of the cluster. The archive is written to the backup directory, named after the
+blueprint and the time of the backup, e.g. prod-20240501-020000.tar.gz.
+
+With --keep, only the newest archives of the blueprint are kept in the directory, so that the command can be
+scheduled, e.g. with cron. Restore an archive with 'bctl restore --from'.
+`,
+		Args:    cobra.NoArgs,
+		PreRunE: actions(inv.loadBlueprint, inv.loadKubeConfig),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return commands.Backup(&inv.blueprint, inv.blueprintFile, inv.kubeConfig, opts)
+		},
+	}
+
+	flags := cmd.Flags()
+	inv.addBlueprintFileFlags(flags)
+	inv.addKubeFlags(flags)
+	flags.StringVar(&opts.Dir, "dir", ".", "Directory the backup archive is written to")
+	flags.IntVar(&opts.Keep, "keep", 0, "Number of backups of the blueprint to keep in the directory, the older ones are removed (0 keeps all of them)")
+
+	return cmd
+}
+
+func restoreCmd() *cobra.Command {
+	inv := newInvocation()
+	var from string
+
+	cmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Restore a k0s cluster from a backup",
+		Long: `
+Restore a k0s cluster from a backup.
+
+The cluster is rebuilt on the hosts of the blueprint from an archive of 'bctl backup'. The checksums of the
+archive are verified before anything is changed on the hosts. The hosts must not run k0s yet, and
+spec.kubernetes.version must be the k0s version the backup was taken with.
+
+The blueprint of the backed up cluster is part of the archive, as blueprint.yaml.
+`,
+		Args:    cobra.NoArgs,
+		PreRunE: actions(inv.loadBlueprint, inv.loadKubeConfig),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return commands.Restore(&inv.blueprint, inv.kubeConfig, from, inv.skipPreflight)
+		},
+	}
+
+	flags := cmd.Flags()
+	inv.addBlueprintFileFlags(flags)
+	inv.addKubeFlags(flags)
+	flags.StringVar(&from, "from", "", "Backup archive to restore")
+	flags.BoolVarP(&inv.skipPreflight, "skip-preflight", "", false, "Do not check that the hosts are ready for k0s before restoring")
+	_ = cmd.MarkFlagRequired("from")
+
+	return cmd
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/commands"
+)
+
+func diffCmd() *cobra.Command {
+	inv := newInvocation()
+
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Show what applying the blueprint would change on the cluster",
+		Long: `
+Show what applying the blueprint would change on the cluster.
+
+The changes include a Kubernetes upgrade, the addons added, changed or removed, and the settings of
+spec.operator that the live Blueprint Operator deployment has drifted from.
+`,
+		Args:    cobra.NoArgs,
+		PreRunE: actions(inv.loadBlueprint, inv.loadKubeConfig),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return commands.Diff(&inv.blueprint, inv.kubeConfig, os.Stdout)
+		},
+	}
+
+	flags := cmd.Flags()
+	inv.addBlueprintFileFlags(flags)
+	inv.addKubeFlags(flags)
+
+	return cmd
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/commands"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/utils"
+)
+
+// fleetInvocation is the state of a single fleet command invocation
+type fleetInvocation struct {
+	fleetFile string
+	fleet     types.Fleet
+	opts      commands.FleetOptions
+}
+
+func fleetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fleet",
+		Short: "Manage a fleet of clusters that share a blueprint",
+		Long: `
+Manage a fleet of clusters that share a blueprint.
+
+The fleet file lists the clusters with their kubeconfig and context, and the overrides merged into the blueprint
+for each of them. The clusters are rolled out in waves, in the order of spec.waves, followed by the clusters
+without a wave. Put a canary cluster in the first wave: once a cluster fails, no other cluster is started.
+
+Example fleet file:
+
+  apiVersion: blueprint.mirantis.com/v1alpha1
+  kind: Fleet
+  metadata:
+    name: production
+  spec:
+    blueprint: blueprint.yaml
+    concurrency: 5
+    waves: [canary, rest]
+    clusters:
+      - name: eu-1
+        kubeconfig: kubeconfigs/eu-1.yaml
+        wave: canary
+      - name: us-1
+        context: us-1-admin
+        wave: rest
+        overrides:
+          spec:
+            components:
+              addons:
+                - name: ingress
+                  chart:
+                    version: 4.10.0
+`,
+		Args: cobra.NoArgs,
+		RunE: runHelp,
+	}
+
+	cmd.AddCommand(
+		fleetApplyCmd(),
+		fleetDiffCmd(),
+		fleetStatusCmd(),
+	)
+
+	return cmd
+}
+
+func fleetApplyCmd() *cobra.Command {
+	inv := &fleetInvocation{}
+
+	cmd := &cobra.Command{
+		Use:     "apply",
+		Short:   "Apply the blueprint to the clusters of the fleet",
+		Args:    cobra.NoArgs,
+		PreRunE: inv.loadFleet,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Info().Msgf("Applying fleet at %s", inv.fleetFile)
+			return commands.FleetApply(&inv.fleet, inv.opts)
+		},
+	}
+
+	flags := cmd.Flags()
+	inv.addFleetFlags(flags)
+	flags.BoolVarP(&inv.opts.ContinueOnError, "continue-on-error", "", false, "Keep rolling out the blueprint after it failed on a cluster")
+	flags.DurationVarP(&inv.opts.HealthTimeout, "health-timeout", "", 10*time.Minute, "Time each cluster has to become healthy before the rollout continues")
+	flags.StringVarP(&inv.opts.ImageRegistry, "image-registry", "", "", "Image registry to pull BOP images from")
+	flags.BoolVarP(&inv.opts.PruneNodes, "prune-nodes", "", false, "Drain and delete the nodes of the hosts removed from the blueprint, and reset k0s on the hosts")
+	flags.BoolVarP(&inv.opts.Force, "force", "", false, "Remove the nodes of the hosts removed from the blueprint without a confirmation")
+	flags.BoolVarP(&inv.opts.SkipPreflight, "skip-preflight", "", false, "Do not check that the clusters are ready for the blueprint before applying it")
+
+	return cmd
+}
+
+func fleetDiffCmd() *cobra.Command {
+	inv := &fleetInvocation{}
+
+	cmd := &cobra.Command{
+		Use:     "diff",
+		Short:   "Show what applying the blueprint would change on the clusters of the fleet",
+		Args:    cobra.NoArgs,
+		PreRunE: inv.loadFleet,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return commands.FleetDiff(&inv.fleet, inv.opts)
+		},
+	}
+
+	inv.addFleetFlags(cmd.Flags())
+
+	return cmd
+}
+
+func fleetStatusCmd() *cobra.Command {
+	inv := &fleetInvocation{}
+
+	cmd := &cobra.Command{
+		Use:     "status",
+		Short:   "Get the status of the blueprint on the clusters of the fleet",
+		Args:    cobra.NoArgs,
+		PreRunE: inv.loadFleet,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return commands.FleetStatus(&inv.fleet, inv.opts)
+		},
+	}
+
+	inv.addFleetFlags(cmd.Flags())
+
+	return cmd
+}
+
+func (inv *fleetInvocation) loadFleet(cmd *cobra.Command, args []string) error {
+	var err error
+	log.Debug().Msgf("Loading fleet from %q", inv.fleetFile)
+	if inv.fleet, err = utils.LoadFleet(inv.fleetFile); err != nil {
+		return fmt.Errorf("failed to load fleet file at %q: %w", inv.fleetFile, err)
+	}
+	return nil
+}
+
+func (inv *fleetInvocation) addFleetFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&inv.fleetFile, "file", "f", utils.DefaultFleetPath, "Path to the fleet file")
+	flags.IntVarP(&inv.opts.Concurrency, "concurrency", "", 0, "Number of clusters of a wave to work on at the same time (overrides spec.concurrency)")
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/commands"
+)
+
+func hostsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hosts",
+		Short: "Manage the hosts of a k0s blueprint",
+		Args:  cobra.NoArgs,
+		RunE:  runHelp,
+	}
+
+	cmd.AddCommand(hostsCheckCmd(), hostsImportCmd())
+	return cmd
+}
+
+func hostsCheckCmd() *cobra.Command {
+	inv := newInvocation()
+
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Check that the hosts of the blueprint can run k0s",
+		Long: `
+Check that the hosts of the blueprint can run k0s.
+
+Every host is checked over SSH, in parallel: the operating system and architecture, the kernel modules and
+parameters of the workers, the ports k0s listens on for the role of the host, the memory and disk space, the
+clock and its synchronization, and the connections between the controllers on ports 6443, 9443 and 2380.
+The results are printed in a matrix of the hosts and the checks. The same checks run before 'bctl apply'.
+`,
+		Args:    cobra.NoArgs,
+		PreRunE: actions(inv.loadBlueprint),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return commands.HostsCheck(&inv.blueprint)
+		},
+	}
+
+	flags := cmd.Flags()
+	inv.addBlueprintFileFlags(flags)
+
+	return cmd
+}
+
+func hostsImportCmd() *cobra.Command {
+	inv := newInvocation()
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Print the hosts of the blueprint imported from spec.kubernetes.infra.hostsFrom",
+		Long: `
+Print the hosts of the blueprint imported from spec.kubernetes.infra.hostsFrom.
+
+The hosts are read from the outputs of a Terraform state, or of 'terraform output -json', or from an Ansible
+inventory in the INI or YAML format. Each host gets the role of its groups in hostsFrom.roles, and the SSH
+settings the inventory does not set are taken from hostsFrom.ssh. The imported hosts are checked like the hosts
+of the blueprint, and are printed with them as the hosts field of spec.kubernetes.infra.
+`,
+		Args:    cobra.NoArgs,
+		PreRunE: actions(inv.loadBlueprint),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return commands.HostsImport(&inv.blueprint, os.Stdout)
+		},
+	}
+
+	flags := cmd.Flags()
+	inv.addBlueprintFileFlags(flags)
+
+	return cmd
+}
@@ -1,64 +1,49 @@
 package cmd
 
 import (
-	"fmt"
-	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
-	"github.com/mirantiscontainers/blueprint-cli/pkg/distro"
-	"github.com/mirantiscontainers/blueprint-cli/pkg/utils"
-	"github.com/rs/zerolog/log"
+	"time"
+
 	"github.com/spf13/cobra"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/commands"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
 )
 
 func kubeConfigCmd() *cobra.Command {
+	inv := newInvocation()
+	var opts commands.KubeConfigOptions
+
 	cmd := &cobra.Command{
-		Use:     "kubeconfig",
-		Short:   "Generate kubeconfig file for the Blueprint",
+		Use:   "kubeconfig",
+		Short: "Generate kubeconfig file for the Blueprint",
+		Long: `
+Generate the kubeconfig file for the Blueprint cluster.
+
+By default, the kubeconfig is printed to stdout. Use --output to write it to a file,
+or --merge to merge it into the kubeconfig used by bctl (e.g. ~/.kube/config) and make it the current context.
+
+For a cluster with an external Kubernetes provider, a flattened copy of the current context is generated.
+
+Use --service-account to generate a kubeconfig for CI that authenticates with the token of a dedicated
+service account, which is only granted the given cluster role in its namespace.
+`,
 		Args:    cobra.NoArgs,
-		PreRunE: actions(loadBlueprint),
+		PreRunE: actions(inv.loadBlueprint, inv.loadKubeConfig),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runKubeConfig()
+			return commands.KubeConfig(&inv.blueprint, inv.kubeConfig, opts)
 		},
 	}
 
 	flags := cmd.Flags()
-	addBlueprintFileFlags(flags)
+	inv.addBlueprintFileFlags(flags)
+	inv.addKubeFlags(flags)
+	flags.StringVarP(&opts.Output, "output", "o", "", "Write the kubeconfig to this file instead of stdout")
+	flags.BoolVarP(&opts.Merge, "merge", "", false, "Merge the kubeconfig into the kubeconfig file and switch to its context")
+	flags.BoolVarP(&opts.Minify, "minify", "", false, "Remove everything but the context of the Blueprint cluster from the kubeconfig")
+	flags.StringVarP(&opts.ServiceAccount.Name, "service-account", "", "", "Generate a kubeconfig with the token of this service account, creating it if needed")
+	flags.StringVarP(&opts.ServiceAccount.Namespace, "service-account-namespace", "", constants.NamespaceBlueprint, "Namespace of the service account, to which its rights are limited")
+	flags.StringVarP(&opts.ServiceAccount.ClusterRole, "service-account-role", "", "view", "Cluster role granted to the service account in its namespace")
+	flags.DurationVarP(&opts.ServiceAccount.Duration, "service-account-token-duration", "", 24*time.Hour, "Validity of the service account token")
 
 	return cmd
 }
-func runKubeConfig() error {
-	// Determine the distro
-	provider, err := distro.GetProvider(&blueprint, kubeConfig)
-	if err != nil {
-		return fmt.Errorf("failed to determine kubernetes provider: %w", err)
-	}
-
-	// Check if the cluster exists
-	exists, err := provider.Exists()
-	if err != nil {
-		return fmt.Errorf("failed to check if cluster exists: %w", err)
-	}
-
-	if !exists {
-		return fmt.Errorf("cluster doesn't exist: %s", blueprint.Metadata.Name)
-	}
-
-	if provider.Type() == constants.ProviderK0s {
-		k0sConfig, err := distro.CreateTempK0sConfig(&blueprint)
-		if err != nil {
-			log.Fatal().Err(err).Msg("failed to get k0s config path")
-		}
-
-		if err := utils.ExecCommand(fmt.Sprintf("k0sctl kubeconfig --config %s", k0sConfig)); err != nil {
-			return fmt.Errorf("failed to get kubeconfig for k0s cluster %s : %w ", blueprint.Metadata.Name, err)
-		}
-
-	} else if provider.Type() == constants.ProviderKind {
-		if err := utils.ExecCommand(fmt.Sprintf("kind get kubeconfig --name %s", blueprint.Metadata.Name)); err != nil {
-			return fmt.Errorf("failed to get kubeconfig for kind cluster %s : %w ", blueprint.Metadata.Name, err)
-		}
-	} else if provider.Type() == constants.ProviderExisting {
-		return fmt.Errorf("provider: %s not supported.", constants.ProviderExisting)
-	}
-
-	return nil
-}
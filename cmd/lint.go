@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/commands"
+)
+
+func lintCmd() *cobra.Command {
+	inv := newInvocation()
+
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Check the blueprint offline",
+		Long: `
+Check the blueprint offline, without connecting to the hosts or to the cluster.
+
+The blueprint is validated like before 'bctl apply', and warnings are printed for what is valid but could fail
+to install, like a k0s version that the Blueprint Operator version of the blueprint does not support, or a field
+of the k0s config that bctl does not know.
+`,
+		Args:    cobra.NoArgs,
+		PreRunE: actions(inv.loadBlueprint),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, warning := range commands.Lint(&inv.blueprint) {
+				log.Warn().Msg(warning)
+			}
+			log.Info().Msgf("The blueprint %s is valid", inv.blueprintFile)
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	inv.addBlueprintFileFlags(flags)
+
+	return cmd
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/commands"
+)
+
+func preflightCmd() *cobra.Command {
+	inv := newInvocation()
+
+	cmd := &cobra.Command{
+		Use:   "preflight",
+		Short: "Check that the cluster is ready for the blueprint",
+		Long: `
+Check that the cluster is ready for the blueprint to be applied.
+
+The checks cover API server reachability and TLS, the Kubernetes version, the permissions needed to install
+the Blueprint Operator, the default StorageClass, CoreDNS, the cert-manager CRDs used by the blueprint resources
+and the state of the blueprint-system namespace. The same checks run before 'bctl apply'.
+`,
+		Args:    cobra.NoArgs,
+		PreRunE: actions(inv.loadBlueprint, inv.loadKubeConfig),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return commands.Preflight(&inv.blueprint, inv.kubeConfig)
+		},
+	}
+
+	flags := cmd.Flags()
+	inv.addBlueprintFileFlags(flags)
+	inv.addKubeFlags(flags)
+
+	return cmd
+}
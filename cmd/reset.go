@@ -9,6 +9,8 @@ import (
 
 // resetCmd represents the apply command
 func resetCmd() *cobra.Command {
+	inv := newInvocation()
+
 	cmd := &cobra.Command{
 		Use:   "reset",
 		Short: "Reset the cluster to a clean state",
@@ -20,16 +22,16 @@ For a cluster with kind, it will delete the cluster (same as 'kind delete cluste
 For a cluster with an external Kubernetes provider, this will remove Blueprint Operator and all associated resources.
 `,
 		Args:    cobra.NoArgs,
-		PreRunE: actions(loadBlueprint, loadKubeConfig),
+		PreRunE: actions(inv.loadBlueprint, inv.loadKubeConfig),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			log.Info().Msgf("Resetting blueprint at %s", blueprintFlag)
-			return commands.Reset(&blueprint, kubeConfig, force)
+			log.Info().Msgf("Resetting blueprint at %s", inv.blueprintFile)
+			return commands.Reset(&inv.blueprint, inv.kubeConfig, inv.force)
 		},
 	}
 
 	flags := cmd.Flags()
-	addBlueprintFileFlags(flags)
-	addKubeFlags(flags)
-	addForceFlag(flags)
+	inv.addBlueprintFileFlags(flags)
+	inv.addKubeFlags(flags)
+	inv.addForceFlag(flags)
 	return cmd
 }
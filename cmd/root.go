@@ -5,8 +5,8 @@ import (
 
 	"github.com/mattn/go-colorable"
 	"github.com/mirantiscontainers/blueprint-cli/internal/logger"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/commands"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
-	"github.com/mirantiscontainers/blueprint-cli/pkg/distro"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/k8s"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/utils"
@@ -22,13 +22,7 @@ const (
 )
 
 var (
-	pFlags        *PersistenceFlags
-	blueprintFlag string
-	force         bool
-	imageRegistry string
-
-	blueprint  types.Blueprint
-	kubeConfig *k8s.KubeConfig
+	pFlags *PersistenceFlags
 
 	rootCmd = &cobra.Command{
 		Use:   appName,
@@ -41,8 +35,7 @@ var (
 		SilenceUsage: true,
 	}
 
-	kubeFlags = genericclioptions.NewConfigFlags(k8s.UsePersistentConfig)
-	out       = colorable.NewColorableStdout()
+	out = colorable.NewColorableStdout()
 )
 
 func init() {
@@ -54,8 +47,16 @@ func init() {
 		resetCmd(),
 		upgradeCmd(),
 		statusCmd(),
+		diffCmd(),
 		kubeConfigCmd(),
+		preflightCmd(),
 		verifyCmd(),
+		fleetCmd(),
+		hostsCmd(),
+		backupCmd(),
+		restoreCmd(),
+		versionsCmd(),
+		lintCmd(),
 	)
 
 	pFlags = NewPersistenceFlags()
@@ -75,15 +76,43 @@ func runHelp(cmd *cobra.Command, args []string) error {
 	return cmd.Help()
 }
 
-func loadBlueprint(cmd *cobra.Command, args []string) error {
+// invocation is the state of a single command invocation: the blueprint it works on, the cluster it connects to
+// and the flags of the command
+type invocation struct {
+	blueprintFile string
+	blueprint     types.Blueprint
+	kubeFlags     *genericclioptions.ConfigFlags
+	kubeConfig    *k8s.KubeConfig
+
+	force         bool
+	imageRegistry string
+
+	registryDockerConfig  string
+	registryUsernameEnv   string
+	registryPasswordEnv   string
+	copyPullSecretToAddon bool
+	noRollback            bool
+	prune                 bool
+	pruneDryRun           bool
+	pruneNodes            bool
+	skipPreflight         bool
+}
+
+func newInvocation() *invocation {
+	return &invocation{
+		kubeFlags: genericclioptions.NewConfigFlags(k8s.UsePersistentConfig),
+	}
+}
+
+func (inv *invocation) loadBlueprint(cmd *cobra.Command, args []string) error {
 	var err error
-	log.Debug().Msgf("Loading blueprint from %q", blueprintFlag)
-	if blueprint, err = utils.LoadBlueprint(blueprintFlag); err != nil {
-		return fmt.Errorf("failed to load blueprint file at %q: %w", blueprintFlag, err)
+	log.Debug().Msgf("Loading blueprint from %q", inv.blueprintFile)
+	if inv.blueprint, err = utils.LoadBlueprint(inv.blueprintFile); err != nil {
+		return fmt.Errorf("failed to load blueprint file at %q: %w", inv.blueprintFile, err)
 	}
 
 	// Validate the blueprint
-	if err := blueprint.Validate(); err != nil {
+	if err := inv.blueprint.Validate(); err != nil {
 		return err
 	}
 
@@ -92,71 +121,84 @@ func loadBlueprint(cmd *cobra.Command, args []string) error {
 
 // loadKubeConfig loads the kubeconfig file
 // This function should be added as a pre-run hook for all commands that connects to the cluster
-// kubeconfig priority:
-// 1. Explicit file provided to bctl with --kubeconfig flag
-// 2. Blueprint kubeconfig location
-// 3. File from the environment variable KUBECONFIG
-// 4. File located at default location (e.g. ~/.kube/config)
-func loadKubeConfig(cmd *cobra.Command, args []string) error {
-
-	// Default to an existing cluster if the blueprint does not have a Kubernetes section
-	if blueprint.Spec.Kubernetes == nil {
-		log.Debug().Msg("No Kubernetes section found in blueprint, defaulting to an existing cluster")
-		blueprint.Spec.Kubernetes = &types.Kubernetes{
-			Provider: constants.ProviderExisting,
-		}
-	}
-
-	// Determine the distro
-	provider, err := distro.GetProvider(&blueprint, kubeConfig)
-	if err != nil {
-		return fmt.Errorf("failed to determine kubernetes provider: %w", err)
-	}
-
-	// If the kubeconfig flag is not used and the blueprint kubeconfig is not empty, use the kubeconfig from the blueprint
-	if kubeFlags.KubeConfig != nil && *kubeFlags.KubeConfig == "" && blueprint.Spec.Kubernetes.KubeConfig != "" {
-		log.Debug().Msg("Using kubeconfig file specified in blueprint")
-		kubeFlags.KubeConfig = strPtr(blueprint.Spec.Kubernetes.KubeConfig)
-	}
-
-	// unless context flag is passed, explicitly set the context to use for kubeconfig
-	if kubeFlags.Context == nil || *kubeFlags.Context == "" {
-		context := provider.GetKubeConfigContext()
-		kubeFlags.Context = &context
+// See commands.NewKubeConfig for the priority of the kubeconfig locations
+func (inv *invocation) loadKubeConfig(cmd *cobra.Command, args []string) error {
+	var err error
+	if inv.kubeConfig, err = commands.NewKubeConfig(&inv.blueprint, inv.kubeFlags); err != nil {
+		return err
 	}
 
-	// The remaining kubeconfig options (3&4) are handled by the genericclioptions library
-
-	kubeConfig = k8s.NewConfig(kubeFlags)
-
 	// TODO (ranyodh): remove this hack
 	// This is a hack to ensure that the kubeconfig file is not loaded for apply command
 	// because the cluster is not yet created at this point
-	if cmd.Name() == "apply" {
+	// The kubeconfig command generates the kubeconfig, so the file may not exist yet either, and neither does a restored cluster
+	if cmd.Name() == "apply" || cmd.Name() == "kubeconfig" || cmd.Name() == "restore" {
 		return nil
 	}
 
-	log.Debug().Msgf("Loading kubeconfig from %q", kubeConfig.GetConfigPath())
+	log.Debug().Msgf("Loading kubeconfig from %q", inv.kubeConfig.GetConfigPath())
 	// Try to load kubeconfig file here, and fail early if it is not present
-	if err := kubeConfig.TryLoad(); err != nil {
+	if err := inv.kubeConfig.TryLoad(); err != nil {
 		return err
 	}
 	return nil
 }
 
-func addForceFlag(flags *pflag.FlagSet) {
-	flags.BoolVarP(&force, "force", "", false, "Bypass user confirmation for a command")
+func (inv *invocation) addForceFlag(flags *pflag.FlagSet) {
+	flags.BoolVarP(&inv.force, "force", "", false, "Bypass user confirmation for a command")
+}
+
+func (inv *invocation) addPruneNodesFlag(flags *pflag.FlagSet) {
+	flags.BoolVarP(&inv.pruneNodes, "prune-nodes", "", false, "Drain and delete the nodes of the hosts removed from the blueprint, and reset k0s on the hosts")
+}
+
+func (inv *invocation) addBlueprintFileFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&inv.blueprintFile, "file", "f", constants.DefaultBlueprintFileName, "Path to the blueprint file")
 }
 
-func addBlueprintFileFlags(flags *pflag.FlagSet) {
-	flags.StringVarP(&blueprintFlag, "file", "f", constants.DefaultBlueprintFileName, "Path to the blueprint file")
+func (inv *invocation) addImageRegistryFlag(flags *pflag.FlagSet) {
+	flags.StringVarP(&inv.imageRegistry, "image-registry", "", "", "Image registry to pull BOP images from")
 }
 
-func addImageRegistryFlag(flags *pflag.FlagSet) {
-	flags.StringVarP(&imageRegistry, "image-registry", "", "", "Image registry to pull BOP images from")
+func (inv *invocation) addRegistryCredentialsFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&inv.registryDockerConfig, "registry-docker-config", "", "", "Path to a docker config.json with credentials for the image registry")
+	flags.StringVarP(&inv.registryUsernameEnv, "registry-username-env", "", "", "Environment variable holding the image registry username")
+	flags.StringVarP(&inv.registryPasswordEnv, "registry-password-env", "", "", "Environment variable holding the image registry password")
+	flags.BoolVarP(&inv.copyPullSecretToAddon, "copy-pull-secret", "", false, "Copy the image pull secret into every addon namespace")
+}
+
+// loadRegistryCredentials overrides the blueprint registry credentials with the ones provided by flags
+func (inv *invocation) loadRegistryCredentials(cmd *cobra.Command, args []string) error {
+	if inv.registryDockerConfig == "" && inv.registryUsernameEnv == "" && inv.registryPasswordEnv == "" && !inv.copyPullSecretToAddon {
+		return nil
+	}
+
+	if inv.blueprint.Spec.Registry == nil {
+		inv.blueprint.Spec.Registry = &types.Registry{}
+	}
+	if inv.blueprint.Spec.Registry.Credentials == nil {
+		inv.blueprint.Spec.Registry.Credentials = &types.RegistryCredentials{}
+	}
+
+	creds := inv.blueprint.Spec.Registry.Credentials
+	if inv.registryDockerConfig != "" {
+		creds.DockerConfig = inv.registryDockerConfig
+		creds.UsernameEnv, creds.PasswordEnv = "", ""
+	}
+	if inv.registryUsernameEnv != "" || inv.registryPasswordEnv != "" {
+		creds.UsernameEnv, creds.PasswordEnv = inv.registryUsernameEnv, inv.registryPasswordEnv
+		creds.DockerConfig = ""
+	}
+	if inv.copyPullSecretToAddon {
+		creds.CopyToAddonNamespaces = true
+	}
+
+	return inv.blueprint.Spec.Registry.Validate()
 }
 
-func addKubeFlags(flags *pflag.FlagSet) {
+func (inv *invocation) addKubeFlags(flags *pflag.FlagSet) {
+	kubeFlags := inv.kubeFlags
+
 	// Exposing certain flags from k8s.io/cli-runtime/pkg/genericclioptions
 	// To expose all flags, use kubeFlags.AddFlags(flags)
 	flags.StringVar(kubeFlags.KubeConfig, "kubeconfig", "", "Path to the kubeconfig file to use for CLI requests")
@@ -177,7 +219,3 @@ func addKubeFlags(flags *pflag.FlagSet) {
 
 	flags.StringVar(kubeFlags.BearerToken, "token", "", "Bearer token for authentication to the API server")
 }
-
-func strPtr(s string) *string {
-	return &s
-}
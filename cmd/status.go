@@ -8,24 +8,26 @@ import (
 )
 
 func statusCmd() *cobra.Command {
+	inv := newInvocation()
+
 	cmd := &cobra.Command{
 		Use:     "status",
 		Short:   "Get the status of the blueprint",
 		Args:    cobra.MaximumNArgs(1),
-		PreRunE: actions(loadBlueprint, loadKubeConfig),
+		PreRunE: actions(inv.loadBlueprint, inv.loadKubeConfig),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			log.Info().Msgf("Getting status of blueprint at %s", blueprintFlag)
+			log.Info().Msgf("Getting status of blueprint at %s", inv.blueprintFile)
 			if len(args) > 0 {
-				return commands.AddonSpecificStatus(kubeConfig, args[0])
+				return commands.AddonSpecificStatus(inv.kubeConfig, args[0])
 			}
 
-			return commands.Status(kubeConfig)
+			return commands.Status(&inv.blueprint, inv.kubeConfig)
 		},
 	}
 
 	flags := cmd.Flags()
-	addBlueprintFileFlags(flags)
-	addKubeFlags(flags)
+	inv.addBlueprintFileFlags(flags)
+	inv.addKubeFlags(flags)
 
 	return cmd
 }
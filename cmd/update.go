@@ -2,26 +2,34 @@ package cmd
 
 import (
 	"github.com/mirantiscontainers/blueprint-cli/pkg/commands"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/distro"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
 // updateCmd represents the apply command
 func updateCmd() *cobra.Command {
+	inv := newInvocation()
+	var upgrade distro.UpgradeOptions
+
 	cmd := &cobra.Command{
 		Use:     "update",
 		Short:   "Update the cluster according to the blueprint",
 		Args:    cobra.NoArgs,
-		PreRunE: actions(loadBlueprint, loadKubeConfig),
+		PreRunE: actions(inv.loadBlueprint, inv.loadKubeConfig),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			log.Info().Msgf("Updating blueprint at %s", blueprintFlag)
-			return commands.Update(&blueprint, kubeConfig)
+			log.Info().Msgf("Updating blueprint at %s", inv.blueprintFile)
+			return commands.Update(&inv.blueprint, inv.kubeConfig, inv.pruneNodes, inv.force, upgrade)
 		},
 	}
 
 	flags := cmd.Flags()
-	addBlueprintFileFlags(flags)
-	addKubeFlags(flags)
+	inv.addBlueprintFileFlags(flags)
+	inv.addKubeFlags(flags)
+	inv.addForceFlag(flags)
+	inv.addPruneNodesFlag(flags)
+	flags.IntVarP(&upgrade.WorkerBatchSize, "worker-batch-size", "", 1, "Number of workers upgraded at the same time when the kubernetes version changes")
+	flags.BoolVarP(&upgrade.Resume, "resume", "", false, "Resume an interrupted kubernetes upgrade after the last upgraded node")
 
 	return cmd
 }
@@ -8,21 +8,27 @@ import (
 
 // updateCmd represents the apply command
 func upgradeCmd() *cobra.Command {
+	inv := newInvocation()
+
 	cmd := &cobra.Command{
 		Use:     "upgrade",
 		Short:   "Upgrade blueprint operator on the cluster",
 		Args:    cobra.NoArgs,
-		PreRunE: actions(loadBlueprint, loadKubeConfig),
+		PreRunE: actions(inv.loadBlueprint, inv.loadRegistryCredentials, inv.loadKubeConfig),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			log.Info().Msgf("Upgrading blueprint at %s", blueprintFlag)
-			return commands.Upgrade(&blueprint, kubeConfig, imageRegistry)
+			log.Info().Msgf("Upgrading blueprint at %s", inv.blueprintFile)
+			return commands.Upgrade(&inv.blueprint, inv.kubeConfig, inv.imageRegistry, !inv.noRollback, inv.prune, inv.pruneDryRun)
 		},
 	}
 
 	flags := cmd.Flags()
-	addBlueprintFileFlags(flags)
-	addKubeFlags(flags)
-	addImageRegistryFlag(flags)
+	inv.addBlueprintFileFlags(flags)
+	inv.addKubeFlags(flags)
+	inv.addImageRegistryFlag(flags)
+	inv.addRegistryCredentialsFlags(flags)
+	flags.BoolVarP(&inv.noRollback, "no-rollback", "", false, "Do not restore the previous Blueprint Operator when the upgrade fails health checks")
+	flags.BoolVarP(&inv.prune, "prune", "", true, "Delete the Blueprint Operator objects that were removed from the new manifest")
+	flags.BoolVarP(&inv.pruneDryRun, "prune-dry-run", "", false, "List the objects that would be pruned by the upgrade without changing the cluster")
 
 	return cmd
 }
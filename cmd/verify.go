@@ -7,19 +7,21 @@ import (
 )
 
 func verifyCmd() *cobra.Command {
+	inv := newInvocation()
+
 	cmd := &cobra.Command{
 		Use:     "verify",
 		Short:   "Verifies the blueprint is valid and can be applied to the cluster. Specifically checks helm chart addons",
 		Args:    cobra.NoArgs,
-		PreRunE: actions(loadBlueprint, loadKubeConfig),
+		PreRunE: actions(inv.loadBlueprint, inv.loadKubeConfig),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			log.Info().Msgf("Verifying blueprint at %s", blueprintFlag)
-			return commands.Verify(&blueprint, kubeConfig)
+			log.Info().Msgf("Verifying blueprint at %s", inv.blueprintFile)
+			return commands.Verify(&inv.blueprint, inv.kubeConfig)
 		},
 	}
 
 	flags := cmd.Flags()
-	addBlueprintFileFlags(flags)
+	inv.addBlueprintFileFlags(flags)
 
 	return cmd
 }
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/commands"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+func versionsCmd() *cobra.Command {
+	inv := newInvocation()
+	var all, loaded bool
+
+	cmd := &cobra.Command{
+		Use:   "versions",
+		Short: "List the k0s and Blueprint Operator releases and the combinations supported",
+		Long: `
+List the k0s and Blueprint Operator releases and the combinations supported.
+
+The releases are read from the GitHub releases of k0s and of the Blueprint Operator, and cached for a day. When
+the releases cannot be read, the cached ones are listed, or for k0s, a list embedded in bctl. The versions of
+the blueprint are marked with a '*', when the blueprint file exists.
+`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// the blueprint is optional, unless a file is given
+			if _, err := os.Stat(inv.blueprintFile); err != nil && !cmd.Flags().Changed("file") {
+				return nil
+			}
+			loaded = true
+			return inv.loadBlueprint(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var blueprint *types.Blueprint
+			if loaded {
+				blueprint = &inv.blueprint
+			}
+			return commands.Versions(blueprint, all, os.Stdout)
+		},
+	}
+
+	flags := cmd.Flags()
+	inv.addBlueprintFileFlags(flags)
+	flags.BoolVar(&all, "all", false, "List all the k0s releases, not only the latest patch release of each minor version")
+
+	return cmd
+}
@@ -0,0 +1,251 @@
+// Package backup reads and writes the backup archives of bctl
+// An archive is a tar.gz with the k0s backup of the cluster, the blueprint it was created from,
+// the objects of the Blueprint Operator, and a metadata file with the checksums of all of them.
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// MetadataFile describes the archive; it is written last, once the checksums of the other files are known
+	MetadataFile = "metadata.yaml"
+	// BlueprintFile is the blueprint the cluster was created from
+	BlueprintFile = "blueprint.yaml"
+	// ResourcesFile are the objects of the Blueprint Operator in the cluster
+	ResourcesFile = "resources.yaml"
+	// K0sBackupFile is the archive created by k0s backup on a controller
+	K0sBackupFile = "k0s-backup.tar.gz"
+
+	// timeFormat is the format of the time in the names of the archives; it sorts in chronological order
+	timeFormat = "20060102-150405"
+)
+
+// Metadata describes a backup archive
+type Metadata struct {
+	// Name is the name of the blueprint
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+	// K0sVersion is the version of k0s that created the k0s backup
+	K0sVersion string `json:"k0sVersion,omitempty"`
+	// Checksums are the sha256 sums of the files of the archive, by name
+	Checksums map[string]string `json:"checksums"`
+}
+
+// FileName returns the name of the archive of the blueprint created at the time
+func FileName(name string, created time.Time) string {
+	return fmt.Sprintf("%s-%s.tar.gz", name, created.UTC().Format(timeFormat))
+}
+
+// Writer writes a backup archive
+type Writer struct {
+	gzip      *gzip.Writer
+	tar       *tar.Writer
+	checksums map[string]string
+}
+
+// NewWriter returns a writer of an archive to w
+func NewWriter(w io.Writer) *Writer {
+	gz := gzip.NewWriter(w)
+	return &Writer{gzip: gz, tar: tar.NewWriter(gz), checksums: make(map[string]string)}
+}
+
+// AddBytes adds a file with the data to the archive
+func (w *Writer) AddBytes(name string, data []byte) error {
+	return w.add(name, int64(len(data)), bytes.NewReader(data))
+}
+
+// AddFile adds the local file at path to the archive under the name
+func (w *Writer) AddFile(name string, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	return w.add(name, info.Size(), file)
+}
+
+func (w *Writer) add(name string, size int64, r io.Reader) error {
+	if name == MetadataFile {
+		return fmt.Errorf("%s is written when the archive is closed", MetadataFile)
+	}
+	header := &tar.Header{Name: name, Mode: 0o600, Size: size, ModTime: time.Now()}
+	if err := w.tar.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to add %s to the archive: %w", name, err)
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(w.tar, hash), r); err != nil {
+		return fmt.Errorf("failed to add %s to the archive: %w", name, err)
+	}
+	w.checksums[name] = hex.EncodeToString(hash.Sum(nil))
+	return nil
+}
+
+// Close writes the metadata with the checksums of the files and closes the archive
+func (w *Writer) Close(metadata Metadata) error {
+	metadata.Checksums = w.checksums
+	data, err := yaml.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+
+	header := &tar.Header{Name: MetadataFile, Mode: 0o600, Size: int64(len(data)), ModTime: time.Now()}
+	if err := w.tar.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := w.tar.Write(data); err != nil {
+		return err
+	}
+	if err := w.tar.Close(); err != nil {
+		return err
+	}
+	return w.gzip.Close()
+}
+
+// Extract extracts the archive at path into the directory and verifies its integrity
+// The archive is rejected if it cannot be read, if a file is missing or was not listed in the metadata,
+// or if the checksum of a file does not match, so that a restore never starts from a corrupted backup.
+func Extract(path string, dir string) (*Metadata, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("backup %s is not a gzip archive: %w", path, err)
+	}
+	defer gz.Close()
+
+	var metadata *Metadata
+	checksums := make(map[string]string)
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("backup %s is corrupted: %w", path, err)
+		}
+		if header.Typeflag != tar.TypeReg || header.Name != filepath.Base(header.Name) {
+			return nil, fmt.Errorf("backup %s has an unexpected entry %q", path, header.Name)
+		}
+
+		if header.Name == MetadataFile {
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				return nil, fmt.Errorf("backup %s is corrupted: %w", path, err)
+			}
+			metadata = &Metadata{}
+			if err := yaml.Unmarshal(data, metadata); err != nil {
+				return nil, fmt.Errorf("failed to parse the metadata of backup %s: %w", path, err)
+			}
+			continue
+		}
+
+		checksum, err := extractFile(reader, filepath.Join(dir, header.Name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract %s from backup %s: %w", header.Name, path, err)
+		}
+		checksums[header.Name] = checksum
+	}
+
+	if metadata == nil {
+		return nil, fmt.Errorf("backup %s has no %s, it is incomplete", path, MetadataFile)
+	}
+	if err := verify(metadata.Checksums, checksums); err != nil {
+		return nil, fmt.Errorf("backup %s is corrupted: %w", path, err)
+	}
+	return metadata, nil
+}
+
+// extractFile writes the current file of the archive to path and returns its checksum
+func extractFile(reader io.Reader, path string) (string, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(file, hash), reader); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), file.Close()
+}
+
+// verify compares the checksums of the extracted files with the ones of the metadata
+func verify(want map[string]string, got map[string]string) error {
+	for _, name := range []string{BlueprintFile, ResourcesFile, K0sBackupFile} {
+		if _, ok := want[name]; !ok {
+			return fmt.Errorf("%s is missing", name)
+		}
+	}
+	for name, checksum := range want {
+		actual, ok := got[name]
+		if !ok {
+			return fmt.Errorf("%s is missing", name)
+		}
+		if actual != checksum {
+			return fmt.Errorf("checksum of %s does not match: expected %s, got %s", name, checksum, actual)
+		}
+	}
+	for name := range got {
+		if _, ok := want[name]; !ok {
+			return fmt.Errorf("%s is not part of the backup", name)
+		}
+	}
+	return nil
+}
+
+// Prune removes the oldest archives of the blueprint from the directory, keeping the newest ones
+// It returns the paths of the removed archives.
+func Prune(dir string, name string, keep int) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, name+"-*.tar.gz"))
+	if err != nil {
+		return nil, err
+	}
+
+	// only the archives named by FileName, so that the archives of a blueprint whose name has the same prefix are kept
+	var archives []string
+	for _, match := range matches {
+		created := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(match), name+"-"), ".tar.gz")
+		if _, err := time.Parse(timeFormat, created); err == nil {
+			archives = append(archives, match)
+		}
+	}
+	if len(archives) <= keep {
+		return nil, nil
+	}
+
+	slices.Sort(archives)
+	removed := archives[:len(archives)-keep]
+	for _, archive := range removed {
+		if err := os.Remove(archive); err != nil {
+			return nil, fmt.Errorf("failed to remove old backup %s: %w", archive, err)
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,128 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+// writeArchive writes an archive with the files and returns its path
+func writeArchive(t *testing.T, files map[string]string) string {
+	path := filepath.Join(t.TempDir(), FileName("test", time.Now()))
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	writer := NewWriter(file)
+	for name, data := range files {
+		if err := writer.AddBytes(name, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(Metadata{Name: "test", Created: time.Now(), K0sVersion: "v1.31.1+k0s.0"}); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func backupFiles() map[string]string {
+	return map[string]string{
+		BlueprintFile: "kind: Blueprint\n",
+		ResourcesFile: "items: []\n",
+		K0sBackupFile: "k0s backup",
+	}
+}
+
+// TestExtract tests that an archive is extracted with its metadata and that a corrupted archive is rejected
+func TestExtract(t *testing.T) {
+	g := NewWithT(t)
+
+	path := writeArchive(t, backupFiles())
+	dir := t.TempDir()
+	metadata, err := Extract(path, dir)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(metadata.Name).To(Equal("test"))
+	g.Expect(metadata.K0sVersion).To(Equal("v1.31.1+k0s.0"))
+	g.Expect(metadata.Checksums).To(HaveLen(3))
+
+	data, err := os.ReadFile(filepath.Join(dir, K0sBackupFile))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(data)).To(Equal("k0s backup"))
+
+	// a truncated archive cannot be read
+	content, err := os.ReadFile(path)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(os.WriteFile(path, content[:len(content)/2], 0o600)).To(Succeed())
+	_, err = Extract(path, t.TempDir())
+	g.Expect(err).To(HaveOccurred())
+
+	// an archive without the k0s backup is incomplete
+	files := backupFiles()
+	delete(files, K0sBackupFile)
+	_, err = Extract(writeArchive(t, files), t.TempDir())
+	g.Expect(err).To(MatchError(ContainSubstring("k0s-backup.tar.gz is missing")))
+}
+
+// TestVerify tests that the checksums of the extracted files are compared with the ones of the metadata
+func TestVerify(t *testing.T) {
+	want := map[string]string{BlueprintFile: "a", ResourcesFile: "b", K0sBackupFile: "c"}
+
+	tests := map[string]struct {
+		got     map[string]string
+		wantErr string
+	}{
+		"valid":         {got: map[string]string{BlueprintFile: "a", ResourcesFile: "b", K0sBackupFile: "c"}},
+		"modified file": {got: map[string]string{BlueprintFile: "a", ResourcesFile: "b", K0sBackupFile: "d"}, wantErr: "checksum of k0s-backup.tar.gz does not match"},
+		"missing file":  {got: map[string]string{BlueprintFile: "a", K0sBackupFile: "c"}, wantErr: "resources.yaml is missing"},
+		"extra file":    {got: map[string]string{BlueprintFile: "a", ResourcesFile: "b", K0sBackupFile: "c", "other": "e"}, wantErr: "other is not part of the backup"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+			err := verify(want, tc.got)
+			if tc.wantErr == "" {
+				g.Expect(err).ToNot(HaveOccurred())
+			} else {
+				g.Expect(err).To(MatchError(ContainSubstring(tc.wantErr)))
+			}
+		})
+	}
+}
+
+// TestPrune tests that only the newest archives of the blueprint are kept
+func TestPrune(t *testing.T) {
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+	start := time.Date(2024, 5, 1, 2, 0, 0, 0, time.UTC)
+	var archives []string
+	for day := range 4 {
+		archives = append(archives, FileName("prod", start.AddDate(0, 0, day)))
+	}
+	others := []string{FileName("prod-eu", start), "prod-notes.tar.gz"}
+	for _, name := range append(archives, others...) {
+		g.Expect(os.WriteFile(filepath.Join(dir, name), nil, 0o600)).To(Succeed())
+	}
+
+	removed, err := Prune(dir, "prod", 2)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(removed).To(Equal([]string{filepath.Join(dir, archives[0]), filepath.Join(dir, archives[1])}))
+
+	entries, err := os.ReadDir(dir)
+	g.Expect(err).ToNot(HaveOccurred())
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	g.Expect(names).To(ConsistOf(archives[2], archives[3], others[0], others[1]))
+
+	removed, err = Prune(dir, "prod", 2)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(removed).To(BeEmpty())
+}
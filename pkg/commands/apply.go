@@ -3,29 +3,27 @@ package commands
 import (
 	"context"
 	"fmt"
-	"net"
-	"os"
 	"regexp"
-	"strings"
-	"time"
 
 	"github.com/mirantiscontainers/blueprint-cli/pkg/components"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/distro"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/k8s"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/readiness"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
 
 	"github.com/rs/zerolog/log"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
 )
 
 // Apply installs the Blueprint Operator and applies the components defined in the blueprint
-func Apply(blueprint *types.Blueprint, kubeConfig *k8s.KubeConfig, providerInstallOnly bool, imageRegistry string) error {
+// The preflight checks run before anything is changed in the cluster, unless they are skipped
+// The hosts of a k0s cluster are checked before k0s is installed or refreshed on them, unless the preflight checks are skipped
+// The nodes of the hosts removed from the blueprint are removed from the cluster when pruneNodes is set,
+// after a confirmation unless forced
+func Apply(blueprint *types.Blueprint, kubeConfig *k8s.KubeConfig, providerInstallOnly bool, imageRegistry string, skipPreflight bool, pruneNodes bool, force bool) error {
 	// Determine the distro
 	provider, err := distro.GetProvider(blueprint, kubeConfig)
 	if err != nil {
@@ -44,6 +42,12 @@ func Apply(blueprint *types.Blueprint, kubeConfig *k8s.KubeConfig, providerInsta
 			return fmt.Errorf("cluster %q already exists", blueprint.Metadata.Name)
 		}
 	}
+	if !skipPreflight {
+		if err := HostsCheck(blueprint); err != nil {
+			return fmt.Errorf("hosts are not ready for k0s: %w", err)
+		}
+	}
+
 	if !exists {
 		if err := provider.Install(); err != nil {
 			return fmt.Errorf("failed to install cluster: %w", err)
@@ -73,6 +77,17 @@ func Apply(blueprint *types.Blueprint, kubeConfig *k8s.KubeConfig, providerInsta
 		panic(err)
 	}
 
+	if exists {
+		if err := removeDroppedNodes(blueprint, kubeConfig, pruneNodes, force); err != nil {
+			return fmt.Errorf("failed to remove the nodes of the removed hosts: %w", err)
+		}
+	} else if infra := blueprint.Spec.Kubernetes.Infra; blueprint.Spec.Kubernetes.Provider == constants.ProviderK0s && infra != nil {
+		// the hosts of a new cluster are recorded so that the hosts removed from the blueprint later can be reset
+		if err := recordHosts(context.Background(), k8sclient, infra.Hosts); err != nil {
+			return err
+		}
+	}
+
 	// For existing clusters, determine whether blueprint is currently installed
 	installOperator := true
 	if exists {
@@ -99,50 +114,56 @@ func Apply(blueprint *types.Blueprint, kubeConfig *k8s.KubeConfig, providerInsta
 		}
 	}
 
-	// @todo: display the version of the operator
 	if installOperator {
-		uri, err := determineOperatorUri(blueprint.Spec.Version)
-		if err != nil {
-			return fmt.Errorf("failed to determine operator URI: %w", err)
-		}
-
-		var needCleanup bool
-		uri, needCleanup, err = setImageRegistry(uri, imageRegistry)
+		dynamicClient, err := k8s.GetDynamicClient(kubeConfig)
 		if err != nil {
-			return fmt.Errorf("failed to set image registry in BOP manifest: %w", err)
-		}
-		if needCleanup {
-			defer os.Remove(strings.TrimPrefix(uri, "file://"))
+			return fmt.Errorf("failed to get kubernetes dynamic client: %w", err)
 		}
 
 		log.Info().Msg("Wait for networking pods to be up")
-		if err := k8s.WaitForPods(k8sclient, constants.NamespaceKubeSystem); err != nil {
+		if err := readiness.NewWaiter(dynamicClient).WaitForNamespace(context.Background(), constants.NamespaceKubeSystem); err != nil {
 			return fmt.Errorf("failed to wait for pods in %s namespace: %w", constants.NamespaceKubeSystem, err)
 		}
+	}
 
-		// Check network connectivity
-		if err := testClusterConnectivity(kubeConfig); err != nil {
-			return fmt.Errorf("failed to test cluster connectivity: %w", err)
+	if !skipPreflight {
+		if err := Preflight(blueprint, kubeConfig); err != nil {
+			return fmt.Errorf("cluster is not ready for the blueprint: %w", err)
 		}
+	}
 
-		log.Info().Msgf("Installing Blueprint Operator")
-		log.Debug().Msgf("Installing Blueprint Operator using manifest file: %s", blueprint.Spec.Version)
-
-		var client kubernetes.Interface
-		var dynamicClient dynamic.Interface
+	if err := ensureRegistryCredentials(k8sclient, blueprint, imageRegistry); err != nil {
+		return fmt.Errorf("failed to set up registry credentials: %w", err)
+	}
 
-		if client, err = k8s.GetClient(kubeConfig); err != nil {
-			return fmt.Errorf("failed to get kubernetes client: %q", err)
+	// @todo: display the version of the operator
+	if installOperator {
+		uri, cleanup, err := operatorManifest(blueprint, imageRegistry)
+		if err != nil {
+			return err
 		}
-		if dynamicClient, err = k8s.GetDynamicClient(kubeConfig); err != nil {
-			return fmt.Errorf("failed to get kubernetes dynamic client: %q", err)
+		defer cleanup()
+
+		clients, err := k8s.GetClients(kubeConfig)
+		if err != nil {
+			return fmt.Errorf("failed to get kubernetes clients: %w", err)
 		}
 
-		if err = k8s.ApplyYaml(client, dynamicClient, uri); err != nil {
+		log.Info().Msgf("Installing Blueprint Operator")
+		log.Debug().Msgf("Installing Blueprint Operator using manifest file: %s", blueprint.Spec.Version)
+
+		if err = k8s.ApplyYaml(clients, uri, operatorManifestMutators(blueprint)...); err != nil {
 			return fmt.Errorf("failed to install Blueprint Operator: %w", err)
 		}
 	} else {
 		log.Info().Msg("Blueprint Operator already installed")
+		if err := reconcileOperatorDeployment(k8sclient, operatorSettings(blueprint)); err != nil {
+			return err
+		}
+	}
+
+	if err := attachRegistryCredentials(k8sclient, blueprint); err != nil {
+		return err
 	}
 
 	// Wait for the pods to be ready
@@ -152,47 +173,17 @@ func Apply(blueprint *types.Blueprint, kubeConfig *k8s.KubeConfig, providerInsta
 
 	// install components
 	log.Info().Msgf("Applying Blueprint Operator resource")
-	err = components.ApplyBlueprint(kubeConfig, blueprint)
+	boundlessClient, err := k8s.GetBoundlessClient(kubeConfig)
 	if err != nil {
-		return fmt.Errorf("failed to install components: %w", err)
+		return fmt.Errorf("failed to get blueprint client: %w", err)
 	}
-
-	log.Info().Msgf("Finished installing Blueprint Operator")
-
-	return nil
-}
-
-func testClusterConnectivity(kubeConfig *k8s.KubeConfig) error {
-
-	// Extract the rest.Config from the clientset
-	cfg, err := kubeConfig.RESTConfig()
-	if err != nil {
-		return fmt.Errorf("unable to get REST config for dynaminc kube client: %v", err)
-	}
-
-	config := rest.CopyConfig(cfg)
-
-	apiServer := config.Host
-	if apiServer == "" {
-		return fmt.Errorf("kubernetes API server address is not defined in config")
-	}
-
-	log.Info().Msgf("Testing connectivity to the Kubernetes API server at %s\n", apiServer)
-
-	// Extract the hostname and port from the API server URL
-	host, port, err := net.SplitHostPort(apiServer[8:]) // Remove "https://"
+	err = components.ApplyBlueprint(boundlessClient, blueprint)
 	if err != nil {
-		return fmt.Errorf("failed to parse API server address: %v", err)
+		return fmt.Errorf("failed to install components: %w", err)
 	}
 
-	// Attempt to connect to the API server
-	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, port), 5*time.Second)
-	if err != nil {
-		return fmt.Errorf("failed to connect to Kubernetes API server: %v", err)
-	}
-	defer conn.Close()
+	log.Info().Msgf("Finished installing Blueprint Operator")
 
-	log.Info().Msgf("Successfully connected to the Kubernetes API server.")
 	return nil
 }
 
@@ -0,0 +1,285 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/k0sproject/version"
+	"github.com/rs/zerolog/log"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+
+	"github.com/mirantiscontainers/blueprint-operator/api/v1alpha1"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/backup"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/distro"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/k8s"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/utils"
+)
+
+// operatorResources are the resources of the Blueprint Operator saved in the backups, by kind, in the order the operator creates them
+var operatorResources = []struct{ kind, resource string }{
+	{kind: "Installation", resource: "installations"},
+	{kind: "Blueprint", resource: "blueprints"},
+	{kind: "Addon", resource: "addons"},
+	{kind: "Manifest", resource: "manifests"},
+}
+
+// BackupOptions are the options of a backup
+type BackupOptions struct {
+	// Dir is the directory the archive is written to
+	Dir string
+	// Keep is the number of archives of the blueprint kept in the directory, the older ones are removed; 0 keeps all of them
+	Keep int
+}
+
+// Backup writes an archive with a k0s backup of the cluster, the blueprint file and the objects of the Blueprint Operator
+func Backup(blueprint *types.Blueprint, blueprintFile string, kubeConfig *k8s.KubeConfig, opts BackupOptions) error {
+	k0s, err := k0sProvider(blueprint, kubeConfig)
+	if err != nil {
+		return err
+	}
+
+	blueprintData, err := utils.ReadFile(blueprintFile)
+	if err != nil {
+		return fmt.Errorf("failed to read blueprint file %q: %w", blueprintFile, err)
+	}
+
+	dynamicClient, err := k8s.GetDynamicClient(kubeConfig)
+	if err != nil {
+		return fmt.Errorf("failed to get kubernetes dynamic client: %w", err)
+	}
+	resources, err := operatorObjects(context.Background(), dynamicClient)
+	if err != nil {
+		return err
+	}
+
+	k0sBackup, err := os.CreateTemp("", "k0s-backup-*.tar.gz")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(k0sBackup.Name())
+	defer k0sBackup.Close()
+	k0sVersion, err := k0s.Backup(k0sBackup)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(opts.Dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create backup directory %q: %w", opts.Dir, err)
+	}
+	created := time.Now()
+	path := filepath.Join(opts.Dir, backup.FileName(blueprint.Metadata.Name, created))
+	metadata := backup.Metadata{Name: blueprint.Metadata.Name, Created: created, K0sVersion: k0sVersion}
+	files := map[string]string{backup.K0sBackupFile: k0sBackup.Name()}
+	if err := writeBackup(path, metadata, files, map[string][]byte{
+		backup.BlueprintFile: blueprintData,
+		backup.ResourcesFile: resources,
+	}); err != nil {
+		return fmt.Errorf("failed to write backup %s: %w", path, err)
+	}
+	log.Info().Msgf("Backup of cluster %q written to %s", blueprint.Metadata.Name, path)
+
+	if opts.Keep > 0 {
+		removed, err := backup.Prune(opts.Dir, blueprint.Metadata.Name, opts.Keep)
+		if err != nil {
+			return err
+		}
+		for _, archive := range removed {
+			log.Info().Msgf("Removed old backup %s", archive)
+		}
+	}
+	return nil
+}
+
+// writeBackup writes the archive next to its final path, and moves it there once it is complete
+// A backup that fails half way is never mistaken for a complete one, e.g. when the old backups are pruned.
+func writeBackup(path string, metadata backup.Metadata, files map[string]string, data map[string][]byte) error {
+	partial := path + ".partial"
+	file, err := os.OpenFile(partial, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(partial)
+	defer file.Close()
+
+	writer := backup.NewWriter(file)
+	for name, content := range data {
+		if err := writer.AddBytes(name, content); err != nil {
+			return err
+		}
+	}
+	for name, source := range files {
+		if err := writer.AddFile(name, source); err != nil {
+			return err
+		}
+	}
+	if err := writer.Close(metadata); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(partial, path)
+}
+
+// Restore rebuilds the cluster on the hosts of the blueprint from a backup archive
+// The archive is verified before anything is changed on the hosts. The hosts must not run k0s yet.
+func Restore(blueprint *types.Blueprint, kubeConfig *k8s.KubeConfig, from string, skipPreflight bool) error {
+	dir, err := os.MkdirTemp("", "bctl-restore-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	log.Info().Msgf("Verifying backup %s", from)
+	metadata, err := backup.Extract(from, dir)
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf("Backup of cluster %q taken on %s with k0s %s", metadata.Name, metadata.Created.Format(time.RFC1123), metadata.K0sVersion)
+	if metadata.Name != blueprint.Metadata.Name {
+		log.Warn().Msgf("The backup was taken of cluster %q, it is restored as cluster %q", metadata.Name, blueprint.Metadata.Name)
+	}
+
+	// k0s only restores a backup with the version that took it
+	if metadata.K0sVersion != "" {
+		backupVersion, err := version.NewVersion(metadata.K0sVersion)
+		if err != nil {
+			return fmt.Errorf("invalid k0s version %q in backup: %w", metadata.K0sVersion, err)
+		}
+		if !backupVersion.Equal(version.MustParse(blueprint.Spec.Kubernetes.Version)) {
+			return fmt.Errorf("the backup was taken with k0s %s, set spec.kubernetes.version to it to restore the backup", metadata.K0sVersion)
+		}
+	}
+
+	k0s, err := k0sProvider(blueprint, kubeConfig)
+	if err != nil {
+		return err
+	}
+	exists, err := k0s.Exists()
+	if err != nil {
+		return fmt.Errorf("failed to check if cluster exists: %w", err)
+	}
+	if exists {
+		return fmt.Errorf("cluster %q already exists; reset it with 'bctl reset' before restoring a backup", blueprint.Metadata.Name)
+	}
+
+	if !skipPreflight {
+		if err := HostsCheck(blueprint); err != nil {
+			return fmt.Errorf("hosts are not ready for k0s: %w", err)
+		}
+	}
+
+	log.Info().Msgf("Restoring cluster %q", blueprint.Metadata.Name)
+	if err := k0s.Restore(filepath.Join(dir, backup.K0sBackupFile)); err != nil {
+		return err
+	}
+	if err := kubeConfig.TryLoad(); err != nil {
+		return err
+	}
+	if err := k0s.SetupClient(); err != nil {
+		return fmt.Errorf("failed to setup client: %w", err)
+	}
+
+	// the objects are part of the k0s backup, only the ones that did not make it are created again
+	resources, err := os.ReadFile(filepath.Join(dir, backup.ResourcesFile))
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := k8s.GetDynamicClient(kubeConfig)
+	if err != nil {
+		return fmt.Errorf("failed to get kubernetes dynamic client: %w", err)
+	}
+	if err := restoreOperatorObjects(context.Background(), dynamicClient, resources); err != nil {
+		return err
+	}
+
+	log.Info().Msgf("Restored cluster %q", blueprint.Metadata.Name)
+	return nil
+}
+
+// k0sProvider returns the k0s provider of the blueprint, or an error for the other providers
+func k0sProvider(blueprint *types.Blueprint, kubeConfig *k8s.KubeConfig) (*distro.K0s, error) {
+	provider, err := distro.GetProvider(blueprint, kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine kubernetes provider: %w", err)
+	}
+	k0s, ok := provider.(*distro.K0s)
+	if !ok {
+		return nil, fmt.Errorf("backups are only supported for k0s clusters, not for provider %q", provider.Type())
+	}
+	return k0s, nil
+}
+
+// operatorObjects returns the objects of the Blueprint Operator as a YAML list
+// The fields set by the API server are removed, so that the objects can be created again in a new cluster.
+func operatorObjects(ctx context.Context, client dynamic.Interface) ([]byte, error) {
+	list := &unstructured.UnstructuredList{Object: map[string]interface{}{"apiVersion": "v1", "kind": "List"}}
+	for _, resource := range operatorResources {
+		gvr := v1alpha1.GroupVersion.WithResource(resource.resource)
+		objects, err := client.Resource(gvr).List(ctx, metav1.ListOptions{})
+		if apierrors.IsNotFound(err) {
+			log.Debug().Msgf("No %s in the cluster, the CRD is not installed", gvr.Resource)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", gvr.Resource, err)
+		}
+		for _, object := range objects.Items {
+			unstructured.RemoveNestedField(object.Object, "status")
+			for _, field := range []string{"uid", "resourceVersion", "generation", "creationTimestamp", "managedFields", "ownerReferences"} {
+				unstructured.RemoveNestedField(object.Object, "metadata", field)
+			}
+			list.Items = append(list.Items, object)
+		}
+	}
+
+	data, err := list.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return yaml.JSONToYAML(data)
+}
+
+// restoreOperatorObjects creates the objects of the YAML list that do not exist in the cluster
+func restoreOperatorObjects(ctx context.Context, client dynamic.Interface, data []byte) error {
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse the resources of the backup: %w", err)
+	}
+	list := &unstructured.UnstructuredList{}
+	if err := list.UnmarshalJSON(jsonData); err != nil {
+		return fmt.Errorf("failed to parse the resources of the backup: %w", err)
+	}
+
+	var created int
+	for _, object := range list.Items {
+		i := slices.IndexFunc(operatorResources, func(resource struct{ kind, resource string }) bool {
+			return resource.kind == object.GetKind()
+		})
+		if i < 0 || object.GroupVersionKind().GroupVersion() != v1alpha1.GroupVersion {
+			return fmt.Errorf("unexpected %s %q in the resources of the backup", object.GetKind(), object.GetName())
+		}
+		gvr := v1alpha1.GroupVersion.WithResource(operatorResources[i].resource)
+
+		_, err := client.Resource(gvr).Namespace(object.GetNamespace()).Create(ctx, &object, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to restore %s %q: %w", object.GetKind(), object.GetName(), err)
+		}
+		created++
+	}
+	log.Info().Msgf("Restored %d of the %d Blueprint Operator objects of the backup, the others were restored with k0s", created, len(list.Items))
+	return nil
+}
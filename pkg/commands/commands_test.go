@@ -1,15 +1,36 @@
 package commands
 
 import (
+	"context"
 	"fmt"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/k0sproject/dig"
+	"github.com/mirantiscontainers/blueprint-operator/api/v1alpha1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery/cached/memory"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	fakekubernetes "k8s.io/client-go/kubernetes/fake"
 
+	"github.com/mirantiscontainers/blueprint-cli/boundlessclientset/fake"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/k8s"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/readiness"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
 )
 
 var _ = Describe("Commands", func() {
@@ -63,7 +84,7 @@ var _ = Describe("Commands", func() {
 			testBopFile, err := os.CreateTemp("", "test-bop-*.yaml")
 			Expect(err).ToNot(HaveOccurred())
 
-			manifestBytes, err := downloadRemoteManifest(remoteURI)
+			manifestBytes, err := downloadRemoteManifest(http.DefaultClient, remoteURI)
 			Expect(err).ToNot(HaveOccurred())
 
 			n, err := testBopFile.Write(manifestBytes)
@@ -84,14 +105,14 @@ var _ = Describe("Commands", func() {
 		})
 
 		It("fails with an empty manifest", func() {
-			_, needCleanup, err := setImageRegistry("", "registry.mirantis.com")
+			_, needCleanup, err := setImageRegistry(http.DefaultClient, "", "registry.mirantis.com")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(Equal("empty BOP manifest URI"))
 			Expect(needCleanup).To(BeFalse())
 		})
 
 		It("fails with a bad link for remote manifest", func() {
-			_, needCleanup, err := setImageRegistry(uris[remoteKey]+"oops", "registry.mirantis.com")
+			_, needCleanup, err := setImageRegistry(http.DefaultClient, uris[remoteKey]+"oops", "registry.mirantis.com")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("unable to obtain BOP manifest"))
 			Expect(needCleanup).To(BeFalse())
@@ -100,7 +121,7 @@ var _ = Describe("Commands", func() {
 		DescribeTable("should return original URI",
 			func(testURIKey, registry string) {
 				testURI := uris[testURIKey]
-				uri, needCleanup, err := setImageRegistry(testURI, registry)
+				uri, needCleanup, err := setImageRegistry(http.DefaultClient, testURI, registry)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(needCleanup).To(BeFalse())
 				Expect(uri).To(Equal(testURI))
@@ -114,7 +135,7 @@ var _ = Describe("Commands", func() {
 		DescribeTable("should update registry and return updated URI",
 			func(testURIKey string) {
 				testURI := uris[testURIKey]
-				uri, needCleanup, err := setImageRegistry(testURI, "registry.mirantis.com")
+				uri, needCleanup, err := setImageRegistry(http.DefaultClient, testURI, "registry.mirantis.com")
 				Expect(err).ToNot(HaveOccurred())
 				defer os.Remove(strings.TrimPrefix(uri, "file://"))
 
@@ -132,6 +153,462 @@ var _ = Describe("Commands", func() {
 		)
 	})
 
+	Context("with operator manifest", func() {
+		It("downloads a remote manifest through the proxy of the blueprint", func() {
+			var proxied []string
+			proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				proxied = append(proxied, r.URL.String())
+				w.Write([]byte("image: " + constants.MirantisImageRegistry + "/blueprint-operator:v1.0.0\n"))
+			}))
+			defer proxy.Close()
+
+			blueprint := &types.Blueprint{Spec: types.BlueprintSpec{
+				Version: "http://operator.example.com/blueprint-operator.yaml",
+				Proxy:   &types.Proxy{HTTP: proxy.URL},
+			}}
+			uri, cleanup, err := operatorManifest(blueprint, "registry.example.com")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(proxied).To(Equal([]string{"http://operator.example.com/blueprint-operator.yaml"}))
+
+			path := strings.TrimPrefix(uri, "file://")
+			manifest, err := os.ReadFile(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(manifest)).To(Equal("image: registry.example.com/blueprint-operator:v1.0.0\n"))
+
+			cleanup()
+			Expect(path).ToNot(BeAnExistingFile())
+		})
+	})
+
+	Context("with registry credentials", func() {
+		It("builds a docker config from environment variables", func() {
+			GinkgoT().Setenv("TEST_REGISTRY_USER", "bob")
+			GinkgoT().Setenv("TEST_REGISTRY_PASSWORD", "secret")
+
+			data, err := buildDockerConfigJSON(&types.RegistryCredentials{
+				UsernameEnv: "TEST_REGISTRY_USER",
+				PasswordEnv: "TEST_REGISTRY_PASSWORD",
+			}, "registry.example.com/mirror")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(Equal(`{"auths":{"registry.example.com":{"username":"bob","password":"secret","auth":"Ym9iOnNlY3JldA=="}}}`))
+		})
+		It("prefers the credentials server over the image registry", func() {
+			GinkgoT().Setenv("TEST_REGISTRY_USER", "bob")
+			GinkgoT().Setenv("TEST_REGISTRY_PASSWORD", "secret")
+
+			data, err := buildDockerConfigJSON(&types.RegistryCredentials{
+				Server:      "auth.example.com",
+				UsernameEnv: "TEST_REGISTRY_USER",
+				PasswordEnv: "TEST_REGISTRY_PASSWORD",
+			}, "registry.example.com/mirror")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(ContainSubstring(`"auth.example.com"`))
+		})
+		It("fails when the environment variables are not set", func() {
+			_, err := buildDockerConfigJSON(&types.RegistryCredentials{
+				UsernameEnv: "TEST_REGISTRY_UNSET_USER",
+				PasswordEnv: "TEST_REGISTRY_UNSET_PASSWORD",
+			}, "registry.example.com")
+			Expect(err).To(HaveOccurred())
+		})
+		It("adds the pull secret to the service accounts of the addon namespaces", func() {
+			GinkgoT().Setenv("TEST_REGISTRY_USER", "bob")
+			GinkgoT().Setenv("TEST_REGISTRY_PASSWORD", "secret")
+
+			client := fakekubernetes.NewSimpleClientset(&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "grafana", Namespace: "monitoring"}})
+			blueprint := &types.Blueprint{Spec: types.BlueprintSpec{
+				Registry: &types.Registry{Credentials: &types.RegistryCredentials{
+					UsernameEnv: "TEST_REGISTRY_USER", PasswordEnv: "TEST_REGISTRY_PASSWORD", CopyToAddonNamespaces: true,
+				}},
+				Components: types.Components{Addons: []types.Addon{{Name: "grafana", Namespace: "monitoring"}}},
+			}}
+			Expect(ensureRegistryCredentials(client, blueprint, "registry.example.com")).To(Succeed())
+
+			for _, name := range []string{"default", "grafana"} {
+				sa, err := client.CoreV1().ServiceAccounts("monitoring").Get(context.Background(), name, metav1.GetOptions{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(sa.ImagePullSecrets).To(ConsistOf(corev1.LocalObjectReference{Name: constants.RegistryCredentialsSecret}))
+			}
+			_, err := client.CoreV1().ServiceAccounts(constants.NamespaceBlueprint).Get(context.Background(), "default", metav1.GetOptions{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+		It("fails without a registry server", func() {
+			GinkgoT().Setenv("TEST_REGISTRY_USER", "bob")
+			GinkgoT().Setenv("TEST_REGISTRY_PASSWORD", "secret")
+
+			_, err := buildDockerConfigJSON(&types.RegistryCredentials{
+				UsernameEnv: "TEST_REGISTRY_USER",
+				PasswordEnv: "TEST_REGISTRY_PASSWORD",
+			}, "")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("with operator settings", func() {
+		var deployment *appsv1.Deployment
+		var settings *types.Operator
+
+		BeforeEach(func() {
+			replicas := int32(1)
+			deployment = &appsv1.Deployment{
+				Spec: appsv1.DeploymentSpec{
+					Replicas: &replicas,
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{Name: "kube-rbac-proxy", Image: "ghcr.io/mirantiscontainers/kube-rbac-proxy:v1.0.0"},
+								{Name: "manager", Image: "ghcr.io/mirantiscontainers/blueprint-operator:v1.0.0", Args: []string{"--leader-elect"}},
+							},
+						},
+					},
+				},
+			}
+
+			desiredReplicas := int32(2)
+			settings = &types.Operator{
+				Replicas:          &desiredReplicas,
+				NodeSelector:      map[string]string{"node-role": "infra"},
+				PriorityClassName: "system-cluster-critical",
+				ExtraArgs:         []string{"--zap-log-level=debug"},
+				Env:               []corev1.EnvVar{{Name: "HTTPS_PROXY", Value: "http://proxy:3128"}},
+			}
+		})
+
+		It("applies the settings to the manager container", func() {
+			Expect(applyOperatorSettings(settings, deployment)).To(Succeed())
+			Expect(*deployment.Spec.Replicas).To(Equal(int32(2)))
+			Expect(deployment.Spec.Template.Spec.NodeSelector).To(HaveKeyWithValue("node-role", "infra"))
+			Expect(deployment.Spec.Template.Spec.PriorityClassName).To(Equal("system-cluster-critical"))
+
+			manager := deployment.Spec.Template.Spec.Containers[1]
+			Expect(manager.Args).To(Equal([]string{"--leader-elect", "--zap-log-level=debug"}))
+			Expect(manager.Env).To(Equal([]corev1.EnvVar{{Name: "HTTPS_PROXY", Value: "http://proxy:3128"}}))
+			Expect(deployment.Spec.Template.Spec.Containers[0].Args).To(BeEmpty())
+		})
+
+		It("reports the drifted fields", func() {
+			drift, err := operatorDrift(settings, deployment)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift).To(ConsistOf("replicas", "nodeSelector", "priorityClassName", "extraArgs", "env"))
+		})
+
+		It("reports no drift once the settings are applied", func() {
+			Expect(applyOperatorSettings(settings, deployment)).To(Succeed())
+			drift, err := operatorDrift(settings, deployment)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift).To(BeEmpty())
+		})
+
+		It("removes the extra args and env dropped from the settings", func() {
+			Expect(applyOperatorSettings(settings, deployment)).To(Succeed())
+			Expect(deployment.Annotations).To(HaveKey(operatorSettingsAnnotation))
+
+			settings.ExtraArgs = nil
+			settings.Env = []corev1.EnvVar{{Name: "NO_PROXY", Value: "example.com"}}
+			drift, err := operatorDrift(settings, deployment)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift).To(ConsistOf("extraArgs", "env"))
+
+			Expect(applyOperatorSettings(settings, deployment)).To(Succeed())
+			manager := deployment.Spec.Template.Spec.Containers[1]
+			Expect(manager.Args).To(Equal([]string{"--leader-elect"}))
+			Expect(manager.Env).To(Equal([]corev1.EnvVar{{Name: "NO_PROXY", Value: "example.com"}}))
+
+			drift, err = operatorDrift(nil, deployment)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift).To(ConsistOf("env"))
+			Expect(applyOperatorSettings(&types.Operator{}, deployment)).To(Succeed())
+			Expect(deployment.Spec.Template.Spec.Containers[1].Env).To(BeEmpty())
+			Expect(deployment.Annotations).ToNot(HaveKey(operatorSettingsAnnotation))
+		})
+
+		It("adds the proxy env without overriding the operator env", func() {
+			blueprint := &types.Blueprint{Spec: types.BlueprintSpec{
+				Operator: settings,
+				Proxy:    &types.Proxy{HTTPS: "http://corporate:8080", NoProxy: []string{"example.com"}},
+			}}
+
+			env := operatorSettings(blueprint).Env
+			Expect(env).To(ContainElements(
+				corev1.EnvVar{Name: "HTTPS_PROXY", Value: "http://proxy:3128"},
+				corev1.EnvVar{Name: "https_proxy", Value: "http://corporate:8080"},
+				corev1.EnvVar{Name: "NO_PROXY", Value: "example.com,localhost,127.0.0.1,.svc,.cluster.local"},
+			))
+			Expect(settings.Env).To(HaveLen(1))
+		})
+	})
+
+	Context("with the blueprint client", func() {
+		var client *fake.Clientset
+
+		BeforeEach(func() {
+			client = fake.NewSimpleClientset(
+				&v1alpha1.Addon{
+					ObjectMeta: metav1.ObjectMeta{Name: "metallb", Namespace: constants.NamespaceBlueprint},
+					Spec:       v1alpha1.AddonSpec{Name: "metallb", Kind: constants.AddonChart},
+				},
+				&v1alpha1.Addon{
+					ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"},
+				},
+			)
+		})
+
+		It("lists the addons of the blueprint namespace", func() {
+			addons, err := getAddons(client)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addons.Items).To(HaveLen(1))
+			Expect(addons.Items[0].Name).To(Equal("metallb"))
+		})
+
+		It("gets an addon by name", func() {
+			addon, err := getAddon(client, "metallb")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addon.Spec.Kind).To(Equal(constants.AddonChart))
+
+			_, err = getAddon(client, "missing")
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Context("with removed hosts", func() {
+		node := func(name, ip string) corev1.Node {
+			return corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Status:     corev1.NodeStatus{Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: ip}}},
+			}
+		}
+		nodes := []corev1.Node{node("controller", "10.0.0.1"), node("worker-1", "10.0.0.2"), node("worker-2", "10.0.0.3")}
+
+		It("detects the nodes that are not the nodes of the hosts", func() {
+			dropped, err := droppedNodes(nodes, map[string]string{"10.0.0.1": "controller", "192.168.0.2": "worker-1"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dropped).To(HaveLen(1))
+			Expect(dropped[0].Name).To(Equal("worker-2"))
+		})
+
+		It("does not remove any node when the node of a host is not found", func() {
+			_, err := droppedNodes(nodes, map[string]string{"10.0.0.1": "controller", "10.0.0.2": "ip-10-0-0-2"})
+			Expect(err).To(MatchError(`node "ip-10-0-0-2" of host 10.0.0.2 is not in the cluster; not removing any node`))
+		})
+
+		It("does not remove any node without hosts that have one", func() {
+			_, err := droppedNodes(nodes, map[string]string{})
+			Expect(err).To(MatchError(ContainSubstring("not removing any of the 3 nodes")))
+		})
+
+		host := func(address, role string) types.Host {
+			return types.Host{SSH: &types.SSHHost{Address: address, Port: 22, User: "root", KeyPath: "/keys/" + address}, Role: role}
+		}
+
+		It("detects the hosts removed from the blueprint", func() {
+			applied := []types.Host{host("10.0.0.1", "controller"), host("10.0.0.2", "controller"), host("10.0.0.3", "worker")}
+			dropped := droppedHosts(applied, []types.Host{host("10.0.0.1", "controller"), host("10.0.0.3", "worker")})
+			Expect(dropped).To(Equal([]types.Host{host("10.0.0.2", "controller")}))
+		})
+
+		It("records the hosts with their SSH settings", func() {
+			client := fakekubernetes.NewSimpleClientset()
+			hosts, err := appliedHosts(context.Background(), client)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hosts).To(BeEmpty())
+
+			applied := []types.Host{host("10.0.0.1", "controller"), host("10.0.0.2", "worker")}
+			Expect(recordHosts(context.Background(), client, applied)).To(Succeed())
+			Expect(recordHosts(context.Background(), client, applied[:1])).To(Succeed())
+			hosts, err = appliedHosts(context.Background(), client)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hosts).To(Equal(applied[:1]))
+		})
+
+		DescribeTable("resets the removed hosts",
+			func(role string, etcd bool, commands []string) {
+				var ran []string
+				run := func(host types.Host, command string) (string, error) {
+					ran = append(ran, command)
+					return "", nil
+				}
+				Expect(resetHost(host("10.0.0.2", role), etcd, run)).To(Succeed())
+				Expect(ran).To(Equal(commands))
+			},
+			Entry("with a controller leaving etcd first", "controller", true, []string{k0sEtcdLeaveCommand, k0sResetCommand}),
+			Entry("with a controller+worker leaving etcd first", "controller+worker", true, []string{k0sEtcdLeaveCommand, k0sResetCommand}),
+			Entry("with a controller without etcd", "controller", false, []string{k0sResetCommand}),
+			Entry("with a worker", "worker", true, []string{k0sResetCommand}),
+		)
+
+		It("does not reset a controller that cannot leave etcd", func() {
+			var ran []string
+			run := func(host types.Host, command string) (string, error) {
+				ran = append(ran, command)
+				return "", fmt.Errorf("connection refused")
+			}
+			err := resetHost(host("10.0.0.2", "controller"), true, run)
+			Expect(err).To(MatchError(ContainSubstring("failed to remove the etcd member of host 10.0.0.2")))
+			Expect(ran).To(Equal([]string{k0sEtcdLeaveCommand}))
+		})
+	})
+
+	Context("with imported hosts", func() {
+		It("prints the hosts of the blueprint", func() {
+			blueprint := &types.Blueprint{Spec: types.BlueprintSpec{Kubernetes: &types.Kubernetes{Infra: &types.Infra{
+				HostsFrom: &types.HostsFrom{Terraform: "terraform.tfstate", Roles: map[string]string{"controllers": "single"}},
+				Hosts:     []types.Host{{Role: "single", SSH: &types.SSHHost{Address: "10.0.0.1", User: "root", Port: 22, KeyPath: "/keys/id_ed25519"}}},
+			}}}}
+
+			var out strings.Builder
+			Expect(HostsImport(blueprint, &out)).To(Succeed())
+			Expect(out.String()).To(Equal("hosts:\n- role: single\n  ssh:\n    address: 10.0.0.1\n    keyPath: /keys/id_ed25519\n    port: 22\n    user: root\n"))
+		})
+
+		It("fails without hostsFrom", func() {
+			blueprint := &types.Blueprint{Spec: types.BlueprintSpec{Kubernetes: &types.Kubernetes{Infra: &types.Infra{}}}}
+			Expect(HostsImport(blueprint, &strings.Builder{})).To(MatchError(ContainSubstring("no spec.kubernetes.infra.hostsFrom")))
+		})
+	})
+
+	Context("with rollback", func() {
+		configMaps := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+		deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+		pods := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+		var clients *k8s.Clients
+		var dynamicClient *fakedynamic.FakeDynamicClient
+		var waiter *readiness.Waiter
+		var uri string
+
+		// operatorDeployment returns the operator deployment with the number of available replicas
+		operatorDeployment := func(available int64) *unstructured.Unstructured {
+			deployment := &unstructured.Unstructured{}
+			deployment.SetAPIVersion("apps/v1")
+			deployment.SetKind("Deployment")
+			deployment.SetNamespace(constants.NamespaceBlueprint)
+			deployment.SetName(constants.BlueprintOperatorDeployment)
+			deployment.Object["spec"] = map[string]interface{}{"replicas": int64(1)}
+			deployment.Object["status"] = map[string]interface{}{"replicas": int64(1), "updatedReplicas": int64(1), "availableReplicas": available}
+			return deployment
+		}
+
+		BeforeEach(func() {
+			client := fakekubernetes.NewSimpleClientset()
+			discovery := client.Discovery().(*fakediscovery.FakeDiscovery)
+			discovery.Resources = []*metav1.APIResourceList{{
+				GroupVersion: "v1",
+				APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: metav1.Verbs{"get", "list", "create", "update", "delete"}}},
+			}}
+			config := &unstructured.Unstructured{}
+			config.SetAPIVersion("v1")
+			config.SetKind("ConfigMap")
+			config.SetNamespace(constants.NamespaceBlueprint)
+			config.SetName("operator-config")
+			config.Object["data"] = map[string]interface{}{"version": "old"}
+			listKinds := map[schema.GroupVersionResource]string{configMaps: "ConfigMapList", deployments: "DeploymentList", pods: "PodList"}
+			dynamicClient = fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, config, operatorDeployment(1))
+			clients = k8s.NewClients(client, dynamicClient, memory.NewMemCacheClient(discovery))
+			waiter = readiness.NewWaiter(dynamicClient)
+			waiter.Timeout, waiter.Interval = 100*time.Millisecond, 10*time.Millisecond
+
+			uri = filepath.Join(GinkgoT().TempDir(), "blueprint-operator.yaml")
+			manifest := fmt.Sprintf("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: operator-config\n  namespace: %s\ndata:\n  version: new\n", constants.NamespaceBlueprint)
+			Expect(os.WriteFile(uri, []byte(manifest), 0o644)).To(Succeed())
+		})
+
+		It("restores the snapshot of the deployed operator", func() {
+			snapshot, err := k8s.SnapshotYaml(clients, uri, constants.InventoryOwnerOperator)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(k8s.ApplyYaml(clients, uri)).To(Succeed())
+
+			err = rollbackUpgrade(clients, &types.Blueprint{}, snapshot, waiter, fmt.Errorf("upgraded Blueprint Operator failed health checks"))
+			Expect(err).To(MatchError("upgraded Blueprint Operator failed health checks; the previous Blueprint Operator was restored"))
+
+			live, err := dynamicClient.Resource(configMaps).Namespace(constants.NamespaceBlueprint).Get(context.Background(), "operator-config", metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(live.Object["data"]).To(Equal(map[string]interface{}{"version": "old"}))
+		})
+
+		It("reports a restored operator that does not become ready", func() {
+			snapshot, err := k8s.SnapshotYaml(clients, uri, constants.InventoryOwnerOperator)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(k8s.ApplyYaml(clients, uri)).To(Succeed())
+			_, err = dynamicClient.Resource(deployments).Namespace(constants.NamespaceBlueprint).Update(context.Background(), operatorDeployment(0), metav1.UpdateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = rollbackUpgrade(clients, &types.Blueprint{}, snapshot, waiter, fmt.Errorf("upgraded Blueprint Operator failed health checks"))
+			Expect(err).To(MatchError(ContainSubstring("the previous Blueprint Operator was restored but is not ready: timed out waiting for deployments/")))
+		})
+
+		It("returns the upgrade error without a snapshot", func() {
+			Expect(k8s.ApplyYaml(clients, uri)).To(Succeed())
+
+			err := rollbackUpgrade(clients, &types.Blueprint{}, nil, waiter, fmt.Errorf("failed to upgrade blueprint operator"))
+			Expect(err).To(MatchError("failed to upgrade blueprint operator"))
+
+			live, err := dynamicClient.Resource(configMaps).Namespace(constants.NamespaceBlueprint).Get(context.Background(), "operator-config", metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(live.Object["data"]).To(Equal(map[string]interface{}{"version": "new"}))
+		})
+	})
+
+	Context("with lint", func() {
+		It("warns about a k0s version the operator does not support", func() {
+			blueprint := &types.Blueprint{Spec: types.BlueprintSpec{Version: "latest", Kubernetes: &types.Kubernetes{Provider: constants.ProviderK0s, Version: "1.26.15+k0s.0"}}}
+			Expect(Lint(blueprint)).To(ConsistOf(ContainSubstring("k0s 1.26.15+k0s.0 is not supported by the Blueprint Operator latest")))
+		})
+
+		It("warns about the fields of the k0s config that bctl does not know", func() {
+			config := dig.Mapping{"spec": map[string]interface{}{"network": map[string]interface{}{"provder": "calico"}}}
+			blueprint := &types.Blueprint{Spec: types.BlueprintSpec{Version: "latest", Kubernetes: &types.Kubernetes{Provider: constants.ProviderK0s, Version: "1.31.1+k0s.0", Config: config}}}
+			Expect(Lint(blueprint)).To(ConsistOf(ContainSubstring("unknown field spec.network.provder in kubernetes.config")))
+		})
+
+		It("does not warn about a supported combination", func() {
+			blueprint := &types.Blueprint{Spec: types.BlueprintSpec{Version: "latest", Kubernetes: &types.Kubernetes{Provider: constants.ProviderK0s, Version: "1.31.1+k0s.0"}}}
+			Expect(Lint(blueprint)).To(BeEmpty())
+		})
+	})
+
+	Context("with backups", func() {
+		listKinds := map[schema.GroupVersionResource]string{}
+		for _, resource := range operatorResources {
+			listKinds[v1alpha1.GroupVersion.WithResource(resource.resource)] = resource.kind + "List"
+		}
+		object := func(kind, namespace, name string) *unstructured.Unstructured {
+			u := &unstructured.Unstructured{}
+			u.SetGroupVersionKind(v1alpha1.GroupVersion.WithKind(kind))
+			u.SetNamespace(namespace)
+			u.SetName(name)
+			u.SetUID("1234")
+			u.SetResourceVersion("42")
+			return u
+		}
+
+		It("saves the operator objects without their server fields and creates the missing ones again", func() {
+			source := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds,
+				object("Blueprint", "default", "prod"),
+				object("Addon", constants.NamespaceBlueprint, "metallb"),
+			)
+			data, err := operatorObjects(context.Background(), source)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(ContainSubstring("name: metallb"))
+			Expect(string(data)).ToNot(ContainSubstring("uid"))
+			Expect(string(data)).ToNot(ContainSubstring("resourceVersion"))
+
+			// the blueprint was restored with k0s, the addon was not
+			target := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds,
+				object("Blueprint", "default", "prod"),
+			)
+			Expect(restoreOperatorObjects(context.Background(), target, data)).To(Succeed())
+			addon, err := target.Resource(v1alpha1.GroupVersion.WithResource("addons")).Namespace(constants.NamespaceBlueprint).
+				Get(context.Background(), "metallb", metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addon.GetKind()).To(Equal("Addon"))
+		})
+
+		It("rejects objects that are not operator objects", func() {
+			data := []byte("apiVersion: v1\nkind: List\nitems:\n- apiVersion: v1\n  kind: Secret\n  metadata:\n    name: token\n")
+			err := restoreOperatorObjects(context.Background(), fakedynamic.NewSimpleDynamicClient(runtime.NewScheme()), data)
+			Expect(err).To(MatchError(ContainSubstring(`unexpected Secret "token"`)))
+		})
+	})
+
 	It("detect image registry", func() {
 		detected, err := detectDeployedRegistry([]corev1.Container{
 			{
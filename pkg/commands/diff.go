@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/components"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/distro"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/k8s"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+// Diff prints the changes that applying the blueprint would make to the cluster
+// The changes include the drift of the operator deployment from the blueprint operator settings.
+func Diff(blueprint *types.Blueprint, kubeConfig *k8s.KubeConfig, w io.Writer) error {
+	diff, err := blueprintChanges(context.Background(), blueprint, kubeConfig)
+	if err != nil {
+		return err
+	}
+
+	if len(diff) == 0 {
+		fmt.Fprintln(w, "The cluster is up to date with the blueprint")
+		return nil
+	}
+	for _, change := range diff {
+		fmt.Fprintf(w, "- %s\n", change)
+	}
+	return nil
+}
+
+// blueprintChanges returns what applying the blueprint would change in the cluster
+func blueprintChanges(ctx context.Context, blueprint *types.Blueprint, kubeConfig *k8s.KubeConfig) ([]string, error) {
+	provider, err := distro.GetProvider(blueprint, kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine kubernetes provider: %w", err)
+	}
+
+	var diff []string
+	needsUpgrade, err := provider.NeedsUpgrade(blueprint)
+	if err != nil {
+		return nil, err
+	}
+	if needsUpgrade {
+		diff = append(diff, "kubernetes provider upgrade")
+	}
+
+	client, err := k8s.GetClient(kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubernetes client: %w", err)
+	}
+	operator, err := client.AppsV1().Deployments(constants.NamespaceBlueprint).Get(ctx, constants.BlueprintOperatorDeployment, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return append(diff, "Blueprint Operator is not installed"), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Blueprint Operator deployment: %w", err)
+	}
+	drift, err := operatorDrift(operatorSettings(blueprint), operator)
+	if err != nil {
+		return nil, err
+	}
+	if len(drift) > 0 {
+		diff = append(diff, fmt.Sprintf("operator %s changed", strings.Join(drift, ", ")))
+	}
+
+	boundlessClient, err := k8s.GetBoundlessClient(kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blueprint client: %w", err)
+	}
+	blueprintDiff, err := components.DiffBlueprint(boundlessClient, blueprint)
+	if err != nil {
+		return nil, err
+	}
+	return append(diff, blueprintDiff...), nil
+}
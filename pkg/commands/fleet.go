@@ -0,0 +1,229 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mirantiscontainers/blueprint-operator/api/v1alpha1"
+	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/mirantiscontainers/blueprint-cli/boundlessclientset"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/fleet"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/k8s"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/readiness"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/utils"
+)
+
+// FleetOptions are the options of the fleet commands
+type FleetOptions struct {
+	// Concurrency overrides the concurrency of the fleet file when it is set
+	Concurrency int
+	// ContinueOnError keeps rolling out the blueprint after it failed on a cluster
+	ContinueOnError bool
+	// HealthTimeout is the time the clusters have to become healthy after the blueprint is applied
+	HealthTimeout time.Duration
+	ImageRegistry string
+	SkipPreflight bool
+	// PruneNodes drains and deletes the nodes of the hosts removed from the blueprint
+	PruneNodes bool
+	// Force removes the nodes of the hosts removed from the blueprint without a confirmation
+	Force bool
+}
+
+// FleetApply applies the blueprint to the clusters of the fleet, one wave after the other
+// Each cluster has to become healthy before the rollout continues, so that a failure stops it
+func FleetApply(fleetFile *types.Fleet, opts FleetOptions) error {
+	return runFleet(fleetFile, rolloutOptions(fleetFile, opts), func(ctx context.Context, target *fleet.Target) (string, error) {
+		if err := Apply(target.Blueprint, target.KubeConfig, false, opts.ImageRegistry, opts.SkipPreflight, opts.PruneNodes, opts.Force); err != nil {
+			return "", err
+		}
+		return waitForBlueprintHealthy(ctx, target, opts.HealthTimeout)
+	})
+}
+
+// FleetDiff prints the changes that applying the blueprint would make to each cluster of the fleet
+func FleetDiff(fleetFile *types.Fleet, opts FleetOptions) error {
+	return runFleet(fleetFile, readOnlyOptions(fleetFile, opts), diffCluster)
+}
+
+// FleetStatus prints the health of the blueprint on each cluster of the fleet
+func FleetStatus(fleetFile *types.Fleet, opts FleetOptions) error {
+	return runFleet(fleetFile, readOnlyOptions(fleetFile, opts), func(ctx context.Context, target *fleet.Target) (string, error) {
+		return blueprintHealth(ctx, target)
+	})
+}
+
+// runFleet runs the action against the clusters of the fleet and prints the summary
+func runFleet(fleetFile *types.Fleet, opts fleet.Options, action fleet.Action) error {
+	targets, err := fleetTargets(fleetFile)
+	if err != nil {
+		return err
+	}
+
+	results := fleet.Rollout(context.Background(), fleet.Waves(targets, fleetFile.Spec.Waves), opts, action)
+	fmt.Println()
+	fleet.PrintSummary(os.Stdout, results)
+
+	if failures := fleet.Failures(results); len(failures) > 0 {
+		return fmt.Errorf("failed on %d of %d clusters", len(failures), len(results))
+	}
+	return nil
+}
+
+func rolloutOptions(fleetFile *types.Fleet, opts FleetOptions) fleet.Options {
+	concurrency := fleetFile.Spec.Concurrency
+	if opts.Concurrency > 0 {
+		concurrency = opts.Concurrency
+	}
+	return fleet.Options{Concurrency: concurrency, ContinueOnError: opts.ContinueOnError}
+}
+
+// readOnlyOptions runs read-only commands against all the clusters, even after one of them failed
+func readOnlyOptions(fleetFile *types.Fleet, opts FleetOptions) fleet.Options {
+	options := rolloutOptions(fleetFile, opts)
+	options.ContinueOnError = true
+	return options
+}
+
+// fleetTargets loads the blueprint and the kubeconfig of every cluster of the fleet
+// All the clusters are loaded before the rollout starts, so that an invalid override does not stop it halfway
+func fleetTargets(fleetFile *types.Fleet) ([]*fleet.Target, error) {
+	var targets []*fleet.Target
+	for _, cluster := range fleetFile.Spec.Clusters {
+		blueprint, err := utils.LoadFleetBlueprint(fleetFile, &cluster)
+		if err != nil {
+			return nil, err
+		}
+
+		flags := genericclioptions.NewConfigFlags(k8s.UsePersistentConfig)
+		kubeConfigPath, kubeContext := cluster.KubeConfig, cluster.Context
+		flags.KubeConfig = &kubeConfigPath
+		flags.Context = &kubeContext
+
+		kubeConfig, err := NewKubeConfig(&blueprint, flags)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load kubeconfig of cluster %q: %w", cluster.Name, err)
+		}
+
+		targets = append(targets, &fleet.Target{
+			Name:       cluster.Name,
+			Wave:       cluster.Wave,
+			Blueprint:  &blueprint,
+			KubeConfig: kubeConfig,
+		})
+	}
+	return targets, nil
+}
+
+// waitForBlueprintHealthy waits for the operator and the addons of the blueprint to become available
+func waitForBlueprintHealthy(ctx context.Context, target *fleet.Target, timeout time.Duration) (string, error) {
+	dynamicClient, err := k8s.GetDynamicClient(target.KubeConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to get kubernetes client: %w", err)
+	}
+	operator := readiness.Deployment(constants.NamespaceBlueprint, constants.BlueprintOperatorDeployment, constants.OperatorRolloutTimeout)
+	if err := readiness.NewWaiter(dynamicClient).Wait(ctx, operator); err != nil {
+		return "", err
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var message string
+	var healthErr error
+	err = wait.PollUntilContextCancel(timeoutCtx, readiness.DefaultInterval, true, func(ctx context.Context) (bool, error) {
+		message, healthErr = blueprintHealth(ctx, target)
+		return healthErr == nil, nil
+	})
+	if err != nil {
+		return message, fmt.Errorf("blueprint is not healthy after %s: %w", timeout, healthErr)
+	}
+	return message, nil
+}
+
+// blueprintHealth checks that the operator is available and that the enabled addons of the blueprint are
+func blueprintHealth(ctx context.Context, target *fleet.Target) (string, error) {
+	client, err := k8s.GetClient(target.KubeConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to get kubernetes client: %w", err)
+	}
+	operator, err := client.AppsV1().Deployments(constants.NamespaceBlueprint).Get(ctx, constants.BlueprintOperatorDeployment, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return "", fmt.Errorf("no blueprint operator installation detected")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get Blueprint Operator deployment: %w", err)
+	}
+	if operator.Status.AvailableReplicas == 0 {
+		return "", fmt.Errorf("blueprint operator is not available")
+	}
+
+	boundlessClient, err := k8s.GetBoundlessClient(target.KubeConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to get blueprint client: %w", err)
+	}
+	return addonHealth(ctx, boundlessClient, target.Blueprint)
+}
+
+// addonHealth checks that the enabled addons of the blueprint are available
+func addonHealth(ctx context.Context, client boundlessclientset.BoundlessV1Alpha1Interface, blueprint *types.Blueprint) (string, error) {
+	addons, err := client.Addons(constants.NamespaceBlueprint).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to list addons: %w", err)
+	}
+
+	var enabled int
+	var unhealthy []string
+	for _, addon := range blueprint.Spec.Components.Addons {
+		if !addon.Enabled {
+			continue
+		}
+		enabled++
+
+		i := slices.IndexFunc(addons.Items, func(a v1alpha1.Addon) bool { return a.Name == addon.Name })
+		if i < 0 {
+			unhealthy = append(unhealthy, fmt.Sprintf("%s (not created)", addon.Name))
+			continue
+		}
+		status := addons.Items[i].Status.Type
+		if status != v1alpha1.TypeComponentAvailable && status != v1alpha1.TypeComponentReady {
+			unhealthy = append(unhealthy, fmt.Sprintf("%s (%s)", addon.Name, statusOrUnknown(status)))
+		}
+	}
+
+	message := fmt.Sprintf("%d/%d addons available", enabled-len(unhealthy), enabled)
+	if len(unhealthy) > 0 {
+		return message, fmt.Errorf("addons not available: %s", strings.Join(unhealthy, ", "))
+	}
+	return message, nil
+}
+
+// diffCluster describes what applying the blueprint would change in the cluster
+func diffCluster(ctx context.Context, target *fleet.Target) (string, error) {
+	diff, err := blueprintChanges(ctx, target.Blueprint, target.KubeConfig)
+	if err != nil {
+		return "", err
+	}
+	if len(diff) == 0 {
+		return "up to date", nil
+	}
+	log.Debug().Msgf("Cluster %s: %s", target.Name, strings.Join(diff, "; "))
+	return strings.Join(diff, "; "), nil
+}
+
+func statusOrUnknown(status v1alpha1.StatusType) string {
+	if status == "" {
+		return "Unknown"
+	}
+	return string(status)
+}
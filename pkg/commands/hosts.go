@@ -0,0 +1,28 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+// HostsImport prints the hosts of the blueprint, with the ones imported from spec.kubernetes.infra.hostsFrom,
+// as the hosts field of spec.kubernetes.infra
+func HostsImport(blueprint *types.Blueprint, w io.Writer) error {
+	kubernetes := blueprint.Spec.Kubernetes
+	if kubernetes == nil || kubernetes.Infra == nil || kubernetes.Infra.HostsFrom == nil {
+		return fmt.Errorf("the blueprint has no spec.kubernetes.infra.hostsFrom to import hosts from")
+	}
+
+	data, err := yaml.Marshal(struct {
+		Hosts []types.Host `json:"hosts"`
+	}{Hosts: kubernetes.Infra.Hosts})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,151 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/distro"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/k8s"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+// KubeConfigOptions are the options of the kubeconfig command
+type KubeConfigOptions struct {
+	// Output is the file the kubeconfig is written to
+	Output string
+	// Merge merges the kubeconfig into the kubeconfig file used by bctl and makes it the current context
+	Merge bool
+	// Minify removes everything but the context of the blueprint cluster from the kubeconfig
+	Minify bool
+	// ServiceAccount generates a kubeconfig for a service account instead of the cluster admin when its name is set
+	ServiceAccount k8s.ServiceAccountAccess
+}
+
+// NewKubeConfig returns the kubeconfig used to connect to the cluster of the blueprint
+// The blueprint defaults to an existing cluster if it does not have a Kubernetes section
+// kubeconfig priority:
+// 1. Explicit file set in the flags
+// 2. Blueprint kubeconfig location
+// 3. File from the environment variable KUBECONFIG
+// 4. File located at default location (e.g. ~/.kube/config)
+// The context is the one of the blueprint cluster, unless it is set in the flags
+func NewKubeConfig(blueprint *types.Blueprint, flags *genericclioptions.ConfigFlags) (*k8s.KubeConfig, error) {
+	if blueprint.Spec.Kubernetes == nil {
+		log.Debug().Msg("No Kubernetes section found in blueprint, defaulting to an existing cluster")
+		blueprint.Spec.Kubernetes = &types.Kubernetes{
+			Provider: constants.ProviderExisting,
+		}
+	}
+
+	provider, err := distro.GetProvider(blueprint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine kubernetes provider: %w", err)
+	}
+
+	if flags.KubeConfig != nil && *flags.KubeConfig == "" && blueprint.Spec.Kubernetes.KubeConfig != "" {
+		log.Debug().Msg("Using kubeconfig file specified in blueprint")
+		kubeConfigPath := blueprint.Spec.Kubernetes.KubeConfig
+		flags.KubeConfig = &kubeConfigPath
+	}
+
+	if flags.Context == nil || *flags.Context == "" {
+		kubeContext := provider.GetKubeConfigContext()
+		flags.Context = &kubeContext
+	}
+
+	// The remaining kubeconfig options (3&4) are handled by the genericclioptions library
+	return k8s.NewConfig(flags), nil
+}
+
+// KubeConfig generates the kubeconfig of the blueprint cluster
+// The kubeconfig is printed to stdout unless it is written to a file or merged into the kubeconfig used by bctl
+func KubeConfig(blueprint *types.Blueprint, kubeConfig *k8s.KubeConfig, opts KubeConfigOptions) error {
+	provider, err := distro.GetProvider(blueprint, kubeConfig)
+	if err != nil {
+		return fmt.Errorf("failed to determine kubernetes provider: %w", err)
+	}
+
+	// unmanaged clusters are read from the kubeconfig itself
+	if provider.Type() != constants.ProviderExisting {
+		exists, err := provider.Exists()
+		if err != nil {
+			return fmt.Errorf("failed to check if cluster exists: %w", err)
+		}
+		if !exists {
+			return fmt.Errorf("cluster doesn't exist: %s", blueprint.Metadata.Name)
+		}
+	}
+
+	config, err := provider.ClusterKubeConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get kubeconfig for cluster %s: %w", blueprint.Metadata.Name, err)
+	}
+
+	if opts.Minify {
+		if config, err = k8s.MinifyConfig(*config, provider.GetKubeConfigContext()); err != nil {
+			return err
+		}
+	}
+
+	if opts.ServiceAccount.Name != "" {
+		if config, err = serviceAccountKubeConfig(config, opts.ServiceAccount); err != nil {
+			return err
+		}
+	}
+
+	return writeKubeConfig(config, kubeConfig, opts)
+}
+
+// serviceAccountKubeConfig uses the admin kubeconfig to set up the service account and returns a kubeconfig with its token
+func serviceAccountKubeConfig(admin *clientcmdapi.Config, access k8s.ServiceAccountAccess) (*clientcmdapi.Config, error) {
+	restConfig, err := clientcmd.NewDefaultClientConfig(*admin, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cluster kubeconfig: %w", err)
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create k8s client: %w", err)
+	}
+
+	config, err := k8s.ServiceAccountKubeConfig(client, *admin, access)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubeconfig for service account %s/%s: %w", access.Namespace, access.Name, err)
+	}
+	log.Info().Msgf("Created service account %s/%s bound to cluster role %q", access.Namespace, access.Name, access.ClusterRole)
+
+	return config, nil
+}
+
+// writeKubeConfig writes the kubeconfig to the destinations selected by the options
+func writeKubeConfig(config *clientcmdapi.Config, kubeConfig *k8s.KubeConfig, opts KubeConfigOptions) error {
+	if opts.Output != "" {
+		if err := clientcmd.WriteToFile(*config, opts.Output); err != nil {
+			return fmt.Errorf("failed to write kubeconfig to %q: %w", opts.Output, err)
+		}
+		log.Info().Msgf("Kubeconfig written to %s", opts.Output)
+	}
+
+	if opts.Merge {
+		if err := kubeConfig.MergeConfig(*config); err != nil {
+			return fmt.Errorf("failed to merge kubeconfig into %q: %w", kubeConfig.GetConfigPath(), err)
+		}
+		log.Info().Msgf("Context %q merged into %s", config.CurrentContext, kubeConfig.GetConfigPath())
+	}
+
+	if opts.Output != "" || opts.Merge {
+		return nil
+	}
+
+	data, err := clientcmd.Write(*config)
+	if err != nil {
+		return fmt.Errorf("failed to serialize kubeconfig: %w", err)
+	}
+	fmt.Print(string(data))
+	return nil
+}
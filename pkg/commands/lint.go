@@ -0,0 +1,26 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/k0sconfig"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/versions"
+)
+
+// Lint returns the warnings about a valid blueprint that could fail to install, without connecting to the cluster
+func Lint(blueprint *types.Blueprint) []string {
+	var warnings []string
+
+	if kubernetes := blueprint.Spec.Kubernetes; kubernetes != nil && kubernetes.Provider == constants.ProviderK0s {
+		if warning := versions.Check(blueprint.Spec.Version, kubernetes.Version); warning != "" {
+			warnings = append(warnings, warning)
+		}
+		for _, field := range k0sconfig.UnknownFields(kubernetes.Config, kubernetes.Version) {
+			warnings = append(warnings, fmt.Sprintf("unknown field %s in kubernetes.config; check that it is not a typo and that the k0s version supports it", field))
+		}
+	}
+
+	return warnings
+}
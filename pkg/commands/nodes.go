@@ -0,0 +1,242 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/distro"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/k8s"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/ssh"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/utils"
+)
+
+const (
+	// k0sResetCommand removes k0s from a host; k0s has to be stopped before it can be reset
+	k0sResetCommand = "sudo k0s stop; sudo k0s reset"
+	// k0sEtcdLeaveCommand removes the etcd member of the controller of the host from the etcd cluster
+	k0sEtcdLeaveCommand = "sudo k0s etcd leave"
+	// appliedHostsKey is the key of the hosts in the secret of the applied hosts
+	appliedHostsKey = "hosts.json"
+)
+
+// removeDroppedNodes drains and deletes the nodes of the hosts that were removed from the blueprint, and resets k0s
+// on the removed hosts
+// The nodes are matched with the node names reported by the hosts of the blueprint, and only removed when prune is set,
+// after a confirmation unless forced. The removed hosts are the hosts of the last applied blueprint that are not
+// in the blueprint anymore, with their SSH settings, so that bctl can reach them: a removed controller leaves etcd,
+// as it has no node when it does not run a worker, and every removed host is reset once its node is deleted.
+// The hosts of the blueprint are then recorded, with the removed hosts that were kept.
+func removeDroppedNodes(blueprint *types.Blueprint, kubeConfig *k8s.KubeConfig, prune bool, force bool) error {
+	infra := blueprint.Spec.Kubernetes.Infra
+	if blueprint.Spec.Kubernetes.Provider != constants.ProviderK0s || infra == nil || isLocalHost(infra.Hosts) {
+		return nil
+	}
+
+	client, err := k8s.GetClient(kubeConfig)
+	if err != nil {
+		return fmt.Errorf("failed to get kubernetes client: %w", err)
+	}
+	ctx := context.Background()
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+	applied, err := appliedHosts(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	hostNodes, err := distro.HostNodeNames(infra.Hosts, runOnHost)
+	if err != nil {
+		return fmt.Errorf("cannot tell which nodes belong to the hosts of the blueprint: %w", err)
+	}
+	dropped, err := droppedNodes(nodes.Items, hostNodes)
+	if err != nil {
+		return err
+	}
+	removed := droppedHosts(applied, infra.Hosts)
+	if len(dropped) == 0 && len(removed) == 0 {
+		return recordHosts(ctx, client, infra.Hosts)
+	}
+
+	if len(dropped) > 0 {
+		fmt.Println("The following nodes are not hosts of the blueprint anymore:")
+		for _, node := range dropped {
+			fmt.Printf("  %s (%s)\n", node.Name, k8s.NodeInternalIP(node))
+		}
+	}
+	if len(removed) > 0 {
+		fmt.Println("The following hosts were removed from the blueprint:")
+		for _, host := range removed {
+			fmt.Printf("  %s (%s)\n", host.SSH.Address, host.Role)
+		}
+	}
+	keep := func(message string) error {
+		log.Warn().Msg(message)
+		return recordHosts(ctx, client, append(slices.Clone(infra.Hosts), removed...))
+	}
+	if !prune {
+		return keep("Keeping the nodes of the removed hosts; run again with --prune-nodes to drain and delete them and reset k0s on the hosts")
+	}
+	if !force {
+		ok, err := utils.Confirm("Drain and delete these nodes, and reset k0s on the removed hosts?")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return keep("Keeping the nodes of the removed hosts")
+		}
+	}
+
+	// the nodes of the removed hosts are told apart by the node names the hosts report, when they can be reached
+	removedNodes := map[string]bool{}
+	for _, host := range removed {
+		names, err := distro.HostNodeNames([]types.Host{host}, runOnHost)
+		if err != nil {
+			log.Warn().Msgf("Failed to get the node name of removed host %s: %s", host.SSH.Address, err)
+			continue
+		}
+		for _, name := range names {
+			removedNodes[name] = true
+		}
+	}
+
+	for _, node := range dropped {
+		if err := removeNode(ctx, client, node); err != nil {
+			return err
+		}
+		if !removedNodes[node.Name] {
+			log.Warn().Msgf("Node %q was deleted; run %q on its host (%s) to remove k0s from it", node.Name, k0sResetCommand, k8s.NodeInternalIP(node))
+		}
+	}
+	etcd := distro.UsesEtcd(blueprint.Spec.Kubernetes.Config)
+	for _, host := range removed {
+		if err := resetHost(host, etcd, runOnHost); err != nil {
+			return err
+		}
+	}
+	return recordHosts(ctx, client, infra.Hosts)
+}
+
+// removeNode drains and deletes the node
+// The node is kept if it cannot be drained, so that no pod is disrupted beyond its PodDisruptionBudget
+func removeNode(ctx context.Context, client kubernetes.Interface, node corev1.Node) error {
+	log.Info().Msgf("Removing node %q", node.Name)
+	if err := k8s.CordonNode(ctx, client, node.Name); err != nil {
+		return err
+	}
+	if err := k8s.DrainNode(ctx, client, node.Name, constants.NodeDrainTimeout); err != nil {
+		return fmt.Errorf("node %q was cordoned but not removed: %w", node.Name, err)
+	}
+	return k8s.DeleteNode(ctx, client, node.Name)
+}
+
+// resetHost removes k0s from a host removed from the blueprint, once its node is deleted
+// The etcd member of a controller leaves the etcd cluster first, so that the remaining members keep their quorum.
+func resetHost(host types.Host, etcd bool, run func(host types.Host, command string) (string, error)) error {
+	if etcd && distro.HostRunsController(host) {
+		log.Info().Msgf("Removing the etcd member of host %s", host.SSH.Address)
+		if _, err := run(host, k0sEtcdLeaveCommand); err != nil {
+			return fmt.Errorf("failed to remove the etcd member of host %s, run %q on it: %w", host.SSH.Address, k0sEtcdLeaveCommand, err)
+		}
+	}
+	log.Info().Msgf("Resetting k0s on host %s", host.SSH.Address)
+	if _, err := run(host, k0sResetCommand); err != nil {
+		return fmt.Errorf("failed to reset k0s on host %s, run %q on it: %w", host.SSH.Address, k0sResetCommand, err)
+	}
+	return nil
+}
+
+// runOnHost runs the command on the host over SSH
+func runOnHost(host types.Host, command string) (string, error) {
+	return ssh.Run(*host.SSH, command)
+}
+
+// droppedHosts returns the applied hosts that are not hosts of the blueprint anymore, by SSH address
+func droppedHosts(applied []types.Host, hosts []types.Host) []types.Host {
+	var dropped []types.Host
+	for _, host := range applied {
+		if host.SSH == nil {
+			continue
+		}
+		if !slices.ContainsFunc(hosts, func(h types.Host) bool { return h.SSH != nil && h.SSH.Address == host.SSH.Address }) {
+			dropped = append(dropped, host)
+		}
+	}
+	return dropped
+}
+
+// appliedHosts returns the hosts recorded by the last apply or update, if any
+func appliedHosts(ctx context.Context, client kubernetes.Interface) ([]types.Host, error) {
+	secret, err := client.CoreV1().Secrets(constants.NamespaceKubeSystem).Get(ctx, constants.AppliedHostsSecret, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the applied hosts: %w", err)
+	}
+
+	var hosts []types.Host
+	if err := json.Unmarshal(secret.Data[appliedHostsKey], &hosts); err != nil {
+		return nil, fmt.Errorf("failed to parse the applied hosts of secret %s/%s: %w", constants.NamespaceKubeSystem, constants.AppliedHostsSecret, err)
+	}
+	return hosts, nil
+}
+
+// recordHosts records the hosts for the next apply or update to find the hosts removed from the blueprint
+func recordHosts(ctx context.Context, client kubernetes.Interface, hosts []types.Host) error {
+	data, err := json.Marshal(hosts)
+	if err != nil {
+		return fmt.Errorf("failed to record the applied hosts: %w", err)
+	}
+	return k8s.CreateOrUpdateSecret(client, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: constants.AppliedHostsSecret, Namespace: constants.NamespaceKubeSystem},
+		Type:       corev1.SecretTypeOpaque,
+		Data:       map[string][]byte{appliedHostsKey: data},
+	})
+}
+
+// droppedNodes returns the nodes that are not the node of any host, given the node names of the hosts by address
+// A host whose node is not in the cluster means the nodes cannot be matched with the hosts, so no node is returned
+func droppedNodes(nodes []corev1.Node, hostNodes map[string]string) ([]corev1.Node, error) {
+	if len(hostNodes) == 0 && len(nodes) > 0 {
+		return nil, fmt.Errorf("none of the hosts of the blueprint runs a kubelet; not removing any of the %d nodes", len(nodes))
+	}
+	addresses := make([]string, 0, len(hostNodes))
+	hostNames := map[string]bool{}
+	for address, name := range hostNodes {
+		addresses = append(addresses, address)
+		hostNames[name] = true
+	}
+	slices.Sort(addresses)
+	for _, address := range addresses {
+		if !slices.ContainsFunc(nodes, func(node corev1.Node) bool { return node.Name == hostNodes[address] }) {
+			return nil, fmt.Errorf("node %q of host %s is not in the cluster; not removing any node", hostNodes[address], address)
+		}
+	}
+
+	var dropped []corev1.Node
+	for _, node := range nodes {
+		if !hostNames[node.Name] {
+			dropped = append(dropped, node)
+		}
+	}
+	return dropped, nil
+}
+
+// isLocalHost returns true if the cluster runs on the machine of bctl
+func isLocalHost(hosts []types.Host) bool {
+	return slices.ContainsFunc(hosts, func(host types.Host) bool {
+		return host.LocalHost != nil && host.LocalHost.Enabled
+	})
+}
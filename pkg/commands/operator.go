@@ -0,0 +1,304 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/k8s"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+const operatorManagerContainer = "manager"
+
+// operatorSettingsAnnotation records the extra args and env bctl set on the operator deployment,
+// so that the ones removed from the blueprint are removed from the deployment too
+const operatorSettingsAnnotation = "blueprint.mirantis.com/operator-settings"
+
+// appliedSettings are the operator settings recorded in the operatorSettingsAnnotation
+type appliedSettings struct {
+	ExtraArgs []string `json:"extraArgs,omitempty"`
+	Env       []string `json:"env,omitempty"`
+}
+
+// operatorMutator returns a manifest mutator that applies the blueprint operator settings
+// to the Blueprint Operator deployment of the manifest
+func operatorMutator(settings *types.Operator) k8s.ObjectMutator {
+	return func(obj *unstructured.Unstructured) error {
+		if settings == nil || !isOperatorDeployment(obj) {
+			return nil
+		}
+
+		var deployment appsv1.Deployment
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &deployment); err != nil {
+			return fmt.Errorf("failed to convert operator deployment: %w", err)
+		}
+
+		if err := applyOperatorSettings(settings, &deployment); err != nil {
+			return err
+		}
+
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&deployment)
+		if err != nil {
+			return fmt.Errorf("failed to convert operator deployment: %w", err)
+		}
+		obj.SetUnstructuredContent(content)
+
+		return nil
+	}
+}
+
+// operatorManifestMutators returns the mutators applied to the Blueprint Operator manifest
+func operatorManifestMutators(blueprint *types.Blueprint) []k8s.ObjectMutator {
+	return []k8s.ObjectMutator{
+		operatorMutator(operatorSettings(blueprint)),
+		k8s.InventoryMutator(constants.InventoryOwnerOperator, blueprint.Spec.Version),
+	}
+}
+
+// operatorSettings returns the blueprint operator settings with the proxy environment variables of the blueprint
+// Env vars set in the operator settings take precedence over the proxy ones
+func operatorSettings(blueprint *types.Blueprint) *types.Operator {
+	proxyEnv := blueprint.Spec.ProxyEnv()
+	if proxyEnv == nil {
+		return blueprint.Spec.Operator
+	}
+
+	settings := &types.Operator{}
+	if blueprint.Spec.Operator != nil {
+		copied := *blueprint.Spec.Operator
+		settings = &copied
+	}
+
+	names := make([]string, 0, len(proxyEnv))
+	for name := range proxyEnv {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	env := make([]corev1.EnvVar, 0, len(names))
+	for _, name := range names {
+		env = append(env, corev1.EnvVar{Name: name, Value: proxyEnv[name]})
+	}
+	settings.Env = mergeEnv(env, settings.Env)
+
+	return settings
+}
+
+func isOperatorDeployment(obj *unstructured.Unstructured) bool {
+	return obj.GetKind() == "Deployment" &&
+		obj.GetName() == constants.BlueprintOperatorDeployment &&
+		obj.GetNamespace() == constants.NamespaceBlueprint
+}
+
+// applyOperatorSettings sets the blueprint operator settings on the deployment
+// Extra args and env are merged into the ones of the manager container, so applying the settings twice is a noop.
+// The extra args and env that bctl set before and that are no longer in the settings are removed.
+func applyOperatorSettings(settings *types.Operator, deployment *appsv1.Deployment) error {
+	podSpec := &deployment.Spec.Template.Spec
+
+	if settings.Replicas != nil {
+		replicas := *settings.Replicas
+		deployment.Spec.Replicas = &replicas
+	}
+	if settings.NodeSelector != nil {
+		podSpec.NodeSelector = settings.NodeSelector
+	}
+	if settings.Tolerations != nil {
+		podSpec.Tolerations = settings.Tolerations
+	}
+	if settings.Affinity != nil {
+		podSpec.Affinity = settings.Affinity
+	}
+	if settings.PriorityClassName != "" {
+		podSpec.PriorityClassName = settings.PriorityClassName
+	}
+
+	i, err := operatorContainerIndex(podSpec.Containers)
+	if err != nil {
+		return err
+	}
+	container := &podSpec.Containers[i]
+
+	if settings.Resources != nil {
+		container.Resources = *settings.Resources
+	}
+
+	previous, err := previousSettings(deployment)
+	if err != nil {
+		return err
+	}
+	container.Args = slices.DeleteFunc(container.Args, func(arg string) bool {
+		return slices.Contains(previous.ExtraArgs, arg) && !slices.Contains(settings.ExtraArgs, arg)
+	})
+	container.Env = slices.DeleteFunc(container.Env, func(env corev1.EnvVar) bool {
+		return slices.Contains(previous.Env, env.Name) && !slices.ContainsFunc(settings.Env, func(e corev1.EnvVar) bool { return e.Name == env.Name })
+	})
+
+	for _, arg := range settings.ExtraArgs {
+		if !slices.Contains(container.Args, arg) {
+			container.Args = append(container.Args, arg)
+		}
+	}
+	container.Env = mergeEnv(container.Env, settings.Env)
+
+	return recordSettings(settings, deployment)
+}
+
+// previousSettings returns the extra args and env bctl set on the deployment before
+func previousSettings(deployment *appsv1.Deployment) (appliedSettings, error) {
+	var applied appliedSettings
+	value, ok := deployment.Annotations[operatorSettingsAnnotation]
+	if !ok {
+		return applied, nil
+	}
+	if err := json.Unmarshal([]byte(value), &applied); err != nil {
+		return applied, fmt.Errorf("invalid annotation %s of the operator deployment: %w", operatorSettingsAnnotation, err)
+	}
+	return applied, nil
+}
+
+// recordSettings records the extra args and env of the settings on the deployment
+func recordSettings(settings *types.Operator, deployment *appsv1.Deployment) error {
+	applied := appliedSettings{ExtraArgs: settings.ExtraArgs}
+	for _, env := range settings.Env {
+		applied.Env = append(applied.Env, env.Name)
+	}
+
+	if len(applied.ExtraArgs) == 0 && len(applied.Env) == 0 {
+		delete(deployment.Annotations, operatorSettingsAnnotation)
+		return nil
+	}
+	value, err := json.Marshal(applied)
+	if err != nil {
+		return fmt.Errorf("failed to record the operator settings: %w", err)
+	}
+	if deployment.Annotations == nil {
+		deployment.Annotations = map[string]string{}
+	}
+	deployment.Annotations[operatorSettingsAnnotation] = string(value)
+	return nil
+}
+
+// operatorContainerIndex returns the index of the Blueprint Operator container
+func operatorContainerIndex(containers []corev1.Container) (int, error) {
+	for i, container := range containers {
+		if bopImageRegex.MatchString(container.Image) {
+			return i, nil
+		}
+	}
+	for i, container := range containers {
+		if container.Name == operatorManagerContainer {
+			return i, nil
+		}
+	}
+
+	return -1, fmt.Errorf("unable to find Blueprint Operator container in the operator deployment")
+}
+
+// mergeEnv sets the env vars in the existing list, replacing the ones with the same name
+func mergeEnv(existing []corev1.EnvVar, env []corev1.EnvVar) []corev1.EnvVar {
+	for _, e := range env {
+		i := slices.IndexFunc(existing, func(ev corev1.EnvVar) bool { return ev.Name == e.Name })
+		if i >= 0 {
+			existing[i] = e
+		} else {
+			existing = append(existing, e)
+		}
+	}
+	return existing
+}
+
+// operatorDrift returns the fields of the live operator deployment that differ from the blueprint operator settings
+// Without settings, only the extra args and env that bctl set before are drift.
+func operatorDrift(settings *types.Operator, live *appsv1.Deployment) ([]string, error) {
+	if settings == nil {
+		settings = &types.Operator{}
+	}
+
+	desired := live.DeepCopy()
+	if err := applyOperatorSettings(settings, desired); err != nil {
+		return nil, err
+	}
+
+	var drift []string
+	if !equality.Semantic.DeepEqual(desired.Spec.Replicas, live.Spec.Replicas) {
+		drift = append(drift, "replicas")
+	}
+
+	desiredPod, livePod := desired.Spec.Template.Spec, live.Spec.Template.Spec
+	if !equality.Semantic.DeepEqual(desiredPod.NodeSelector, livePod.NodeSelector) {
+		drift = append(drift, "nodeSelector")
+	}
+	if !equality.Semantic.DeepEqual(desiredPod.Tolerations, livePod.Tolerations) {
+		drift = append(drift, "tolerations")
+	}
+	if !equality.Semantic.DeepEqual(desiredPod.Affinity, livePod.Affinity) {
+		drift = append(drift, "affinity")
+	}
+	if desiredPod.PriorityClassName != livePod.PriorityClassName {
+		drift = append(drift, "priorityClassName")
+	}
+
+	i, err := operatorContainerIndex(livePod.Containers)
+	if err != nil {
+		return nil, err
+	}
+	desiredContainer, liveContainer := desiredPod.Containers[i], livePod.Containers[i]
+	if !equality.Semantic.DeepEqual(desiredContainer.Resources, liveContainer.Resources) {
+		drift = append(drift, "resources")
+	}
+	if !equality.Semantic.DeepEqual(desiredContainer.Args, liveContainer.Args) {
+		drift = append(drift, "extraArgs")
+	}
+	if !equality.Semantic.DeepEqual(desiredContainer.Env, liveContainer.Env) {
+		drift = append(drift, "env")
+	}
+
+	return drift, nil
+}
+
+// reconcileOperatorDeployment applies the blueprint operator settings to the live operator deployment
+// It is used when the operator is already installed and its manifest is not reapplied
+func reconcileOperatorDeployment(client kubernetes.Interface, settings *types.Operator) error {
+	if settings == nil {
+		settings = &types.Operator{}
+	}
+
+	deployments := client.AppsV1().Deployments(constants.NamespaceBlueprint)
+	live, err := deployments.Get(context.TODO(), constants.BlueprintOperatorDeployment, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get Blueprint Operator deployment: %w", err)
+	}
+
+	drift, err := operatorDrift(settings, live)
+	if err != nil {
+		return err
+	}
+	if len(drift) == 0 {
+		log.Debug().Msg("Blueprint Operator deployment matches the operator settings")
+		return nil
+	}
+
+	log.Info().Msgf("Updating Blueprint Operator deployment (%s)", strings.Join(drift, ", "))
+	if err = applyOperatorSettings(settings, live); err != nil {
+		return err
+	}
+	if _, err = deployments.Update(context.TODO(), live, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update Blueprint Operator deployment: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/k8s"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/preflight"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/ssh"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+// Preflight checks that the cluster is ready for the blueprint to be applied and prints the results
+// It returns an error if any of the checks failed
+func Preflight(blueprint *types.Blueprint, kubeConfig *k8s.KubeConfig) error {
+	log.Info().Msg("Running preflight checks")
+
+	config, err := kubeConfig.RESTConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get REST config: %w", err)
+	}
+	clients, err := k8s.GetClients(kubeConfig)
+	if err != nil {
+		return fmt.Errorf("failed to get kubernetes clients: %w", err)
+	}
+
+	uri, cleanup, err := operatorManifest(blueprint, "")
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	manifest, err := k8s.ReadYamlManifest(uri)
+	if err != nil {
+		return fmt.Errorf("failed to read manifest from %q: %w", uri, err)
+	}
+
+	cluster := &preflight.Cluster{
+		Clients:   clients,
+		Config:    config,
+		Blueprint: blueprint,
+		Manifest:  manifest,
+	}
+	report := preflight.Run(context.Background(), cluster, preflight.Checks())
+	report.Print(os.Stdout)
+
+	if failed := report.Failed(); len(failed) > 0 {
+		return fmt.Errorf("%d preflight checks failed", len(failed))
+	}
+	return nil
+}
+
+// HostsCheck checks that the hosts of the blueprint can run k0s and prints the results of every host
+// It returns an error if any of the checks failed on any of the hosts
+func HostsCheck(blueprint *types.Blueprint) error {
+	hosts := sshHosts(blueprint)
+	if len(hosts) == 0 {
+		log.Info().Msg("The blueprint has no SSH hosts to check")
+		return nil
+	}
+
+	log.Info().Msgf("Checking %d hosts", len(hosts))
+	report := preflight.RunHosts(context.Background(), ssh.DefaultPool(), hosts, preflight.HostChecks())
+	report.Print(os.Stdout)
+
+	if failed := report.Failed(); len(failed) > 0 {
+		return fmt.Errorf("host checks failed on %d hosts", len(failed))
+	}
+	return nil
+}
+
+// sshHosts returns the hosts of a k0s blueprint that are reached over SSH
+func sshHosts(blueprint *types.Blueprint) []types.Host {
+	kubernetes := blueprint.Spec.Kubernetes
+	if kubernetes == nil || kubernetes.Provider != constants.ProviderK0s || kubernetes.Infra == nil {
+		return nil
+	}
+	var hosts []types.Host
+	for _, host := range kubernetes.Infra.Hosts {
+		if host.SSH != nil {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
@@ -0,0 +1,166 @@
+package commands
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/k8s"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+// dockerConfigJSON is the content of a kubernetes.io/dockerconfigjson secret
+type dockerConfigJSON struct {
+	Auths map[string]dockerConfigEntry `json:"auths"`
+}
+
+type dockerConfigEntry struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Auth     string `json:"auth,omitempty"`
+}
+
+// ensureRegistryCredentials creates the image pull secret in the blueprint namespace
+// and, if requested, in every addon namespace, where it is added to the service accounts
+// The service accounts an addon creates later have to reference the secret themselves, e.g. through the values of its chart.
+// It is a noop when the blueprint has no registry credentials
+func ensureRegistryCredentials(client kubernetes.Interface, blueprint *types.Blueprint, imageRegistry string) error {
+	creds := registryCredentials(blueprint)
+	if creds == nil {
+		return nil
+	}
+
+	data, err := buildDockerConfigJSON(creds, imageRegistry)
+	if err != nil {
+		return fmt.Errorf("failed to build registry credentials: %w", err)
+	}
+
+	namespaces := []string{constants.NamespaceBlueprint}
+	if creds.CopyToAddonNamespaces {
+		for _, addon := range blueprint.Spec.Components.Addons {
+			if addon.Namespace != "" && !strings.EqualFold(addon.Namespace, constants.NamespaceBlueprint) {
+				namespaces = append(namespaces, addon.Namespace)
+			}
+		}
+	}
+
+	for _, namespace := range namespaces {
+		if err := k8s.EnsureNamespace(client, namespace); err != nil {
+			return err
+		}
+
+		log.Info().Msgf("Creating image pull secret %s/%s", namespace, constants.RegistryCredentialsSecret)
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      constants.RegistryCredentialsSecret,
+				Namespace: namespace,
+			},
+			Type: corev1.SecretTypeDockerConfigJson,
+			Data: map[string][]byte{
+				corev1.DockerConfigJsonKey: data,
+			},
+		}
+		if err := k8s.CreateOrUpdateSecret(client, secret); err != nil {
+			return err
+		}
+
+		// the operator service accounts are only created with the operator, see attachRegistryCredentials
+		if namespace == constants.NamespaceBlueprint {
+			continue
+		}
+		if err := k8s.EnsureServiceAccount(client, namespace, "default"); err != nil {
+			return err
+		}
+		if err := k8s.AddImagePullSecret(client, namespace, constants.RegistryCredentialsSecret); err != nil {
+			return fmt.Errorf("failed to add image pull secret to the service accounts of namespace %q: %w", namespace, err)
+		}
+	}
+
+	return nil
+}
+
+// attachRegistryCredentials adds the image pull secret to the operator's service accounts
+// Applying the operator manifest resets the service accounts, so this has to run after every apply
+func attachRegistryCredentials(client kubernetes.Interface, blueprint *types.Blueprint) error {
+	if registryCredentials(blueprint) == nil {
+		return nil
+	}
+
+	if err := k8s.AddImagePullSecret(client, constants.NamespaceBlueprint, constants.RegistryCredentialsSecret); err != nil {
+		return fmt.Errorf("failed to add image pull secret to operator service accounts: %w", err)
+	}
+	if err := k8s.RecreatePodsWithoutPullSecret(client, constants.NamespaceBlueprint, constants.RegistryCredentialsSecret); err != nil {
+		return fmt.Errorf("failed to restart operator pods: %w", err)
+	}
+
+	return nil
+}
+
+func registryCredentials(blueprint *types.Blueprint) *types.RegistryCredentials {
+	if blueprint.Spec.Registry == nil {
+		return nil
+	}
+	return blueprint.Spec.Registry.Credentials
+}
+
+// buildDockerConfigJSON returns the docker config.json content for the given credentials
+// A docker config file is used as is; otherwise the username and password are read from the environment
+// and registered for the credentials server, or the host of the image registry
+func buildDockerConfigJSON(creds *types.RegistryCredentials, imageRegistry string) ([]byte, error) {
+	if creds.DockerConfig != "" {
+		data, err := os.ReadFile(creds.DockerConfig)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read docker config %s: %w", creds.DockerConfig, err)
+		}
+
+		var cfg dockerConfigJSON
+		if err = json.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("unable to parse docker config %s: %w", creds.DockerConfig, err)
+		}
+		if len(cfg.Auths) == 0 {
+			return nil, fmt.Errorf("docker config %s does not contain any auths", creds.DockerConfig)
+		}
+		return data, nil
+	}
+
+	username := os.Getenv(creds.UsernameEnv)
+	if username == "" {
+		return nil, fmt.Errorf("environment variable %s with the registry username is not set", creds.UsernameEnv)
+	}
+	password := os.Getenv(creds.PasswordEnv)
+	if password == "" {
+		return nil, fmt.Errorf("environment variable %s with the registry password is not set", creds.PasswordEnv)
+	}
+
+	server := creds.Server
+	if server == "" {
+		server = registryHost(imageRegistry)
+	}
+	if server == "" {
+		return nil, fmt.Errorf("unable to determine the registry server; set registry.credentials.server or use --image-registry")
+	}
+
+	return json.Marshal(dockerConfigJSON{
+		Auths: map[string]dockerConfigEntry{
+			server: {
+				Username: username,
+				Password: password,
+				Auth:     base64.StdEncoding.EncodeToString([]byte(username + ":" + password)),
+			},
+		},
+	})
+}
+
+// registryHost returns the host part of an image registry, e.g. "registry.example.com" for "registry.example.com/mirror"
+func registryHost(imageRegistry string) string {
+	host, _, _ := strings.Cut(imageRegistry, "/")
+	return host
+}
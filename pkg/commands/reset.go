@@ -1,17 +1,16 @@
 package commands
 
 import (
-	"bufio"
 	"fmt"
-	"os"
 
-	"github.com/fatih/color"
 	"github.com/rs/zerolog/log"
 
 	"github.com/mirantiscontainers/blueprint-cli/pkg/components"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/distro"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/k8s"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/utils"
 )
 
 // Reset resets the cluster
@@ -19,14 +18,9 @@ func Reset(blueprint *types.Blueprint, kubeConfig *k8s.KubeConfig, force bool) e
 	log.Info().Msg("Resetting cluster")
 
 	if !force {
-		color.Red("This will remove all resources and completely destroy the cluster. Are you sure? (N/y)")
-		reader := bufio.NewReader(os.Stdin)
-		answer, err := reader.ReadString('\n')
-		if err != nil {
-			return fmt.Errorf("failed to read input: %w", err)
-		}
-		if answer != "y\n" {
-			return nil
+		ok, err := utils.Confirm("This will remove all resources and completely destroy the cluster. Are you sure?")
+		if err != nil || !ok {
+			return err
 		}
 	}
 
@@ -38,15 +32,20 @@ func Reset(blueprint *types.Blueprint, kubeConfig *k8s.KubeConfig, force bool) e
 
 	// Uninstall components
 	log.Info().Msgf("Reset Blueprint Operator resources")
-	err = components.RemoveComponents(provider.GetKubeConfig(), blueprint)
+	boundlessClient, err := k8s.GetBoundlessClient(provider.GetKubeConfig())
+	if err != nil {
+		return fmt.Errorf("failed to get blueprint client: %w", err)
+	}
+	err = components.RemoveComponents(boundlessClient, blueprint)
 	if err != nil {
 		return fmt.Errorf("failed to reset components: %w", err)
 	}
 
-	uri, err := determineOperatorUri(blueprint.Spec.Version)
+	uri, cleanup, err := operatorManifest(blueprint, "")
 	if err != nil {
-		return fmt.Errorf("failed to determine operator URI: %w", err)
+		return err
 	}
+	defer cleanup()
 
 	log.Info().Msgf("Uninstalling Blueprint Operator")
 	log.Debug().Msgf("Uninstalling blueprint operator using manifest file: %s", uri)
@@ -59,5 +58,40 @@ func Reset(blueprint *types.Blueprint, kubeConfig *k8s.KubeConfig, force bool) e
 		return fmt.Errorf("failed to reset cluster: %w", err)
 	}
 
+	// The kubeconfig entries of unmanaged clusters are not added by bctl, so they are kept
+	if provider.Type() == constants.ProviderExisting {
+		return nil
+	}
+
+	return cleanupKubeConfig(kubeConfig, provider.GetKubeConfigContext())
+}
+
+// cleanupKubeConfig removes the kubeconfig entries of the deleted cluster and reports what was changed
+func cleanupKubeConfig(kubeConfig *k8s.KubeConfig, context string) error {
+	removal, err := kubeConfig.DelContext(context)
+	if err != nil {
+		return fmt.Errorf("failed to remove context %q from kubeconfig %q: %w", context, kubeConfig.GetConfigPath(), err)
+	}
+
+	if removal.Context == "" {
+		log.Debug().Msgf("Context %q not found in kubeconfig %q. No changes made", context, kubeConfig.GetConfigPath())
+		return nil
+	}
+
+	log.Info().Msgf("Removed context %q from kubeconfig %q", removal.Context, kubeConfig.GetConfigPath())
+	if removal.Cluster != "" {
+		log.Info().Msgf("Removed cluster %q from kubeconfig", removal.Cluster)
+	}
+	if removal.User != "" {
+		log.Info().Msgf("Removed user %q from kubeconfig", removal.User)
+	}
+	if removal.CurrentContextChanged {
+		if removal.CurrentContext != "" {
+			log.Info().Msgf("Switched current context to %q", removal.CurrentContext)
+		} else {
+			log.Info().Msg("Cleared current context; no previous context is available")
+		}
+	}
+
 	return nil
 }
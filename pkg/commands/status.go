@@ -6,15 +6,15 @@ import (
 	"strings"
 
 	"github.com/mirantiscontainers/blueprint-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/kubernetes/scheme"
-	"k8s.io/client-go/tools/clientcmd"
 
 	"github.com/mirantiscontainers/blueprint-cli/boundlessclientset"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/k8s"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/utils"
 )
 
@@ -27,7 +27,8 @@ const (
 )
 
 // Status prints the status of the blueprint operator and any installed addons
-func Status(kubeConfig *k8s.KubeConfig) error {
+// It also reports when the operator deployment has drifted from the blueprint operator settings
+func Status(blueprint *types.Blueprint, kubeConfig *k8s.KubeConfig) error {
 	k8sclient, err := k8s.GetClient(kubeConfig)
 	if err != nil {
 		panic(err)
@@ -42,6 +43,7 @@ func Status(kubeConfig *k8s.KubeConfig) error {
 		}
 	} else {
 		utils.PrintDeploymentStatus(*operatorDeployment)
+		printOperatorDrift(operatorSettings(blueprint), operatorDeployment)
 	}
 
 	helmController, err := k8sclient.AppsV1().Deployments(helmControllerNamespace).Get(context.TODO(), helmControllerDeployment, metav1.GetOptions{})
@@ -57,7 +59,12 @@ func Status(kubeConfig *k8s.KubeConfig) error {
 
 	fmt.Println("-------------------------------------------------------")
 
-	addonList, err := getAddons(kubeConfig)
+	boundlessClient, err := k8s.GetBoundlessClient(kubeConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	addonList, err := getAddons(boundlessClient)
 	if err != nil {
 		panic(err)
 	}
@@ -77,7 +84,12 @@ func Status(kubeConfig *k8s.KubeConfig) error {
 
 // AddonSpecificStatus prints the status of a specific addon
 func AddonSpecificStatus(kubeConfig *k8s.KubeConfig, providedAddonName string) error {
-	providedAddon, err := getAddon(kubeConfig, providedAddonName)
+	boundlessClient, err := k8s.GetBoundlessClient(kubeConfig)
+	if err != nil {
+		return err
+	}
+
+	providedAddon, err := getAddon(boundlessClient, providedAddonName)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			return fmt.Errorf("invalid input %s, no addon named %s exists", providedAddonName, providedAddonName)
@@ -102,7 +114,7 @@ func AddonSpecificStatus(kubeConfig *k8s.KubeConfig, providedAddonName string) e
 		printHelmchartResources(k8sclient, *providedAddon)
 
 	} else {
-		printManifestResources(kubeConfig, *providedAddon, k8sclient)
+		printManifestResources(boundlessClient, *providedAddon, k8sclient)
 
 	}
 	fmt.Println("-------------------------------------------------------")
@@ -135,15 +147,8 @@ func AddonSpecificStatus(kubeConfig *k8s.KubeConfig, providedAddonName string) e
 	return nil
 }
 
-func getAddon(kubeConfig *k8s.KubeConfig, addonName string) (*v1alpha1.Addon, error) {
-	v1alpha1.AddToScheme(scheme.Scheme)
-
-	clientSet, err := getBoundlessClientSet(kubeConfig)
-	if err != nil {
-		return nil, err
-	}
-
-	addon, err := clientSet.Addons(constants.NamespaceBlueprint).Get(addonName, metav1.GetOptions{})
+func getAddon(client boundlessclientset.BoundlessV1Alpha1Interface, addonName string) (*v1alpha1.Addon, error) {
+	addon, err := client.Addons(constants.NamespaceBlueprint).Get(context.TODO(), addonName, metav1.GetOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -151,41 +156,27 @@ func getAddon(kubeConfig *k8s.KubeConfig, addonName string) (*v1alpha1.Addon, er
 	return addon, nil
 }
 
-func getAddons(kubeConfig *k8s.KubeConfig) (*v1alpha1.AddonList, error) {
-	v1alpha1.AddToScheme(scheme.Scheme)
-
-	clientSet, err := getBoundlessClientSet(kubeConfig)
-	if err != nil {
-		return nil, err
-	}
-
-	addonList, err := clientSet.Addons(constants.NamespaceBlueprint).List(metav1.ListOptions{})
+func getAddons(client boundlessclientset.BoundlessV1Alpha1Interface) (*v1alpha1.AddonList, error) {
+	addonList, err := client.Addons(constants.NamespaceBlueprint).List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
 		return nil, err
 	}
 	return addonList, nil
 }
 
-func getBoundlessClientSet(kubeConfig *k8s.KubeConfig) (*boundlessclientset.BoundlessV1Alpha1Client, error) {
-	config, err := clientcmd.BuildConfigFromFlags("", kubeConfig.GetConfigPath())
+func printOperatorDrift(settings *types.Operator, operatorDeployment *appsv1.Deployment) {
+	drift, err := operatorDrift(settings, operatorDeployment)
 	if err != nil {
-		return nil, err
+		fmt.Printf("Unable to compare Blueprint Operator with the blueprint operator settings: %s\n", err)
+		return
 	}
-
-	clientSet, err := boundlessclientset.NewForConfig(config)
-	if err != nil {
-		return nil, err
+	if len(drift) > 0 {
+		fmt.Printf("Blueprint Operator has drifted from the blueprint operator settings: %s\n", strings.Join(drift, ", "))
 	}
-	return clientSet, nil
 }
 
-func printManifestResources(kubeConfig *k8s.KubeConfig, providedAddon v1alpha1.Addon, k8sclient *kubernetes.Clientset) {
-	clientSet, err := getBoundlessClientSet(kubeConfig)
-	if err != nil {
-		panic(err)
-	}
-
-	manifest, err := clientSet.Manifests(constants.NamespaceBlueprint).Get(providedAddon.Spec.Name, metav1.GetOptions{})
+func printManifestResources(client boundlessclientset.BoundlessV1Alpha1Interface, providedAddon v1alpha1.Addon, k8sclient *kubernetes.Clientset) {
+	manifest, err := client.Manifests(constants.NamespaceBlueprint).Get(context.TODO(), providedAddon.Spec.Name, metav1.GetOptions{})
 	if err != nil {
 		panic(err)
 	}
@@ -11,31 +11,45 @@ import (
 )
 
 // Update updates the Blueprint Operator and applies the components defined in the blueprint
-func Update(blueprint *types.Blueprint, kubeConfig *k8s.KubeConfig) error {
+// The nodes of the hosts removed from the blueprint are removed from the cluster when pruneNodes is set,
+// after a confirmation unless forced
+// A resumed provider upgrade skips the version checks, since some of the nodes already run the new version
+func Update(blueprint *types.Blueprint, kubeConfig *k8s.KubeConfig, pruneNodes bool, force bool, upgrade distro.UpgradeOptions) error {
 	// Determine the distro
 	provider, err := distro.GetProvider(blueprint, kubeConfig)
 	if err != nil {
 		return fmt.Errorf("failed to determine kubernetes provider: %w", err)
 	}
 
-	needsUpgrade, err := provider.NeedsUpgrade(blueprint)
-	if err != nil {
-		return err
+	needsUpgrade := upgrade.Resume
+	if !upgrade.Resume {
+		if needsUpgrade, err = provider.NeedsUpgrade(blueprint); err != nil {
+			return err
+		}
+		if needsUpgrade {
+			if err := provider.ValidateProviderUpgrade(blueprint); err != nil {
+				return fmt.Errorf("provider failed pre-upgrade validation and may require manual changes: %w", err)
+			}
+		}
 	}
 
 	if needsUpgrade {
-		if err := provider.ValidateProviderUpgrade(blueprint); err != nil {
-			return fmt.Errorf("provider failed pre-upgrade validation and may require manual changes: %w", err)
-		}
-
 		log.Info().Msgf("Updating provider")
-		if err := provider.Upgrade(); err != nil {
+		if err := provider.Upgrade(upgrade); err != nil {
 			return fmt.Errorf("failed to update provider: %w", err)
 		}
 	}
 
+	if err := removeDroppedNodes(blueprint, kubeConfig, pruneNodes, force); err != nil {
+		return fmt.Errorf("failed to remove the nodes of the removed hosts: %w", err)
+	}
+
 	log.Info().Msgf("Applying Blueprint Operator resources")
-	if err := components.ApplyBlueprint(kubeConfig, blueprint); err != nil {
+	boundlessClient, err := k8s.GetBoundlessClient(kubeConfig)
+	if err != nil {
+		return fmt.Errorf("failed to get blueprint client: %w", err)
+	}
+	if err := components.ApplyBlueprint(boundlessClient, blueprint); err != nil {
 		return fmt.Errorf("failed to update components: %w", err)
 	}
 
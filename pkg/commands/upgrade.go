@@ -3,22 +3,25 @@ package commands
 import (
 	"context"
 	"fmt"
-	"os"
-	"strings"
+	"slices"
 
 	"github.com/rs/zerolog/log"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/dynamic"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/client-go/kubernetes"
 
 	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/distro"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/k8s"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/readiness"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
 )
 
 // Upgrade upgrades the Blueprint Operator
-func Upgrade(blueprint *types.Blueprint, kubeConfig *k8s.KubeConfig, imageRegistry string) error {
+// When rollback is enabled, the live operator objects are restored if the upgraded operator does not become healthy
+// When prune is enabled, the operator objects that are not part of the new manifest are deleted after the upgrade;
+// pruneDryRun only lists these objects and leaves the cluster unchanged
+func Upgrade(blueprint *types.Blueprint, kubeConfig *k8s.KubeConfig, imageRegistry string, rollback bool, prune bool, pruneDryRun bool) error {
 	var client kubernetes.Interface
 	var err error
 
@@ -47,29 +50,62 @@ func Upgrade(blueprint *types.Blueprint, kubeConfig *k8s.KubeConfig, imageRegist
 		)
 	}
 
-	uri, err := determineOperatorUri(blueprint.Spec.Version)
+	uri, cleanup, err := operatorManifest(blueprint, imageRegistry)
 	if err != nil {
-		return fmt.Errorf("failed to determine operator URI: %w", err)
+		return err
 	}
+	defer cleanup()
 
-	var needCleanup bool
-	uri, needCleanup, err = setImageRegistry(uri, imageRegistry)
+	clients, err := k8s.GetClients(kubeConfig)
 	if err != nil {
-		return fmt.Errorf("failed to set image registry in BOP manifest: %w", err)
+		return fmt.Errorf("failed to get kubernetes clients: %w", err)
 	}
-	if needCleanup {
-		defer os.Remove(strings.TrimPrefix(uri, "file://"))
+
+	var prunable []unstructured.Unstructured
+	if prune || pruneDryRun {
+		if prunable, err = findPrunableOperatorObjects(clients, uri); err != nil {
+			return err
+		}
+	}
+	if pruneDryRun {
+		printPrunable(prunable)
+		return nil
+	}
+
+	if err := ensureRegistryCredentials(client, blueprint, imageRegistry); err != nil {
+		return fmt.Errorf("failed to set up registry credentials: %w", err)
 	}
 
-	var dynamicClient dynamic.Interface
+	mutators := operatorManifestMutators(blueprint)
+	waiter := readiness.NewWaiter(clients.Dynamic)
+	waiter.Timeout = constants.OperatorRolloutTimeout
 
-	if dynamicClient, err = k8s.GetDynamicClient(kubeConfig); err != nil {
-		return fmt.Errorf("failed to get kubernetes dynamic client: %q", err)
+	var snapshot *k8s.Snapshot
+	if rollback {
+		log.Info().Msg("Taking a snapshot of the deployed Blueprint Operator")
+		if snapshot, err = k8s.SnapshotYaml(clients, uri, constants.InventoryOwnerOperator, mutators...); err != nil {
+			return fmt.Errorf("failed to take a snapshot of the deployed Blueprint Operator: %w", err)
+		}
 	}
 
 	log.Info().Msgf("Upgrading Blueprint Operator using manifest file %q", uri)
-	if err := k8s.ApplyYaml(client, dynamicClient, uri); err != nil {
-		return fmt.Errorf("failed to upgrade blueprint operator: %w", err)
+	if err := k8s.ApplyYaml(clients, uri, mutators...); err != nil {
+		return rollbackUpgrade(clients, blueprint, snapshot, waiter, fmt.Errorf("failed to upgrade blueprint operator: %w", err))
+	}
+
+	if err := attachRegistryCredentials(client, blueprint); err != nil {
+		return rollbackUpgrade(clients, blueprint, snapshot, waiter, err)
+	}
+
+	if err := waitForOperator(waiter); err != nil {
+		return rollbackUpgrade(clients, blueprint, snapshot, waiter, fmt.Errorf("upgraded Blueprint Operator failed health checks: %w", err))
+	}
+
+	if len(prunable) > 0 {
+		log.Info().Msgf("Pruning %d objects that were removed from the Blueprint Operator manifest", len(prunable))
+		if err := k8s.Prune(clients, prunable); err != nil {
+			return err
+		}
 	}
 
 	log.Info().Msgf("Finished updating Blueprint Operator")
@@ -88,3 +124,58 @@ func Upgrade(blueprint *types.Blueprint, kubeConfig *k8s.KubeConfig, imageRegist
 	}
 	return nil
 }
+
+// rollbackUpgrade restores the snapshot of the Blueprint Operator taken before the upgrade, and waits for the
+// restored operator to become ready
+// It returns the upgrade error, annotated with the outcome of the rollback
+func rollbackUpgrade(clients *k8s.Clients, blueprint *types.Blueprint, snapshot *k8s.Snapshot, waiter *readiness.Waiter, upgradeErr error) error {
+	if snapshot == nil {
+		return upgradeErr
+	}
+
+	log.Error().Msgf("%s", upgradeErr)
+	log.Warn().Msg("Rolling back Blueprint Operator to the previously deployed version")
+	if err := snapshot.Restore(clients); err != nil {
+		return fmt.Errorf("%w; rollback failed: %v", upgradeErr, err)
+	}
+	if err := attachRegistryCredentials(clients.Client, blueprint); err != nil {
+		return fmt.Errorf("%w; rollback failed: %v", upgradeErr, err)
+	}
+	if err := waitForOperator(waiter); err != nil {
+		return fmt.Errorf("%w; the previous Blueprint Operator was restored but is not ready: %v", upgradeErr, err)
+	}
+
+	log.Info().Msg("Rolled back Blueprint Operator")
+	return fmt.Errorf("%w; the previous Blueprint Operator was restored", upgradeErr)
+}
+
+// waitForOperator waits for the rollout of the Blueprint Operator deployment, within the timeout of the waiter
+func waitForOperator(waiter *readiness.Waiter) error {
+	operator := readiness.Deployment(constants.NamespaceBlueprint, constants.BlueprintOperatorDeployment, 0)
+	return waiter.Wait(context.Background(), operator)
+}
+
+// findPrunableOperatorObjects returns the deployed operator objects that are not part of the manifest at the URI
+func findPrunableOperatorObjects(clients *k8s.Clients, uri string) ([]unstructured.Unstructured, error) {
+	objs, err := k8s.FindPrunable(clients, constants.InventoryOwnerOperator, uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find objects to prune: %w", err)
+	}
+
+	// never remove the namespace the operator is installed in
+	return slices.DeleteFunc(objs, func(o unstructured.Unstructured) bool {
+		return o.GetKind() == "Namespace" && o.GetName() == constants.NamespaceBlueprint
+	}), nil
+}
+
+func printPrunable(objs []unstructured.Unstructured) {
+	if len(objs) == 0 {
+		fmt.Println("No objects would be pruned")
+		return
+	}
+
+	fmt.Printf("%-30s %-30s %-50s\n", "KIND", "NAMESPACE", "NAME")
+	for _, o := range objs {
+		fmt.Printf("%-30s %-30s %-50s\n", o.GetKind(), o.GetNamespace(), o.GetName())
+	}
+}
@@ -11,6 +11,8 @@ import (
 	"strings"
 
 	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/utils"
 	"github.com/rs/zerolog/log"
 )
 
@@ -75,7 +77,7 @@ func parseUri(version string) (string, error) {
 // an updated manifest is saved to a temporary file and its path is returned;
 // if the image registry is not provided or is the default one, the original URI is returned;
 // the second return value indicates whether the temporary file was created and should be removed later
-func setImageRegistry(bopURI, imageRegistry string) (string, bool, error) {
+func setImageRegistry(client *http.Client, bopURI, imageRegistry string) (string, bool, error) {
 	if bopURI == "" {
 		return "", false, fmt.Errorf("empty BOP manifest URI")
 	}
@@ -88,7 +90,7 @@ func setImageRegistry(bopURI, imageRegistry string) (string, bool, error) {
 	if strings.HasPrefix(bopURI, "file://") {
 		manifestBytes, err = readLocalManifest(strings.TrimPrefix(bopURI, "file://"))
 	} else {
-		manifestBytes, err = downloadRemoteManifest(bopURI)
+		manifestBytes, err = downloadRemoteManifest(client, bopURI)
 	}
 	if err != nil {
 		return "", false, fmt.Errorf("unable to obtain BOP manifest: %w", err)
@@ -96,9 +98,58 @@ func setImageRegistry(bopURI, imageRegistry string) (string, bool, error) {
 
 	manifestBytes = bytes.ReplaceAll(manifestBytes, []byte(constants.MirantisImageRegistry), []byte(imageRegistry))
 
+	uri, err := writeTempManifest(manifestBytes, "with updated image registry")
+	if err != nil {
+		return "", false, err
+	}
+	return uri, true, nil
+}
+
+// operatorManifest returns the URI of the operator manifest of the blueprint with the image registry set
+// A remote manifest is downloaded through the proxy of the blueprint, so that each cluster of a fleet uses its own;
+// the returned function removes the files downloaded or written for it.
+func operatorManifest(blueprint *types.Blueprint, imageRegistry string) (string, func(), error) {
+	uri, err := determineOperatorUri(blueprint.Spec.Version)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to determine operator URI: %w", err)
+	}
+
+	var temporary []string
+	cleanup := func() {
+		for _, uri := range temporary {
+			_ = os.Remove(strings.TrimPrefix(uri, "file://"))
+		}
+	}
+
+	client := utils.NewHTTPClient(blueprint.Spec.Proxy, blueprint.Spec.NoProxy())
+	if !strings.HasPrefix(uri, "file://") {
+		manifestBytes, err := downloadRemoteManifest(client, uri)
+		if err != nil {
+			return "", nil, fmt.Errorf("unable to obtain BOP manifest: %w", err)
+		}
+		if uri, err = writeTempManifest(manifestBytes, "downloaded"); err != nil {
+			return "", nil, err
+		}
+		temporary = append(temporary, uri)
+	}
+
+	uri, needCleanup, err := setImageRegistry(client, uri, imageRegistry)
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to set image registry in BOP manifest: %w", err)
+	}
+	if needCleanup {
+		temporary = append(temporary, uri)
+	}
+
+	return uri, cleanup, nil
+}
+
+// writeTempManifest saves the manifest to a temporary file and returns its URI
+func writeTempManifest(manifestBytes []byte, description string) (_ string, err error) {
 	tmpManifest, err := os.CreateTemp("", "bop-*.yaml")
 	if err != nil {
-		return "", false, fmt.Errorf("unable to create temporary manifest file: %w", err)
+		return "", fmt.Errorf("unable to create temporary manifest file: %w", err)
 	}
 
 	defer func() {
@@ -110,17 +161,17 @@ func setImageRegistry(bopURI, imageRegistry string) (string, bool, error) {
 
 	writtenN, err := tmpManifest.Write(manifestBytes)
 	if err != nil {
-		return "", false, fmt.Errorf("unable to write temporary manifest file with updated image registry: %w", err)
+		return "", fmt.Errorf("unable to write temporary manifest file %s: %w", description, err)
 	}
 	if writtenN != len(manifestBytes) {
-		err = fmt.Errorf("unable to write temporary manifest file with updated image registry: wrote %d bytes, expected %d", writtenN, len(manifestBytes))
-		return "", false, err
+		err = fmt.Errorf("unable to write temporary manifest file %s: wrote %d bytes, expected %d", description, writtenN, len(manifestBytes))
+		return "", err
 	}
 	if err = tmpManifest.Close(); err != nil {
-		return "", false, fmt.Errorf("unable to close temporary manifest file: %w", err)
+		return "", fmt.Errorf("unable to close temporary manifest file: %w", err)
 	}
 
-	return fmt.Sprintf("file://%s", tmpManifest.Name()), true, nil
+	return fmt.Sprintf("file://%s", tmpManifest.Name()), nil
 }
 
 func readLocalManifest(bopPath string) ([]byte, error) {
@@ -138,8 +189,8 @@ func readLocalManifest(bopPath string) ([]byte, error) {
 	return manifestBytes, nil
 }
 
-func downloadRemoteManifest(bopURI string) ([]byte, error) {
-	resp, err := http.Get(bopURI)
+func downloadRemoteManifest(client *http.Client, bopURI string) ([]byte, error) {
+	resp, err := client.Get(bopURI)
 	if err != nil {
 		return nil, fmt.Errorf("unable to download BOP manifest from %s: %w", bopURI, err)
 	}
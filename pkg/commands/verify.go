@@ -56,19 +56,24 @@ func Verify(blueprint *types.Blueprint, kubeConfig *k8s.KubeConfig) error {
 
 	blueprint.Spec.Components.Addons = helmAddons
 
+	boundlessClient, err := k8s.GetBoundlessClient(kubeConfig)
+	if err != nil {
+		return fmt.Errorf("failed to get blueprint client: %w", err)
+	}
+
 	defer func() {
 		// dry running helm charts still creates the addon and chart CR , although helm chart contents are not created
 		// clean up the dry addons before exiting
 
 		blueprint.Spec.Components.Addons = nil
-		err = components.ApplyBlueprint(kubeConfig, blueprint)
+		err = components.ApplyBlueprint(boundlessClient, blueprint)
 		if err != nil {
 			log.Error().Msgf("failed to reset blueprint: %v", err)
 		}
 
 	}()
 
-	err = components.ApplyBlueprint(kubeConfig, blueprint)
+	err = components.ApplyBlueprint(boundlessClient, blueprint)
 	if err != nil {
 		return fmt.Errorf("failed to install components: %w", err)
 	}
@@ -0,0 +1,150 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/k0sproject/version"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/utils"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/versions"
+)
+
+// Versions prints the k0s and Blueprint Operator releases, and the combinations of them that are supported
+// The versions of the blueprint are marked, when there is one. Only the latest patch release of each k0s minor
+// version is printed, unless all is set.
+func Versions(blueprint *types.Blueprint, all bool, w io.Writer) error {
+	client := http.DefaultClient
+	if blueprint != nil {
+		client = utils.NewHTTPClient(blueprint.Spec.Proxy, blueprint.Spec.NoProxy())
+	}
+	k0sReleases, err := versions.K0s(client)
+	if err != nil {
+		return err
+	}
+	operatorReleases, err := versions.Operator(client)
+	if err != nil {
+		return err
+	}
+
+	k0sCurrent, operatorCurrent := blueprintVersions(blueprint)
+
+	k0s := k0sReleases.LatestPatches()
+	if all {
+		k0s = k0sReleases.Versions
+	}
+	rows := [][]string{{"CURRENT", "K0S", "KUBERNETES"}}
+	for _, v := range k0s {
+		rows = append(rows, []string{currentMark(v.Equal(k0sCurrent)), v.String(), versions.Minor(v)})
+	}
+	fmt.Fprintf(w, "k0s releases (%s):\n", k0sReleases.Origin)
+	printTable(w, rows)
+
+	// the operator versions of the blueprint that are not in the releases, like latest, are listed first
+	operators := make([]string, 0, len(operatorReleases.Versions)+1)
+	if operatorCurrent != "" && !slices.ContainsFunc(operatorReleases.Versions, func(v *version.Version) bool {
+		return sameVersion(v.String(), operatorCurrent)
+	}) {
+		operators = append(operators, operatorCurrent)
+	}
+	for _, v := range operatorReleases.Versions {
+		operators = append(operators, v.String())
+	}
+
+	// the combinations are printed for the Kubernetes minor versions of the k0s releases
+	var minors []string
+	for _, v := range k0sReleases.LatestPatches() {
+		minors = append(minors, versions.Minor(v))
+	}
+	if k0sCurrent != nil && !slices.Contains(minors, versions.Minor(k0sCurrent)) {
+		minors = append(minors, versions.Minor(k0sCurrent))
+	}
+
+	rows = [][]string{append([]string{"CURRENT", "OPERATOR", "KUBERNETES"}, minors...)}
+	for _, operator := range operators {
+		current := sameVersion(operator, operatorCurrent)
+		row := []string{currentMark(current), operator, "-"}
+		if minimum, maximum, ok := versions.KubernetesRange(operator); ok {
+			row[2] = fmt.Sprintf("%s to %s", minimum, maximum)
+		}
+		for _, minor := range minors {
+			supported := "-"
+			if versions.Supports(operator, minor) {
+				supported = "yes"
+				if current && k0sCurrent != nil && minor == versions.Minor(k0sCurrent) {
+					supported += "*"
+				}
+			}
+			row = append(row, supported)
+		}
+		rows = append(rows, row)
+	}
+	fmt.Fprintf(w, "\nBlueprint Operator releases and the k0s versions they support (%s):\n", operatorReleases.Origin)
+	if len(operators) == 0 {
+		fmt.Fprintln(w, "No release found")
+		return nil
+	}
+	printTable(w, rows)
+
+	if k0sCurrent != nil && operatorCurrent != "" {
+		if warning := versions.Check(operatorCurrent, k0sCurrent.String()); warning != "" {
+			fmt.Fprintf(w, "\nThe blueprint is not supported: %s\n", warning)
+		}
+	}
+	return nil
+}
+
+// blueprintVersions returns the k0s and operator versions of the blueprint, when there is one
+func blueprintVersions(blueprint *types.Blueprint) (*version.Version, string) {
+	if blueprint == nil {
+		return nil, ""
+	}
+
+	var k0s *version.Version
+	if kubernetes := blueprint.Spec.Kubernetes; kubernetes != nil && kubernetes.Provider == constants.ProviderK0s {
+		k0s, _ = version.NewVersion(kubernetes.Version)
+	}
+	operator := blueprint.Spec.Version
+	if operator == "" {
+		operator = "latest"
+	}
+	return k0s, operator
+}
+
+// sameVersion tells whether two operator versions are the same, with or without the v prefix
+func sameVersion(a string, b string) bool {
+	return strings.TrimPrefix(a, "v") == strings.TrimPrefix(b, "v")
+}
+
+func currentMark(current bool) string {
+	if current {
+		return "*"
+	}
+	return ""
+}
+
+// printTable prints the rows in columns, the first row being the header
+func printTable(w io.Writer, rows [][]string) {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+
+	for _, row := range rows {
+		line := make([]string, len(row))
+		for i, cell := range row {
+			line[i] = fmt.Sprintf("%-*s", widths[i], cell)
+		}
+		fmt.Fprintln(w, strings.TrimRight(strings.Join(line, "  "), " "))
+	}
+}
@@ -16,13 +16,14 @@ import (
 
 	"github.com/mirantiscontainers/blueprint-operator/api/v1alpha1"
 
+	"github.com/mirantiscontainers/blueprint-cli/boundlessclientset"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/k8s"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
 )
 
 // ApplyBlueprint applies a Blueprint object to the cluster
-func ApplyBlueprint(kubeConfig *k8s.KubeConfig, cluster *types.Blueprint) error {
+func ApplyBlueprint(client boundlessclientset.BoundlessV1Alpha1Interface, cluster *types.Blueprint) error {
 	components := cluster.Spec.Components
 
 	// Get the list of addons
@@ -45,7 +46,7 @@ func ApplyBlueprint(kubeConfig *k8s.KubeConfig, cluster *types.Blueprint) error
 	}
 
 	log.Info().Msg("Applying Blueprint")
-	if err := k8s.CreateOrUpdate(kubeConfig, &c); err != nil {
+	if err := k8s.CreateOrUpdateBlueprint(client, &c); err != nil {
 		return fmt.Errorf("failed to create/update Blueprint object: %v", err)
 	}
 
@@ -53,7 +54,7 @@ func ApplyBlueprint(kubeConfig *k8s.KubeConfig, cluster *types.Blueprint) error
 }
 
 // RemoveComponents removes all components from the cluster
-func RemoveComponents(kubeConfig *k8s.KubeConfig, cluster *types.Blueprint) error {
+func RemoveComponents(client boundlessclientset.BoundlessV1Alpha1Interface, cluster *types.Blueprint) error {
 	components := cluster.Spec.Components
 
 	// Get the list of addons
@@ -76,7 +77,7 @@ func RemoveComponents(kubeConfig *k8s.KubeConfig, cluster *types.Blueprint) erro
 	}
 
 	log.Info().Msg("Resetting Blueprint")
-	if err := k8s.Delete(kubeConfig, &c); err != nil {
+	if err := k8s.DeleteBlueprint(client, &c); err != nil {
 		return fmt.Errorf("failed to reset Blueprint object: %v", err)
 	}
 
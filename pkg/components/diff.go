@@ -0,0 +1,56 @@
+package components
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/mirantiscontainers/blueprint-operator/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/mirantiscontainers/blueprint-cli/boundlessclientset"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+// DiffBlueprint compares the Blueprint object in the cluster with the blueprint
+// It returns a description of each difference, or nothing if the cluster is up to date
+func DiffBlueprint(client boundlessclientset.BoundlessV1Alpha1Interface, cluster *types.Blueprint) ([]string, error) {
+	live, err := client.Blueprints(v1.NamespaceDefault).Get(context.TODO(), cluster.Metadata.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return []string{fmt.Sprintf("blueprint %q is not applied", cluster.Metadata.Name)}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Blueprint object: %w", err)
+	}
+
+	desired, err := getAddons(&cluster.Spec.Components)
+	if err != nil {
+		return nil, err
+	}
+
+	var diff []string
+	liveAddons := live.Spec.Components.Addons
+	for _, addon := range desired {
+		i := slices.IndexFunc(liveAddons, func(a v1alpha1.AddonSpec) bool { return a.Name == addon.Name })
+		switch {
+		case i < 0:
+			diff = append(diff, fmt.Sprintf("addon %q added", addon.Name))
+		case !equality.Semantic.DeepEqual(liveAddons[i], addon):
+			diff = append(diff, fmt.Sprintf("addon %q changed", addon.Name))
+		}
+	}
+	for _, addon := range liveAddons {
+		if !slices.ContainsFunc(desired, func(a v1alpha1.AddonSpec) bool { return a.Name == addon.Name }) {
+			diff = append(diff, fmt.Sprintf("addon %q removed", addon.Name))
+		}
+	}
+
+	if !equality.Semantic.DeepEqual(live.Spec.Resources, getResources(cluster.Spec.Resources)) {
+		diff = append(diff, "resources changed")
+	}
+
+	return diff, nil
+}
@@ -23,6 +23,27 @@ const (
 
 	BlueprintOperatorDeployment = "blueprint-operator-controller-manager"
 
+	// OperatorRolloutTimeout is the timeout for the Blueprint Operator deployment to become available after an upgrade
+	OperatorRolloutTimeout = 5 * time.Minute
+
+	// NodeDrainTimeout is the timeout for the pods of a node to be evicted before the node is removed or upgraded
+	NodeDrainTimeout = 5 * time.Minute
+
+	// NodeUpgradeTimeout is the timeout for a node to come back healthy after its k0s is upgraded
+	NodeUpgradeTimeout = 10 * time.Minute
+
+	// SSHKeyPassphraseEnv is the environment variable with the passphrase of the encrypted SSH keys of the hosts
+	SSHKeyPassphraseEnv = "BCTL_SSH_KEY_PASSPHRASE"
+
+	// InventoryOwnerOperator is the inventory owner of the objects applied from the Blueprint Operator manifest
+	InventoryOwnerOperator = "blueprint-operator"
+
+	// RegistryCredentialsSecret is the name of the image pull secret created from the blueprint registry credentials
+	RegistryCredentialsSecret = "blueprint-registry-credentials"
+
+	// AppliedHostsSecret is the name of the secret of kube-system that records the hosts of the last applied blueprint
+	AppliedHostsSecret = "blueprint-applied-hosts"
+
 	// These semver regex come from the official semver spec: https://semver.org/#is-there-a-suggested-regular-expression-regex-to-check-a-semver-string
 	// but they have been modified into a version without a leading v, a version with a leading v, and a version where the leading v is optional
 	SemverRegexWithV     = `^[v](0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)$`
@@ -32,4 +53,14 @@ const (
 	K0sSemverRegex = `^[v]?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:\+(k[0-9a-zA-Z-]s+(?:\.[0-9a-zA-Z-]+)*))?$`
 
 	MirantisImageRegistry = "ghcr.io/mirantiscontainers"
+
+	// DefaultPodCIDR is the pod CIDR used by k0s and kind when the cluster config does not set one
+	DefaultPodCIDR = "10.244.0.0/16"
+	// DefaultServiceCIDR is the service CIDR used by k0s and kind when the cluster config does not set one
+	DefaultServiceCIDR = "10.96.0.0/12"
+
+	// MinKubernetesVersion is the oldest Kubernetes version supported by the Blueprint Operator
+	MinKubernetesVersion = "1.27.0"
+	// MaxKubernetesVersion is the newest Kubernetes minor version the Blueprint Operator is tested with
+	MaxKubernetesVersion = "1.31"
 )
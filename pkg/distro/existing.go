@@ -1,22 +1,27 @@
 package distro
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
 	"github.com/rs/zerolog/log"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 
 	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/k8s"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/readiness"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/utils"
 )
 
 // Existing is the existing provider
 type Existing struct {
-	kubeConfig *k8s.KubeConfig
-	client     *kubernetes.Clientset
+	kubeConfig    *k8s.KubeConfig
+	client        *kubernetes.Clientset
+	dynamicClient *dynamic.DynamicClient
 }
 
 // NewExistingProvider returns a new existing provider
@@ -33,12 +38,16 @@ func (e *Existing) SetupClient() error {
 	if err != nil {
 		return fmt.Errorf("failed to create k8s client: %w", err)
 	}
+	e.dynamicClient, err = k8s.GetDynamicClient(e.kubeConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create k8s dynamic client: %w", err)
+	}
 	return e.WaitForNodes()
 }
 
 // WaitForNodes waits for nodes to be ready
 func (e *Existing) WaitForNodes() error {
-	if err := k8s.WaitForNodes(e.client); err != nil {
+	if err := readiness.NewWaiter(e.dynamicClient).WaitForNodes(context.Background()); err != nil {
 		return fmt.Errorf("failed to wait for nodes: %w", err)
 	}
 
@@ -47,7 +56,7 @@ func (e *Existing) WaitForNodes() error {
 
 // WaitForPods waits for pods to be ready
 func (e *Existing) WaitForPods() error {
-	if err := k8s.WaitForPods(e.client, constants.NamespaceBlueprint); err != nil {
+	if err := readiness.NewWaiter(e.dynamicClient).WaitForNamespace(context.Background(), constants.NamespaceBlueprint); err != nil {
 		return fmt.Errorf("failed to wait for pods: %w", err)
 	}
 
@@ -67,7 +76,7 @@ func (e *Existing) Refresh() error {
 }
 
 // Update updates the existing cluster
-func (e *Existing) Upgrade() error {
+func (e *Existing) Upgrade(opts UpgradeOptions) error {
 	return nil
 }
 
@@ -107,6 +116,21 @@ func (e *Existing) GetKubeConfigContext() string {
 	return ""
 }
 
+// ClusterKubeConfig returns a flattened copy of the current context of the kubeconfig, with only the cluster and user it references
+func (e *Existing) ClusterKubeConfig() (*clientcmdapi.Config, error) {
+	config, err := e.kubeConfig.RawConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig from %q: %w", e.kubeConfig.GetConfigPath(), err)
+	}
+
+	contextName, err := e.kubeConfig.CurrentContextName()
+	if err != nil {
+		return nil, err
+	}
+
+	return k8s.MinifyConfig(config, contextName)
+}
+
 // Type returns the type of the provider
 func (e *Existing) Type() string {
 	return constants.ProviderExisting
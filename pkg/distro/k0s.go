@@ -1,38 +1,46 @@
 package distro
 
 import (
-	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"strings"
 
-	"k8s.io/apimachinery/pkg/api/errors"
-
 	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/k8s"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/readiness"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/ssh"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/utils"
 
 	"github.com/k0sproject/version"
 	"github.com/rs/zerolog/log"
 	"gopkg.in/yaml.v2"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
 // K0s is the k0s provider
 type K0s struct {
-	name       string
-	k0sConfig  string
-	kubeConfig *k8s.KubeConfig
-	client     *kubernetes.Clientset
+	name          string
+	blueprint     *types.Blueprint
+	k0sConfig     string
+	k0sctl        *k0sctl
+	kubeConfig    *k8s.KubeConfig
+	client        *kubernetes.Clientset
+	dynamicClient *dynamic.DynamicClient
+	// airgap are the local files of an airgap install, once they are prepared
+	airgap *airgapFiles
 }
 
 // NewK0sProvider returns a new k0s provider
 func NewK0sProvider(blueprint *types.Blueprint, kubeConfig *k8s.KubeConfig) *K0s {
 	provider := &K0s{
 		name:       blueprint.Metadata.Name,
+		blueprint:  blueprint,
 		kubeConfig: kubeConfig,
 	}
 
@@ -41,6 +49,7 @@ func NewK0sProvider(blueprint *types.Blueprint, kubeConfig *k8s.KubeConfig) *K0s
 		log.Fatal().Err(err).Msg("failed to get k0s config path")
 	}
 	provider.k0sConfig = k0sConfig
+	provider.k0sctl = &k0sctl{config: k0sConfig, hosts: blueprint.Spec.Kubernetes.Infra.Hosts}
 
 	return provider
 }
@@ -50,12 +59,18 @@ func (k *K0s) Install() error {
 	kubeConfigPath := k.kubeConfig.GetConfigPath()
 	log.Debug().Msgf("Creating k0s cluster %q with kubeConfig at: %s", k.name, kubeConfigPath)
 
-	if err := utils.ExecCommand(fmt.Sprintf("k0sctl apply --config %s --no-wait", k.k0sConfig)); err != nil {
+	if err := k.checkBastions(); err != nil {
+		return err
+	}
+	if err := k.prepareAirgap(); err != nil {
+		return err
+	}
+	if err := k.k0sctl.run("apply", "--no-wait"); err != nil {
 		return fmt.Errorf("failed to install k0s: %w", err)
 	}
 
 	// create kubeconfig
-	if err := WriteK0sKubeConfig(k.k0sConfig, k.kubeConfig); err != nil {
+	if err := k.writeKubeConfig(); err != nil {
 		return fmt.Errorf("failed to write kubeconfig: %w", err)
 	}
 	log.Trace().Msgf("kubeconfig file for k0s cluster: %s", kubeConfigPath)
@@ -68,22 +83,64 @@ func (k *K0s) Refresh() error {
 	kubeConfigPath := k.kubeConfig.GetConfigPath()
 	log.Debug().Msgf("Refreshing k0s cluster %q with kubeConfig at: %s", k.name, kubeConfigPath)
 
-	if err := utils.ExecCommand(fmt.Sprintf("k0sctl apply --config %s --no-wait", k.k0sConfig)); err != nil {
-		return fmt.Errorf("k0sctl apply failed: %w", err)
+	if err := k.checkBastions(); err != nil {
+		return err
+	}
+	if err := k.prepareAirgap(); err != nil {
+		return err
+	}
+	if err := k.k0sctl.run("apply", "--no-wait"); err != nil {
+		return fmt.Errorf("failed to refresh k0s: %w", err)
 	}
 
 	return nil
 }
 
-// Update updates k0s using k0sctl
-func (k *K0s) Upgrade() error {
-	if err := utils.ExecCommand(fmt.Sprintf("k0sctl apply --config %s --no-wait", k.k0sConfig)); err != nil {
+// Upgrade upgrades k0s to the version of the blueprint
+// The remote hosts are upgraded one node at a time, see rollingUpgrade; k0sctl then reapplies the k0s config.
+// A local k0s has a single node, so k0sctl upgrades it directly.
+func (k *K0s) Upgrade(opts UpgradeOptions) error {
+	if err := k.checkBastions(); err != nil {
+		return err
+	}
+	if err := k.prepareAirgap(); err != nil {
+		return err
+	}
+
+	if !k.isLocalK0s(k.blueprint) {
+		if err := k.rollingUpgrade(opts); err != nil {
+			return err
+		}
+	}
 
+	if err := k.k0sctl.run("apply", "--no-wait"); err != nil {
 		return fmt.Errorf("failed to update k0s: %w", err)
 	}
 	return nil
 }
 
+// checkBastions checks that the bastions of the hosts are reachable
+// A bastion that is down would otherwise fail k0sctl for all the hosts behind it, with an error about the hosts.
+func (k *K0s) checkBastions() error {
+	checked := make(map[string]bool)
+	for _, host := range k.blueprint.Spec.Kubernetes.Infra.Hosts {
+		if host.SSH == nil || host.SSH.Bastion == nil {
+			continue
+		}
+		bastion := *host.SSH.Bastion
+		if checked[ssh.Address(bastion)] {
+			continue
+		}
+		checked[ssh.Address(bastion)] = true
+
+		log.Debug().Msgf("Checking that bastion %s is reachable", ssh.Address(bastion))
+		if err := ssh.CheckReachable(bastion); err != nil {
+			return fmt.Errorf("bastion of host %s is not reachable: %w", host.SSH.Address, err)
+		}
+	}
+	return nil
+}
+
 // SetupClient sets up the kubernets client for the distro
 func (k *K0s) SetupClient() error {
 	var err error
@@ -91,20 +148,59 @@ func (k *K0s) SetupClient() error {
 	if err != nil {
 		return fmt.Errorf("failed to create k8s client: %w", err)
 	}
+	k.dynamicClient, err = k8s.GetDynamicClient(k.kubeConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create k8s dynamic client: %w", err)
+	}
 	return k.WaitForNodes()
 }
 
-// Exists checks if k0s exists using k0sctl
+// k0sInstalledCmd tells whether the admin kubeconfig, which k0sctl generates the kubeconfig from, is on the host
+// It succeeds either way, so that a failure is a host that cannot be reached rather than a missing cluster
+const k0sInstalledCmd = "sudo test -f /var/lib/k0s/pki/admin.conf && echo installed || echo missing"
+
+// Exists checks if k0s is installed on the first controller
+// A controller that cannot be reached is an error, not a cluster that does not exist
 func (k *K0s) Exists() (bool, error) {
-	err := utils.ExecCommandQuietly("bash", "-c", fmt.Sprintf("k0sctl kubeconfig -c %s", k.k0sConfig))
-	if err != nil && strings.Contains(err.Error(), "exit status 1") {
-		return false, nil
+	if k.isLocalK0s(k.blueprint) {
+		installed, err := k0sInstalled(func(command string) (string, error) {
+			output, err := exec.Command("sh", "-c", command).Output()
+			return string(output), err
+		})
+		if err != nil {
+			return false, fmt.Errorf("failed to check for k0s on localhost: %w", err)
+		}
+		return installed, nil
+	}
+
+	controllers := k.getControllerHosts(k.blueprint)
+	if len(controllers) == 0 {
+		return false, fmt.Errorf("no controller host to check for k0s")
 	}
+	controller := *controllers[0].SSH
+	installed, err := k0sInstalled(func(command string) (string, error) {
+		return ssh.Run(controller, command)
+	})
 	if err != nil {
-		return false, err
+		return false, fmt.Errorf("failed to check for k0s on controller %s: %w", controller.Address, err)
 	}
+	return installed, nil
+}
 
-	return true, nil
+// k0sInstalled runs k0sInstalledCmd with the runner and returns whether k0s is installed
+func k0sInstalled(run func(command string) (string, error)) (bool, error) {
+	output, err := run(k0sInstalledCmd)
+	if err != nil {
+		return false, err
+	}
+	switch strings.TrimSpace(output) {
+	case "installed":
+		return true, nil
+	case "missing":
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected output %q", strings.TrimSpace(output))
+	}
 }
 
 // Reset resets k0s using k0sctl
@@ -112,9 +208,7 @@ func (k *K0s) Reset() error {
 	log.Debug().Msgf("Resetting k0s cluster: %s", k.name)
 
 	// Since we are confirming the reset ourselves, we know by this point that we will always force the reset
-	resetCmd := fmt.Sprintf("k0sctl reset -f --config %s", k.k0sConfig)
-
-	if err := utils.ExecCommand(resetCmd); err != nil {
+	if err := k.k0sctl.run("reset", "--force"); err != nil {
 		return fmt.Errorf("failed to reset k0s: %w", err)
 	}
 
@@ -126,6 +220,11 @@ func (k *K0s) GetKubeConfigContext() string {
 	return k.name
 }
 
+// ClusterKubeConfig returns the admin kubeconfig of the k0s cluster
+func (k *K0s) ClusterKubeConfig() (*clientcmdapi.Config, error) {
+	return k.k0sctlKubeConfig()
+}
+
 // Type returns the type of the provider
 func (k *K0s) Type() string {
 	return constants.ProviderK0s
@@ -138,7 +237,7 @@ func (k *K0s) GetKubeConfig() *k8s.KubeConfig {
 
 // WaitForNodes waits for nodes to be ready
 func (k *K0s) WaitForNodes() error {
-	if err := k8s.WaitForNodes(k.client); err != nil {
+	if err := readiness.NewWaiter(k.dynamicClient).WaitForNodes(context.Background()); err != nil {
 		return fmt.Errorf("failed to wait for nodes: %w", err)
 	}
 
@@ -147,41 +246,36 @@ func (k *K0s) WaitForNodes() error {
 
 // WaitForPods waits for pods to be ready
 func (k *K0s) WaitForPods() error {
-	if err := k8s.WaitForPods(k.client, constants.NamespaceBlueprint); err != nil {
+	if err := readiness.NewWaiter(k.dynamicClient).WaitForNamespace(context.Background(), constants.NamespaceBlueprint); err != nil {
 		return fmt.Errorf("failed to wait for pods: %w", err)
 	}
 
 	return nil
 }
 
-func WriteK0sKubeConfig(k0sctlConfig string, kubeConfig *k8s.KubeConfig) error {
-	c := exec.Command("k0sctl", "kubeconfig", "--config", k0sctlConfig)
-	c.Stderr = os.Stderr
-
-	buf := new(bytes.Buffer)
-	c.Stdout = buf
-
-	err := c.Run()
-	if err != nil {
-		return fmt.Errorf("failed to generate kubeconfig: %w", err)
-	}
-
-	configClient, err := clientcmd.NewClientConfigFromBytes(buf.Bytes())
+// writeKubeConfig merges the admin kubeconfig of the cluster into the kubeconfig file
+func (k *K0s) writeKubeConfig() error {
+	config, err := k.k0sctlKubeConfig()
 	if err != nil {
 		return err
 	}
 
-	rawConfig, err := configClient.RawConfig()
+	return k.kubeConfig.MergeConfig(*config)
+}
+
+// k0sctlKubeConfig returns the admin kubeconfig of the cluster generated by k0sctl
+func (k *K0s) k0sctlKubeConfig() (*clientcmdapi.Config, error) {
+	data, err := k.k0sctl.output("kubeconfig")
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to generate kubeconfig: %w", err)
 	}
 
-	err = kubeConfig.MergeConfig(rawConfig)
+	config, err := clientcmd.Load(data)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to parse kubeconfig generated by k0sctl: %w", err)
 	}
 
-	return nil
+	return config, nil
 }
 
 // CreateTempK0sConfig creates a k0s config file from the blueprint in the tmp directory
@@ -219,7 +313,8 @@ func writeToTempFile(k0sctlConfig []byte) (string, error) {
 
 // getInstalledVersion returns version of k0s
 // for local k0s it will get the k0s version of the local machine
-// otherwise it will get k0s version on the first controller node that does not throw an error
+// otherwise it will get the k0s version of every controller that does not throw an error, and return the oldest one,
+// so that a partially upgraded cluster still needs an upgrade
 func (k *K0s) getInstalledVersion(blueprint *types.Blueprint) (string, error) {
 
 	if k.isLocalK0s(blueprint) {
@@ -231,39 +326,44 @@ func (k *K0s) getInstalledVersion(blueprint *types.Blueprint) (string, error) {
 		return out, nil
 	}
 
-	controllers := k.getControllerHosts(blueprint)
-
-	for _, controller := range controllers {
-		key, err := utils.ReadFile(controller.SSH.KeyPath)
+	var oldest *version.Version
+	for _, controller := range k.getControllerHosts(blueprint) {
+		// k0sctl has no apparent way to get version of k0s previously installed so get the k0s version directly on the controller nodes
+		stdout, err := ssh.Run(*controller.SSH, "sudo k0s version")
 		if err != nil {
-			return "", err
-		}
-
-		// k0sctl has no apparent way to get version of k0s previously installed so get the k0s version directly on the first controller node
-		stdout, stderr, err := utils.RemoteCommand(controller.SSH.User, controller.SSH.Address, string(key), "sudo k0s version")
-		if err != nil {
-			log.Warn().Msgf("unable to get k0s version on host %s : %s, %s", controller.SSH.Address, stderr, err)
+			log.Warn().Msgf("unable to get k0s version on host %s : %s", controller.SSH.Address, err)
 
 			// try to get version from another controller
 			continue
 		}
 
-		return stdout, nil
-
+		installed, err := version.NewVersion(stdout)
+		if err != nil {
+			return "", fmt.Errorf("unable to parse k0s version %q of host %s: %w", stdout, controller.SSH.Address, err)
+		}
+		if oldest != nil && !installed.Equal(oldest) {
+			log.Warn().Msgf("controllers run different k0s versions: %s and %s", oldest, installed)
+		}
+		if oldest == nil || installed.LessThan(oldest) {
+			oldest = installed
+		}
 	}
 
-	// if we got here all controllers have errors when getting version
-	return "", fmt.Errorf("unable to get k0s version of cluster")
+	if oldest == nil {
+		// if we got here all controllers have errors when getting version
+		return "", fmt.Errorf("unable to get k0s version of cluster")
+	}
+	return oldest.String(), nil
 }
 
+// getControllerHosts returns the remote hosts that run a k0s controller
 func (k *K0s) getControllerHosts(blueprint *types.Blueprint) []types.Host {
 	var hosts []types.Host
 
 	for _, host := range blueprint.Spec.Kubernetes.Infra.Hosts {
-		// match both controller and controller+worker role
-		if strings.Contains(host.Role, "controller") {
+		// match both controller and controller+worker role, a single node is a controller as well
+		if host.SSH != nil && (strings.Contains(host.Role, "controller") || host.Role == "single") {
 			hosts = append(hosts, host)
-			break
 		}
 	}
 	return hosts
@@ -271,14 +371,19 @@ func (k *K0s) getControllerHosts(blueprint *types.Blueprint) []types.Host {
 
 func (k *K0s) isLocalK0s(blueprint *types.Blueprint) bool {
 	// if running localhost there should just be 1 host
-	return len(blueprint.Spec.Kubernetes.Infra.Hosts) > 0 && blueprint.Spec.Kubernetes.Infra.Hosts[0].LocalHost.Enabled
+	return len(blueprint.Spec.Kubernetes.Infra.Hosts) > 0 && blueprint.Spec.Kubernetes.Infra.Hosts[0].LocalHost != nil && blueprint.Spec.Kubernetes.Infra.Hosts[0].LocalHost.Enabled
 }
 
 // NeedsUpgrade checks if an upgrade of the provider is required
 // return true if the providedVersion is greater than the installed Version
 // return false if the versions are equal
 // throw an error if the providedVersion is lower than the installed Version (don't support downgrade)
+// throw an error if a previous upgrade was interrupted, since some of the nodes may already run the new version
 func (k *K0s) NeedsUpgrade(blueprint *types.Blueprint) (bool, error) {
+	if _, err := loadUpgradeState(k.name, blueprint.Spec.Kubernetes.Version, false); err != nil {
+		return false, err
+	}
+
 	installedVersion, err := k.getInstalledVersion(blueprint)
 	if err != nil {
 		return false, fmt.Errorf("failed to get installed k0s version: %w", err)
@@ -298,7 +403,7 @@ func (k *K0s) NeedsUpgrade(blueprint *types.Blueprint) (bool, error) {
 }
 
 // ValidateProviderUpgrade does some validation that the controller nodes will be able to run the new version of k0s proposed in the blueprint
-// First download new version of k0s binary and place in tmp folder
+// First download new version of k0s binary and place in tmp folder, or upload the one of an airgap install
 // Use new binary to run k0s config validate which validates config will work on new version
 // In some k0s upgrade scenarios (such as previously existing config fields that have been removed in newer version) it requires user to update the node configs
 func (k *K0s) ValidateProviderUpgrade(blueprint *types.Blueprint) error {
@@ -307,41 +412,40 @@ func (k *K0s) ValidateProviderUpgrade(blueprint *types.Blueprint) error {
 	defer func() {
 		// cleanup the temp k0s binaries used to validate each controller
 		for _, controller := range controllers {
-			key, err := utils.ReadFile(controller.SSH.KeyPath)
-			if err != nil {
-				log.Warn().Msgf("failed to read ssh key during cleanup for host %s", controller.SSH.Address)
-			}
-
-			_, cleanupErr, err := utils.RemoteCommand(controller.SSH.User, controller.SSH.Address, string(key), "sudo rm -f /tmp/k0s")
-			if err != nil {
-				if !errors.IsNotFound(err) {
-					log.Warn().Msgf("failed to clean up temp k0s binary for host %s : %s", controller.SSH.Address, cleanupErr)
-				}
+			if _, err := ssh.Run(*controller.SSH, "sudo rm -f /tmp/k0s"); err != nil {
+				log.Warn().Msgf("failed to clean up temp k0s binary for host %s : %s", controller.SSH.Address, err)
 			}
 		}
 	}()
 
-	for _, controller := range controllers {
-		key, err := utils.ReadFile(controller.SSH.KeyPath)
-		if err != nil {
+	// an airgap install uploads its k0s binary, the hosts cannot download one
+	var airgap *airgapFiles
+	if blueprint.Spec.Kubernetes.Airgap != nil {
+		var err error
+		if airgap, err = resolveAirgap(blueprint); err != nil {
 			return err
 		}
+	}
 
-		log.Info().Msg("Downloading new version of k0s binary")
-		downloadCmd := fmt.Sprintf("curl -sSLf https://get.k0s.sh | sed -e 's;k0sInstallPath=/usr/local/bin;k0sInstallPath=/tmp;' | sudo K0S_VERSION=v%s sh", blueprint.Spec.Kubernetes.Version)
-
-		_, downloadErr, err := utils.RemoteCommand(controller.SSH.User, controller.SSH.Address, string(key), downloadCmd)
-		if err != nil {
-			log.Error().Msgf("failed to install new version of k0s binary on host %s : %s", controller.SSH.Address, downloadErr)
-			return err
+	for _, controller := range controllers {
+		if airgap != nil {
+			log.Info().Msgf("Uploading new version of k0s binary to host %s", controller.SSH.Address)
+			if err := ssh.Upload(*controller.SSH, airgap.binary, "/tmp/k0s", 0o755); err != nil {
+				return fmt.Errorf("failed to upload new version of k0s binary to host %s: %w", controller.SSH.Address, err)
+			}
+		} else {
+			log.Info().Msgf("Downloading new version of k0s binary on host %s", controller.SSH.Address)
+			env := ssh.EnvAssignments(blueprint.Spec.HostEnvironment(controller))
+			downloadCmd := fmt.Sprintf("%scurl -sSLf https://get.k0s.sh | sed -e 's;k0sInstallPath=/usr/local/bin;k0sInstallPath=/tmp;' | sudo %sK0S_VERSION=v%s sh", env, env, blueprint.Spec.Kubernetes.Version)
+			if _, err := ssh.Run(*controller.SSH, downloadCmd); err != nil {
+				return fmt.Errorf("failed to install new version of k0s binary on host %s: %w", controller.SSH.Address, err)
+			}
 		}
 
-		log.Info().Msg("Validating existing config with new version of k0s binary")
-		validateCmd := fmt.Sprintf("sudo /tmp/k0s config validate --config /etc/k0s/k0s.yaml")
-		_, validateErr, err := utils.RemoteCommand(controller.SSH.User, controller.SSH.Address, string(key), validateCmd)
-		if err != nil {
-			log.Error().Msgf("validation of new provider version failed on host %s : %s", controller.SSH.Address, validateErr)
-			return err
+		log.Info().Msgf("Validating existing config with new version of k0s binary on host %s", controller.SSH.Address)
+		validateCmd := "sudo /tmp/k0s config validate --config /etc/k0s/k0s.yaml"
+		if _, err := ssh.Run(*controller.SSH, validateCmd); err != nil {
+			return fmt.Errorf("validation of new provider version failed on host %s: %w", controller.SSH.Address, err)
 		}
 	}
 
@@ -0,0 +1,53 @@
+package distro
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+// TestK0sInstalled tests that a host that cannot be reached is an error rather than a cluster that does not exist
+func TestK0sInstalled(t *testing.T) {
+	tests := map[string]struct {
+		output    string
+		err       error
+		installed bool
+		wantErr   string
+	}{
+		"installed": {
+			output:    "installed\n",
+			installed: true,
+		},
+		"not installed": {
+			output: "missing\n",
+		},
+		"unreachable host": {
+			err:     errors.New("dial tcp 10.0.0.1:22: connect: no route to host"),
+			wantErr: "no route to host",
+		},
+		"unexpected output": {
+			output:  "sudo: a password is required\n",
+			wantErr: "unexpected output",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			var commands []string
+			installed, err := k0sInstalled(func(command string) (string, error) {
+				commands = append(commands, command)
+				return tc.output, tc.err
+			})
+			g.Expect(commands).To(Equal([]string{k0sInstalledCmd}))
+			if tc.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tc.wantErr)))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(installed).To(Equal(tc.installed))
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package distro
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/k0sproject/version"
+	"github.com/rs/zerolog/log"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/ssh"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/utils"
+)
+
+const (
+	// remoteK0sBinary is the path of the k0s binary on the hosts
+	remoteK0sBinary = "/usr/local/bin/k0s"
+	// remoteImageBundle is the path of the image bundle on the hosts, in the directory k0s imports the image bundles
+	// from when a worker starts; k0s only imports the files of the directory with a .tar extension.
+	remoteImageBundle = "/var/lib/k0s/images/bundle.tar"
+	// defaultAirgapArch is the architecture of the fetched files when kubernetes.airgap.arch is not set
+	defaultAirgapArch = "amd64"
+)
+
+// airgapFiles are the local files of an airgap install
+type airgapFiles struct {
+	binary string
+	bundle string
+}
+
+// prepareAirgap makes the files of spec.kubernetes.airgap available to the hosts before k0sctl runs
+// The missing files are fetched, the image bundle is uploaded to the hosts that run a worker, and the k0sctl config
+// is written again with the local k0s binary, which k0sctl uploads to every host instead of downloading k0s.
+func (k *K0s) prepareAirgap() error {
+	airgap := k.blueprint.Spec.Kubernetes.Airgap
+	if airgap == nil {
+		return nil
+	}
+
+	files, err := resolveAirgap(k.blueprint)
+	if err != nil {
+		return err
+	}
+	k.airgap = files
+
+	// the fetched files are set in the blueprint, so that the k0sctl config points at them
+	airgap.K0sBinary, airgap.ImageBundle = files.binary, files.bundle
+	config, err := CreateTempK0sConfig(k.blueprint)
+	if err != nil {
+		return fmt.Errorf("failed to write the k0sctl config: %w", err)
+	}
+	os.Remove(k.k0sConfig)
+	k.k0sConfig = config
+	k.k0sctl.config = config
+
+	return k.uploadBundle()
+}
+
+// uploadBundle puts the image bundle in the images directory of k0s on the hosts that run a worker
+// The bundle is uploaded as bundle.tar whatever the name of the local file, e.g. the cached download, which has no extension.
+func (k *K0s) uploadBundle() error {
+	dst := remoteImageBundle
+
+	var wg sync.WaitGroup
+	hosts := k.blueprint.Spec.Kubernetes.Infra.Hosts
+	errs := make([]error, len(hosts))
+	for i, host := range hosts {
+		// controllers only run the control plane, which does not need the images
+		if host.Role == "controller" {
+			continue
+		}
+
+		if host.LocalHost != nil && host.LocalHost.Enabled {
+			log.Info().Msgf("Copying the image bundle %s to the local host", k.airgap.bundle)
+			install := fmt.Sprintf("sudo install -D -m 0644 %q %q", k.airgap.bundle, dst)
+			if err := runLocal(install, nil); err != nil {
+				errs[i] = fmt.Errorf("failed to copy the image bundle: %w", err)
+			}
+			continue
+		}
+		if host.SSH == nil {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			log.Info().Msgf("Uploading the image bundle %s to host %s", filepath.Base(k.airgap.bundle), host.SSH.Address)
+			if err := ssh.Upload(*host.SSH, k.airgap.bundle, dst, 0o644); err != nil {
+				errs[i] = fmt.Errorf("failed to upload the image bundle to host %s: %w", host.SSH.Address, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// resolveAirgap returns the absolute paths of the files of the airgap install of the blueprint
+// The files that are not set are downloaded from the k0s release of the version through the proxy of the blueprint,
+// unless they are already cached.
+func resolveAirgap(blueprint *types.Blueprint) (*airgapFiles, error) {
+	airgap, k0sVersion := blueprint.Spec.Kubernetes.Airgap, blueprint.Spec.Kubernetes.Version
+	client := utils.NewHTTPClient(blueprint.Spec.Proxy, blueprint.Spec.NoProxy())
+	files := &airgapFiles{binary: airgap.K0sBinary, bundle: airgap.ImageBundle}
+
+	if files.binary == "" || files.bundle == "" {
+		v, err := version.NewVersion(k0sVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid kubernetes.version %q: %w", k0sVersion, err)
+		}
+		arch := airgap.Arch
+		if arch == "" {
+			arch = defaultAirgapArch
+		}
+		dir, err := airgapCacheDir(v)
+		if err != nil {
+			return nil, err
+		}
+
+		if files.binary == "" {
+			files.binary = filepath.Join(dir, fmt.Sprintf("k0s-%s-%s", v, arch))
+			if err := fetch(client, v.DownloadURL("linux", arch), files.binary, 0o755); err != nil {
+				return nil, fmt.Errorf("failed to fetch the k0s binary: %w", err)
+			}
+		}
+		if files.bundle == "" {
+			files.bundle = filepath.Join(dir, fmt.Sprintf("k0s-airgap-bundle-%s-%s", v, arch))
+			if err := fetch(client, v.AirgapDownloadURL(arch), files.bundle, 0o644); err != nil {
+				return nil, fmt.Errorf("failed to fetch the k0s airgap image bundle: %w", err)
+			}
+		}
+	}
+
+	var err error
+	if files.binary, err = filepath.Abs(files.binary); err != nil {
+		return nil, err
+	}
+	if files.bundle, err = filepath.Abs(files.bundle); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// airgapCacheDir returns the directory the files of the k0s version are cached in
+func airgapCacheDir(v *version.Version) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine the bctl cache directory: %w", err)
+	}
+	return filepath.Join(dir, "bctl", "k0s", v.String()), nil
+}
+
+// fetch downloads the URL to the path, unless the path already exists
+// The download is written next to the path and moved there once complete, so that an interrupted one is not cached.
+func fetch(client *http.Client, url string, path string, mode os.FileMode) error {
+	if _, err := os.Stat(path); err == nil {
+		log.Debug().Msgf("Using cached %s", path)
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	log.Info().Msgf("Downloading %s", url)
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+
+	partial := path + ".partial"
+	file, err := os.OpenFile(partial, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(partial)
+	defer file.Close()
+
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		return fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(partial, path)
+}
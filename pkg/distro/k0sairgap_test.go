@@ -0,0 +1,80 @@
+package distro
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/utils"
+)
+
+// TestFetch tests that a file is downloaded once and then used from the cache, and that a failed download is not cached
+func TestFetch(t *testing.T) {
+	g := NewWithT(t)
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("k0s binary"))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "v1.31.1+k0s.0", "k0s")
+	g.Expect(fetch(http.DefaultClient, server.URL+"/k0s", path, 0o755)).To(Succeed())
+	g.Expect(fetch(http.DefaultClient, server.URL+"/k0s", path, 0o755)).To(Succeed())
+	g.Expect(requests).To(Equal(1))
+
+	data, err := os.ReadFile(path)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(data)).To(Equal("k0s binary"))
+	info, err := os.Stat(path)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(info.Mode().Perm()).To(BeEquivalentTo(0o755))
+
+	missing := filepath.Join(t.TempDir(), "bundle")
+	g.Expect(fetch(http.DefaultClient, server.URL+"/missing", missing, 0o644)).To(MatchError(ContainSubstring("404 Not Found")))
+	g.Expect(missing).ToNot(BeAnExistingFile())
+	g.Expect(missing + ".partial").ToNot(BeAnExistingFile())
+}
+
+// TestFetchThroughProxy tests that a file is downloaded through the proxy of the client
+func TestFetchThroughProxy(t *testing.T) {
+	g := NewWithT(t)
+
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		w.Write([]byte("k0s binary"))
+	}))
+	defer proxy.Close()
+
+	client := utils.NewHTTPClient(&types.Proxy{HTTP: proxy.URL}, nil)
+	path := filepath.Join(t.TempDir(), "k0s")
+	g.Expect(fetch(client, "http://k0s.example.com/k0s", path, 0o755)).To(Succeed())
+	g.Expect(proxied).To(Equal([]string{"http://k0s.example.com/k0s"}))
+}
+
+// TestResolveAirgap tests that the local files of an airgap install are used as is, with absolute paths
+func TestResolveAirgap(t *testing.T) {
+	g := NewWithT(t)
+
+	blueprint := &types.Blueprint{Spec: types.BlueprintSpec{Kubernetes: &types.Kubernetes{
+		Version: "1.31.1+k0s.0",
+		Airgap:  &types.Airgap{K0sBinary: "airgap/k0s", ImageBundle: "/airgap/bundle"},
+	}}}
+	files, err := resolveAirgap(blueprint)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	binary, err := filepath.Abs("airgap/k0s")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(files).To(Equal(&airgapFiles{binary: binary, bundle: "/airgap/bundle"}))
+}
@@ -0,0 +1,86 @@
+package distro
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/ssh"
+)
+
+// Backup takes a backup of the cluster with k0s backup on a controller and copies the archive to w
+// It returns the version of k0s that took the backup, which is needed to restore it.
+func (k *K0s) Backup(w io.Writer) (string, error) {
+	version, err := k.getInstalledVersion(k.blueprint)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the k0s version: %w", err)
+	}
+
+	// k0s names the archive after the time it was taken, so it is saved in a directory of its own
+	dir := fmt.Sprintf("/tmp/bctl-backup-%d", time.Now().UnixNano())
+	backup := fmt.Sprintf("sudo mkdir -p %s && sudo k0s backup --save-path %s", dir, dir)
+	download := fmt.Sprintf("sudo sh -c 'cat %s/k0s_backup_*.tar.gz'", dir)
+	cleanup := "sudo rm -rf " + dir
+
+	if k.isLocalK0s(k.blueprint) {
+		log.Info().Msg("Taking a k0s backup of the local host")
+		defer runLocal(cleanup, nil)
+		if err := runLocal(backup, nil); err != nil {
+			return "", fmt.Errorf("failed to take a k0s backup: %w", err)
+		}
+		if err := runLocal(download, w); err != nil {
+			return "", fmt.Errorf("failed to copy the k0s backup: %w", err)
+		}
+		return version, nil
+	}
+
+	controllers := k.getControllerHosts(k.blueprint)
+	if len(controllers) == 0 {
+		return "", fmt.Errorf("the blueprint has no controller to take the backup on")
+	}
+	host := *controllers[0].SSH
+
+	log.Info().Msgf("Taking a k0s backup on controller %s", host.Address)
+	defer func() {
+		if _, err := ssh.Run(host, cleanup); err != nil {
+			log.Warn().Msgf("Failed to remove the k0s backup from controller %s: %s", host.Address, err)
+		}
+	}()
+	if _, err := ssh.Run(host, backup); err != nil {
+		return "", fmt.Errorf("failed to take a k0s backup on controller %s: %w", host.Address, err)
+	}
+	if err := ssh.Stream(host, download, w); err != nil {
+		return "", fmt.Errorf("failed to download the k0s backup from controller %s: %w", host.Address, err)
+	}
+	return version, nil
+}
+
+// Restore installs k0s on the hosts of the blueprint from a k0s backup
+// k0sctl uploads the backup to the first controller and restores it before the other hosts join the cluster,
+// so the hosts must not run k0s yet.
+func (k *K0s) Restore(path string) error {
+	log.Debug().Msgf("Restoring k0s cluster %q from %s", k.name, path)
+
+	if err := k.checkBastions(); err != nil {
+		return err
+	}
+	if err := k.prepareAirgap(); err != nil {
+		return err
+	}
+	if err := k.k0sctl.run("apply", "--no-wait", "--restore-from", path); err != nil {
+		return fmt.Errorf("failed to restore k0s: %w", err)
+	}
+	return k.writeKubeConfig()
+}
+
+// runLocal runs the command on the local host, copying its output to stdout if any
+func runLocal(command string, stdout io.Writer) error {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
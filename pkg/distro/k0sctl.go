@@ -0,0 +1,65 @@
+package distro
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/ssh"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+// k0sctl runs the k0sctl commands of the k0s provider with its k0sctl config
+// k0sctl is not a dependency of this module, so its binary has to be on the PATH
+type k0sctl struct {
+	config string
+	// hosts are the hosts of the config, whose keys k0sctl cannot use by itself are served by the ssh-agent of bctl
+	hosts []types.Host
+}
+
+// run runs the k0sctl subcommand with the config, showing its output
+func (k *k0sctl) run(command string, args ...string) error {
+	cmd := k.command(command, args...)
+	cmd.Stdout = os.Stdout
+	if err := k.runWithAgent(cmd); err != nil {
+		return fmt.Errorf("k0sctl %s failed: %w", command, err)
+	}
+	return nil
+}
+
+// output runs the k0sctl subcommand with the config and returns its stdout
+func (k *k0sctl) output(command string, args ...string) ([]byte, error) {
+	stdout := new(bytes.Buffer)
+	cmd := k.command(command, args...)
+	cmd.Stdout = stdout
+	if err := k.runWithAgent(cmd); err != nil {
+		return nil, fmt.Errorf("k0sctl %s failed: %w", command, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+func (k *k0sctl) command(command string, args ...string) *exec.Cmd {
+	args = append([]string{command, "--config", k.config}, args...)
+	cmd := exec.Command("k0sctl", args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	log.Debug().Msgf("Running %s", strings.Join(cmd.Args, " "))
+	return cmd
+}
+
+// runWithAgent runs the command with SSH_AUTH_SOCK set to the ssh-agent of bctl, when a host needs it
+func (k *k0sctl) runWithAgent(cmd *exec.Cmd) error {
+	agent, err := ssh.ServeAgent(k.hosts)
+	if err != nil {
+		return err
+	}
+	if agent != nil {
+		defer agent.Close()
+		cmd.Env = append(os.Environ(), "SSH_AUTH_SOCK="+agent.Socket)
+	}
+	return cmd.Run()
+}
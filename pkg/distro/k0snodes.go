@@ -0,0 +1,66 @@
+package distro
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+// nodeNameCommand prints the hostname k0s names the node of a host after
+const nodeNameCommand = "hostname"
+
+// hostnameOverride is the node name set in the kubelet args of the k0s install flags
+var hostnameOverride = regexp.MustCompile(`--hostname-override[= ]([^\s"',]+)`)
+
+// HostRunsKubelet returns true if k0s registers a node for the host; controller only hosts have none
+func HostRunsKubelet(host types.Host) bool {
+	return host.Role != "controller"
+}
+
+// HostRunsController returns true if the host runs a k0s controller, and with etcd, an etcd member
+func HostRunsController(host types.Host) bool {
+	return host.Role != "worker"
+}
+
+// HostNodeNames returns the name of the node of each host that runs a kubelet, by address of the host
+// k0s names a node after the lowercase hostname reported by its host, unless the kubelet args of the install flags
+// override it. Two hosts with the same node name are an error, as their nodes could not be told apart.
+func HostNodeNames(hosts []types.Host, run func(host types.Host, command string) (string, error)) (map[string]string, error) {
+	names := map[string]string{}
+	owners := map[string]string{}
+	for _, host := range hosts {
+		if host.SSH == nil || !HostRunsKubelet(host) {
+			continue
+		}
+		name, err := hostNodeName(host, run)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the node name of host %s: %w", host.SSH.Address, err)
+		}
+		if owner, ok := owners[name]; ok {
+			return nil, fmt.Errorf("hosts %s and %s both register node %q", owner, host.SSH.Address, name)
+		}
+		owners[name] = host.SSH.Address
+		names[host.SSH.Address] = name
+	}
+	return names, nil
+}
+
+func hostNodeName(host types.Host, run func(host types.Host, command string) (string, error)) (string, error) {
+	for _, flag := range host.InstallFlags {
+		if m := hostnameOverride.FindStringSubmatch(flag); m != nil {
+			return strings.ToLower(m[1]), nil
+		}
+	}
+
+	output, err := run(host, nodeNameCommand)
+	if err != nil {
+		return "", err
+	}
+	name := strings.ToLower(strings.TrimSpace(output))
+	if name == "" {
+		return "", fmt.Errorf("the host reported no hostname")
+	}
+	return name, nil
+}
@@ -0,0 +1,65 @@
+package distro
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+// TestHostNodeNames tests that the node names are the ones reported by the hosts that run a kubelet
+func TestHostNodeNames(t *testing.T) {
+	hostnames := map[string]string{"10.0.0.1": "Controller-1", "10.0.0.2": "worker-1", "10.0.0.3": "worker-1"}
+	run := func(host types.Host, command string) (string, error) {
+		if hostname, ok := hostnames[host.SSH.Address]; ok && command == nodeNameCommand {
+			return hostname + "\n", nil
+		}
+		return "", errors.New("connection refused")
+	}
+
+	tests := map[string]struct {
+		hosts   []types.Host
+		want    map[string]string
+		wantErr string
+	}{
+		"reported hostnames": {
+			hosts: []types.Host{upgradeHost("10.0.0.1", "controller+worker"), upgradeHost("10.0.0.2", "worker")},
+			want:  map[string]string{"10.0.0.1": "controller-1", "10.0.0.2": "worker-1"},
+		},
+		"controller only host": {
+			hosts: []types.Host{upgradeHost("10.0.0.4", "controller"), upgradeHost("10.0.0.2", "worker")},
+			want:  map[string]string{"10.0.0.2": "worker-1"},
+		},
+		"hostname override": {
+			hosts: []types.Host{{
+				SSH:          &types.SSHHost{Address: "10.0.0.4"},
+				Role:         "worker",
+				InstallFlags: []string{`--kubelet-extra-args="--hostname-override=Worker-4 --v=2"`},
+			}},
+			want: map[string]string{"10.0.0.4": "worker-4"},
+		},
+		"same node name": {
+			hosts:   []types.Host{upgradeHost("10.0.0.2", "worker"), upgradeHost("10.0.0.3", "worker")},
+			wantErr: `hosts 10.0.0.2 and 10.0.0.3 both register node "worker-1"`,
+		},
+		"unreachable host": {
+			hosts:   []types.Host{upgradeHost("10.0.0.4", "worker")},
+			wantErr: "failed to get the node name of host 10.0.0.4: connection refused",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+			names, err := HostNodeNames(tt.hosts, run)
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(tt.wantErr))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(names).To(Equal(tt.want))
+		})
+	}
+}
@@ -0,0 +1,387 @@
+package distro
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/k0sproject/dig"
+	"github.com/k0sproject/version"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/k8s"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/ssh"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+// upgradeInterval is the interval between two health checks of an upgraded node
+var upgradeInterval = 5 * time.Second
+
+// hostRunner runs commands on the hosts of the cluster, and uploads files to them
+type hostRunner interface {
+	run(host types.Host, command string) (string, error)
+	upload(host types.Host, src string, dst string, mode os.FileMode) error
+}
+
+// sshRunner runs the commands over SSH
+type sshRunner struct{}
+
+func (sshRunner) run(host types.Host, command string) (string, error) {
+	return ssh.Run(*host.SSH, command)
+}
+
+func (sshRunner) upload(host types.Host, src string, dst string, mode os.FileMode) error {
+	return ssh.Upload(*host.SSH, src, dst, mode)
+}
+
+// upgradeStep is a group of hosts that are upgraded together
+type upgradeStep struct {
+	hosts      []types.Host
+	controller bool
+}
+
+// upgradePlan returns the steps that upgrade the hosts
+// The controllers are upgraded first, one at a time, then the workers in batches. The upgraded hosts are skipped.
+func upgradePlan(hosts []types.Host, batchSize int, upgraded []string) []upgradeStep {
+	batchSize = max(batchSize, 1)
+
+	var steps []upgradeStep
+	var workers []types.Host
+	for _, host := range hosts {
+		if host.SSH == nil || slices.Contains(upgraded, host.SSH.Address) {
+			continue
+		}
+		if host.Role == "worker" {
+			workers = append(workers, host)
+			continue
+		}
+		steps = append(steps, upgradeStep{hosts: []types.Host{host}, controller: true})
+	}
+
+	for start := 0; start < len(workers); start += batchSize {
+		end := min(start+batchSize, len(workers))
+		steps = append(steps, upgradeStep{hosts: workers[start:end]})
+	}
+	return steps
+}
+
+// upgradeState records the hosts upgraded by an upgrade, so that an interrupted upgrade can be resumed
+type upgradeState struct {
+	Version  string   `yaml:"version"`
+	Upgraded []string `yaml:"upgraded"`
+
+	path string
+}
+
+// upgradeStatePath returns the path of the upgrade state of the cluster
+func upgradeStatePath(name string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine the bctl config directory: %w", err)
+	}
+	return filepath.Join(dir, "bctl", "upgrades", name+".yaml"), nil
+}
+
+// loadUpgradeState returns the state of the upgrade of the cluster to the version
+// An interrupted upgrade has to be resumed before the cluster can be upgraded again
+func loadUpgradeState(name string, to string, resume bool) (*upgradeState, error) {
+	path, err := upgradeStatePath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &upgradeState{Version: to, path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if resume {
+			return nil, fmt.Errorf("no interrupted upgrade of cluster %q to resume", name)
+		}
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upgrade state: %w", err)
+	}
+	if err := yaml.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse upgrade state %s: %w", path, err)
+	}
+
+	if !resume {
+		return nil, fmt.Errorf("the upgrade of cluster %q to %s was interrupted after %d nodes; run it again with --resume, or remove %s to start over", name, state.Version, len(state.Upgraded), path)
+	}
+	if state.Version != to {
+		return nil, fmt.Errorf("cannot resume the upgrade of cluster %q to %s with version %s", name, state.Version, to)
+	}
+	return state, nil
+}
+
+// record marks the hosts as upgraded
+func (s *upgradeState) record(hosts ...types.Host) error {
+	for _, host := range hosts {
+		s.Upgraded = append(s.Upgraded, host.SSH.Address)
+	}
+
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("failed to save upgrade state: %w", err)
+	}
+	if err := os.WriteFile(s.path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save upgrade state: %w", err)
+	}
+	return nil
+}
+
+// done removes the state of a completed upgrade
+func (s *upgradeState) done() error {
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove upgrade state: %w", err)
+	}
+	return nil
+}
+
+// rollingUpgrade upgrades the k0s hosts of the cluster one node at a time
+// It stops at the first node that does not come back healthy, leaving it cordoned, so that the upgrade can be resumed once it is fixed
+func (k *K0s) rollingUpgrade(opts UpgradeOptions) error {
+	client, err := k8s.GetClient(k.kubeConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create k8s client: %w", err)
+	}
+
+	to := k.blueprint.Spec.Kubernetes.Version
+	state, err := loadUpgradeState(k.name, to, opts.Resume)
+	if err != nil {
+		return err
+	}
+
+	upgrader := &nodeUpgrader{
+		client:  client,
+		runner:  sshRunner{},
+		version: to,
+		etcd:    UsesEtcd(k.blueprint.Spec.Kubernetes.Config),
+		timeout: constants.NodeUpgradeTimeout,
+	}
+	if k.airgap != nil {
+		upgrader.binary = k.airgap.binary
+	}
+	// the hosts download k0s with the proxy environment k0sctl sets up for k0s
+	hosts := slices.Clone(k.blueprint.Spec.Kubernetes.Infra.Hosts)
+	for i := range hosts {
+		hosts[i].Environment = k.blueprint.Spec.HostEnvironment(hosts[i])
+	}
+	steps := upgradePlan(hosts, opts.WorkerBatchSize, state.Upgraded)
+	for i, step := range steps {
+		if err := upgrader.upgrade(context.Background(), step); err != nil {
+			return fmt.Errorf("upgrade paused after %d of %d steps: %w; fix the node and run the update again with --resume", i, len(steps), err)
+		}
+		if err := state.record(step.hosts...); err != nil {
+			return err
+		}
+	}
+
+	log.Info().Msgf("Upgraded all the nodes to k0s %s", to)
+	return state.done()
+}
+
+// UsesEtcd returns true if the k0s config stores the cluster state in etcd, which is the default
+func UsesEtcd(config dig.Mapping) bool {
+	storage := types.DigToString(config, "spec", "storage", "type")
+	return storage == "" || storage == "etcd"
+}
+
+// nodeUpgrader upgrades the k0s hosts and checks that they come back healthy
+type nodeUpgrader struct {
+	client  kubernetes.Interface
+	runner  hostRunner
+	version string
+	// etcd checks the etcd member of the controllers
+	etcd bool
+	// binary is the local k0s binary uploaded to the hosts of an airgap install, the hosts download k0s otherwise
+	binary  string
+	timeout time.Duration
+}
+
+// upgrade upgrades the hosts of the step
+// The nodes of the hosts are drained before the upgrade and uncordoned once they are ready at the new version
+func (u *nodeUpgrader) upgrade(ctx context.Context, step upgradeStep) error {
+	nodes, err := u.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	// controller only hosts do not run the kubelet, so they have no node
+	// the node of any other host has to be found, as upgrading it without draining it first would disrupt its pods
+	hostNodes, err := HostNodeNames(step.hosts, u.runner.run)
+	if err != nil {
+		return err
+	}
+	for address, name := range hostNodes {
+		if !slices.ContainsFunc(nodes.Items, func(node corev1.Node) bool { return node.Name == name }) {
+			return fmt.Errorf("node %q of host %s is not in the cluster, so it cannot be drained before the upgrade", name, address)
+		}
+	}
+
+	for _, host := range step.hosts {
+		node, ok := hostNodes[host.SSH.Address]
+		// the node of a single node cluster cannot be drained, its pods have nowhere to go
+		if !ok || host.Role == "single" {
+			continue
+		}
+		if err := k8s.CordonNode(ctx, u.client, node); err != nil {
+			return err
+		}
+		if err := k8s.DrainNode(ctx, u.client, node, constants.NodeDrainTimeout); err != nil {
+			return fmt.Errorf("node %q is cordoned but could not be drained: %w", node, err)
+		}
+	}
+
+	if err := u.upgradeHosts(step.hosts); err != nil {
+		return err
+	}
+
+	for _, host := range step.hosts {
+		if step.controller {
+			if err := u.waitForController(ctx, host); err != nil {
+				return err
+			}
+		}
+		node, ok := hostNodes[host.SSH.Address]
+		if !ok {
+			continue
+		}
+		if err := u.waitForNode(ctx, node); err != nil {
+			return err
+		}
+		if err := k8s.UncordonNode(ctx, u.client, node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upgradeHosts installs the new k0s version on the hosts at the same time and restarts it
+func (u *nodeUpgrader) upgradeHosts(hosts []types.Host) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(hosts))
+	for i, host := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			log.Info().Msgf("Upgrading k0s on host %s (%s)", host.SSH.Address, host.Role)
+			if u.binary != "" {
+				if err := u.runner.upload(host, u.binary, remoteK0sBinary, 0o755); err != nil {
+					errs[i] = fmt.Errorf("failed to upload k0s to host %s: %w", host.SSH.Address, err)
+					return
+				}
+			}
+			_, errs[i] = u.runner.run(host, u.upgradeCommand(host))
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// upgradeCommand returns the command that installs the new k0s version on the host and restarts it
+// The install script is downloaded and run with the environment of the host, e.g. its proxy. It writes the binary
+// in place, which fails with "text file busy" while k0s runs, so k0s is stopped first; it is started again even when
+// the install fails, with the previous binary, and the command then fails with the status of the install.
+func (u *nodeUpgrader) upgradeCommand(host types.Host) string {
+	if u.binary != "" {
+		return "sudo k0s stop && sudo k0s start"
+	}
+	env := ssh.EnvAssignments(host.Environment)
+	return fmt.Sprintf("sudo k0s stop && { %scurl -sSLf https://get.k0s.sh | sudo %sK0S_VERSION=v%s sh; installed=$?; sudo k0s start && exit $installed; }",
+		env, env, strings.TrimPrefix(u.version, "v"))
+}
+
+// waitForController waits for the controller to run the new version with a healthy API server and etcd member
+func (u *nodeUpgrader) waitForController(ctx context.Context, host types.Host) error {
+	checks := []string{"sudo k0s version", "sudo k0s kubectl get --raw=/readyz"}
+	if u.etcd {
+		checks = append(checks, "sudo k0s etcd member-list")
+	}
+
+	var lastErr error
+	err := wait.PollUntilContextTimeout(ctx, upgradeInterval, u.timeout, true, func(ctx context.Context) (bool, error) {
+		installed, err := u.runner.run(host, checks[0])
+		if err != nil {
+			lastErr = err
+			return false, nil
+		}
+		if !sameK0sVersion(installed, u.version) {
+			lastErr = fmt.Errorf("host %s runs k0s %s", host.SSH.Address, strings.TrimSpace(installed))
+			return false, nil
+		}
+		for _, check := range checks[1:] {
+			if _, err := u.runner.run(host, check); err != nil {
+				lastErr = err
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("controller %s is not healthy after the upgrade: %w", host.SSH.Address, lastErr)
+	}
+	log.Info().Msgf("Controller %s is healthy", host.SSH.Address)
+	return nil
+}
+
+// waitForNode waits for the node to be ready with the kubelet of the new version
+func (u *nodeUpgrader) waitForNode(ctx context.Context, name string) error {
+	kubeletVersion := "v" + strings.SplitN(strings.TrimPrefix(u.version, "v"), "+", 2)[0]
+
+	var status string
+	err := wait.PollUntilContextTimeout(ctx, upgradeInterval, u.timeout, true, func(ctx context.Context) (bool, error) {
+		node, err := u.client.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			status = err.Error()
+			return false, nil
+		}
+
+		kubelet := node.Status.NodeInfo.KubeletVersion
+		if kubelet != kubeletVersion && !strings.HasPrefix(kubelet, kubeletVersion+"+") {
+			status = fmt.Sprintf("kubelet version is %s", kubelet)
+			return false, nil
+		}
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue {
+				return true, nil
+			}
+		}
+		status = "not ready"
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("node %q did not come back after the upgrade: %s", name, status)
+	}
+	log.Info().Msgf("Node %q is ready at %s", name, kubeletVersion)
+	return nil
+}
+
+// sameK0sVersion returns true if the output of k0s version is the version
+func sameK0sVersion(installed string, want string) bool {
+	a, err := version.NewVersion(strings.TrimSpace(installed))
+	if err != nil {
+		return false
+	}
+	b, err := version.NewVersion(want)
+	if err != nil {
+		return false
+	}
+	return a.Equal(b)
+}
@@ -0,0 +1,238 @@
+package distro
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekubernetes "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+func upgradeHost(address string, role string) types.Host {
+	return types.Host{SSH: &types.SSHHost{Address: address, User: "root", Port: 22}, Role: role}
+}
+
+func stepAddresses(steps []upgradeStep) [][]string {
+	var addresses [][]string
+	for _, step := range steps {
+		var names []string
+		for _, host := range step.hosts {
+			names = append(names, host.SSH.Address)
+		}
+		addresses = append(addresses, names)
+	}
+	return addresses
+}
+
+// TestUpgradePlan tests that the controllers are upgraded one at a time before the workers are upgraded in batches
+func TestUpgradePlan(t *testing.T) {
+	hosts := []types.Host{
+		upgradeHost("w1", "worker"),
+		upgradeHost("c1", "controller"),
+		upgradeHost("w2", "worker"),
+		upgradeHost("c2", "controller+worker"),
+		upgradeHost("w3", "worker"),
+	}
+
+	tests := map[string]struct {
+		batchSize int
+		upgraded  []string
+		want      [][]string
+	}{
+		"one at a time":    {batchSize: 1, want: [][]string{{"c1"}, {"c2"}, {"w1"}, {"w2"}, {"w3"}}},
+		"default batch":    {batchSize: 0, want: [][]string{{"c1"}, {"c2"}, {"w1"}, {"w2"}, {"w3"}}},
+		"batches":          {batchSize: 2, want: [][]string{{"c1"}, {"c2"}, {"w1", "w2"}, {"w3"}}},
+		"resumed":          {batchSize: 2, upgraded: []string{"c1", "c2", "w1"}, want: [][]string{{"w2", "w3"}}},
+		"already upgraded": {batchSize: 2, upgraded: []string{"c1", "c2", "w1", "w2", "w3"}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+			steps := upgradePlan(hosts, tt.batchSize, tt.upgraded)
+			g.Expect(stepAddresses(steps)).To(Equal(tt.want))
+			for _, step := range steps {
+				g.Expect(step.controller).To(Equal(step.hosts[0].Role != "worker"))
+			}
+		})
+	}
+}
+
+// TestUpgradeState tests that an interrupted upgrade has to be resumed with the same version
+func TestUpgradeState(t *testing.T) {
+	g := NewWithT(t)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	_, err := loadUpgradeState("test", "1.31.1+k0s.0", true)
+	g.Expect(err).To(MatchError(ContainSubstring("no interrupted upgrade")))
+
+	state, err := loadUpgradeState("test", "1.31.1+k0s.0", false)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(state.record(upgradeHost("c1", "controller"))).To(Succeed())
+
+	_, err = loadUpgradeState("test", "1.31.1+k0s.0", false)
+	g.Expect(err).To(MatchError(ContainSubstring("run it again with --resume")))
+	_, err = loadUpgradeState("test", "1.31.2+k0s.0", true)
+	g.Expect(err).To(MatchError(ContainSubstring("cannot resume")))
+
+	resumed, err := loadUpgradeState("test", "1.31.1+k0s.0", true)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(resumed.Upgraded).To(Equal([]string{"c1"}))
+
+	g.Expect(resumed.done()).To(Succeed())
+	_, err = loadUpgradeState("test", "1.31.1+k0s.0", false)
+	g.Expect(err).ToNot(HaveOccurred())
+}
+
+// fakeRunner upgrades the kubelet of the nodes of the hosts it upgrades
+type fakeRunner struct {
+	client *fakekubernetes.Clientset
+	// broken hosts do not come back after the upgrade
+	broken map[string]bool
+
+	mu       sync.Mutex
+	version  map[string]string
+	commands []string
+}
+
+func (r *fakeRunner) run(host types.Host, command string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commands = append(r.commands, host.SSH.Address+": "+command)
+
+	switch {
+	case strings.Contains(command, "sudo k0s start"):
+		if r.broken[host.SSH.Address] {
+			return "", nil
+		}
+		r.version[host.SSH.Address] = "v1.31.1+k0s.0"
+		node, err := r.client.CoreV1().Nodes().Get(context.Background(), host.SSH.Address, metav1.GetOptions{})
+		if err != nil {
+			return "", nil
+		}
+		node.Status.NodeInfo.KubeletVersion = "v1.31.1+k0s"
+		_, err = r.client.CoreV1().Nodes().UpdateStatus(context.Background(), node, metav1.UpdateOptions{})
+		return "", err
+	case command == "sudo k0s version":
+		return r.version[host.SSH.Address] + "\n", nil
+	case command == nodeNameCommand:
+		return strings.ToUpper(host.SSH.Address) + "\n", nil
+	}
+	return "", nil
+}
+
+func (r *fakeRunner) upload(host types.Host, src string, dst string, mode os.FileMode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commands = append(r.commands, fmt.Sprintf("%s: upload %s to %s (%o)", host.SSH.Address, src, dst, mode))
+	return nil
+}
+
+func upgradeNode(name string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			NodeInfo:   corev1.NodeSystemInfo{KubeletVersion: "v1.30.4+k0s"},
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+		},
+	}
+}
+
+// TestNodeUpgrader tests that the nodes are drained, upgraded and uncordoned, and that a node that does not come back stays cordoned
+func TestNodeUpgrader(t *testing.T) {
+	g := NewWithT(t)
+	upgradeInterval = 10 * time.Millisecond
+
+	client := fakekubernetes.NewSimpleClientset(upgradeNode("c1"), upgradeNode("w1"), upgradeNode("w2"))
+	runner := &fakeRunner{client: client, broken: map[string]bool{"w2": true}, version: map[string]string{}}
+	upgrader := &nodeUpgrader{client: client, runner: runner, version: "1.31.1+k0s.0", etcd: true, timeout: 200 * time.Millisecond}
+
+	hosts := []types.Host{upgradeHost("c1", "controller+worker"), upgradeHost("w1", "worker"), upgradeHost("w2", "worker")}
+	steps := upgradePlan(hosts, 1, nil)
+
+	g.Expect(upgrader.upgrade(context.Background(), steps[0])).To(Succeed())
+	g.Expect(runner.commands).To(ContainElements("c1: sudo k0s kubectl get --raw=/readyz", "c1: sudo k0s etcd member-list"))
+	g.Expect(upgrader.upgrade(context.Background(), steps[1])).To(Succeed())
+
+	err := upgrader.upgrade(context.Background(), steps[2])
+	g.Expect(err).To(MatchError(ContainSubstring(`node "w2" did not come back after the upgrade: kubelet version is v1.30.4+k0s`)))
+
+	for name, cordoned := range map[string]bool{"c1": false, "w1": false, "w2": true} {
+		node, err := client.CoreV1().Nodes().Get(context.Background(), name, metav1.GetOptions{})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(node.Spec.Unschedulable).To(Equal(cordoned), fmt.Sprintf("node %s", name))
+	}
+}
+
+// TestNodeUpgraderMissingNode tests that a host whose node is not found is not upgraded without being drained
+func TestNodeUpgraderMissingNode(t *testing.T) {
+	g := NewWithT(t)
+
+	client := fakekubernetes.NewSimpleClientset(upgradeNode("w1"))
+	runner := &fakeRunner{client: client, version: map[string]string{}}
+	upgrader := &nodeUpgrader{client: client, runner: runner, version: "1.31.1+k0s.0", timeout: 200 * time.Millisecond}
+
+	steps := upgradePlan([]types.Host{upgradeHost("w1", "worker"), upgradeHost("w2", "worker")}, 2, nil)
+	err := upgrader.upgrade(context.Background(), steps[0])
+	g.Expect(err).To(MatchError(`node "w2" of host w2 is not in the cluster, so it cannot be drained before the upgrade`))
+	g.Expect(runner.commands).ToNot(ContainElement(ContainSubstring("sudo k0s start")))
+}
+
+// TestNodeUpgraderProxy tests that the hosts download k0s with their proxy environment
+func TestNodeUpgraderProxy(t *testing.T) {
+	g := NewWithT(t)
+	upgradeInterval = 10 * time.Millisecond
+
+	client := fakekubernetes.NewSimpleClientset(upgradeNode("w1"))
+	runner := &fakeRunner{client: client, version: map[string]string{}}
+	upgrader := &nodeUpgrader{client: client, runner: runner, version: "1.31.1+k0s.0", timeout: 200 * time.Millisecond}
+
+	host := upgradeHost("w1", "worker")
+	host.Environment = map[string]string{"HTTPS_PROXY": "http://proxy:3128", "NO_PROXY": "10.0.0.0/8"}
+	steps := upgradePlan([]types.Host{host}, 1, nil)
+	g.Expect(upgrader.upgrade(context.Background(), steps[0])).To(Succeed())
+	g.Expect(runner.commands).To(ContainElement(
+		"w1: sudo k0s stop && { HTTPS_PROXY='http://proxy:3128' NO_PROXY='10.0.0.0/8' curl -sSLf https://get.k0s.sh | " +
+			"sudo HTTPS_PROXY='http://proxy:3128' NO_PROXY='10.0.0.0/8' K0S_VERSION=v1.31.1+k0s.0 sh; installed=$?; sudo k0s start && exit $installed; }",
+	))
+}
+
+// TestUpgradeCommandOrder tests that k0s is stopped before the install script replaces its binary, and started after it
+func TestUpgradeCommandOrder(t *testing.T) {
+	g := NewWithT(t)
+
+	upgrader := &nodeUpgrader{version: "v1.31.1+k0s.0"}
+	command := upgrader.upgradeCommand(upgradeHost("w1", "worker"))
+
+	stop, install, start := strings.Index(command, "sudo k0s stop"), strings.Index(command, "get.k0s.sh"), strings.Index(command, "sudo k0s start")
+	g.Expect(stop).To(Equal(0))
+	g.Expect(install).To(BeNumerically(">", stop))
+	g.Expect(start).To(BeNumerically(">", install))
+}
+
+// TestNodeUpgraderAirgap tests that the k0s binary of an airgap install is uploaded instead of downloaded by the hosts
+func TestNodeUpgraderAirgap(t *testing.T) {
+	g := NewWithT(t)
+	upgradeInterval = 10 * time.Millisecond
+
+	client := fakekubernetes.NewSimpleClientset(upgradeNode("w1"))
+	runner := &fakeRunner{client: client, version: map[string]string{}}
+	upgrader := &nodeUpgrader{client: client, runner: runner, version: "1.31.1+k0s.0", binary: "/cache/k0s-v1.31.1+k0s.0-amd64", timeout: 200 * time.Millisecond}
+
+	steps := upgradePlan([]types.Host{upgradeHost("w1", "worker")}, 1, nil)
+	g.Expect(upgrader.upgrade(context.Background(), steps[0])).To(Succeed())
+	g.Expect(runner.commands).To(ContainElements(
+		"w1: upload /cache/k0s-v1.31.1+k0s.0-amd64 to /usr/local/bin/k0s (755)",
+		"w1: sudo k0s stop && sudo k0s start",
+	))
+	g.Expect(runner.commands).ToNot(ContainElement(ContainSubstring("get.k0s.sh")))
+}
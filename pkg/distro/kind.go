@@ -1,26 +1,32 @@
 package distro
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
 	"github.com/k0sproject/dig"
 	"github.com/rs/zerolog/log"
 	"gopkg.in/yaml.v2"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 
 	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/k8s"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/readiness"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/utils"
 )
 
 // Kind is the kind provider
 type Kind struct {
-	name       string
-	kindConfig dig.Mapping
-	kubeConfig *k8s.KubeConfig
-	client     *kubernetes.Clientset
+	name          string
+	kindConfig    dig.Mapping
+	kubeConfig    *k8s.KubeConfig
+	client        *kubernetes.Clientset
+	dynamicClient *dynamic.DynamicClient
 }
 
 // NewKindProvider returns a new kind provider
@@ -64,7 +70,7 @@ func (k *Kind) Refresh() error {
 	return nil
 }
 
-func (k *Kind) Upgrade() error {
+func (k *Kind) Upgrade(opts UpgradeOptions) error {
 	return nil
 }
 
@@ -75,6 +81,10 @@ func (k *Kind) SetupClient() error {
 	if err != nil {
 		return fmt.Errorf("failed to create k8s client: %w", err)
 	}
+	k.dynamicClient, err = k8s.GetDynamicClient(k.kubeConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create k8s dynamic client: %w", err)
+	}
 	return k.WaitForNodes()
 }
 
@@ -107,6 +117,21 @@ func (k *Kind) GetKubeConfigContext() string {
 	return "kind-" + k.name
 }
 
+// ClusterKubeConfig returns the kubeconfig of the kind cluster
+func (k *Kind) ClusterKubeConfig() (*clientcmdapi.Config, error) {
+	out, err := utils.ExecCommandWithReturn(fmt.Sprintf("kind get kubeconfig --name %s", k.name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubeconfig for kind cluster %q: %w", k.name, err)
+	}
+
+	config, err := clientcmd.Load([]byte(out))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig of kind cluster %q: %w", k.name, err)
+	}
+
+	return config, nil
+}
+
 // Type returns the type of the provider
 func (k *Kind) Type() string {
 	return constants.ProviderKind
@@ -119,7 +144,7 @@ func (k *Kind) GetKubeConfig() *k8s.KubeConfig {
 
 // WaitForPods waits for pods to be ready
 func (k *Kind) WaitForPods() error {
-	if err := k8s.WaitForPods(k.client, constants.NamespaceBlueprint); err != nil {
+	if err := readiness.NewWaiter(k.dynamicClient).WaitForNamespace(context.Background(), constants.NamespaceBlueprint); err != nil {
 		return fmt.Errorf("failed to wait for pods: %w", err)
 	}
 
@@ -128,7 +153,7 @@ func (k *Kind) WaitForPods() error {
 
 // WaitForNodes waits for nodes to be ready
 func (k *Kind) WaitForNodes() error {
-	if err := k8s.WaitForNodes(k.client); err != nil {
+	if err := readiness.NewWaiter(k.dynamicClient).WaitForNodes(context.Background()); err != nil {
 		return fmt.Errorf("failed to wait for nodes: %w", err)
 	}
 
@@ -3,6 +3,8 @@ package distro
 import (
 	"fmt"
 
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
 	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/k8s"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
@@ -12,11 +14,12 @@ import (
 type Provider interface {
 	Install() error
 	Refresh() error
-	Upgrade() error
+	Upgrade(opts UpgradeOptions) error
 	SetupClient() error
 	Exists() (bool, error)
 	Reset() error
 	GetKubeConfigContext() string
+	ClusterKubeConfig() (*clientcmdapi.Config, error)
 	Type() string
 	GetKubeConfig() *k8s.KubeConfig
 	WaitForNodes() error
@@ -25,6 +28,14 @@ type Provider interface {
 	ValidateProviderUpgrade(blueprint *types.Blueprint) error
 }
 
+// UpgradeOptions are the options of an upgrade of the kubernetes provider
+type UpgradeOptions struct {
+	// WorkerBatchSize is the number of workers that are upgraded at the same time
+	WorkerBatchSize int
+	// Resume continues an interrupted upgrade after the last node that was upgraded
+	Resume bool
+}
+
 // GetProvider returns a new provider
 func GetProvider(blueprint *types.Blueprint, kubeConfig *k8s.KubeConfig) (Provider, error) {
 	switch blueprint.Spec.Kubernetes.Provider {
@@ -0,0 +1,203 @@
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/k8s"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+// Outcome is the result of an action on a cluster
+type Outcome string
+
+const (
+	Succeeded Outcome = "succeeded"
+	Failed    Outcome = "failed"
+	Skipped   Outcome = "skipped"
+)
+
+// Target is a cluster of the fleet with the blueprint and the kubeconfig to use for it
+type Target struct {
+	Name       string
+	Wave       string
+	Blueprint  *types.Blueprint
+	KubeConfig *k8s.KubeConfig
+}
+
+// Action runs a command against a cluster
+// The returned message is shown in the summary, next to the error if there is one
+type Action func(ctx context.Context, target *Target) (string, error)
+
+// Wave is a group of clusters that are rolled out together
+type Wave struct {
+	Name    string
+	Targets []*Target
+}
+
+// Options control how the action is rolled out
+type Options struct {
+	// Concurrency is the number of clusters of a wave the action runs against at the same time
+	// 0 runs the action against all the clusters of a wave at once
+	Concurrency int
+	// ContinueOnError keeps rolling out the action after it failed on a cluster
+	ContinueOnError bool
+}
+
+// Result is the result of the action on a cluster
+type Result struct {
+	Cluster  string
+	Wave     string
+	Outcome  Outcome
+	Message  string
+	Duration time.Duration
+}
+
+// Waves groups the targets by wave, in the order of the waves
+// The targets without a wave form the last wave
+func Waves(targets []*Target, order []string) []Wave {
+	var waves []Wave
+	for _, name := range append(order, "") {
+		wave := Wave{Name: name}
+		for _, target := range targets {
+			if target.Wave == name {
+				wave.Targets = append(wave.Targets, target)
+			}
+		}
+		if len(wave.Targets) > 0 {
+			waves = append(waves, wave)
+		}
+	}
+	return waves
+}
+
+// Rollout runs the action against the clusters, one wave after the other
+// Once the action failed on a cluster, no other cluster is started and the following waves are skipped,
+// unless ContinueOnError is set. The clusters that already started are run to completion.
+// The results are in the order of the waves and their targets.
+func Rollout(ctx context.Context, waves []Wave, opts Options, action Action) []Result {
+	var results []Result
+	stopped := false
+	for _, wave := range waves {
+		if stopped {
+			for _, target := range wave.Targets {
+				results = append(results, skipped(target, "a previous wave failed"))
+			}
+			continue
+		}
+
+		log.Info().Msgf("Rolling out wave %s to %d clusters", waveName(wave.Name), len(wave.Targets))
+		waveResults := rolloutWave(ctx, wave, opts, action)
+		results = append(results, waveResults...)
+
+		if !opts.ContinueOnError && len(Failures(waveResults)) > 0 {
+			stopped = true
+		}
+	}
+	return results
+}
+
+// rolloutWave runs the action against the clusters of the wave with bounded concurrency
+func rolloutWave(ctx context.Context, wave Wave, opts Options, action Action) []Result {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 || concurrency > len(wave.Targets) {
+		concurrency = len(wave.Targets)
+	}
+
+	results := make([]Result, len(wave.Targets))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failed := false
+
+	for i, target := range wave.Targets {
+		slots <- struct{}{}
+
+		mu.Lock()
+		stop := failed && !opts.ContinueOnError
+		mu.Unlock()
+		if err := ctx.Err(); err != nil {
+			<-slots
+			results[i] = skipped(target, err.Error())
+			continue
+		}
+		if stop {
+			<-slots
+			results[i] = skipped(target, "a previous cluster failed")
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, target *Target) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			log.Info().Msgf("Cluster %s: starting", target.Name)
+			start := time.Now()
+			message, err := action(ctx, target)
+			result := Result{Cluster: target.Name, Wave: target.Wave, Outcome: Succeeded, Message: message, Duration: time.Since(start)}
+			if err != nil {
+				log.Error().Msgf("Cluster %s: %s", target.Name, err)
+				result.Outcome = Failed
+				result.Message = joinMessage(message, err.Error())
+
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+			} else {
+				log.Info().Msgf("Cluster %s: done", target.Name)
+			}
+			results[i] = result
+		}(i, target)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// Failures returns the results of the clusters the action failed on
+func Failures(results []Result) []Result {
+	var failures []Result
+	for _, result := range results {
+		if result.Outcome == Failed {
+			failures = append(failures, result)
+		}
+	}
+	return failures
+}
+
+// PrintSummary prints a table with the result of each cluster
+func PrintSummary(w io.Writer, results []Result) {
+	fmt.Fprintf(w, "%-24s %-12s %-10s %-10s %s\n", "CLUSTER", "WAVE", "RESULT", "DURATION", "MESSAGE")
+	for _, result := range results {
+		duration := "-"
+		if result.Outcome != Skipped {
+			duration = result.Duration.Round(time.Second).String()
+		}
+		fmt.Fprintf(w, "%-24s %-12s %-10s %-10s %s\n", result.Cluster, waveName(result.Wave), strings.ToUpper(string(result.Outcome)), duration, result.Message)
+	}
+}
+
+func skipped(target *Target, reason string) Result {
+	return Result{Cluster: target.Name, Wave: target.Wave, Outcome: Skipped, Message: fmt.Sprintf("not started: %s", reason)}
+}
+
+func joinMessage(message string, err string) string {
+	if message == "" {
+		return err
+	}
+	return fmt.Sprintf("%s: %s", message, err)
+}
+
+func waveName(name string) string {
+	if name == "" {
+		return "-"
+	}
+	return name
+}
@@ -0,0 +1,108 @@
+package fleet
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+// TestWaves tests that the targets are grouped in the order of the waves, followed by the ones without a wave
+func TestWaves(t *testing.T) {
+	g := NewWithT(t)
+
+	targets := []*Target{
+		{Name: "a", Wave: "rest"},
+		{Name: "b"},
+		{Name: "c", Wave: "canary"},
+		{Name: "d", Wave: "rest"},
+	}
+
+	waves := Waves(targets, []string{"canary", "empty", "rest"})
+	g.Expect(waves).To(HaveLen(3))
+	g.Expect(names(waves[0].Targets)).To(Equal([]string{"c"}))
+	g.Expect(names(waves[1].Targets)).To(Equal([]string{"a", "d"}))
+	g.Expect(waves[2].Name).To(BeEmpty())
+	g.Expect(names(waves[2].Targets)).To(Equal([]string{"b"}))
+}
+
+// TestRollout tests that a failure stops the rollout unless errors are ignored
+func TestRollout(t *testing.T) {
+	waves := []Wave{
+		{Name: "canary", Targets: []*Target{{Name: "canary-1", Wave: "canary"}}},
+		{Name: "rest", Targets: []*Target{{Name: "rest-1", Wave: "rest"}, {Name: "rest-2", Wave: "rest"}}},
+	}
+
+	tests := map[string]struct {
+		failing         string
+		continueOnError bool
+		want            []Outcome
+	}{
+		"all succeed":               {want: []Outcome{Succeeded, Succeeded, Succeeded}},
+		"canary fails":              {failing: "canary-1", want: []Outcome{Failed, Skipped, Skipped}},
+		"canary fails and continue": {failing: "canary-1", continueOnError: true, want: []Outcome{Failed, Succeeded, Succeeded}},
+		"cluster of a wave fails":   {failing: "rest-1", want: []Outcome{Succeeded, Failed, Skipped}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			action := func(ctx context.Context, target *Target) (string, error) {
+				if target.Name == tc.failing {
+					return "", errors.New("unhealthy")
+				}
+				return "healthy", nil
+			}
+			results := Rollout(context.Background(), waves, Options{Concurrency: 1, ContinueOnError: tc.continueOnError}, action)
+
+			var outcomes []Outcome
+			for _, result := range results {
+				outcomes = append(outcomes, result.Outcome)
+			}
+			g.Expect(outcomes).To(Equal(tc.want))
+		})
+	}
+}
+
+// TestRolloutConcurrency tests that no more clusters than the concurrency are worked on at the same time
+func TestRolloutConcurrency(t *testing.T) {
+	g := NewWithT(t)
+
+	var targets []*Target
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		targets = append(targets, &Target{Name: name})
+	}
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	action := func(ctx context.Context, target *Target) (string, error) {
+		mu.Lock()
+		running++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return "", nil
+	}
+
+	results := Rollout(context.Background(), Waves(targets, nil), Options{Concurrency: 2}, action)
+	g.Expect(results).To(HaveLen(6))
+	g.Expect(Failures(results)).To(BeEmpty())
+	g.Expect(maxRunning).To(Equal(2))
+}
+
+func names(targets []*Target) []string {
+	var names []string
+	for _, target := range targets {
+		names = append(names, target.Name)
+	}
+	return names
+}
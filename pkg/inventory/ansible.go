@@ -0,0 +1,331 @@
+package inventory
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+// ansibleInventory is an Ansible inventory, in the INI or the YAML format
+type ansibleInventory struct {
+	// hosts are the names of the hosts, in the order of the inventory
+	hosts []string
+	// vars are the variables of the hosts
+	vars   map[string]map[string]string
+	groups map[string]*ansibleGroup
+}
+
+type ansibleGroup struct {
+	hosts    []string
+	children []string
+	vars     map[string]string
+}
+
+func newAnsibleInventory() *ansibleInventory {
+	return &ansibleInventory{vars: make(map[string]map[string]string), groups: make(map[string]*ansibleGroup)}
+}
+
+func (inv *ansibleInventory) group(name string) *ansibleGroup {
+	group, ok := inv.groups[name]
+	if !ok {
+		group = &ansibleGroup{vars: make(map[string]string)}
+		inv.groups[name] = group
+	}
+	return group
+}
+
+func (inv *ansibleInventory) addHost(group string, name string, vars map[string]string) {
+	if !slices.Contains(inv.hosts, name) {
+		inv.hosts = append(inv.hosts, name)
+		inv.vars[name] = make(map[string]string)
+	}
+	for key, value := range vars {
+		inv.vars[name][key] = value
+	}
+	if g := inv.group(group); !slices.Contains(g.hosts, name) {
+		g.hosts = append(g.hosts, name)
+	}
+}
+
+// readAnsible reads the hosts of an Ansible inventory
+// The inventory is read as YAML when its extension is .yml or .yaml, and as INI otherwise.
+// The SSH settings are taken from the ansible_host, ansible_user, ansible_port and ansible_ssh_private_key_file variables.
+func readAnsible(path string) ([]host, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var inv *ansibleInventory
+	switch filepath.Ext(path) {
+	case ".yml", ".yaml":
+		inv, err = parseAnsibleYAML(data)
+	default:
+		inv, err = parseAnsibleINI(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var hosts []host
+	for _, name := range inv.hosts {
+		vars := inv.hostVars(name)
+		h := host{name: name, groups: inv.groupsOf(name), ssh: types.SSHHost{
+			Address: name,
+			User:    firstOf(vars, "ansible_user", "ansible_ssh_user"),
+			KeyPath: firstOf(vars, "ansible_ssh_private_key_file", "ansible_private_key_file"),
+		}}
+		if address := firstOf(vars, "ansible_host", "ansible_ssh_host"); address != "" {
+			h.ssh.Address = address
+		}
+		if port := firstOf(vars, "ansible_port", "ansible_ssh_port"); port != "" {
+			if h.ssh.Port, err = strconv.Atoi(port); err != nil {
+				return nil, fmt.Errorf("invalid port %q of host %s", port, name)
+			}
+		}
+		hosts = append(hosts, h)
+	}
+	return hosts, nil
+}
+
+// parseAnsibleINI parses an inventory in the INI format, with [group], [group:vars] and [group:children] sections
+func parseAnsibleINI(data []byte) (*ansibleInventory, error) {
+	inv := newAnsibleInventory()
+	group, section := "ungrouped", "hosts"
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			group, section = strings.Trim(line, "[]"), "hosts"
+			if name, kind, ok := strings.Cut(group, ":"); ok {
+				group, section = name, kind
+			}
+			if section != "hosts" && section != "vars" && section != "children" {
+				return nil, fmt.Errorf("line %d: unknown section %q", number, line)
+			}
+			inv.group(group)
+			continue
+		}
+
+		switch section {
+		case "hosts":
+			fields := splitFields(line)
+			if strings.Contains(fields[0], "[") {
+				return nil, fmt.Errorf("line %d: host ranges such as %s are not supported", number, fields[0])
+			}
+			vars := make(map[string]string)
+			for _, field := range fields[1:] {
+				key, value, ok := strings.Cut(field, "=")
+				if !ok {
+					return nil, fmt.Errorf("line %d: expected a key=value variable, got %q", number, field)
+				}
+				vars[key] = value
+			}
+			inv.addHost(group, fields[0], vars)
+		case "vars":
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected a key=value variable, got %q", number, line)
+			}
+			inv.group(group).vars[strings.TrimSpace(key)] = unquote(strings.TrimSpace(value))
+		case "children":
+			inv.group(line)
+			inv.group(group).children = append(inv.group(group).children, line)
+		}
+	}
+	return inv, scanner.Err()
+}
+
+// ansibleYAMLGroup is a group of an inventory in the YAML format
+// The hosts and the children are kept in the order of the inventory.
+type ansibleYAMLGroup struct {
+	Hosts    yaml.MapSlice          `yaml:"hosts"`
+	Vars     map[string]interface{} `yaml:"vars"`
+	Children yaml.MapSlice          `yaml:"children"`
+}
+
+// parseAnsibleYAML parses an inventory in the YAML format, whose top level are the groups, usually only all
+func parseAnsibleYAML(data []byte) (*ansibleInventory, error) {
+	var groups yaml.MapSlice
+	if err := yaml.Unmarshal(data, &groups); err != nil {
+		return nil, fmt.Errorf("failed to parse the inventory: %w", err)
+	}
+
+	inv := newAnsibleInventory()
+	for _, item := range groups {
+		if err := inv.addYAMLGroup(fmt.Sprint(item.Key), item.Value); err != nil {
+			return nil, err
+		}
+	}
+	return inv, nil
+}
+
+func (inv *ansibleInventory) addYAMLGroup(name string, value interface{}) error {
+	// the group is decoded again from its generic value
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return err
+	}
+	var group ansibleYAMLGroup
+	if err := yaml.Unmarshal(data, &group); err != nil {
+		return fmt.Errorf("invalid group %s: %w", name, err)
+	}
+
+	for key, value := range group.Vars {
+		inv.group(name).vars[key] = fmt.Sprint(value)
+	}
+	for _, item := range group.Hosts {
+		vars := make(map[string]string)
+		switch hostVars := item.Value.(type) {
+		case yaml.MapSlice:
+			for _, v := range hostVars {
+				vars[fmt.Sprint(v.Key)] = fmt.Sprint(v.Value)
+			}
+		case map[interface{}]interface{}:
+			for key, value := range hostVars {
+				vars[fmt.Sprint(key)] = fmt.Sprint(value)
+			}
+		}
+		inv.addHost(name, fmt.Sprint(item.Key), vars)
+	}
+	for _, item := range group.Children {
+		child := fmt.Sprint(item.Key)
+		inv.group(name).children = append(inv.group(name).children, child)
+		if err := inv.addYAMLGroup(child, item.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parents returns the groups that have the group as a child; every group but all is a child of all
+func (inv *ansibleInventory) parents(name string) []string {
+	var parents []string
+	for parent, group := range inv.groups {
+		if slices.Contains(group.children, name) {
+			parents = append(parents, parent)
+		}
+	}
+	if len(parents) == 0 && name != "all" {
+		parents = append(parents, "all")
+	}
+	slices.Sort(parents)
+	return parents
+}
+
+// groupsOf returns the groups of the host: the ones it is in and their ancestors
+func (inv *ansibleInventory) groupsOf(name string) []string {
+	var groups []string
+	var visit func(group string)
+	visit = func(group string) {
+		if slices.Contains(groups, group) {
+			return
+		}
+		groups = append(groups, group)
+		for _, parent := range inv.parents(group) {
+			visit(parent)
+		}
+	}
+	for group, g := range inv.groups {
+		if slices.Contains(g.hosts, name) {
+			visit(group)
+		}
+	}
+	if !slices.Contains(groups, "all") {
+		groups = append(groups, "all")
+	}
+	slices.Sort(groups)
+	return groups
+}
+
+// depth returns the distance of the group from all
+func (inv *ansibleInventory) depth(name string, seen []string) int {
+	if name == "all" || slices.Contains(seen, name) {
+		return 0
+	}
+	var depth int
+	for _, parent := range inv.parents(name) {
+		depth = max(depth, inv.depth(parent, append(seen, name))+1)
+	}
+	return depth
+}
+
+// hostVars returns the variables of the host, like Ansible merges them:
+// the variables of the groups, the ones of the child groups overriding the ones of their parents, then the ones of the host
+func (inv *ansibleInventory) hostVars(name string) map[string]string {
+	groups := inv.groupsOf(name)
+	slices.SortStableFunc(groups, func(a, b string) int {
+		return inv.depth(a, nil) - inv.depth(b, nil)
+	})
+
+	vars := make(map[string]string)
+	for _, group := range groups {
+		if g, ok := inv.groups[group]; ok {
+			for key, value := range g.vars {
+				vars[key] = value
+			}
+		}
+	}
+	for key, value := range inv.vars[name] {
+		vars[key] = value
+	}
+	return vars
+}
+
+// firstOf returns the value of the first of the variables that is set
+func firstOf(vars map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if value := vars[key]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// splitFields splits a host line into its fields, keeping the quoted values together
+func splitFields(line string) []string {
+	var fields []string
+	var field strings.Builder
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+		case quote == 0 && (r == ' ' || r == '\t'):
+			if field.Len() > 0 {
+				fields = append(fields, field.String())
+				field.Reset()
+			}
+		default:
+			field.WriteRune(r)
+		}
+	}
+	if field.Len() > 0 {
+		fields = append(fields, field.String())
+	}
+	return fields
+}
+
+// unquote removes the quotes around a value
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
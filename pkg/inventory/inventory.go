@@ -0,0 +1,168 @@
+// Package inventory imports the hosts of a blueprint from the inventories of provisioning tools
+// The hosts are read from the outputs of a Terraform state or from an Ansible inventory,
+// and get the role of the groups they belong to.
+package inventory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+// defaultPort is the SSH port of the hosts when neither the inventory nor hostsFrom.ssh set one
+const defaultPort = 22
+
+// host is a host of an inventory with the groups it belongs to
+type host struct {
+	name   string
+	groups []string
+	// ssh are the SSH settings set by the inventory, the empty ones are taken from hostsFrom.ssh
+	ssh types.SSHHost
+}
+
+// Import returns the hosts of the inventory of hostsFrom with the role of their groups
+// The path of the inventory is relative to dir, the directory of the blueprint. The hosts that are not
+// in any of the groups of the roles are left out, and the others are checked like the hosts of the blueprint.
+func Import(from *types.HostsFrom, dir string) ([]types.Host, error) {
+	var source string
+	var hosts []host
+	var err error
+	if from.Terraform != "" {
+		source = relativeTo(dir, from.Terraform)
+		hosts, err = readTerraform(source, from.Roles)
+	} else {
+		source = relativeTo(dir, from.Ansible)
+		hosts, err = readAnsible(source)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to import hosts from %s: %w", source, err)
+	}
+
+	var imported []types.Host
+	for _, h := range hosts {
+		role, err := roleOf(h.groups, from.Roles)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import host %s from %s: %w", h.name, source, err)
+		}
+		if role == "" {
+			continue
+		}
+
+		ssh := withDefaults(h.ssh, from.SSH)
+		host := types.Host{SSH: &ssh, Role: role}
+		if err := host.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid host %s imported from %s: %w", h.name, source, err)
+		}
+		imported = append(imported, host)
+	}
+
+	if len(imported) == 0 {
+		var groups []string
+		for group := range from.Roles {
+			groups = append(groups, group)
+		}
+		slices.Sort(groups)
+		return nil, fmt.Errorf("no hosts of groups %s in %s", strings.Join(groups, ", "), source)
+	}
+	return imported, nil
+}
+
+// Merge returns the hosts with the imported hosts that are not one of them
+// A host of the blueprint takes precedence over an imported host with the same address.
+func Merge(hosts []types.Host, imported []types.Host) []types.Host {
+	for _, host := range imported {
+		if slices.ContainsFunc(hosts, func(h types.Host) bool { return h.SSH != nil && h.SSH.Address == host.SSH.Address }) {
+			log.Debug().Msgf("Host %s is already in spec.kubernetes.infra.hosts, it is not imported again", host.SSH.Address)
+			continue
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
+// roleOf returns the role of a host from the roles of its groups, or an empty role if none of its groups has one
+// A host in a group of controllers and in a group of workers is both.
+func roleOf(groups []string, roles map[string]string) (string, error) {
+	var found []string
+	for _, group := range groups {
+		if role, ok := roles[group]; ok && !slices.Contains(found, role) {
+			found = append(found, role)
+		}
+	}
+
+	switch {
+	case len(found) == 0:
+		return "", nil
+	case len(found) == 1:
+		return found[0], nil
+	}
+	for _, role := range found {
+		if role != "controller" && role != "worker" && role != "controller+worker" {
+			return "", fmt.Errorf("the groups of the host have the conflicting roles %s", strings.Join(found, ", "))
+		}
+	}
+	return "controller+worker", nil
+}
+
+// withDefaults returns the SSH settings of a host, completed with the defaults
+func withDefaults(ssh types.SSHHost, defaults *types.SSHHost) types.SSHHost {
+	if defaults != nil {
+		if ssh.User == "" {
+			ssh.User = defaults.User
+		}
+		if ssh.KeyPath == "" {
+			ssh.KeyPath = defaults.KeyPath
+		}
+		if ssh.Port == 0 {
+			ssh.Port = defaults.Port
+		}
+		if ssh.Bastion == nil {
+			ssh.Bastion = defaults.Bastion
+		}
+	}
+	if ssh.Port == 0 {
+		ssh.Port = defaultPort
+	}
+	ssh.KeyPath = expandHome(ssh.KeyPath)
+	return ssh
+}
+
+// addHost adds the host to the hosts, or adds the group to the host if it is already one of them
+func addHost(hosts []host, h host, group string) []host {
+	for i := range hosts {
+		if hosts[i].ssh.Address == h.ssh.Address {
+			if !slices.Contains(hosts[i].groups, group) {
+				hosts[i].groups = append(hosts[i].groups, group)
+			}
+			return hosts
+		}
+	}
+	h.groups = []string{group}
+	return append(hosts, h)
+}
+
+// relativeTo returns the path joined to the directory, unless it is absolute
+func relativeTo(dir string, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// expandHome replaces a leading ~ with the home directory of the user, as the inventories usually set the keys
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
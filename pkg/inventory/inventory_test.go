@@ -0,0 +1,280 @@
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+const terraformOutputs = `{
+  "controllers": {"sensitive": false, "type": ["list", "string"], "value": ["10.0.0.1", "10.0.0.2"]},
+  "workers": {"sensitive": false, "value": {
+    "worker-1": {"address": "10.0.1.1", "user": "ec2-user", "port": 2222},
+    "worker-2": "10.0.1.2"
+  }},
+  "edge": {"value": "10.0.0.2"},
+  "vpc_id": {"value": "vpc-1234"}
+}`
+
+const terraformState = `{
+  "version": 4,
+  "terraform_version": "1.9.5",
+  "outputs": {
+    "controller": {"value": {"address": "10.0.0.1"}, "type": ["object", {"address": "string"}]}
+  },
+  "resources": []
+}`
+
+// terraformOtherOutputs has outputs that are not hosts, and outputs named like the fields of a state
+const terraformOtherOutputs = `{
+  "node_count": {"value": 3},
+  "public": {"value": true},
+  "version": {"value": ["10.0.0.1"]},
+  "outputs": {"value": {"worker-1": "10.0.1.1"}}
+}`
+
+const ansibleINI = `
+# the cluster
+bastion.example.com
+
+[controllers]
+cp-1 ansible_host=10.0.0.1
+cp-2 ansible_host=10.0.0.2 ansible_user="admin"
+
+[workers]
+node-1 ansible_host=10.0.1.1 ansible_port=2222
+node-2 ansible_host=10.0.1.2
+
+[gpu]
+node-2
+
+[workers:vars]
+ansible_user = ubuntu
+
+[k0s:children]
+controllers
+workers
+
+[k0s:vars]
+ansible_user=root
+ansible_ssh_private_key_file=KEY
+`
+
+const ansibleYAML = `
+all:
+  vars:
+    ansible_ssh_private_key_file: KEY
+  children:
+    k0s:
+      vars:
+        ansible_user: root
+      children:
+        controllers:
+          hosts:
+            cp-1:
+              ansible_host: 10.0.0.1
+        workers:
+          vars:
+            ansible_user: ubuntu
+          hosts:
+            node-1:
+              ansible_host: 10.0.1.1
+              ansible_port: 2222
+            node-2:
+`
+
+func writeFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// summary returns the address, role, user and port of the hosts
+func summary(hosts []types.Host) [][]interface{} {
+	var s [][]interface{}
+	for _, host := range hosts {
+		s = append(s, []interface{}{host.SSH.Address, host.Role, host.SSH.User, host.SSH.Port})
+	}
+	return s
+}
+
+// TestImport tests that the hosts of the groups of the roles are imported with their SSH settings
+func TestImport(t *testing.T) {
+	key := writeFile(t, "id_ed25519", "key")
+	defaults := &types.SSHHost{User: "core", KeyPath: key}
+
+	tests := map[string]struct {
+		file    string
+		content string
+		from    types.HostsFrom
+		want    [][]interface{}
+	}{
+		"terraform outputs": {
+			file:    "outputs.json",
+			content: terraformOutputs,
+			from:    types.HostsFrom{Roles: map[string]string{"controllers": "controller", "workers": "worker", "edge": "worker"}, SSH: defaults},
+			want: [][]interface{}{
+				{"10.0.0.1", "controller", "core", 22},
+				{"10.0.0.2", "controller+worker", "core", 22},
+				{"10.0.1.1", "worker", "ec2-user", 2222},
+				{"10.0.1.2", "worker", "core", 22},
+			},
+		},
+		"terraform state": {
+			file:    "terraform.tfstate",
+			content: terraformState,
+			from:    types.HostsFrom{Roles: map[string]string{"controller": "single"}, SSH: defaults},
+			want:    [][]interface{}{{"10.0.0.1", "single", "core", 22}},
+		},
+		"terraform other outputs": {
+			file:    "outputs.json",
+			content: terraformOtherOutputs,
+			from:    types.HostsFrom{Roles: map[string]string{"version": "controller", "outputs": "worker"}, SSH: defaults},
+			want: [][]interface{}{
+				{"10.0.1.1", "worker", "core", 22},
+				{"10.0.0.1", "controller", "core", 22},
+			},
+		},
+		"terraform state with other outputs": {
+			file:    "terraform.tfstate",
+			content: `{"version": 4, "outputs": {"node_count": {"value": 3}, "version": {"value": "10.0.0.1"}}}`,
+			from:    types.HostsFrom{Roles: map[string]string{"version": "single"}, SSH: defaults},
+			want:    [][]interface{}{{"10.0.0.1", "single", "core", 22}},
+		},
+		"ansible ini": {
+			file:    "hosts.ini",
+			content: ansibleINI,
+			from:    types.HostsFrom{Roles: map[string]string{"controllers": "controller", "workers": "worker"}},
+			want: [][]interface{}{
+				{"10.0.0.1", "controller", "root", 22},
+				{"10.0.0.2", "controller", "admin", 22},
+				{"10.0.1.1", "worker", "ubuntu", 2222},
+				{"10.0.1.2", "worker", "ubuntu", 22},
+			},
+		},
+		"ansible yaml": {
+			file:    "inventory.yaml",
+			content: ansibleYAML,
+			from:    types.HostsFrom{Roles: map[string]string{"controllers": "controller", "workers": "worker"}},
+			want: [][]interface{}{
+				{"10.0.0.1", "controller", "root", 22},
+				{"10.0.1.1", "worker", "ubuntu", 2222},
+				{"node-2", "worker", "ubuntu", 22},
+			},
+		},
+		"parent group": {
+			file:    "hosts.ini",
+			content: ansibleINI,
+			from:    types.HostsFrom{Roles: map[string]string{"k0s": "worker"}},
+			want: [][]interface{}{
+				{"10.0.0.1", "worker", "root", 22},
+				{"10.0.0.2", "worker", "admin", 22},
+				{"10.0.1.1", "worker", "ubuntu", 2222},
+				{"10.0.1.2", "worker", "ubuntu", 22},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			path := writeFile(t, tc.file, replaceKey(tc.content, key))
+			from := tc.from
+			if filepath.Ext(tc.file) == ".json" || filepath.Ext(tc.file) == ".tfstate" {
+				from.Terraform = filepath.Base(path)
+			} else {
+				from.Ansible = filepath.Base(path)
+			}
+
+			hosts, err := Import(&from, filepath.Dir(path))
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(summary(hosts)).To(Equal(tc.want))
+			for _, host := range hosts {
+				g.Expect(host.SSH.KeyPath).To(Equal(key))
+			}
+		})
+	}
+}
+
+// TestImportErrors tests that the imported hosts are validated like the hosts of the blueprint
+func TestImportErrors(t *testing.T) {
+	key := writeFile(t, "id_ed25519", "key")
+
+	tests := map[string]struct {
+		file    string
+		content string
+		roles   map[string]string
+		ssh     *types.SSHHost
+		wantErr string
+	}{
+		"no user": {
+			file: "outputs.json", content: terraformOutputs, roles: map[string]string{"controllers": "controller"},
+			ssh: &types.SSHHost{KeyPath: key}, wantErr: "invalid host 10.0.0.1 imported from",
+		},
+		"invalid address": {
+			file: "outputs.json", content: `{"workers": {"value": ["10.0.0.1/24"]}}`, roles: map[string]string{"workers": "worker"},
+			ssh: &types.SSHHost{KeyPath: key, User: "root"}, wantErr: "invalid hosts.ssh.address: 10.0.0.1/24",
+		},
+		"conflicting roles": {
+			file: "hosts.ini", content: ansibleINI, roles: map[string]string{"controllers": "single", "k0s": "worker"},
+			wantErr: "conflicting roles single, worker",
+		},
+		"no hosts": {
+			file: "outputs.json", content: terraformOutputs, roles: map[string]string{"masters": "controller"},
+			wantErr: "no hosts of groups masters in",
+		},
+		"host range": {
+			file: "hosts.ini", content: "[workers]\nnode-[1:3]\n", roles: map[string]string{"workers": "worker"},
+			wantErr: "host ranges such as node-[1:3] are not supported",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			path := writeFile(t, tc.file, replaceKey(tc.content, key))
+			from := types.HostsFrom{Roles: tc.roles, SSH: tc.ssh}
+			if filepath.Ext(tc.file) == ".json" {
+				from.Terraform = path
+			} else {
+				from.Ansible = path
+			}
+
+			_, err := Import(&from, "")
+			g.Expect(err).To(MatchError(ContainSubstring(tc.wantErr)))
+		})
+	}
+}
+
+// TestMerge tests that the imported hosts are added to the hosts of the blueprint once
+func TestMerge(t *testing.T) {
+	g := NewWithT(t)
+
+	hosts := []types.Host{
+		{Role: "controller", SSH: &types.SSHHost{Address: "10.0.0.1", User: "admin"}},
+		{Role: "worker", LocalHost: &types.LocalHost{Enabled: true}},
+	}
+	imported := []types.Host{
+		{Role: "worker", SSH: &types.SSHHost{Address: "10.0.0.1", User: "core"}},
+		{Role: "worker", SSH: &types.SSHHost{Address: "10.0.0.2", User: "core"}},
+	}
+
+	merged := Merge(hosts, imported)
+	g.Expect(merged).To(HaveLen(3))
+	g.Expect(merged[0].SSH.User).To(Equal("admin"))
+	g.Expect(merged[2].SSH.Address).To(Equal("10.0.0.2"))
+	g.Expect(Merge(merged, imported)).To(HaveLen(3))
+}
+
+func replaceKey(content string, key string) string {
+	return strings.ReplaceAll(content, "KEY", key)
+}
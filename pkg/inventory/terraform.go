@@ -0,0 +1,146 @@
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+// terraformOutput is an output of a Terraform state, or of terraform output -json
+type terraformOutput struct {
+	Value interface{} `json:"value"`
+}
+
+// readTerraform reads the hosts of the outputs of a Terraform state file, or of the JSON of terraform output -json
+// Each output is a group of hosts, named after the output. The value of an output is an address, a list of
+// addresses, a map of names to addresses, or the same with objects that have an address and optionally a user,
+// a port and a key_path. Only the outputs of the groups of the roles are read, so the other outputs can be
+// anything, e.g. a number of nodes.
+func readTerraform(path string, roles map[string]string) ([]host, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs, err := parseTerraformOutputs(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for name := range outputs {
+		if _, ok := roles[name]; ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	var hosts []host
+	for _, name := range names {
+		var output terraformOutput
+		if err := json.Unmarshal(outputs[name], &output); err != nil {
+			return nil, fmt.Errorf("invalid output %s: %w", name, err)
+		}
+		group, err := terraformHosts(output.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output %s: %w", name, err)
+		}
+		for _, h := range group {
+			hosts = addHost(hosts, h, name)
+		}
+	}
+	return hosts, nil
+}
+
+// parseTerraformOutputs returns the outputs of a Terraform state, or of terraform output -json, by name
+// A state is told apart by the number of the version of its format, where terraform output -json
+// only has objects, e.g. for an output named version.
+func parseTerraformOutputs(data []byte) (map[string]json.RawMessage, error) {
+	var outputs map[string]json.RawMessage
+	if err := json.Unmarshal(data, &outputs); err != nil {
+		return nil, fmt.Errorf("failed to parse the Terraform JSON: %w", err)
+	}
+
+	var version json.Number
+	if json.Unmarshal(outputs["version"], &version) != nil {
+		return outputs, nil
+	}
+
+	var state struct {
+		Outputs map[string]json.RawMessage `json:"outputs"`
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse the outputs of the Terraform state: %w", err)
+	}
+	return state.Outputs, nil
+}
+
+// terraformHosts returns the hosts of the value of an output
+func terraformHosts(value interface{}) ([]host, error) {
+	switch v := value.(type) {
+	case string:
+		return []host{{name: v, ssh: types.SSHHost{Address: v}}}, nil
+	case []interface{}:
+		var hosts []host
+		for _, item := range v {
+			h, err := terraformHost("", item)
+			if err != nil {
+				return nil, err
+			}
+			hosts = append(hosts, h)
+		}
+		return hosts, nil
+	case map[string]interface{}:
+		if _, ok := v["address"]; ok {
+			h, err := terraformHost("", v)
+			return []host{h}, err
+		}
+		var keys []string
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		var hosts []host
+		for _, key := range keys {
+			h, err := terraformHost(key, v[key])
+			if err != nil {
+				return nil, err
+			}
+			hosts = append(hosts, h)
+		}
+		return hosts, nil
+	default:
+		return nil, fmt.Errorf("unexpected value %v, expected addresses or objects with an address", value)
+	}
+}
+
+// terraformHost returns the host of an address or of an object with an address
+func terraformHost(name string, value interface{}) (host, error) {
+	switch v := value.(type) {
+	case string:
+		if name == "" {
+			name = v
+		}
+		return host{name: name, ssh: types.SSHHost{Address: v}}, nil
+	case map[string]interface{}:
+		address, ok := v["address"].(string)
+		if !ok {
+			return host{}, fmt.Errorf("host %v has no address", value)
+		}
+		if name == "" {
+			name = address
+		}
+		h := host{name: name, ssh: types.SSHHost{Address: address}}
+		h.ssh.User, _ = v["user"].(string)
+		h.ssh.KeyPath, _ = v["key_path"].(string)
+		if port, ok := v["port"].(float64); ok {
+			h.ssh.Port = int(port)
+		}
+		return h, nil
+	default:
+		return host{}, fmt.Errorf("unexpected host %v, expected an address or an object with an address", value)
+	}
+}
@@ -0,0 +1,275 @@
+// Package k0sconfig validates the k0s configuration of a blueprint offline
+// The configuration is checked against a schema of the k0s ClusterConfig, for the k0s version of the blueprint,
+// and with the checks k0s makes on the network, so that mistakes are reported before connecting to the hosts.
+// The schema can lag behind k0s, so the fields it does not have are only reported as warnings.
+package k0sconfig
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/k0sproject/dig"
+	"github.com/k0sproject/version"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
+)
+
+// Validate checks the k0s configuration of a blueprint for the k0s version, or for the latest k0s when it is empty
+// All the problems of the configuration are reported in the error. Fields that are not in the schema are not
+// problems, see UnknownFields.
+func Validate(config dig.Mapping, k0sVersion string) error {
+	v, err := check(config, k0sVersion)
+	if err != nil {
+		return err
+	}
+	if len(v.problems) > 0 {
+		return fmt.Errorf("invalid kubernetes.config: %s", strings.Join(v.problems, "; "))
+	}
+	return nil
+}
+
+// UnknownFields returns the fields of the k0s configuration that are not in the schema for the k0s version
+// k0s may still support them, e.g. when they were added after the schema, but they are likely typos.
+func UnknownFields(config dig.Mapping, k0sVersion string) []string {
+	v, err := check(config, k0sVersion)
+	if err != nil {
+		return nil
+	}
+	return v.unknown
+}
+
+// check checks the k0s configuration against the schema and the network checks of k0s
+func check(config dig.Mapping, k0sVersion string) (*validator, error) {
+	v := &validator{}
+	if k0sVersion != "" {
+		parsed, err := version.NewVersion(k0sVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid kubernetes.version: %s", k0sVersion)
+		}
+		v.version = parsed
+	}
+
+	v.validate("", map[string]interface{}(config), clusterConfig)
+	if len(v.problems) == 0 {
+		v.validateNetwork(config)
+	}
+	return v, nil
+}
+
+type validator struct {
+	// version is the k0s version of the blueprint, nil for the latest one
+	version  *version.Version
+	problems []string
+	// unknown are the fields that are not in the schema
+	unknown []string
+}
+
+func (v *validator) addProblem(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+// validate checks the value at the path against the schema of its field
+func (v *validator) validate(path string, value interface{}, f *field) {
+	if value == nil {
+		return
+	}
+
+	switch f.kind {
+	case kindAny:
+	case kindObject:
+		fields, ok := asMap(value)
+		if !ok {
+			v.addProblem("%s: expected an object, got %v", path, value)
+			return
+		}
+		for _, name := range sortedKeys(fields) {
+			fieldPath := join(path, name)
+			child, ok := f.fields[name]
+			if !ok {
+				v.unknown = append(v.unknown, fieldPath)
+				continue
+			}
+			if !v.supported(fieldPath, child) {
+				continue
+			}
+			v.validate(fieldPath, fields[name], child)
+		}
+	case kindList:
+		items, ok := value.([]interface{})
+		if !ok {
+			v.addProblem("%s: expected a list, got %v", path, value)
+			return
+		}
+		for i, item := range items {
+			v.validate(fmt.Sprintf("%s[%d]", path, i), item, f.items)
+		}
+	case kindMap:
+		values, ok := asMap(value)
+		if !ok {
+			v.addProblem("%s: expected a map of strings, got %v", path, value)
+			return
+		}
+		for _, key := range sortedKeys(values) {
+			if _, ok := values[key].(string); !ok {
+				v.addProblem("%s: expected a string, got %v", join(path, key), values[key])
+			}
+		}
+	case kindString:
+		s, ok := value.(string)
+		if !ok {
+			v.addProblem("%s: expected a string, got %v", path, value)
+			return
+		}
+		if len(f.values) > 0 && !slices.Contains(f.values, s) {
+			v.addProblem("%s: invalid value %q, expected one of %s", path, s, strings.Join(f.values, ", "))
+		}
+	case kindBool:
+		if _, ok := value.(bool); !ok {
+			v.addProblem("%s: expected true or false, got %v", path, value)
+		}
+	case kindInt, kindPort:
+		n, ok := asInt(value)
+		if !ok {
+			v.addProblem("%s: expected an integer, got %v", path, value)
+			return
+		}
+		if f.kind == kindPort && (n < 1 || n > 65535) {
+			v.addProblem("%s: invalid port %d", path, n)
+		}
+	case kindIP:
+		s, ok := value.(string)
+		if !ok || net.ParseIP(s) == nil {
+			v.addProblem("%s: invalid IP address %v", path, value)
+		}
+	case kindCIDR:
+		s, ok := value.(string)
+		if !ok {
+			v.addProblem("%s: invalid CIDR %v", path, value)
+			return
+		}
+		if _, _, err := net.ParseCIDR(s); err != nil {
+			v.addProblem("%s: invalid CIDR %s", path, s)
+		}
+	}
+}
+
+// supported checks that the field exists in the k0s version of the blueprint
+func (v *validator) supported(path string, f *field) bool {
+	if v.version == nil {
+		return f.until == ""
+	}
+	if f.since != "" && v.version.LessThan(version.MustParse(f.since)) {
+		v.addProblem("%s is only supported since k0s %s, not by k0s %s", path, f.since, v.version)
+		return false
+	}
+	if f.until != "" && !v.version.LessThan(version.MustParse(f.until)) {
+		v.addProblem("%s was removed in k0s %s and is not supported by k0s %s", path, f.until, v.version)
+		return false
+	}
+	return true
+}
+
+// validateNetwork checks the CIDRs of the network like k0s: the pods and the services must not share addresses
+func (v *validator) validateNetwork(config dig.Mapping) {
+	podCIDR := digStringOr(config, constants.DefaultPodCIDR, "spec", "network", "podCIDR")
+	serviceCIDR := digStringOr(config, constants.DefaultServiceCIDR, "spec", "network", "serviceCIDR")
+	v.checkOverlap("spec.network.podCIDR", podCIDR, "spec.network.serviceCIDR", serviceCIDR)
+
+	if enabled, _ := lookup(config, "spec", "network", "dualStack", "enabled").(bool); !enabled {
+		return
+	}
+	if provider := digStringOr(config, "kuberouter", "spec", "network", "provider"); provider == "custom" {
+		return
+	}
+	podCIDR6 := digStringOr(config, "", "spec", "network", "dualStack", "IPv6podCIDR")
+	serviceCIDR6 := digStringOr(config, "", "spec", "network", "dualStack", "IPv6serviceCIDR")
+	if podCIDR6 == "" || serviceCIDR6 == "" {
+		v.addProblem("spec.network.dualStack: IPv6podCIDR and IPv6serviceCIDR must be set when dual-stack is enabled")
+		return
+	}
+	for _, c := range []struct{ path, value string }{
+		{path: "spec.network.dualStack.IPv6podCIDR", value: podCIDR6},
+		{path: "spec.network.dualStack.IPv6serviceCIDR", value: serviceCIDR6},
+	} {
+		if ip, _, _ := net.ParseCIDR(c.value); ip.To4() != nil {
+			v.addProblem("%s: %s is not an IPv6 CIDR", c.path, c.value)
+		}
+	}
+	v.checkOverlap("spec.network.dualStack.IPv6podCIDR", podCIDR6, "spec.network.dualStack.IPv6serviceCIDR", serviceCIDR6)
+}
+
+func (v *validator) checkOverlap(pathA string, a string, pathB string, b string) {
+	_, netA, errA := net.ParseCIDR(a)
+	_, netB, errB := net.ParseCIDR(b)
+	if errA != nil || errB != nil {
+		return
+	}
+	if netA.Contains(netB.IP) || netB.Contains(netA.IP) {
+		v.addProblem("%s %s overlaps with %s %s", pathA, a, pathB, b)
+	}
+}
+
+// asMap returns the fields of an object, decoded from JSON or from YAML
+func asMap(value interface{}) (map[string]interface{}, bool) {
+	switch m := value.(type) {
+	case map[string]interface{}:
+		return m, true
+	case dig.Mapping:
+		return m, true
+	}
+	return nil, false
+}
+
+// asInt returns the value of an integer, decoded from JSON or from YAML
+func asInt(value interface{}) (int, bool) {
+	switch n := value.(type) {
+	case int:
+		return n, true
+	case int64:
+		return int(n), true
+	case float64:
+		if n == float64(int(n)) {
+			return int(n), true
+		}
+	}
+	return 0, false
+}
+
+// lookup returns the value at the keys of the configuration, or nil
+// Unlike dig.Mapping.Dig, it also goes through the objects decoded from JSON.
+func lookup(config dig.Mapping, keys ...string) interface{} {
+	var value interface{} = config
+	for _, key := range keys {
+		fields, ok := asMap(value)
+		if !ok {
+			return nil
+		}
+		value = fields[key]
+	}
+	return value
+}
+
+func digStringOr(config dig.Mapping, defaultValue string, keys ...string) string {
+	if s, ok := lookup(config, keys...).(string); ok && s != "" {
+		return s
+	}
+	return defaultValue
+}
+
+func join(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
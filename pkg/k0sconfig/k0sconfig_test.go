@@ -0,0 +1,149 @@
+package k0sconfig
+
+import (
+	"testing"
+
+	"github.com/k0sproject/dig"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+)
+
+// parse decodes the config like the blueprint, from YAML through JSON
+func parse(t *testing.T, config string) dig.Mapping {
+	var m dig.Mapping
+	if err := yaml.Unmarshal([]byte(config), &m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// TestValidate tests the validation of the k0s config of a blueprint
+func TestValidate(t *testing.T) {
+	tests := map[string]struct {
+		config  string
+		version string
+		wantErr string
+	}{
+		"valid config": {
+			config: `
+apiVersion: k0s.k0sproject.io/v1beta1
+kind: ClusterConfig
+metadata:
+  name: k0s
+dynamicConfig: true
+spec:
+  api:
+    address: 10.0.0.1
+    port: 6443
+    sans: [k0s.example.com]
+    extraArgs:
+      audit-log-maxage: "30"
+  network:
+    provider: calico
+    podCIDR: 10.10.0.0/16
+    serviceCIDR: 10.20.0.0/16
+    calico:
+      mode: vxlan
+      mtu: 1450
+    nodeLocalLoadBalancing:
+      enabled: true
+  extensions:
+    helm:
+      charts:
+        - name: prometheus
+          chartname: prometheus-community/prometheus
+          order: 1
+          timeout: 10m
+  telemetry:
+    enabled: false
+`,
+			version: "1.31.1+k0s.0",
+		},
+		"no version": {
+			config: "spec: {network: {controlPlaneLoadBalancing: {enabled: true}}}",
+		},
+		"unknown field": {
+			config: "spec: {network: {provder: calico}}",
+		},
+		"invalid provider": {
+			config:  "spec: {network: {provider: flannel}}",
+			wantErr: `spec.network.provider: invalid value "flannel", expected one of kuberouter, calico, custom`,
+		},
+		"invalid CIDR": {
+			config:  "spec: {network: {podCIDR: 10.10.0.0/33}}",
+			wantErr: "spec.network.podCIDR: invalid CIDR 10.10.0.0/33",
+		},
+		"overlapping CIDRs": {
+			config:  "spec: {network: {podCIDR: 10.0.0.0/8, serviceCIDR: 10.96.0.0/12}}",
+			wantErr: "spec.network.podCIDR 10.0.0.0/8 overlaps with spec.network.serviceCIDR 10.96.0.0/12",
+		},
+		"overlapping default CIDR": {
+			config:  "spec: {network: {serviceCIDR: 10.244.128.0/24}}",
+			wantErr: "spec.network.podCIDR 10.244.0.0/16 overlaps with spec.network.serviceCIDR 10.244.128.0/24",
+		},
+		"dual-stack without IPv6 CIDRs": {
+			config:  "spec: {network: {dualStack: {enabled: true}}}",
+			wantErr: "IPv6podCIDR and IPv6serviceCIDR must be set when dual-stack is enabled",
+		},
+		"dual-stack with an IPv4 CIDR": {
+			config:  "spec: {network: {dualStack: {enabled: true, IPv6podCIDR: 'fd00::/108', IPv6serviceCIDR: 10.30.0.0/16}}}",
+			wantErr: "spec.network.dualStack.IPv6serviceCIDR: 10.30.0.0/16 is not an IPv6 CIDR",
+		},
+		"non-bool dynamicConfig": {
+			config:  "dynamicConfig: 'yes'",
+			wantErr: "dynamicConfig: expected true or false, got yes",
+		},
+		"invalid port": {
+			config:  "spec: {api: {port: 70000}}",
+			wantErr: "spec.api.port: invalid port 70000",
+		},
+		"non-string extra arg": {
+			config:  "spec: {scheduler: {extraArgs: {v: 4}}}",
+			wantErr: "spec.scheduler.extraArgs.v: expected a string, got 4",
+		},
+		"invalid list item": {
+			config:  "spec: {featureGates: [{name: Foo, enabled: 'true'}]}",
+			wantErr: "spec.featureGates[0].enabled: expected true or false, got true",
+		},
+		"all problems": {
+			config:  "spec: {network: {provider: flannel, podCIDR: nope}}",
+			wantErr: `invalid kubernetes.config: spec.network.podCIDR: invalid CIDR nope; spec.network.provider: invalid value "flannel"`,
+		},
+		"field of a newer k0s": {
+			config:  "spec: {network: {controlPlaneLoadBalancing: {enabled: true}}}",
+			version: "1.29.4+k0s.0",
+			wantErr: "spec.network.controlPlaneLoadBalancing is only supported since k0s 1.30.0, not by k0s v1.29.4+k0s.0",
+		},
+		"field of the first k0s that has it": {
+			config:  "spec: {network: {controlPlaneLoadBalancing: {enabled: true}}}",
+			version: "1.30.0+k0s.0",
+		},
+		"field removed from k0s": {
+			config:  "spec: {podSecurityPolicy: {defaultPolicy: 00-k0s-privileged}}",
+			version: "1.27.2+k0s.0",
+			wantErr: "spec.podSecurityPolicy was removed in k0s 1.25.0 and is not supported by k0s v1.27.2+k0s.0",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := Validate(parse(t, tc.config), tc.version)
+			if tc.wantErr == "" {
+				g.Expect(err).ToNot(HaveOccurred())
+			} else {
+				g.Expect(err).To(MatchError(ContainSubstring(tc.wantErr)))
+			}
+		})
+	}
+}
+
+// TestUnknownFields tests that the fields that are not in the schema are reported
+func TestUnknownFields(t *testing.T) {
+	g := NewWithT(t)
+
+	config := parse(t, "spec: {network: {provder: calico, podCIDR: 10.10.0.0/16, kuberouter: {autoMTU: true, mtuu: 1450}}, newFeature: {}}")
+	g.Expect(UnknownFields(config, "1.31.1+k0s.0")).To(Equal([]string{"spec.network.kuberouter.mtuu", "spec.network.provder", "spec.newFeature"}))
+	g.Expect(UnknownFields(parse(t, "spec: {network: {provider: calico}}"), "")).To(BeEmpty())
+}
@@ -0,0 +1,244 @@
+package k0sconfig
+
+// kind is the type of the value of a field
+type kind int
+
+const (
+	kindAny kind = iota
+	kindObject
+	kindList
+	kindMap
+	kindString
+	kindBool
+	kindInt
+	kindPort
+	kindIP
+	kindCIDR
+)
+
+// field is the schema of a field of the k0s configuration
+type field struct {
+	kind kind
+	// fields are the fields of an object
+	fields map[string]*field
+	// items is the schema of the items of a list
+	items *field
+	// values are the allowed values of a string, any string is allowed when empty
+	values []string
+	// since is the k0s version that added the field
+	since string
+	// until is the k0s version that removed the field
+	until string
+}
+
+func object(fields map[string]*field) *field { return &field{kind: kindObject, fields: fields} }
+func list(items *field) *field               { return &field{kind: kindList, items: items} }
+func stringMap() *field                      { return &field{kind: kindMap} }
+func anything() *field                       { return &field{kind: kindAny} }
+func str() *field                            { return &field{kind: kindString} }
+func enum(values ...string) *field           { return &field{kind: kindString, values: values} }
+func boolean() *field                        { return &field{kind: kindBool} }
+func integer() *field                        { return &field{kind: kindInt} }
+func port() *field                           { return &field{kind: kindPort} }
+func ip() *field                             { return &field{kind: kindIP} }
+func cidr() *field                           { return &field{kind: kindCIDR} }
+
+// withSince returns the field, added to k0s in the version
+func (f *field) withSince(version string) *field {
+	f.since = version
+	return f
+}
+
+// withUntil returns the field, removed from k0s in the version
+func (f *field) withUntil(version string) *field {
+	f.until = version
+	return f
+}
+
+// image is the schema of the image of a component
+func image() *field {
+	return object(map[string]*field{
+		"image":   str(),
+		"version": str(),
+	})
+}
+
+func extraArgs() map[string]*field {
+	return map[string]*field{"extraArgs": stringMap()}
+}
+
+// clusterConfig is the schema of the k0s ClusterConfig, k0s.k0sproject.io/v1beta1
+// dynamicConfig is not a field of the ClusterConfig: it is read by bctl and passed to k0sctl.
+var clusterConfig = object(map[string]*field{
+	"apiVersion":    enum("k0s.k0sproject.io/v1beta1"),
+	"kind":          enum("ClusterConfig"),
+	"metadata":      anything(),
+	"dynamicConfig": boolean(),
+	"spec": object(map[string]*field{
+		"api": object(map[string]*field{
+			"address":                ip(),
+			"externalAddress":        str(),
+			"sans":                   list(str()),
+			"port":                   port(),
+			"k0sApiPort":             port(),
+			"extraArgs":              stringMap(),
+			"tunneledNetworkingMode": boolean(),
+			"onlyBindToAddress":      boolean(),
+		}),
+		"storage": object(map[string]*field{
+			"type": enum("etcd", "kine"),
+			"etcd": object(map[string]*field{
+				"peerAddress": ip(),
+				"extraArgs":   stringMap(),
+				"externalCluster": object(map[string]*field{
+					"endpoints":      list(str()),
+					"etcdPrefix":     str(),
+					"caFile":         str(),
+					"clientCertFile": str(),
+					"clientKeyFile":  str(),
+				}),
+			}),
+			"kine": object(map[string]*field{
+				"dataSource": str(),
+			}),
+		}),
+		"network": object(map[string]*field{
+			"provider":      enum("kuberouter", "calico", "custom"),
+			"podCIDR":       cidr(),
+			"serviceCIDR":   cidr(),
+			"clusterDomain": str(),
+			"dualStack": object(map[string]*field{
+				"enabled":         boolean(),
+				"IPv6podCIDR":     cidr(),
+				"IPv6serviceCIDR": cidr(),
+			}),
+			"calico": object(map[string]*field{
+				"mode":                    enum("vxlan", "ipip", "bird"),
+				"overlay":                 enum("Always", "Never", "CrossSubnet"),
+				"vxlanPort":               port(),
+				"vxlanVNI":                integer(),
+				"mtu":                     integer(),
+				"wireguard":               boolean(),
+				"flexVolumeDriverPath":    str(),
+				"ipAutodetectionMethod":   str(),
+				"ipV6AutodetectionMethod": str(),
+				"envVars":                 stringMap(),
+			}),
+			"kuberouter": object(map[string]*field{
+				"autoMTU":        boolean(),
+				"mtu":            integer(),
+				"metricsPort":    port(),
+				"hairpin":        enum("Enabled", "Allowed", "Disabled"),
+				"hairpinMode":    boolean(),
+				"ipMasq":         boolean(),
+				"peerRouterIPs":  str(),
+				"peerRouterASNs": str(),
+				"extraArgs":      stringMap(),
+			}),
+			"kubeProxy": object(map[string]*field{
+				"disabled":           boolean(),
+				"mode":               enum("iptables", "ipvs", "nftables"),
+				"iptables":           anything(),
+				"ipvs":               anything(),
+				"nftables":           anything(),
+				"nodePortAddresses":  str(),
+				"metricsBindAddress": str(),
+				"extraArgs":          stringMap(),
+			}),
+			"nodeLocalLoadBalancing": object(map[string]*field{
+				"enabled": boolean(),
+				"type":    enum("EnvoyProxy"),
+				"envoyProxy": object(map[string]*field{
+					"image":                      image(),
+					"imagePullPolicy":            enum("Always", "IfNotPresent", "Never"),
+					"apiServerBindPort":          port(),
+					"konnectivityServerBindPort": port(),
+				}),
+			}).withSince("1.26.0"),
+			"controlPlaneLoadBalancing": object(map[string]*field{
+				"enabled":    boolean(),
+				"type":       enum("Keepalived"),
+				"keepalived": anything(),
+			}).withSince("1.30.0"),
+		}),
+		"controllerManager": object(extraArgs()),
+		"scheduler":         object(extraArgs()),
+		"installConfig": object(map[string]*field{
+			"users": object(map[string]*field{
+				"etcdUser":          str(),
+				"kineUser":          str(),
+				"konnectivityUser":  str(),
+				"kubeAPIserverUser": str(),
+				"kubeSchedulerUser": str(),
+			}),
+		}),
+		"images": object(map[string]*field{
+			"konnectivity":  image(),
+			"metricsserver": image(),
+			"kubeproxy":     image(),
+			"coredns":       image(),
+			"pause":         image(),
+			"pushgateway":   image(),
+			"envoyproxy":    image(),
+			"calico": object(map[string]*field{
+				"cni":             image(),
+				"node":            image(),
+				"kubecontrollers": image(),
+			}),
+			"kuberouter": object(map[string]*field{
+				"cni":          image(),
+				"cniInstaller": image(),
+			}),
+			"repository":          str(),
+			"default_pull_policy": enum("Always", "IfNotPresent", "Never"),
+		}),
+		"extensions": object(map[string]*field{
+			"helm": object(map[string]*field{
+				"concurrencyLevel": integer(),
+				"repositories": list(object(map[string]*field{
+					"name":     str(),
+					"url":      str(),
+					"caFile":   str(),
+					"certFile": str(),
+					"keyfile":  str(),
+					"username": str(),
+					"password": str(),
+					"insecure": boolean(),
+				})),
+				"charts": list(object(map[string]*field{
+					"name":         str(),
+					"chartname":    str(),
+					"version":      str(),
+					"values":       str(),
+					"namespace":    str(),
+					"timeout":      anything(),
+					"order":        integer(),
+					"forceUpgrade": boolean(),
+				})),
+			}),
+			"storage": object(map[string]*field{
+				"type":                         enum("external_storage", "openebs_local_storage"),
+				"create_default_storage_class": boolean(),
+			}),
+		}),
+		"konnectivity": object(map[string]*field{
+			"agentPort": port(),
+			"adminPort": port(),
+		}),
+		"telemetry": object(map[string]*field{
+			"enabled": boolean(),
+		}),
+		"workerProfiles": list(object(map[string]*field{
+			"name":   str(),
+			"values": anything(),
+		})),
+		"featureGates": list(object(map[string]*field{
+			"name":       str(),
+			"enabled":    boolean(),
+			"components": list(str()),
+		})),
+		"podSecurityPolicy": object(map[string]*field{
+			"defaultPolicy": str(),
+		}).withUntil("1.25.0"),
+	}),
+})
@@ -3,10 +3,26 @@ package k8s
 import (
 	"fmt"
 
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+
+	"github.com/mirantiscontainers/blueprint-cli/boundlessclientset"
 )
 
+// Clients are the clients used to apply manifests to a cluster
+type Clients struct {
+	Client  kubernetes.Interface
+	Dynamic dynamic.Interface
+	// Discovery caches the API resources of the cluster on disk, like kubectl does in ~/.kube/cache
+	Discovery discovery.CachedDiscoveryInterface
+	// Mapper maps kinds to resources using the cached discovery; resetting it invalidates the cache
+	Mapper meta.ResettableRESTMapper
+}
+
 // GetClient returns a kubernetes client
 func GetClient(config *KubeConfig) (*kubernetes.Clientset, error) {
 	cfg, err := config.RESTConfig()
@@ -30,3 +46,42 @@ func GetDynamicClient(config *KubeConfig) (*dynamic.DynamicClient, error) {
 
 	return dynamicClient, err
 }
+
+// GetBoundlessClient returns a client for the Blueprint Operator API
+func GetBoundlessClient(config *KubeConfig) (*boundlessclientset.BoundlessV1Alpha1Client, error) {
+	cfg, err := config.RESTConfig()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get REST config for blueprint client: %v", err)
+	}
+	cfg.WarningHandler = rest.NoWarnings{}
+
+	return boundlessclientset.NewForConfig(cfg)
+}
+
+// GetClients returns the typed and dynamic clients together with a RESTMapper backed by the disk discovery cache
+func GetClients(config *KubeConfig) (*Clients, error) {
+	client, err := GetClient(config)
+	if err != nil {
+		return nil, err
+	}
+	dynamicClient, err := GetDynamicClient(config)
+	if err != nil {
+		return nil, err
+	}
+	discoveryClient, err := config.DiscoveryClient()
+	if err != nil {
+		return nil, fmt.Errorf("unable to instantiate discovery client: %v", err)
+	}
+
+	return NewClients(client, dynamicClient, discoveryClient), nil
+}
+
+// NewClients returns the clients with a deferred RESTMapper on top of the discovery client
+func NewClients(client kubernetes.Interface, dynamicClient dynamic.Interface, discoveryClient discovery.CachedDiscoveryInterface) *Clients {
+	return &Clients{
+		Client:    client,
+		Dynamic:   dynamicClient,
+		Discovery: discoveryClient,
+		Mapper:    restmapper.NewDeferredDiscoveryRESTMapper(discoveryClient),
+	}
+}
@@ -1,10 +1,14 @@
 package k8s
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"sync"
 
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery"
 	restclient "k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
@@ -15,6 +19,9 @@ const (
 	UsePersistentConfig = true
 )
 
+// kubeConfigLock serializes the changes to the kubeconfig files, e.g. of the clusters of a fleet installed at the same time
+var kubeConfigLock sync.Mutex
+
 // KubeConfig tracks a kubernetes configuration.
 type KubeConfig struct {
 	flags *genericclioptions.ConfigFlags
@@ -44,36 +51,185 @@ func (c *KubeConfig) RESTConfig() (*restclient.Config, error) {
 	return c.clientConfig().ClientConfig()
 }
 
+// DiscoveryClient returns a discovery client that caches the API resources of the cluster in the kubectl cache directory
+func (c *KubeConfig) DiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	return c.flags.ToDiscoveryClient()
+}
+
 func (c *KubeConfig) clientConfig() clientcmd.ClientConfig {
 	return c.flags.ToRawKubeConfigLoader()
 }
 
 // MergeConfig merges a new config into the existing config and writes it back to disk.
+// The current context is switched to the one of the new config, and the previous one is recorded in it,
+// so that it can be restored when the context is deleted.
 func (c *KubeConfig) MergeConfig(newConfig clientcmdapi.Config) error {
+	kubeConfigLock.Lock()
+	defer kubeConfigLock.Unlock()
+
 	// if config file doesn't exist, just write the new config
 	if _, err := os.Stat(c.GetConfigPath()); err != nil {
 		return clientcmd.ModifyConfig(c.ConfigAccess(), newConfig, true)
 	}
 
-	existingConfig, err := c.clientConfig().RawConfig()
+	existingConfig, err := c.startingConfig()
 	if err != nil {
 		return err
 	}
+	previous := existingConfig.CurrentContext
 	merge(&existingConfig, &newConfig)
 	existingConfig.CurrentContext = newConfig.CurrentContext
+	if err = recordPreviousContext(&existingConfig, previous); err != nil {
+		return err
+	}
 	return clientcmd.ModifyConfig(c.ConfigAccess(), existingConfig, true)
 }
 
-// DelContext remove a given context from the configuration.
-func (c *KubeConfig) DelContext(n string) error {
-	cfg, err := c.clientConfig().RawConfig()
+// RawConfig returns the kubeconfig as loaded from the kubeconfig files, without the overrides of the flags
+func (c *KubeConfig) RawConfig() (clientcmdapi.Config, error) {
+	return c.clientConfig().RawConfig()
+}
+
+// ContextRemoval reports the changes made to the kubeconfig when a context is deleted
+type ContextRemoval struct {
+	// Context is the deleted context, or empty if it was not found
+	Context string
+	// Cluster is the deleted cluster, or empty if it is still used by other contexts
+	Cluster string
+	// User is the deleted user, or empty if it is still used by other contexts
+	User string
+	// CurrentContextChanged is true if the deleted context was the current context
+	CurrentContextChanged bool
+	// CurrentContext is the new current context, which is empty if no valid previous context was found
+	CurrentContext string
+}
+
+// DelContext removes a given context from the configuration, along with its cluster and user unless other contexts use them.
+// If it is the current context, the context that was current before it was merged is restored if it is still valid,
+// otherwise the current context is cleared.
+func (c *KubeConfig) DelContext(n string) (*ContextRemoval, error) {
+	kubeConfigLock.Lock()
+	defer kubeConfigLock.Unlock()
+
+	removal := &ContextRemoval{}
+	if _, err := os.Stat(c.GetConfigPath()); err != nil {
+		return removal, nil
+	}
+
+	cfg, err := c.startingConfig()
 	if err != nil {
-		return err
+		return nil, err
 	}
+	context, ok := cfg.Contexts[n]
+	if !ok {
+		return removal, nil
+	}
+
 	delete(cfg.Contexts, n)
+	removal.Context = n
+	if !clusterInUse(&cfg, context.Cluster) {
+		delete(cfg.Clusters, context.Cluster)
+		removal.Cluster = context.Cluster
+	}
+	if !userInUse(&cfg, context.AuthInfo) {
+		delete(cfg.AuthInfos, context.AuthInfo)
+		removal.User = context.AuthInfo
+	}
+
+	if cfg.CurrentContext == n {
+		cfg.CurrentContext = ""
+		if previous := previousContext(context); isValidContext(&cfg, previous) {
+			cfg.CurrentContext = previous
+		}
+		removal.CurrentContextChanged = true
+		removal.CurrentContext = cfg.CurrentContext
+	}
 
 	acc := c.ConfigAccess()
-	return clientcmd.ModifyConfig(acc, cfg, true)
+	if err = clientcmd.ModifyConfig(acc, cfg, true); err != nil {
+		return nil, err
+	}
+	return removal, nil
+}
+
+// startingConfig reads the kubeconfig files again, as the persistent client config keeps them as first loaded,
+// which misses the changes made since then
+func (c *KubeConfig) startingConfig() (clientcmdapi.Config, error) {
+	cfg, err := c.ConfigAccess().GetStartingConfig()
+	if err != nil {
+		return clientcmdapi.Config{}, err
+	}
+	return *cfg, nil
+}
+
+// previousContextExtension is the name of the context extension that records the context that was current before it
+const previousContextExtension = "blueprint-cli"
+
+type previousContextRecord struct {
+	PreviousContext string `json:"previousContext"`
+}
+
+// recordPreviousContext records the previous current context in the current context of the config
+func recordPreviousContext(cfg *clientcmdapi.Config, previous string) error {
+	context, ok := cfg.Contexts[cfg.CurrentContext]
+	if !ok || previous == "" || previous == cfg.CurrentContext {
+		return nil
+	}
+
+	raw, err := json.Marshal(previousContextRecord{PreviousContext: previous})
+	if err != nil {
+		return fmt.Errorf("failed to record previous context: %w", err)
+	}
+	if context.Extensions == nil {
+		context.Extensions = map[string]runtime.Object{}
+	}
+	context.Extensions[previousContextExtension] = &runtime.Unknown{Raw: raw, ContentType: runtime.ContentTypeJSON}
+	return nil
+}
+
+// previousContext returns the context that was current before the given context was merged
+func previousContext(context *clientcmdapi.Context) string {
+	ext, ok := context.Extensions[previousContextExtension].(*runtime.Unknown)
+	if !ok {
+		return ""
+	}
+
+	var record previousContextRecord
+	if err := json.Unmarshal(ext.Raw, &record); err != nil {
+		return ""
+	}
+	return record.PreviousContext
+}
+
+// isValidContext returns true if the context exists and its cluster and user exist
+func isValidContext(cfg *clientcmdapi.Config, name string) bool {
+	context, ok := cfg.Contexts[name]
+	if !ok {
+		return false
+	}
+	if _, ok = cfg.Clusters[context.Cluster]; !ok {
+		return false
+	}
+	_, ok = cfg.AuthInfos[context.AuthInfo]
+	return ok
+}
+
+func clusterInUse(cfg *clientcmdapi.Config, cluster string) bool {
+	for _, context := range cfg.Contexts {
+		if context.Cluster == cluster {
+			return true
+		}
+	}
+	return false
+}
+
+func userInUse(cfg *clientcmdapi.Config, user string) bool {
+	for _, context := range cfg.Contexts {
+		if context.AuthInfo == user {
+			return true
+		}
+	}
+	return false
 }
 
 // merge kind config into an existing config
@@ -94,6 +250,25 @@ func merge(existing, new *clientcmdapi.Config) {
 	}
 }
 
+// MinifyConfig returns a copy of the config with only the given context and the cluster and user it references
+// Certificates and keys referenced by path are embedded, so that the config can be used on another machine
+// If the context is empty, the current context of the config is used
+func MinifyConfig(config clientcmdapi.Config, context string) (*clientcmdapi.Config, error) {
+	minified := config.DeepCopy()
+	if context != "" {
+		minified.CurrentContext = context
+	}
+
+	if err := clientcmdapi.MinifyConfig(minified); err != nil {
+		return nil, fmt.Errorf("failed to minify kubeconfig: %w", err)
+	}
+	if err := clientcmdapi.FlattenConfig(minified); err != nil {
+		return nil, fmt.Errorf("failed to flatten kubeconfig: %w", err)
+	}
+
+	return minified, nil
+}
+
 // CurrentContextName returns the currently active config context.
 func (c *KubeConfig) CurrentContextName() (string, error) {
 	if isSet(c.flags.Context) {
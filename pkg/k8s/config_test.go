@@ -0,0 +1,129 @@
+package k8s
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// TestMinifyConfig tests that only the selected context is kept in the minified config
+func TestMinifyConfig(t *testing.T) {
+	g := NewWithT(t)
+
+	config := clientcmdapi.NewConfig()
+	for _, name := range []string{"one", "two"} {
+		config.Clusters[name] = &clientcmdapi.Cluster{Server: "https://" + name}
+		config.AuthInfos[name] = &clientcmdapi.AuthInfo{Token: name}
+		config.Contexts[name] = &clientcmdapi.Context{Cluster: name, AuthInfo: name}
+	}
+	config.CurrentContext = "one"
+
+	minified, err := MinifyConfig(*config, "two")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(minified.CurrentContext).To(Equal("two"))
+	g.Expect(minified.Clusters).To(HaveLen(1))
+	g.Expect(minified.Clusters).To(HaveKey("two"))
+	g.Expect(minified.AuthInfos).To(HaveKey("two"))
+	g.Expect(config.Contexts).To(HaveLen(2))
+
+	_, err = MinifyConfig(*config, "missing")
+	g.Expect(err).To(HaveOccurred())
+}
+
+// TestDelContext tests that the entries merged for a cluster are removed and the previous context is restored
+func TestDelContext(t *testing.T) {
+	g := NewWithT(t)
+
+	path := filepath.Join(t.TempDir(), "config")
+	existing := testConfig("previous")
+	g.Expect(clientcmd.WriteToFile(*existing, path)).To(Succeed())
+
+	flags := genericclioptions.NewConfigFlags(false)
+	flags.KubeConfig = &path
+	kubeConfig := NewConfig(flags)
+
+	g.Expect(kubeConfig.MergeConfig(*testConfig("blueprint"))).To(Succeed())
+	merged, err := clientcmd.LoadFromFile(path)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(merged.CurrentContext).To(Equal("blueprint"))
+
+	removal, err := kubeConfig.DelContext("blueprint")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(*removal).To(Equal(ContextRemoval{
+		Context:               "blueprint",
+		Cluster:               "blueprint",
+		User:                  "blueprint",
+		CurrentContextChanged: true,
+		CurrentContext:        "previous",
+	}))
+
+	cleaned, err := clientcmd.LoadFromFile(path)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cleaned.CurrentContext).To(Equal("previous"))
+	g.Expect(cleaned.Contexts).To(HaveLen(1))
+	g.Expect(cleaned.Clusters).To(HaveLen(1))
+	g.Expect(cleaned.AuthInfos).To(HaveLen(1))
+
+	// the current context is cleared when there is no valid previous context
+	removal, err = kubeConfig.DelContext("previous")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(removal.CurrentContextChanged).To(BeTrue())
+	g.Expect(removal.CurrentContext).To(BeEmpty())
+
+	removal, err = kubeConfig.DelContext("missing")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(removal.Context).To(BeEmpty())
+}
+
+// TestMergeConfigConcurrently tests that the configs merged at the same time into a kubeconfig are all kept,
+// even by clients that loaded the kubeconfig before the other merges
+func TestMergeConfigConcurrently(t *testing.T) {
+	g := NewWithT(t)
+
+	path := filepath.Join(t.TempDir(), "config")
+	g.Expect(clientcmd.WriteToFile(*testConfig("previous"), path)).To(Succeed())
+
+	var kubeConfigs []*KubeConfig
+	for i := 0; i < 5; i++ {
+		flags := genericclioptions.NewConfigFlags(UsePersistentConfig)
+		flags.KubeConfig = &path
+		kubeConfig := NewConfig(flags)
+		_, err := kubeConfig.RawConfig()
+		g.Expect(err).ToNot(HaveOccurred())
+		kubeConfigs = append(kubeConfigs, kubeConfig)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(kubeConfigs))
+	for i, kubeConfig := range kubeConfigs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = kubeConfig.MergeConfig(*testConfig(fmt.Sprintf("cluster-%d", i)))
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+	merged, err := clientcmd.LoadFromFile(path)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(merged.Contexts).To(HaveLen(len(kubeConfigs) + 1))
+	g.Expect(merged.Clusters).To(HaveLen(len(kubeConfigs) + 1))
+}
+
+func testConfig(name string) *clientcmdapi.Config {
+	config := clientcmdapi.NewConfig()
+	config.Clusters[name] = &clientcmdapi.Cluster{Server: "https://" + name}
+	config.AuthInfos[name] = &clientcmdapi.AuthInfo{Token: name}
+	config.Contexts[name] = &clientcmdapi.Context{Cluster: name, AuthInfo: name}
+	config.CurrentContext = name
+	return config
+}
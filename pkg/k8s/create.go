@@ -3,52 +3,35 @@ package k8s
 import (
 	"context"
 	"fmt"
-	"k8s.io/client-go/rest"
 
 	operatorv1alpha1 "github.com/mirantiscontainers/blueprint-operator/api/v1alpha1"
-	"k8s.io/apimachinery/pkg/runtime"
-	"sigs.k8s.io/controller-runtime/pkg/client"
-)
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
-// CreateOrUpdate creates or updates a kubernetes object
-// Note: This only really works for Blueprint objects right now.
-func CreateOrUpdate(config *KubeConfig, obj client.Object) error {
-	// TODO (ranyodh): This is currently using in-cluster client. We should switch to:
-	// - either a dynamic client,
-	// - or generate a client in the `blueprint-operator` to be used here
-	scheme := runtime.NewScheme()
-	_ = operatorv1alpha1.AddToScheme(scheme)
+	"github.com/mirantiscontainers/blueprint-cli/boundlessclientset"
+)
 
-	restConfig, err := config.RESTConfig()
-	if err != nil {
-		return err
-	}
-	restConfig.WarningHandler = rest.NoWarnings{}
-	kubeClient, err := client.New(restConfig, client.Options{Scheme: scheme})
-	if err != nil {
-		return fmt.Errorf("failed to create kubernetes client: %v", err)
-	}
+// CreateOrUpdateBlueprint creates the Blueprint object or updates the existing one
+func CreateOrUpdateBlueprint(client boundlessclientset.BoundlessV1Alpha1Interface, blueprint *operatorv1alpha1.Blueprint) error {
+	blueprints := client.Blueprints(blueprint.Namespace)
 
-	existing := &operatorv1alpha1.Blueprint{}
-	err = kubeClient.Get(context.Background(), client.ObjectKeyFromObject(obj), existing)
+	existing, err := blueprints.Get(context.Background(), blueprint.Name, metav1.GetOptions{})
 	if err != nil {
-		if client.IgnoreNotFound(err) != nil {
-			return fmt.Errorf("failed to get existing blueprints: %v", err)
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to get existing blueprints: %w", err)
 		}
-	}
-	if existing.Name != "" {
-		obj.SetResourceVersion(existing.GetResourceVersion())
-		// @TODO add support for .Patch() or merge exisiting and obj.
-		obj.SetFinalizers(existing.GetFinalizers())
-
-		err = kubeClient.Update(context.Background(), obj)
-		if err != nil {
-			return fmt.Errorf("failed to update cluster object: %v", err)
-		}
-	} else {
-		if err := kubeClient.Create(context.Background(), obj); err != nil {
-			return fmt.Errorf("failed to create cluster object: %v", err)
+		if _, err := blueprints.Create(context.Background(), blueprint, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create cluster object: %w", err)
 		}
+		return nil
+	}
+
+	blueprint.SetResourceVersion(existing.GetResourceVersion())
+	// @TODO add support for .Patch() or merge exisiting and obj.
+	blueprint.SetFinalizers(existing.GetFinalizers())
+
+	if _, err = blueprints.Update(context.Background(), blueprint, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update cluster object: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,41 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	operatorv1alpha1 "github.com/mirantiscontainers/blueprint-operator/api/v1alpha1"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/mirantiscontainers/blueprint-cli/boundlessclientset/fake"
+)
+
+// TestCreateOrUpdateBlueprint tests that the Blueprint object is created and then updated in place
+func TestCreateOrUpdateBlueprint(t *testing.T) {
+	g := NewWithT(t)
+	client := fake.NewSimpleClientset()
+
+	blueprint := &operatorv1alpha1.Blueprint{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: metav1.NamespaceDefault}}
+	g.Expect(CreateOrUpdateBlueprint(client, blueprint)).To(Succeed())
+
+	existing, err := client.Blueprints(metav1.NamespaceDefault).Get(context.Background(), "test", metav1.GetOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	existing.SetFinalizers([]string{"blueprint.mirantis.com/finalizer"})
+	_, err = client.Blueprints(metav1.NamespaceDefault).Update(context.Background(), existing, metav1.UpdateOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	updated := &operatorv1alpha1.Blueprint{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: metav1.NamespaceDefault},
+		Spec:       operatorv1alpha1.BlueprintSpec{Components: operatorv1alpha1.Component{Addons: []operatorv1alpha1.AddonSpec{{Name: "metallb"}}}},
+	}
+	g.Expect(CreateOrUpdateBlueprint(client, updated)).To(Succeed())
+
+	existing, err = client.Blueprints(metav1.NamespaceDefault).Get(context.Background(), "test", metav1.GetOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(existing.Spec.Components.Addons).To(HaveLen(1))
+	g.Expect(existing.Finalizers).To(ConsistOf("blueprint.mirantis.com/finalizer"))
+
+	g.Expect(DeleteBlueprint(client, updated)).To(Succeed())
+	g.Expect(DeleteBlueprint(client, updated)).To(Succeed())
+}
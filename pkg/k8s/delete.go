@@ -3,43 +3,19 @@ package k8s
 import (
 	"context"
 	"fmt"
-	"k8s.io/client-go/rest"
 
 	operatorv1alpha1 "github.com/mirantiscontainers/blueprint-operator/api/v1alpha1"
-	"k8s.io/apimachinery/pkg/runtime"
-	"sigs.k8s.io/controller-runtime/pkg/client"
-)
-
-// Delete deletes a kubernetes object
-func Delete(config *KubeConfig, obj client.Object) error {
-	scheme := runtime.NewScheme()
-	_ = operatorv1alpha1.AddToScheme(scheme)
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
-	restConfig, err := config.RESTConfig()
-	if err != nil {
-		return err
-	}
-	restConfig.WarningHandler = rest.NoWarnings{}
-	kubeClient, err := client.New(restConfig, client.Options{Scheme: scheme})
-	if err != nil {
-		return fmt.Errorf("failed to create kubernetes client: %v", err)
-	}
-
-	existing := &operatorv1alpha1.Blueprint{}
-	err = kubeClient.Get(context.Background(), client.ObjectKeyFromObject(obj), existing)
-	if err != nil {
-		if client.IgnoreNotFound(err) != nil {
-			return fmt.Errorf("failed to get existing blueprints: %v", err)
-		}
-	}
-	if existing.Name != "" {
-		obj.SetResourceVersion(existing.GetResourceVersion())
-		obj.SetFinalizers(existing.GetFinalizers())
+	"github.com/mirantiscontainers/blueprint-cli/boundlessclientset"
+)
 
-		err = kubeClient.Delete(context.Background(), obj)
-		if err != nil {
-			return fmt.Errorf("failed to delete cluster object: %v", err)
-		}
+// DeleteBlueprint deletes the Blueprint object if it exists
+func DeleteBlueprint(client boundlessclientset.BoundlessV1Alpha1Interface, blueprint *operatorv1alpha1.Blueprint) error {
+	err := client.Blueprints(blueprint.Namespace).Delete(context.Background(), blueprint.Name, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete cluster object: %w", err)
 	}
 
 	return nil
@@ -3,108 +3,132 @@ package k8s
 import (
 	"context"
 	"fmt"
+
 	"github.com/rs/zerolog/log"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/kubernetes"
 )
 
+// ObjectMutator modifies an object read from a manifest before it is applied to the cluster
+type ObjectMutator func(obj *unstructured.Unstructured) error
+
 // ApplyYaml applies a yaml manifest to the cluster from the URI. The URI can be a file path or a URL
-// It creates CRDs first and then other objects
+// Objects are applied in dependency order: namespaces, CRDs, RBAC, configuration, workloads, webhook configurations
+// and custom resources, so that the webhooks of the manifest admit its custom resources.
+// CRDs are waited for until they are established, and webhook configurations until the deployments serving them are available
+// The mutators are called in order for every object of the manifest before it is applied
 // @TODO: Make this function testable by passing a "uri reader"
-func ApplyYaml(client kubernetes.Interface, dynamicClient dynamic.Interface, uri string) error {
+func ApplyYaml(clients *Clients, uri string, mutators ...ObjectMutator) error {
 	var err error
 
-	objs, err := readYamlManifest(uri)
+	objs, err := ReadYamlManifest(uri)
 	if err != nil {
 		return fmt.Errorf("failed to read manifest from %q: %w", uri, err)
 	}
 
-	// separate out the CRDs and other objects
-	// CRDs need to be created first
-	crds, others := splitCrdAndOthers(objs)
-	log.Trace().Msgf("Found %d CRDs and %d other objects", len(crds), len(others))
-
-	ctx := context.Background()
-	for _, o := range crds {
-		if err = createOrUpdateObject(ctx, client, dynamicClient, &o); err != nil {
-			return fmt.Errorf("failed to apply crds resources from manifest at %q: %w", uri, err)
+	for i := range objs {
+		for _, mutate := range mutators {
+			if err = mutate(&objs[i]); err != nil {
+				return fmt.Errorf("failed to modify %s %q from manifest at %q: %w", objs[i].GetKind(), objs[i].GetName(), uri, err)
+			}
 		}
 	}
 
-	// create other objects
-	for _, o := range others {
-		if err = createOrUpdateObject(ctx, client, dynamicClient, &o); err != nil {
-			return fmt.Errorf("failed to apply resources from manifest at %q: %w", uri, err)
+	if err = applyObjects(context.Background(), clients, objs); err != nil {
+		return fmt.Errorf("failed to apply resources from manifest at %q: %w", uri, err)
+	}
+	return nil
+}
+
+// applyObjects applies the objects phase by phase, waiting for the readiness gates between phases
+func applyObjects(ctx context.Context, clients *Clients, objs []unstructured.Unstructured) error {
+	phases := groupByPhase(objs)
+	log.Trace().Msgf("Found %d CRDs and %d other objects", len(phases[phaseCRDs]), len(objs)-len(phases[phaseCRDs]))
+
+	for phase, phaseObjs := range phases {
+		if len(phaseObjs) == 0 {
+			continue
+		}
+
+		if applyPhase(phase) == phaseWebhooks {
+			if err := waitForWebhookBackends(ctx, clients, phaseObjs); err != nil {
+				return err
+			}
+		}
+
+		for _, o := range phaseObjs {
+			if err := applyWithRetry(ctx, clients, &o); err != nil {
+				return fmt.Errorf("failed to apply %s %q: %w", o.GetKind(), o.GetName(), err)
+			}
+		}
+
+		if applyPhase(phase) == phaseCRDs {
+			if err := waitForCRDsEstablished(ctx, clients.Dynamic, phaseObjs); err != nil {
+				return err
+			}
+			// the cached discovery does not know about the new kinds yet
+			clients.Mapper.Reset()
 		}
 	}
+
 	return nil
 }
 
 // DeleteYamlObjects deletes all objects in the cluster that are specified in the yaml
 func DeleteYamlObjects(kc *KubeConfig, uri string) error {
-	var err error
-	var client kubernetes.Interface
-	var dynamicClient dynamic.Interface
-
-	if client, err = GetClient(kc); err != nil {
-		return fmt.Errorf("failed to get kubernetes client: %q", err)
-	}
-	if dynamicClient, err = GetDynamicClient(kc); err != nil {
-		return fmt.Errorf("failed to get kubernetes dynamic client: %q", err)
+	clients, err := GetClients(kc)
+	if err != nil {
+		return fmt.Errorf("failed to get kubernetes clients: %w", err)
 	}
 
-	objs, err := readYamlManifest(uri)
+	objs, err := ReadYamlManifest(uri)
 	if err != nil {
 		return fmt.Errorf("failed to read manifest from %q: %w", uri, err)
 	}
 
 	log.Info().Msgf("Deleting %d objects", len(objs))
 	ctx := context.Background()
-	for _, o := range objs {
-		if err = deleteObject(ctx, client, dynamicClient, &o); err != nil {
-			return fmt.Errorf("failed to reset obj resources from manifest at %q: %w", uri, err)
+
+	// delete in the reverse order of apply, so that custom resources go first and namespaces last
+	phases := groupByPhase(objs)
+	for phase := len(phases) - 1; phase >= 0; phase-- {
+		for _, o := range phases[phase] {
+			if err = deleteObject(ctx, clients, &o); err != nil {
+				return fmt.Errorf("failed to reset obj resources from manifest at %q: %w", uri, err)
+			}
 		}
 	}
 
 	return nil
 }
 
-func splitCrdAndOthers(objs []unstructured.Unstructured) ([]unstructured.Unstructured, []unstructured.Unstructured) {
-	var crds []unstructured.Unstructured
-	var others []unstructured.Unstructured
-	for _, o := range objs {
-		if o.GetKind() == "CustomResourceDefinition" {
-			crds = append(crds, o)
-		} else {
-			others = append(others, o)
-		}
-	}
-	return crds, others
-}
-
-func createOrUpdateObject(ctx context.Context, client kubernetes.Interface, dynamicClient dynamic.Interface, obj *unstructured.Unstructured) error {
-	gvr, _ := getResource(client, obj)
-	namespace := obj.GetNamespace()
+func createOrUpdateObject(ctx context.Context, clients *Clients, obj *unstructured.Unstructured) error {
 	objName := obj.GetName()
 
-	existing, err := dynamicClient.Resource(gvr).Namespace(namespace).Get(ctx, objName, metav1.GetOptions{})
+	resource, err := resourceFor(clients, obj)
+	if err != nil {
+		return err
+	}
+
+	existing, err := resource.Get(ctx, objName, metav1.GetOptions{})
 	if errors.IsNotFound(err) {
 		log.Trace().Msgf("Creating %q of kind %q", objName, obj.GetKind())
-		_, err = dynamicClient.Resource(gvr).Namespace(namespace).Create(ctx, obj, metav1.CreateOptions{})
+		_, err = resource.Create(ctx, obj, metav1.CreateOptions{})
 		if err != nil {
-			return fmt.Errorf("failed to create resource: %q", err)
+			return fmt.Errorf("failed to create resource: %w", err)
 		}
 		log.Trace().Msgf("Created %q of kind %q", objName, obj.GetKind())
+	} else if err != nil {
+		return fmt.Errorf("failed to get resource: %w", err)
 	} else {
 		log.Trace().Msgf("Updating %q of kind %q", objName, obj.GetKind())
 		obj.SetResourceVersion(existing.GetResourceVersion())
-		_, err = dynamicClient.Resource(gvr).Namespace(namespace).Update(ctx, obj, metav1.UpdateOptions{})
+		_, err = resource.Update(ctx, obj, metav1.UpdateOptions{})
 		if err != nil {
-			return fmt.Errorf("failed to update resource: %q", err)
+			return fmt.Errorf("failed to update resource: %w", err)
 		}
 		log.Trace().Msgf("Updated %q of kind %q", objName, obj.GetKind())
 	}
@@ -112,44 +136,59 @@ func createOrUpdateObject(ctx context.Context, client kubernetes.Interface, dyna
 	return nil
 }
 
-func deleteObject(ctx context.Context, client kubernetes.Interface, dynamicClient dynamic.Interface, obj *unstructured.Unstructured) error {
-	gvr, _ := getResource(client, obj)
-	namespace := obj.GetNamespace()
+func deleteObject(ctx context.Context, clients *Clients, obj *unstructured.Unstructured) error {
 	objName := obj.GetName()
 
-	_, err := dynamicClient.Resource(gvr).Namespace(namespace).Get(ctx, objName, metav1.GetOptions{})
+	resource, err := resourceFor(clients, obj)
+	if meta.IsNoMatchError(err) {
+		log.Trace().Msgf("Kind %q of %q is not served by the cluster. No changes made", obj.GetKind(), objName)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = resource.Get(ctx, objName, metav1.GetOptions{})
 	if errors.IsNotFound(err) {
 		log.Trace().Msgf("%q was not found. No changes made", objName)
 		return nil
 	}
 
 	log.Trace().Msgf("Deleting %q of kind %q", objName, obj.GetKind())
-	err = dynamicClient.Resource(gvr).Namespace(namespace).Delete(ctx, obj.GetName(), metav1.DeleteOptions{})
+	err = resource.Delete(ctx, obj.GetName(), metav1.DeleteOptions{})
 	if err != nil {
-		return fmt.Errorf("failed to delete resource: %q", err)
+		return fmt.Errorf("failed to delete resource: %w", err)
 	}
 	log.Trace().Msgf("Deleted %q of kind %q", objName, obj.GetKind())
 
 	return nil
 }
 
-// getResource returns the GroupVersionResource for a given object
-// Especially, it discovers the resource name for the given object
-func getResource(client kubernetes.Interface, object *unstructured.Unstructured) (schema.GroupVersionResource, error) {
-	gvk := object.GroupVersionKind()
-	apiResourceList, err := client.Discovery().ServerResourcesForGroupVersion(gvk.GroupVersion().String())
+// resourceFor returns the dynamic client for the resource of the object
+// Namespaced objects without a namespace go to the default namespace, like with kubectl
+// It returns a NoKindMatchError if the kind is not served by the cluster
+func resourceFor(clients *Clients, obj *unstructured.Unstructured) (dynamic.ResourceInterface, error) {
+	gvk := obj.GroupVersionKind()
+	mapping, err := clients.Mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
 	if err != nil {
-		return schema.GroupVersionResource{}, err
+		return nil, err
 	}
+	log.Trace().Msgf("Found resource: %q", mapping.Resource.Resource)
 
-	var resource *metav1.APIResource
-	for _, r := range apiResourceList.APIResources {
-		if r.Kind == gvk.Kind {
-			resource = &r
-			break
-		}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return clients.Dynamic.Resource(mapping.Resource), nil
 	}
+	return clients.Dynamic.Resource(mapping.Resource).Namespace(namespaceOf(mapping, obj)), nil
+}
 
-	log.Trace().Msgf("Found resource: %q", resource.Name)
-	return schema.GroupVersionResource{Group: gvk.Group, Version: gvk.Version, Resource: resource.Name}, nil
+// namespaceOf returns the namespace the object is applied to with the mapping of its kind:
+// none for cluster-scoped objects, and the default namespace for namespaced objects without one
+func namespaceOf(mapping *meta.RESTMapping, obj *unstructured.Unstructured) string {
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return ""
+	}
+	if obj.GetNamespace() == "" {
+		return metav1.NamespaceDefault
+	}
+	return obj.GetNamespace()
 }
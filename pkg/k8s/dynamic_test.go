@@ -0,0 +1,58 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery/cached/memory"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	fakekubernetes "k8s.io/client-go/kubernetes/fake"
+)
+
+// TestCreateOrUpdateObjectScope tests that objects are created with the scope of their resource
+func TestCreateOrUpdateObjectScope(t *testing.T) {
+	g := NewWithT(t)
+
+	client := fakekubernetes.NewSimpleClientset()
+	discovery := client.Discovery().(*fakediscovery.FakeDiscovery)
+	discovery.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap", Namespaced: true}},
+		},
+		{
+			GroupVersion: "rbac.authorization.k8s.io/v1",
+			APIResources: []metav1.APIResource{{Name: "clusterroles", Kind: "ClusterRole", Namespaced: false}},
+		},
+	}
+
+	configMaps := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	clusterRoles := schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"}
+	dynamicClient := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		configMaps:   "ConfigMapList",
+		clusterRoles: "ClusterRoleList",
+	})
+	clients := NewClients(client, dynamicClient, memory.NewMemCacheClient(discovery))
+
+	ctx := context.Background()
+	configMap := object("v1", "ConfigMap", "config")
+	clusterRole := object("rbac.authorization.k8s.io/v1", "ClusterRole", "role")
+	g.Expect(createOrUpdateObject(ctx, clients, &configMap)).To(Succeed())
+	g.Expect(createOrUpdateObject(ctx, clients, &clusterRole)).To(Succeed())
+
+	_, err := dynamicClient.Resource(configMaps).Namespace(metav1.NamespaceDefault).Get(ctx, "config", metav1.GetOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = dynamicClient.Resource(clusterRoles).Get(ctx, "role", metav1.GetOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	unknown := object("example.com/v1", "Widget", "widget")
+	_, err = resourceFor(clients, &unknown)
+	g.Expect(meta.IsNoMatchError(err)).To(BeTrue())
+	g.Expect(deleteObject(ctx, clients, &unknown)).To(Succeed())
+}
@@ -0,0 +1,221 @@
+package k8s
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/discovery"
+)
+
+const (
+	// InventoryOwnerLabel identifies the manifest an object was applied from
+	InventoryOwnerLabel = "blueprint.mirantis.com/inventory-owner"
+	// InventoryVersionLabel is the version of the manifest an object was applied from
+	InventoryVersionLabel = "blueprint.mirantis.com/manifest-version"
+)
+
+// InventoryMutator returns a manifest mutator that adds the inventory labels to every object
+func InventoryMutator(owner string, version string) ObjectMutator {
+	version = inventoryLabelValue(version)
+	return func(obj *unstructured.Unstructured) error {
+		labels := obj.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[InventoryOwnerLabel] = owner
+		labels[InventoryVersionLabel] = version
+		obj.SetLabels(labels)
+		return nil
+	}
+}
+
+// inventoryLabelValue returns the version if it is a valid label value, e.g. a semver or "latest",
+// and a short hash of it otherwise, e.g. for a manifest URI
+func inventoryLabelValue(version string) string {
+	if len(validation.IsValidLabelValue(version)) == 0 {
+		return version
+	}
+	sum := sha256.Sum256([]byte(version))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// FindPrunable returns the live objects of the inventory owner that are not part of the manifest at the URI
+// Objects applied before inventory labels were introduced are not found. CRDs that still have custom resources
+// are kept, as deleting a CRD deletes all its custom resources with it.
+func FindPrunable(clients *Clients, owner string, uri string) ([]unstructured.Unstructured, error) {
+	objs, err := ReadYamlManifest(uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest from %q: %w", uri, err)
+	}
+
+	inManifest := map[string]bool{}
+	for i := range objs {
+		key, err := manifestKey(clients, &objs[i])
+		if err != nil {
+			return nil, err
+		}
+		inManifest[key] = true
+	}
+
+	ctx := context.Background()
+	live, err := listInventory(ctx, clients, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	var prunable []unstructured.Unstructured
+	for _, o := range live {
+		if inManifest[inventoryKey(&o)] {
+			continue
+		}
+		if isCRD(&o) {
+			inUse, err := crdInUse(ctx, clients, &o)
+			if err != nil {
+				return nil, err
+			}
+			if inUse {
+				log.Warn().Msgf("Not pruning CRD %q: it still has custom resources, which would be deleted with it", o.GetName())
+				continue
+			}
+		}
+		prunable = append(prunable, o)
+	}
+
+	return prunable, nil
+}
+
+// listInventory returns the live objects of the inventory owner
+func listInventory(ctx context.Context, clients *Clients, owner string) ([]unstructured.Unstructured, error) {
+	resources, err := listableResources(clients.Discovery)
+	if err != nil {
+		return nil, err
+	}
+
+	selector := metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", InventoryOwnerLabel, owner)}
+	var objs []unstructured.Unstructured
+	for _, gvr := range resources {
+		list, err := clients.Dynamic.Resource(gvr).List(ctx, selector)
+		if err != nil {
+			if errors.IsNotFound(err) || errors.IsMethodNotSupported(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to list %s: %w", gvr.String(), err)
+		}
+		objs = append(objs, list.Items...)
+	}
+
+	return objs, nil
+}
+
+// crdInUse tells whether custom resources of the CRD exist in the cluster
+func crdInUse(ctx context.Context, clients *Clients, crd *unstructured.Unstructured) (bool, error) {
+	group, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+	plural, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "plural")
+	versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+
+	for _, v := range versions {
+		version, _ := v.(map[string]interface{})
+		name, _, _ := unstructured.NestedString(version, "name")
+		if served, _, _ := unstructured.NestedBool(version, "served"); !served || name == "" {
+			continue
+		}
+
+		gvr := schema.GroupVersionResource{Group: group, Version: name, Resource: plural}
+		list, err := clients.Dynamic.Resource(gvr).List(ctx, metav1.ListOptions{Limit: 1})
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to list %s: %w", gvr.String(), err)
+		}
+		return len(list.Items) > 0, nil
+	}
+
+	return false, nil
+}
+
+func isCRD(obj *unstructured.Unstructured) bool {
+	return obj.GroupVersionKind().GroupKind() == schema.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}
+}
+
+// Prune deletes the given objects from the cluster
+func Prune(clients *Clients, objs []unstructured.Unstructured) error {
+	ctx := context.Background()
+	for _, o := range objs {
+		log.Info().Msgf("Pruning %s %q", o.GetKind(), qualifiedName(&o))
+		if err := deleteObject(ctx, clients, &o); err != nil {
+			return fmt.Errorf("failed to prune %s %q: %w", o.GetKind(), qualifiedName(&o), err)
+		}
+	}
+
+	return nil
+}
+
+// listableResources returns the preferred version of every resource that can be listed and deleted
+func listableResources(discoveryClient discovery.DiscoveryInterface) ([]schema.GroupVersionResource, error) {
+	lists, err := discoveryClient.ServerPreferredResources()
+	if err != nil {
+		if !discovery.IsGroupDiscoveryFailedError(err) {
+			return nil, fmt.Errorf("failed to discover server resources: %w", err)
+		}
+		log.Warn().Msgf("Some API groups could not be discovered, objects of these groups are not pruned: %s", err)
+	}
+
+	var resources []schema.GroupVersionResource
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, r := range list.APIResources {
+			// skip subresources such as deployments/status
+			if strings.Contains(r.Name, "/") {
+				continue
+			}
+			if !slices.Contains(r.Verbs, "list") || !slices.Contains(r.Verbs, "delete") {
+				continue
+			}
+			resources = append(resources, gv.WithResource(r.Name))
+		}
+	}
+
+	return resources, nil
+}
+
+// inventoryKey identifies a live object independently of its API version
+func inventoryKey(obj *unstructured.Unstructured) string {
+	return fmt.Sprintf("%s/%s/%s", obj.GroupVersionKind().GroupKind().String(), obj.GetNamespace(), obj.GetName())
+}
+
+// manifestKey identifies an object of a manifest like inventoryKey identifies the live object it is applied to
+// The namespace is the one the object is applied to, e.g. the default namespace when the manifest sets none.
+func manifestKey(clients *Clients, obj *unstructured.Unstructured) (string, error) {
+	gvk := obj.GroupVersionKind()
+	mapping, err := clients.Mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		// the kind is not served yet, so no live object of it can be pruned
+		return inventoryKey(obj), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%s/%s", gvk.GroupKind().String(), namespaceOf(mapping, obj), obj.GetName()), nil
+}
+
+func qualifiedName(obj *unstructured.Unstructured) string {
+	if obj.GetNamespace() == "" {
+		return obj.GetName()
+	}
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
@@ -0,0 +1,137 @@
+package k8s
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery/cached/memory"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	fakekubernetes "k8s.io/client-go/kubernetes/fake"
+)
+
+var (
+	configMapsResource = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	crdsResource       = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+	widgetsResource    = schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	gadgetsResource    = schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "gadgets"}
+)
+
+// inventoryClients returns clients of a cluster with config maps, CRDs, and the widgets and gadgets custom resources
+func inventoryClients(objs ...runtime.Object) (*Clients, *fakedynamic.FakeDynamicClient) {
+	verbs := metav1.Verbs{"get", "list", "create", "update", "delete"}
+	client := fakekubernetes.NewSimpleClientset()
+	discovery := client.Discovery().(*fakediscovery.FakeDiscovery)
+	discovery.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: verbs}},
+		},
+		{
+			GroupVersion: "apiextensions.k8s.io/v1",
+			APIResources: []metav1.APIResource{{Name: "customresourcedefinitions", Kind: "CustomResourceDefinition", Verbs: verbs}},
+		},
+		{
+			GroupVersion: "example.com/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "widgets", Kind: "Widget", Namespaced: true, Verbs: verbs},
+				{Name: "gadgets", Kind: "Gadget", Namespaced: true, Verbs: verbs},
+			},
+		},
+	}
+
+	dynamicClient := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		configMapsResource: "ConfigMapList",
+		crdsResource:       "CustomResourceDefinitionList",
+		widgetsResource:    "WidgetList",
+		gadgetsResource:    "GadgetList",
+	}, objs...)
+	return NewClients(client, dynamicClient, memory.NewMemCacheClient(discovery)), dynamicClient
+}
+
+// inventoryObject returns an object of the inventory of the owner
+func inventoryObject(apiVersion string, kind string, namespace string, name string, owner string) *unstructured.Unstructured {
+	o := object(apiVersion, kind, name)
+	o.SetNamespace(namespace)
+	o.SetLabels(map[string]string{InventoryOwnerLabel: owner})
+	return &o
+}
+
+// crd returns the CRD of a namespaced custom resource of example.com, served in v1
+func crd(plural string, owner string) *unstructured.Unstructured {
+	o := inventoryObject("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", plural+".example.com", owner)
+	o.Object["spec"] = map[string]interface{}{
+		"group":    "example.com",
+		"names":    map[string]interface{}{"plural": plural},
+		"scope":    "Namespaced",
+		"versions": []interface{}{map[string]interface{}{"name": "v1", "served": true, "storage": true}},
+	}
+	return o
+}
+
+func writeManifest(t *testing.T, manifest string) string {
+	path := filepath.Join(t.TempDir(), "manifest.yaml")
+	if err := os.WriteFile(path, []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestFindPrunable tests that only the objects of the owner that left the manifest are pruned
+func TestFindPrunable(t *testing.T) {
+	g := NewWithT(t)
+
+	clients, _ := inventoryClients(
+		// applied to the default namespace from a manifest that sets none
+		inventoryObject("v1", "ConfigMap", metav1.NamespaceDefault, "kept", "operator"),
+		inventoryObject("v1", "ConfigMap", "blueprint-system", "removed", "operator"),
+		inventoryObject("v1", "ConfigMap", "blueprint-system", "other", "someone-else"),
+		crd("widgets", "operator"),
+		crd("gadgets", "operator"),
+		inventoryObject("example.com/v1", "Widget", metav1.NamespaceDefault, "widget", "user"),
+	)
+	uri := writeManifest(t, `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: kept
+`)
+
+	prunable, err := FindPrunable(clients, "operator", uri)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	var names []string
+	for _, o := range prunable {
+		names = append(names, o.GetKind()+"/"+qualifiedName(&o))
+	}
+	// the widgets CRD still has a widget, which would be deleted with it
+	g.Expect(names).To(ConsistOf("ConfigMap/blueprint-system/removed", "CustomResourceDefinition/gadgets.example.com"))
+}
+
+// TestPrune tests that the prunable objects are deleted from the cluster
+func TestPrune(t *testing.T) {
+	g := NewWithT(t)
+
+	removed := inventoryObject("v1", "ConfigMap", "blueprint-system", "removed", "operator")
+	kept := inventoryObject("v1", "ConfigMap", "blueprint-system", "kept", "operator")
+	gadgets := crd("gadgets", "operator")
+	clients, dynamicClient := inventoryClients(removed, kept, gadgets)
+
+	g.Expect(Prune(clients, []unstructured.Unstructured{*removed, *gadgets})).To(Succeed())
+
+	ctx := context.Background()
+	_, err := dynamicClient.Resource(configMapsResource).Namespace("blueprint-system").Get(ctx, "removed", metav1.GetOptions{})
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+	_, err = dynamicClient.Resource(crdsResource).Get(ctx, "gadgets.example.com", metav1.GetOptions{})
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+	_, err = dynamicClient.Resource(configMapsResource).Namespace("blueprint-system").Get(ctx, "kept", metav1.GetOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+}
@@ -11,10 +11,10 @@ import (
 	"k8s.io/apimachinery/pkg/util/yaml"
 )
 
-// readYamlManifest reads a Kubernetes YAML manifest file containing multiple objects and returns the contents
+// ReadYamlManifest reads a Kubernetes YAML manifest file containing multiple objects and returns the contents
 // as array of unstructured objects. The order of the objects in the returned slice is the same as in the file.
 // The uri argument can be a file path or a URL.
-func readYamlManifest(uri string) ([]unstructured.Unstructured, error) {
+func ReadYamlManifest(uri string) ([]unstructured.Unstructured, error) {
 	log.Debug().Msgf("Reading YAML manifest from %q", uri)
 	b, err := utils.ReadURI(uri)
 	if err != nil {
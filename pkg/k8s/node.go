@@ -0,0 +1,144 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// mirrorPodAnnotation is set on the pods the kubelet creates for its static pods
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+// DrainInterval is the interval between two eviction attempts of a pod protected by a PodDisruptionBudget
+var DrainInterval = 5 * time.Second
+
+// CordonNode marks the node as unschedulable
+func CordonNode(ctx context.Context, client kubernetes.Interface, name string) error {
+	return setUnschedulable(ctx, client, name, true)
+}
+
+// UncordonNode marks the node as schedulable
+func UncordonNode(ctx context.Context, client kubernetes.Interface, name string) error {
+	return setUnschedulable(ctx, client, name, false)
+}
+
+func setUnschedulable(ctx context.Context, client kubernetes.Interface, name string, unschedulable bool) error {
+	patch := fmt.Sprintf(`{"spec":{"unschedulable":%t}}`, unschedulable)
+	if _, err := client.CoreV1().Nodes().Patch(ctx, name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to set node %q unschedulable=%t: %w", name, unschedulable, err)
+	}
+	return nil
+}
+
+// DrainNode evicts the pods of the node and waits for them to be gone
+// Pods managed by a DaemonSet, mirror pods and completed pods are left on the node.
+// Evictions refused because of a PodDisruptionBudget are retried until the timeout, so the budgets are never violated.
+func DrainNode(ctx context.Context, client kubernetes.Interface, name string, timeout time.Duration) error {
+	log.Info().Msgf("Draining node %q", name)
+	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var pending []corev1.Pod
+	err := wait.PollUntilContextCancel(timeoutCtx, DrainInterval, true, func(ctx context.Context) (bool, error) {
+		var err error
+		if pending, err = podsToEvict(ctx, client, name); err != nil {
+			return false, err
+		}
+
+		for _, pod := range pending {
+			if pod.DeletionTimestamp != nil {
+				continue
+			}
+			eviction := &policyv1.Eviction{ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace}}
+			err := client.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction)
+			switch {
+			case err == nil:
+				log.Debug().Msgf("Evicted pod %s/%s", pod.Namespace, pod.Name)
+			case errors.IsNotFound(err):
+			case errors.IsTooManyRequests(err):
+				log.Debug().Msgf("Eviction of pod %s/%s refused by a PodDisruptionBudget, retrying", pod.Namespace, pod.Name)
+			default:
+				return false, fmt.Errorf("failed to evict pod %s/%s: %w", pod.Namespace, pod.Name, err)
+			}
+		}
+		return len(pending) == 0, nil
+	})
+	if err != nil && timeoutCtx.Err() != nil {
+		var names []string
+		for _, pod := range pending {
+			names = append(names, pod.Namespace+"/"+pod.Name)
+		}
+		return fmt.Errorf("timed out draining node %q, pods still running: %s; check the PodDisruptionBudgets of these pods", name, strings.Join(names, ", "))
+	}
+	return err
+}
+
+// podsToEvict returns the pods of the node that have to be evicted to drain it
+func podsToEvict(ctx context.Context, client kubernetes.Interface, name string) ([]corev1.Pod, error) {
+	selector := fields.OneTermEqualSelector("spec.nodeName", name).String()
+	pods, err := client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{FieldSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods of node %q: %w", name, err)
+	}
+
+	var evict []corev1.Pod
+	for _, pod := range pods.Items {
+		// the field selector is not supported by every client, e.g. the fake one
+		if pod.Spec.NodeName != name {
+			continue
+		}
+		if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+			continue
+		}
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if slices.ContainsFunc(pod.OwnerReferences, func(ref metav1.OwnerReference) bool { return ref.Kind == "DaemonSet" }) {
+			continue
+		}
+		evict = append(evict, pod)
+	}
+	return evict, nil
+}
+
+// DeleteNode deletes the node object
+// A node that does not exist is ignored
+func DeleteNode(ctx context.Context, client kubernetes.Interface, name string) error {
+	if err := client.CoreV1().Nodes().Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete node %q: %w", name, err)
+	}
+	return nil
+}
+
+// NodeAddresses returns the name and the addresses of the node
+func NodeAddresses(node corev1.Node) []string {
+	addresses := []string{node.Name}
+	for _, address := range node.Status.Addresses {
+		if !slices.Contains(addresses, address.Address) {
+			addresses = append(addresses, address.Address)
+		}
+	}
+	return addresses
+}
+
+// NodeInternalIP returns the internal IP of the node, or its name if it does not have one
+func NodeInternalIP(node corev1.Node) string {
+	for _, address := range node.Status.Addresses {
+		if address.Type == corev1.NodeInternalIP {
+			return address.Address
+		}
+	}
+	return node.Name
+}
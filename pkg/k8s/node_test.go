@@ -0,0 +1,110 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakekubernetes "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func nodePod(name, node string, mutate func(pod *corev1.Pod)) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: node},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	if mutate != nil {
+		mutate(pod)
+	}
+	return pod
+}
+
+// evictionReactor deletes the evicted pods, unless they are protected
+func evictionReactor(client *fakekubernetes.Clientset, protected map[string]bool) k8stesting.ReactionFunc {
+	return func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+		if protected[eviction.Name] {
+			return true, nil, errors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+		}
+		err := client.Tracker().Delete(schema.GroupVersionResource{Version: "v1", Resource: "pods"}, eviction.Namespace, eviction.Name)
+		return true, nil, err
+	}
+}
+
+// TestDrainNode tests that draining a node evicts its pods, except the ones that are not managed by a controller that can reschedule them
+func TestDrainNode(t *testing.T) {
+	g := NewWithT(t)
+	DrainInterval = 10 * time.Millisecond
+
+	client := fakekubernetes.NewSimpleClientset(
+		nodePod("app", "node-1", nil),
+		nodePod("other-node", "node-2", nil),
+		nodePod("daemon", "node-1", func(pod *corev1.Pod) {
+			pod.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "ds"}}
+		}),
+		nodePod("static", "node-1", func(pod *corev1.Pod) {
+			pod.Annotations = map[string]string{mirrorPodAnnotation: "hash"}
+		}),
+		nodePod("job", "node-1", func(pod *corev1.Pod) {
+			pod.Status.Phase = corev1.PodSucceeded
+		}),
+	)
+	client.PrependReactor("create", "pods", evictionReactor(client, nil))
+
+	g.Expect(DrainNode(context.Background(), client, "node-1", time.Second)).To(Succeed())
+
+	pods, err := client.CoreV1().Pods("default").List(context.Background(), metav1.ListOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	var names []string
+	for _, pod := range pods.Items {
+		names = append(names, pod.Name)
+	}
+	g.Expect(names).To(ConsistOf("other-node", "daemon", "static", "job"))
+}
+
+// TestDrainNodeDisruptionBudget tests that a pod protected by a PodDisruptionBudget makes the drain time out
+func TestDrainNodeDisruptionBudget(t *testing.T) {
+	g := NewWithT(t)
+	DrainInterval = 10 * time.Millisecond
+
+	client := fakekubernetes.NewSimpleClientset(nodePod("app", "node-1", nil), nodePod("db", "node-1", nil))
+	client.PrependReactor("create", "pods", evictionReactor(client, map[string]bool{"db": true}))
+
+	err := DrainNode(context.Background(), client, "node-1", 100*time.Millisecond)
+	g.Expect(err).To(MatchError(ContainSubstring("pods still running: default/db")))
+
+	_, err = client.CoreV1().Pods("default").Get(context.Background(), "app", metav1.GetOptions{})
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+}
+
+// TestCordonNode tests that cordoning and uncordoning a node toggles whether it is schedulable
+func TestCordonNode(t *testing.T) {
+	g := NewWithT(t)
+
+	client := fakekubernetes.NewSimpleClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}})
+
+	g.Expect(CordonNode(context.Background(), client, "node-1")).To(Succeed())
+	node, err := client.CoreV1().Nodes().Get(context.Background(), "node-1", metav1.GetOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(node.Spec.Unschedulable).To(BeTrue())
+
+	g.Expect(UncordonNode(context.Background(), client, "node-1")).To(Succeed())
+	node, err = client.CoreV1().Nodes().Get(context.Background(), "node-1", metav1.GetOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(node.Spec.Unschedulable).To(BeFalse())
+
+	g.Expect(DeleteNode(context.Background(), client, "node-1")).To(Succeed())
+	g.Expect(DeleteNode(context.Background(), client, "node-1")).To(Succeed())
+}
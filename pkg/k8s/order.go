@@ -0,0 +1,208 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/readiness"
+)
+
+const (
+	// crdEstablishedTimeout is the timeout for an applied CRD to be served by the API server
+	crdEstablishedTimeout = 1 * time.Minute
+	// webhookBackendTimeout is the timeout for the deployment backing a webhook to become available
+	webhookBackendTimeout = 5 * time.Minute
+	// noMatchRetryTimeout is the timeout for retrying objects whose kind is not served yet
+	noMatchRetryTimeout = 1 * time.Minute
+)
+
+// applyPhase is a class of kinds that are applied together
+// Each phase only depends on the objects of the previous phases
+type applyPhase int
+
+const (
+	phaseNamespaces applyPhase = iota
+	phaseCRDs
+	phaseRBAC
+	phaseConfig
+	phaseWorkloads
+	phaseWebhooks
+	// custom resources go after the webhooks, so that the admission webhooks of the manifest validate them
+	phaseCustomResources
+	phaseCount
+)
+
+var crdResource = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+
+func phaseOf(obj *unstructured.Unstructured) applyPhase {
+	switch obj.GroupVersionKind().GroupKind().String() {
+	case "Namespace":
+		return phaseNamespaces
+	case "CustomResourceDefinition.apiextensions.k8s.io":
+		return phaseCRDs
+	case "ServiceAccount",
+		"Role.rbac.authorization.k8s.io",
+		"ClusterRole.rbac.authorization.k8s.io",
+		"RoleBinding.rbac.authorization.k8s.io",
+		"ClusterRoleBinding.rbac.authorization.k8s.io":
+		return phaseRBAC
+	case "ConfigMap", "Secret":
+		return phaseConfig
+	case "MutatingWebhookConfiguration.admissionregistration.k8s.io",
+		"ValidatingWebhookConfiguration.admissionregistration.k8s.io":
+		return phaseWebhooks
+	}
+	if !isBuiltinGroup(obj.GroupVersionKind().Group) {
+		return phaseCustomResources
+	}
+	return phaseWorkloads
+}
+
+// builtinGroups are the API groups served by the Kubernetes API server itself
+// Groups of CRDs can end in .k8s.io too, e.g. gateway.networking.k8s.io or snapshot.storage.k8s.io.
+var builtinGroups = []string{
+	"",
+	"admissionregistration.k8s.io",
+	"apiextensions.k8s.io",
+	"apiregistration.k8s.io",
+	"apps",
+	"authentication.k8s.io",
+	"authorization.k8s.io",
+	"autoscaling",
+	"batch",
+	"certificates.k8s.io",
+	"coordination.k8s.io",
+	"discovery.k8s.io",
+	"events.k8s.io",
+	"extensions",
+	"flowcontrol.apiserver.k8s.io",
+	"internal.apiserver.k8s.io",
+	"networking.k8s.io",
+	"node.k8s.io",
+	"policy",
+	"rbac.authorization.k8s.io",
+	"resource.k8s.io",
+	"scheduling.k8s.io",
+	"storage.k8s.io",
+	"storagemigration.k8s.io",
+}
+
+// isBuiltinGroup returns true for the API groups of Kubernetes, e.g. apps or networking.k8s.io,
+// and false for the groups of custom resources
+func isBuiltinGroup(group string) bool {
+	return slices.Contains(builtinGroups, group)
+}
+
+// groupByPhase splits the objects by apply phase, keeping the manifest order within a phase
+func groupByPhase(objs []unstructured.Unstructured) [phaseCount][]unstructured.Unstructured {
+	var phases [phaseCount][]unstructured.Unstructured
+	for _, o := range objs {
+		p := phaseOf(&o)
+		phases[p] = append(phases[p], o)
+	}
+	return phases
+}
+
+// applyWithRetry applies the object and retries while its kind is not served yet,
+// e.g. while the API server has not picked up a CRD that was just created
+// Other errors, like a missing namespace, are returned straight away.
+func applyWithRetry(ctx context.Context, clients *Clients, obj *unstructured.Unstructured) error {
+	var lastErr error
+	err := wait.PollUntilContextTimeout(ctx, 2*time.Second, noMatchRetryTimeout, true, func(ctx context.Context) (bool, error) {
+		lastErr = createOrUpdateObject(ctx, clients, obj)
+		if lastErr == nil {
+			return true, nil
+		}
+		if meta.IsNoMatchError(lastErr) {
+			log.Debug().Msgf("Kind %q is not served yet, retrying: %s", obj.GetKind(), lastErr)
+			clients.Mapper.Reset()
+			return false, nil
+		}
+		return false, lastErr
+	})
+	if err != nil && lastErr != nil {
+		return lastErr
+	}
+	return err
+}
+
+// waitForCRDsEstablished waits until the API server serves the custom resources of the CRDs
+func waitForCRDsEstablished(ctx context.Context, dynamicClient dynamic.Interface, crds []unstructured.Unstructured) error {
+	resources := make([]readiness.Resource, 0, len(crds))
+	for _, crd := range crds {
+		resources = append(resources, readiness.Resource{GVR: crdResource, Name: crd.GetName(), Timeout: crdEstablishedTimeout})
+	}
+
+	waiter := readiness.NewWaiter(dynamicClient)
+	waiter.Interval = time.Second
+	if err := waiter.Wait(ctx, resources...); err != nil {
+		return fmt.Errorf("CRDs were not established: %w", err)
+	}
+
+	return nil
+}
+
+// waitForWebhookBackends waits for the deployments serving the webhook configurations to become available
+// Registering a webhook before its service is up makes every matching request fail
+func waitForWebhookBackends(ctx context.Context, clients *Clients, webhookConfigs []unstructured.Unstructured) error {
+	services := map[types.NamespacedName]bool{}
+	for _, config := range webhookConfigs {
+		webhooks, _, _ := unstructured.NestedSlice(config.Object, "webhooks")
+		for _, w := range webhooks {
+			webhook, ok := w.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			namespace, _, _ := unstructured.NestedString(webhook, "clientConfig", "service", "namespace")
+			name, _, _ := unstructured.NestedString(webhook, "clientConfig", "service", "name")
+			if name != "" {
+				services[types.NamespacedName{Namespace: namespace, Name: name}] = true
+			}
+		}
+	}
+
+	var backends []readiness.Resource
+	for key := range services {
+		namespace, name := key.Namespace, key.Name
+		service, err := clients.Client.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get webhook service %s: %w", key, err)
+		}
+		if len(service.Spec.Selector) == 0 {
+			continue
+		}
+
+		deployments, err := clients.Client.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to list deployments in namespace %q: %w", namespace, err)
+		}
+
+		selector := labels.SelectorFromSet(service.Spec.Selector)
+		for _, d := range deployments.Items {
+			if selector.Matches(labels.Set(d.Spec.Template.Labels)) {
+				log.Debug().Msgf("Waiting for deployment %s/%s backing webhook service %s", namespace, d.Name, key)
+				backends = append(backends, readiness.Deployment(namespace, d.Name, webhookBackendTimeout))
+			}
+		}
+	}
+
+	if len(backends) == 0 {
+		return nil
+	}
+	if err := readiness.NewWaiter(clients.Dynamic).Wait(ctx, backends...); err != nil {
+		return fmt.Errorf("webhook services are not available: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// TestGroupByPhase tests that manifest objects are grouped in dependency order
+func TestGroupByPhase(t *testing.T) {
+	g := NewWithT(t)
+
+	manifest := []unstructured.Unstructured{
+		object("blueprint.mirantis.com/v1alpha1", "Blueprint", "blueprint"),
+		object("admissionregistration.k8s.io/v1", "ValidatingWebhookConfiguration", "webhook"),
+		object("apps/v1", "Deployment", "operator"),
+		object("v1", "ConfigMap", "config"),
+		object("rbac.authorization.k8s.io/v1", "ClusterRoleBinding", "binding"),
+		object("apiextensions.k8s.io/v1", "CustomResourceDefinition", "crd"),
+		object("v1", "Namespace", "namespace"),
+		object("v1", "Service", "service"),
+		object("v1", "ServiceAccount", "account"),
+		object("networking.k8s.io/v1", "NetworkPolicy", "policy"),
+		object("gateway.networking.k8s.io/v1", "Gateway", "gateway"),
+		object("snapshot.storage.k8s.io/v1", "VolumeSnapshotClass", "snapshots"),
+	}
+
+	var names []string
+	for _, phase := range groupByPhase(manifest) {
+		for _, o := range phase {
+			names = append(names, o.GetName())
+		}
+	}
+
+	g.Expect(names).To(Equal([]string{"namespace", "crd", "binding", "account", "config", "operator", "service", "policy", "webhook", "blueprint", "gateway", "snapshots"}))
+}
+
+// TestApplyWithRetry tests that only the objects of kinds that are not served yet are retried
+func TestApplyWithRetry(t *testing.T) {
+	g := NewWithT(t)
+
+	clients, dynamicClient := inventoryClients()
+	var creates int
+	dynamicClient.PrependReactor("create", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		creates++
+		return true, nil, errors.NewNotFound(schema.GroupResource{Resource: "namespaces"}, "missing")
+	})
+
+	config := object("v1", "ConfigMap", "config")
+	config.SetNamespace("missing")
+	err := applyWithRetry(context.Background(), clients, &config)
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+	g.Expect(creates).To(Equal(1))
+}
+
+func object(apiVersion string, kind string, name string) unstructured.Unstructured {
+	o := unstructured.Unstructured{}
+	o.SetAPIVersion(apiVersion)
+	o.SetKind(kind)
+	o.SetName(name)
+	return o
+}
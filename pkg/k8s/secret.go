@@ -0,0 +1,129 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// EnsureNamespace creates the namespace if it does not exist yet
+func EnsureNamespace(client kubernetes.Interface, name string) error {
+	ctx := context.Background()
+	_, err := client.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+	if err == nil {
+		return nil
+	}
+	if !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get namespace %q: %w", name, err)
+	}
+
+	log.Debug().Msgf("Creating namespace %q", name)
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if _, err = client.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create namespace %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// CreateOrUpdateSecret creates the secret or replaces the data of an existing one
+func CreateOrUpdateSecret(client kubernetes.Interface, secret *corev1.Secret) error {
+	ctx := context.Background()
+	secrets := client.CoreV1().Secrets(secret.Namespace)
+
+	existing, err := secrets.Get(ctx, secret.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		log.Trace().Msgf("Creating secret %s/%s", secret.Namespace, secret.Name)
+		if _, err = secrets.Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create secret %s/%s: %w", secret.Namespace, secret.Name, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get secret %s/%s: %w", secret.Namespace, secret.Name, err)
+	}
+
+	log.Trace().Msgf("Updating secret %s/%s", secret.Namespace, secret.Name)
+	existing.Type = secret.Type
+	existing.Data = secret.Data
+	if _, err = secrets.Update(ctx, existing, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update secret %s/%s: %w", secret.Namespace, secret.Name, err)
+	}
+
+	return nil
+}
+
+// AddImagePullSecret adds the named secret to the imagePullSecrets of every service account in the namespace
+func AddImagePullSecret(client kubernetes.Interface, namespace string, secretName string) error {
+	ctx := context.Background()
+	serviceAccounts, err := client.CoreV1().ServiceAccounts(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list service accounts in namespace %q: %w", namespace, err)
+	}
+
+	for _, sa := range serviceAccounts.Items {
+		if hasPullSecret(sa.ImagePullSecrets, secretName) {
+			continue
+		}
+
+		log.Debug().Msgf("Adding image pull secret %q to service account %s/%s", secretName, namespace, sa.Name)
+		sa.ImagePullSecrets = append(sa.ImagePullSecrets, corev1.LocalObjectReference{Name: secretName})
+		if _, err = client.CoreV1().ServiceAccounts(namespace).Update(ctx, &sa, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update service account %s/%s: %w", namespace, sa.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// EnsureServiceAccount creates the service account if it does not exist yet
+// The default service account of a namespace is created by the cluster shortly after the namespace,
+// so it may not exist yet right after EnsureNamespace.
+func EnsureServiceAccount(client kubernetes.Interface, namespace string, name string) error {
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	if _, err := client.CoreV1().ServiceAccounts(namespace).Create(context.Background(), sa, metav1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create service account %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
+
+// RecreatePodsWithoutPullSecret deletes the pods in the namespace that were admitted without the named pull secret
+// The pull secrets of a service account are only injected when a pod is created, so the pods' controllers
+// have to recreate them to pick up the secret. Only the pods of a ReplicaSet are deleted, as the other pods
+// may never come back; they are reported instead.
+func RecreatePodsWithoutPullSecret(client kubernetes.Interface, namespace string, secretName string) error {
+	ctx := context.Background()
+	pods, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list pods in namespace %q: %w", namespace, err)
+	}
+
+	for _, pod := range pods.Items {
+		if hasPullSecret(pod.Spec.ImagePullSecrets, secretName) {
+			continue
+		}
+		if owner := metav1.GetControllerOf(&pod); owner == nil || owner.Kind != "ReplicaSet" {
+			log.Warn().Msgf("Pod %s/%s is not managed by a ReplicaSet, restart it to pick up image pull secret %q", namespace, pod.Name, secretName)
+			continue
+		}
+
+		log.Debug().Msgf("Recreating pod %s/%s to pick up image pull secret %q", namespace, pod.Name, secretName)
+		if err = client.CoreV1().Pods(namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete pod %s/%s: %w", namespace, pod.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func hasPullSecret(refs []corev1.LocalObjectReference, name string) bool {
+	return slices.ContainsFunc(refs, func(ref corev1.LocalObjectReference) bool {
+		return ref.Name == name
+	})
+}
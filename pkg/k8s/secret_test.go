@@ -0,0 +1,59 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekubernetes "k8s.io/client-go/kubernetes/fake"
+)
+
+// TestRecreatePodsWithoutPullSecret tests that only the pods of a ReplicaSet without the pull secret are deleted
+func TestRecreatePodsWithoutPullSecret(t *testing.T) {
+	g := NewWithT(t)
+
+	controller := true
+	owned := metav1.ObjectMeta{Namespace: "blueprint-system", OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "manager-1234", Controller: &controller}}}
+	withoutSecret := owned
+	withoutSecret.Name = "without-secret"
+	withSecret := owned
+	withSecret.Name = "with-secret"
+
+	client := fakekubernetes.NewSimpleClientset(
+		&corev1.Pod{ObjectMeta: withoutSecret},
+		&corev1.Pod{ObjectMeta: withSecret, Spec: corev1.PodSpec{ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}}}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "standalone", Namespace: "blueprint-system"}},
+	)
+
+	g.Expect(RecreatePodsWithoutPullSecret(client, "blueprint-system", "registry")).To(Succeed())
+
+	pods, err := client.CoreV1().Pods("blueprint-system").List(context.Background(), metav1.ListOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	var names []string
+	for _, pod := range pods.Items {
+		names = append(names, pod.Name)
+	}
+	g.Expect(names).To(ConsistOf("with-secret", "standalone"))
+}
+
+// TestEnsureServiceAccount tests that a missing service account is created and an existing one is kept
+func TestEnsureServiceAccount(t *testing.T) {
+	g := NewWithT(t)
+
+	existing := &corev1.ServiceAccount{
+		ObjectMeta:       metav1.ObjectMeta{Name: "default", Namespace: "addon"},
+		ImagePullSecrets: []corev1.LocalObjectReference{{Name: "other"}},
+	}
+	client := fakekubernetes.NewSimpleClientset(existing)
+
+	g.Expect(EnsureServiceAccount(client, "addon", "default")).To(Succeed())
+	sa, err := client.CoreV1().ServiceAccounts("addon").Get(context.Background(), "default", metav1.GetOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(sa.ImagePullSecrets).To(HaveLen(1))
+
+	g.Expect(EnsureServiceAccount(client, "monitoring", "default")).To(Succeed())
+	_, err = client.CoreV1().ServiceAccounts("monitoring").Get(context.Background(), "default", metav1.GetOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+}
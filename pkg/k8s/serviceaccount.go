@@ -0,0 +1,114 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// ServiceAccountAccess describes the service account and the rights it is granted
+type ServiceAccountAccess struct {
+	// Name of the service account
+	Name string
+	// Namespace of the service account. The rights are only granted in this namespace
+	Namespace string
+	// ClusterRole bound to the service account in the namespace
+	ClusterRole string
+	// Duration of the token
+	Duration time.Duration
+}
+
+// ServiceAccountKubeConfig creates the service account and a role binding limited to its namespace,
+// and returns a kubeconfig that authenticates to the cluster of the given config with a token of the service account
+func ServiceAccountKubeConfig(client kubernetes.Interface, config clientcmdapi.Config, access ServiceAccountAccess) (*clientcmdapi.Config, error) {
+	ctx := context.Background()
+
+	kubeContext, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return nil, fmt.Errorf("context %q not found in kubeconfig", config.CurrentContext)
+	}
+	cluster, ok := config.Clusters[kubeContext.Cluster]
+	if !ok {
+		return nil, fmt.Errorf("cluster %q not found in kubeconfig", kubeContext.Cluster)
+	}
+
+	if err := EnsureNamespace(client, access.Namespace); err != nil {
+		return nil, err
+	}
+
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: access.Name, Namespace: access.Namespace}}
+	if _, err := client.CoreV1().ServiceAccounts(access.Namespace).Create(ctx, sa, metav1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+		return nil, fmt.Errorf("failed to create service account %s/%s: %w", access.Namespace, access.Name, err)
+	}
+
+	if err := ensureRoleBinding(client, access); err != nil {
+		return nil, err
+	}
+
+	log.Debug().Msgf("Requesting a token for service account %s/%s valid for %s", access.Namespace, access.Name, access.Duration)
+	expiration := int64(access.Duration.Seconds())
+	request := &authenticationv1.TokenRequest{Spec: authenticationv1.TokenRequestSpec{ExpirationSeconds: &expiration}}
+	token, err := client.CoreV1().ServiceAccounts(access.Namespace).CreateToken(ctx, access.Name, request, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token for service account %s/%s: %w", access.Namespace, access.Name, err)
+	}
+	if token.Status.Token == "" {
+		return nil, fmt.Errorf("no token was issued for service account %s/%s", access.Namespace, access.Name)
+	}
+
+	name := fmt.Sprintf("%s@%s", access.Name, kubeContext.Cluster)
+	saConfig := clientcmdapi.NewConfig()
+	saConfig.Clusters[kubeContext.Cluster] = cluster.DeepCopy()
+	saConfig.AuthInfos[name] = &clientcmdapi.AuthInfo{Token: token.Status.Token}
+	saConfig.Contexts[name] = &clientcmdapi.Context{Cluster: kubeContext.Cluster, AuthInfo: name, Namespace: access.Namespace}
+	saConfig.CurrentContext = name
+
+	return saConfig, nil
+}
+
+// ensureRoleBinding binds the cluster role to the service account in its namespace
+func ensureRoleBinding(client kubernetes.Interface, access ServiceAccountAccess) error {
+	ctx := context.Background()
+	bindings := client.RbacV1().RoleBindings(access.Namespace)
+
+	binding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: access.Name, Namespace: access.Namespace},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: access.ClusterRole},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: access.Name, Namespace: access.Namespace}},
+	}
+
+	existing, err := bindings.Get(ctx, binding.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		log.Debug().Msgf("Binding cluster role %q to service account %s/%s", access.ClusterRole, access.Namespace, access.Name)
+		if _, err = bindings.Create(ctx, binding, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create role binding %s/%s: %w", access.Namespace, binding.Name, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get role binding %s/%s: %w", access.Namespace, binding.Name, err)
+	}
+
+	if existing.RoleRef == binding.RoleRef {
+		return nil
+	}
+
+	// the role of a binding cannot be changed, so it is recreated
+	log.Debug().Msgf("Rebinding service account %s/%s to cluster role %q", access.Namespace, access.Name, access.ClusterRole)
+	if err = bindings.Delete(ctx, binding.Name, metav1.DeleteOptions{}); err != nil {
+		return fmt.Errorf("failed to delete role binding %s/%s: %w", access.Namespace, binding.Name, err)
+	}
+	if _, err = bindings.Create(ctx, binding, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create role binding %s/%s: %w", access.Namespace, binding.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakekubernetes "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// TestServiceAccountKubeConfig tests that the service account kubeconfig uses the cluster of the admin config with a token
+func TestServiceAccountKubeConfig(t *testing.T) {
+	g := NewWithT(t)
+
+	client := fakekubernetes.NewSimpleClientset()
+	client.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+		return true, &authenticationv1.TokenRequest{Status: authenticationv1.TokenRequestStatus{Token: "secret-token"}}, nil
+	})
+
+	admin := clientcmdapi.NewConfig()
+	admin.Clusters["test"] = &clientcmdapi.Cluster{Server: "https://127.0.0.1:6443", CertificateAuthorityData: []byte("ca")}
+	admin.AuthInfos["admin"] = &clientcmdapi.AuthInfo{ClientCertificateData: []byte("cert")}
+	admin.Contexts["test"] = &clientcmdapi.Context{Cluster: "test", AuthInfo: "admin"}
+	admin.CurrentContext = "test"
+
+	access := ServiceAccountAccess{Name: "ci", Namespace: "blueprint-system", ClusterRole: "view", Duration: time.Hour}
+	config, err := ServiceAccountKubeConfig(client, *admin, access)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(config.CurrentContext).To(Equal("ci@test"))
+	g.Expect(config.Clusters).To(HaveKeyWithValue("test", admin.Clusters["test"]))
+	g.Expect(config.AuthInfos).To(HaveLen(1))
+	g.Expect(config.AuthInfos["ci@test"].Token).To(Equal("secret-token"))
+	g.Expect(config.Contexts["ci@test"].Namespace).To(Equal("blueprint-system"))
+
+	binding, err := client.RbacV1().RoleBindings("blueprint-system").Get(context.Background(), "ci", metav1.GetOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(binding.RoleRef.Name).To(Equal("view"))
+
+	// changing the role recreates the binding
+	access.ClusterRole = "edit"
+	_, err = ServiceAccountKubeConfig(client, *admin, access)
+	g.Expect(err).ToNot(HaveOccurred())
+	binding, err = client.RbacV1().RoleBindings("blueprint-system").Get(context.Background(), "ci", metav1.GetOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(binding.RoleRef.Name).To(Equal("edit"))
+}
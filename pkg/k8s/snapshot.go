@@ -0,0 +1,119 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Snapshot holds the live state of the objects of a manifest, taken before the manifest is applied
+// Restoring a snapshot puts the existing objects back and removes the ones the manifest added
+type Snapshot struct {
+	// existing are the live objects that the manifest would update, and the other live objects of the owner
+	existing []unstructured.Unstructured
+	// absent are the objects of the manifest that did not exist when the snapshot was taken
+	absent []unstructured.Unstructured
+}
+
+// SnapshotYaml records the live state of every object of the manifest at the URI, and of the inventory owner
+// The objects of the owner that are not part of the manifest, e.g. the ones only the deployed version has,
+// are recorded too, so that a restore brings them back if they were pruned.
+// The mutators must be the same as the ones used to apply the manifest
+func SnapshotYaml(clients *Clients, uri string, owner string, mutators ...ObjectMutator) (*Snapshot, error) {
+	objs, err := ReadYamlManifest(uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest from %q: %w", uri, err)
+	}
+
+	ctx := context.Background()
+	snapshot := &Snapshot{}
+	inManifest := map[string]bool{}
+	for i := range objs {
+		for _, mutate := range mutators {
+			if err = mutate(&objs[i]); err != nil {
+				return nil, fmt.Errorf("failed to modify %s %q from manifest at %q: %w", objs[i].GetKind(), objs[i].GetName(), uri, err)
+			}
+		}
+
+		obj := objs[i]
+		key, err := manifestKey(clients, &obj)
+		if err != nil {
+			return nil, err
+		}
+		inManifest[key] = true
+
+		resource, err := resourceFor(clients, &obj)
+		if meta.IsNoMatchError(err) {
+			// the kind is not served yet, e.g. the CRD is new in this manifest
+			log.Trace().Msgf("Kind %q is not served by the cluster: %s", obj.GetKind(), err)
+			snapshot.absent = append(snapshot.absent, obj)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		live, err := resource.Get(ctx, obj.GetName(), metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			snapshot.absent = append(snapshot.absent, obj)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s %q: %w", obj.GetKind(), obj.GetName(), err)
+		}
+
+		snapshot.existing = append(snapshot.existing, *sanitizeForRestore(live))
+	}
+
+	inventory, err := listInventory(ctx, clients, owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the deployed objects: %w", err)
+	}
+	for i := range inventory {
+		if !inManifest[inventoryKey(&inventory[i])] {
+			snapshot.existing = append(snapshot.existing, *sanitizeForRestore(&inventory[i]))
+		}
+	}
+
+	log.Debug().Msgf("Took snapshot of %d existing and %d absent objects", len(snapshot.existing), len(snapshot.absent))
+	return snapshot, nil
+}
+
+// Restore puts the objects of the snapshot back into the cluster
+// Objects that did not exist when the snapshot was taken are deleted
+func (s *Snapshot) Restore(clients *Clients) error {
+	ctx := context.Background()
+
+	if err := applyObjects(ctx, clients, s.existing); err != nil {
+		return fmt.Errorf("failed to restore objects: %w", err)
+	}
+
+	// delete in reverse order, so that the CRDs added by the manifest go last
+	absent := slices.Clone(s.absent)
+	slices.Reverse(absent)
+	for _, o := range absent {
+		if err := deleteObject(ctx, clients, &o); err != nil {
+			return fmt.Errorf("failed to remove %s %q: %w", o.GetKind(), o.GetName(), err)
+		}
+	}
+
+	return nil
+}
+
+// sanitizeForRestore removes the server populated fields of a live object so that it can be reapplied
+func sanitizeForRestore(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	o := obj.DeepCopy()
+	o.SetResourceVersion("")
+	o.SetUID("")
+	o.SetGeneration(0)
+	o.SetCreationTimestamp(metav1.Time{})
+	o.SetManagedFields(nil)
+	unstructured.RemoveNestedField(o.Object, "status")
+	return o
+}
@@ -0,0 +1,56 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// TestSnapshotRestore tests that restoring a snapshot undoes the apply of a manifest and the prune that followed
+func TestSnapshotRestore(t *testing.T) {
+	g := NewWithT(t)
+
+	updated := inventoryObject("v1", "ConfigMap", metav1.NamespaceDefault, "updated", "operator")
+	updated.Object["data"] = map[string]interface{}{"version": "old"}
+	removed := inventoryObject("v1", "ConfigMap", "blueprint-system", "removed", "operator")
+	clients, dynamicClient := inventoryClients(updated, removed)
+
+	uri := writeManifest(t, `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: updated
+data:
+  version: new
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: added
+  namespace: blueprint-system
+`)
+	mutator := InventoryMutator("operator", "v2")
+
+	snapshot, err := SnapshotYaml(clients, uri, "operator", mutator)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// the upgrade applies the new manifest and prunes the object it no longer has
+	g.Expect(ApplyYaml(clients, uri, mutator)).To(Succeed())
+	g.Expect(Prune(clients, []unstructured.Unstructured{*removed})).To(Succeed())
+
+	g.Expect(snapshot.Restore(clients)).To(Succeed())
+
+	ctx := context.Background()
+	configMaps := dynamicClient.Resource(configMapsResource)
+	live, err := configMaps.Namespace(metav1.NamespaceDefault).Get(ctx, "updated", metav1.GetOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(live.Object["data"]).To(Equal(map[string]interface{}{"version": "old"}))
+	_, err = configMaps.Namespace("blueprint-system").Get(ctx, "removed", metav1.GetOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = configMaps.Namespace("blueprint-system").Get(ctx, "added", metav1.GetOptions{})
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+}
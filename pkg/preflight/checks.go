@@ -0,0 +1,250 @@
+package preflight
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/version"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/readiness"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+const (
+	defaultStorageClassAnnotation     = "storageclass.kubernetes.io/is-default-class"
+	betaDefaultStorageClassAnnotation = "storageclass.beta.kubernetes.io/is-default-class"
+	coreDNSSelector                   = "k8s-app=kube-dns"
+	certManagerGroupVersion           = "cert-manager.io/v1"
+	certManagerChart                  = "cert-manager"
+)
+
+// certManagerResources are the resources used by resources.certManagement
+var certManagerResources = []string{"issuers", "clusterissuers", "certificates"}
+
+func checkAPIServer(ctx context.Context, cluster *Cluster) Result {
+	host := cluster.Config.Host
+
+	if _, err := cluster.Clients.Client.Discovery().ServerVersion(); err != nil {
+		if isTLSError(err) {
+			return Result{
+				Status:      Fail,
+				Message:     fmt.Sprintf("TLS verification of the API server at %s failed: %s", host, err),
+				Remediation: "Make sure the certificate authority in the kubeconfig signed the API server certificate, and that the server address is one of the certificate SANs.",
+			}
+		}
+		return Result{
+			Status:      Fail,
+			Message:     fmt.Sprintf("API server at %s is not reachable: %s", host, err),
+			Remediation: "Check that the cluster is running, that the server address in the kubeconfig is correct, and that no firewall or proxy blocks the connection.",
+		}
+	}
+
+	if cluster.Config.Insecure {
+		return Result{
+			Status:      Warn,
+			Message:     fmt.Sprintf("API server at %s is reachable, but TLS verification is disabled", host),
+			Remediation: "Remove insecure-skip-tls-verify from the kubeconfig and set the certificate authority of the cluster instead.",
+		}
+	}
+	return Result{Status: Pass, Message: fmt.Sprintf("API server at %s is reachable", host)}
+}
+
+// isTLSError returns true if the error is caused by the verification of the server certificate
+func isTLSError(err error) bool {
+	var verificationErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	if errors.As(err, &verificationErr) || errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr) {
+		return true
+	}
+	// client-go does not always wrap the transport errors
+	return strings.Contains(err.Error(), "x509: ") || strings.Contains(err.Error(), "tls: ")
+}
+
+func checkServerVersion(ctx context.Context, cluster *Cluster) Result {
+	info, err := cluster.Clients.Client.Discovery().ServerVersion()
+	if err != nil {
+		return Result{Status: Fail, Message: fmt.Sprintf("failed to get the server version: %s", err)}
+	}
+
+	current, err := version.ParseGeneric(info.GitVersion)
+	if err != nil {
+		return Result{
+			Status:      Warn,
+			Message:     fmt.Sprintf("unable to parse the server version %q", info.GitVersion),
+			Remediation: fmt.Sprintf("Make sure the cluster runs Kubernetes %s to %s.", constants.MinKubernetesVersion, constants.MaxKubernetesVersion),
+		}
+	}
+
+	minimum := version.MustParseGeneric(constants.MinKubernetesVersion)
+	maximum := version.MustParseGeneric(constants.MaxKubernetesVersion)
+	if current.LessThan(minimum) {
+		return Result{
+			Status:      Fail,
+			Message:     fmt.Sprintf("Kubernetes %s is older than the oldest supported version %s", current, minimum),
+			Remediation: fmt.Sprintf("Upgrade the cluster to Kubernetes %s or newer.", minimum),
+		}
+	}
+	if current.Major() > maximum.Major() || (current.Major() == maximum.Major() && current.Minor() > maximum.Minor()) {
+		return Result{
+			Status:      Warn,
+			Message:     fmt.Sprintf("Kubernetes %s is newer than the latest tested version %s", current, constants.MaxKubernetesVersion),
+			Remediation: "Check the release notes of the Blueprint Operator for the Kubernetes versions it supports.",
+		}
+	}
+	return Result{Status: Pass, Message: fmt.Sprintf("Kubernetes %s is supported", current)}
+}
+
+func checkDefaultStorageClass(ctx context.Context, cluster *Cluster) Result {
+	classes, err := cluster.Clients.Client.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return Result{Status: Warn, Message: fmt.Sprintf("failed to list storage classes: %s", err)}
+	}
+
+	var defaults []string
+	for _, class := range classes.Items {
+		if class.Annotations[defaultStorageClassAnnotation] == "true" || class.Annotations[betaDefaultStorageClassAnnotation] == "true" {
+			defaults = append(defaults, class.Name)
+		}
+	}
+
+	switch len(defaults) {
+	case 0:
+		return Result{
+			Status:  Warn,
+			Message: "no default StorageClass found",
+			Remediation: "Persistent volume claims of addons that do not set a storage class will stay pending. " +
+				fmt.Sprintf("Mark a StorageClass as the default with the %q annotation.", defaultStorageClassAnnotation),
+		}
+	case 1:
+		return Result{Status: Pass, Message: fmt.Sprintf("default StorageClass is %q", defaults[0])}
+	default:
+		return Result{
+			Status:      Warn,
+			Message:     fmt.Sprintf("multiple default StorageClasses found: %s", strings.Join(defaults, ", ")),
+			Remediation: fmt.Sprintf("Remove the %q annotation from all StorageClasses but one.", defaultStorageClassAnnotation),
+		}
+	}
+}
+
+func checkCoreDNS(ctx context.Context, cluster *Cluster) Result {
+	remediation := fmt.Sprintf("Addons rely on the cluster DNS to reach services. Check the DNS pods with: kubectl -n %s get pods -l %s", constants.NamespaceKubeSystem, coreDNSSelector)
+
+	deployments, err := cluster.Clients.Client.AppsV1().Deployments(constants.NamespaceKubeSystem).List(ctx, metav1.ListOptions{LabelSelector: coreDNSSelector})
+	if err != nil {
+		return Result{Status: Warn, Message: fmt.Sprintf("failed to list DNS deployments: %s", err)}
+	}
+	if len(deployments.Items) == 0 {
+		return Result{Status: Warn, Message: fmt.Sprintf("no CoreDNS deployment found in %s", constants.NamespaceKubeSystem), Remediation: remediation}
+	}
+
+	for _, deployment := range deployments.Items {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&deployment)
+		if err != nil {
+			return Result{Status: Warn, Message: fmt.Sprintf("failed to read deployment %q: %s", deployment.Name, err)}
+		}
+		obj := &unstructured.Unstructured{Object: content}
+		obj.SetAPIVersion("apps/v1")
+		obj.SetKind("Deployment")
+
+		if result := readiness.Compute(obj); result.Status != readiness.Current {
+			return Result{Status: Fail, Message: fmt.Sprintf("CoreDNS deployment %q is not healthy: %s", deployment.Name, result.Message), Remediation: remediation}
+		}
+	}
+	return Result{Status: Pass, Message: "CoreDNS is available"}
+}
+
+func checkCertManager(ctx context.Context, cluster *Cluster) Result {
+	if !usesCertManagement(cluster.Blueprint) {
+		return Result{Status: Pass, Message: "cert management is not used by the blueprint"}
+	}
+
+	missing := slices.Clone(certManagerResources)
+	resources, err := cluster.Clients.Discovery.ServerResourcesForGroupVersion(certManagerGroupVersion)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return Result{Status: Warn, Message: fmt.Sprintf("failed to discover %s resources: %s", certManagerGroupVersion, err)}
+	}
+	if err == nil {
+		for _, resource := range resources.APIResources {
+			missing = slices.DeleteFunc(missing, func(name string) bool { return name == resource.Name })
+		}
+	}
+	if len(missing) == 0 {
+		return Result{Status: Pass, Message: "cert-manager CRDs are installed"}
+	}
+
+	message := fmt.Sprintf("cert-manager resources are not served: %s", strings.Join(missing, ", "))
+	if hasCertManagerAddon(cluster.Blueprint) {
+		return Result{
+			Status:      Warn,
+			Message:     message + "; they are expected to be installed by the cert-manager addon",
+			Remediation: "Make sure the cert-manager addon installs its CRDs, e.g. with the crds.enabled chart value.",
+		}
+	}
+	return Result{
+		Status:      Fail,
+		Message:     message,
+		Remediation: "Install cert-manager in the cluster, or add it as an addon of the blueprint, before using resources.certManagement.",
+	}
+}
+
+// usesCertManagement returns true if the blueprint defines any cert-manager resource
+func usesCertManagement(blueprint *types.Blueprint) bool {
+	if blueprint.Spec.Resources == nil {
+		return false
+	}
+	certManagement := blueprint.Spec.Resources.CertManagement
+	return len(certManagement.Issuers)+len(certManagement.ClusterIssuers)+len(certManagement.Certificates) > 0
+}
+
+// hasCertManagerAddon returns true if the blueprint installs cert-manager with an addon
+func hasCertManagerAddon(blueprint *types.Blueprint) bool {
+	for _, addon := range blueprint.Spec.Components.Addons {
+		if addon.Enabled && addon.Chart != nil && addon.Chart.Name == certManagerChart {
+			return true
+		}
+	}
+	return false
+}
+
+func checkBlueprintNamespace(ctx context.Context, cluster *Cluster) Result {
+	namespace, err := cluster.Clients.Client.CoreV1().Namespaces().Get(ctx, constants.NamespaceBlueprint, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return Result{Status: Pass, Message: fmt.Sprintf("namespace %q will be created", constants.NamespaceBlueprint)}
+	}
+	if err != nil {
+		return Result{Status: Warn, Message: fmt.Sprintf("failed to get namespace %q: %s", constants.NamespaceBlueprint, err)}
+	}
+
+	if namespace.Status.Phase != corev1.NamespaceTerminating {
+		return Result{Status: Pass, Message: fmt.Sprintf("namespace %q is active", constants.NamespaceBlueprint)}
+	}
+
+	message := fmt.Sprintf("namespace %q is terminating", constants.NamespaceBlueprint)
+	if namespace.DeletionTimestamp != nil {
+		message = fmt.Sprintf("%s since %s", message, namespace.DeletionTimestamp.UTC().Format("2006-01-02 15:04:05 MST"))
+	}
+	for _, condition := range namespace.Status.Conditions {
+		if condition.Status == corev1.ConditionTrue && condition.Message != "" {
+			message = fmt.Sprintf("%s; %s", message, condition.Message)
+		}
+	}
+	return Result{
+		Status:  Fail,
+		Message: message,
+		Remediation: "Objects cannot be created in a terminating namespace. Wait for the deletion to finish; if it does not progress, " +
+			"remove the finalizers of the remaining resources or delete the unavailable API services listed by: kubectl get apiservices",
+	}
+}
@@ -0,0 +1,313 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxClockSkew is the difference between the clock of a host and the one of this machine above which the host fails
+	// The certificates and the etcd leases of k0s do not tolerate much more.
+	maxClockSkew = 30 * time.Second
+	// connectTimeout is the time, in seconds, a controller has to connect to the ports of the other controllers
+	connectTimeout = 3
+	gib            = 1024 * 1024 * 1024
+)
+
+// supportedArchs are the architectures k0s is released for, as reported by uname -m
+var supportedArchs = []string{"x86_64", "aarch64", "armv7l"}
+
+// kernelModules are the kernel modules needed by containerd and the network of the workers
+var kernelModules = []string{"overlay", "br_netfilter"}
+
+// sysctls are the kernel parameters needed by the network of the workers and their expected value
+var sysctls = map[string]string{
+	"net.ipv4.ip_forward":                 "1",
+	"net.bridge.bridge-nf-call-iptables":  "1",
+	"net.bridge.bridge-nf-call-ip6tables": "1",
+}
+
+var (
+	// controllerPorts are the ports k0s listens on on the controllers: the API server, konnectivity, the k0s API and etcd
+	controllerPorts = []int{6443, 8132, 9443, 2380}
+	// workerPorts are the ports k0s listens on on the workers: the kubelet
+	workerPorts = []int{10250}
+	// peerPorts are the ports of a controller the other controllers connect to
+	peerPorts = []int{6443, 9443, 2380}
+)
+
+// k0sProcesses are the processes that may already hold the ports, when k0s already runs on the host
+var k0sProcesses = []string{"k0s", "kube-apiserver", "etcd", "konnectivity", "kubelet"}
+
+// requirements are the minimum memory and disk space, in bytes, of a host by role, from the k0s system requirements
+var requirements = map[string]struct{ memory, disk int64 }{
+	"controller":        {memory: 1 * gib, disk: gib / 2},
+	"worker":            {memory: gib / 2, disk: 13 * gib / 10},
+	"controller+worker": {memory: 1 * gib, disk: 15 * gib / 10},
+	"single":            {memory: 1 * gib, disk: 15 * gib / 10},
+}
+
+// HostChecks returns the checks run on the hosts before k0s is installed, in the order they run
+func HostChecks() []HostCheck {
+	return []HostCheck{
+		{Name: "os-arch", Run: checkOSArch},
+		{Name: "kernel-modules", Applies: (*Host).isWorker, Run: checkKernelModules},
+		{Name: "sysctls", Applies: (*Host).isWorker, Run: checkSysctls},
+		{Name: "ports", Run: checkPorts},
+		{Name: "resources", Run: checkResources},
+		{Name: "time-sync", Run: checkTimeSync},
+		{Name: "controller-connectivity", Applies: func(host *Host) bool {
+			return host.isController() && len(host.Controllers) > 0
+		}, Run: checkControllerConnectivity},
+	}
+}
+
+func checkOSArch(ctx context.Context, host *Host) Result {
+	out, err := host.run("uname -sm")
+	if err != nil {
+		return Result{Status: Fail, Message: fmt.Sprintf("failed to get the operating system: %s", err)}
+	}
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return Result{Status: Fail, Message: fmt.Sprintf("unexpected output of uname: %q", out)}
+	}
+	system, arch := fields[0], fields[1]
+
+	if system != "Linux" {
+		return Result{
+			Status:      Fail,
+			Message:     fmt.Sprintf("operating system %s is not supported", system),
+			Remediation: "k0s only runs on Linux hosts.",
+		}
+	}
+	if !slices.Contains(supportedArchs, arch) {
+		return Result{
+			Status:      Fail,
+			Message:     fmt.Sprintf("architecture %s is not supported", arch),
+			Remediation: fmt.Sprintf("k0s is released for %s.", strings.Join(supportedArchs, ", ")),
+		}
+	}
+	return Result{Status: Pass, Message: fmt.Sprintf("%s %s", system, arch)}
+}
+
+func checkKernelModules(ctx context.Context, host *Host) Result {
+	var missing []string
+	for _, module := range kernelModules {
+		// the module is either loaded, or can be loaded by k0s when it starts
+		if _, err := host.run(fmt.Sprintf("grep -q '^%s ' /proc/modules || modinfo %s", module, module)); err != nil {
+			missing = append(missing, module)
+		}
+	}
+	if len(missing) > 0 {
+		return Result{
+			Status:      Fail,
+			Message:     fmt.Sprintf("kernel modules %s are not available", strings.Join(missing, ", ")),
+			Remediation: "Install the kernel modules of the running kernel, e.g. the linux-modules-extra package, or boot a kernel that has them.",
+		}
+	}
+	return Result{Status: Pass, Message: fmt.Sprintf("kernel modules %s are available", strings.Join(kernelModules, ", "))}
+}
+
+func checkSysctls(ctx context.Context, host *Host) Result {
+	var wrong []string
+	for _, name := range sortedKeys(sysctls) {
+		out, err := host.run("cat /proc/sys/" + strings.ReplaceAll(name, ".", "/"))
+		if err != nil {
+			wrong = append(wrong, name+" is not set")
+			continue
+		}
+		if out != sysctls[name] {
+			wrong = append(wrong, fmt.Sprintf("%s is %s", name, out))
+		}
+	}
+	if len(wrong) > 0 {
+		// the parameters are usually set when the network of the cluster starts, but some hosts reset them
+		return Result{
+			Status:      Warn,
+			Message:     strings.Join(wrong, ", "),
+			Remediation: "Set the parameters to 1 in /etc/sysctl.d and load the br_netfilter module at boot, so that the pod network works after a reboot.",
+		}
+	}
+	return Result{Status: Pass, Message: "kernel parameters are set"}
+}
+
+func checkPorts(ctx context.Context, host *Host) Result {
+	var ports []int
+	if host.isController() {
+		ports = append(ports, controllerPorts...)
+	}
+	if host.isWorker() {
+		ports = append(ports, workerPorts...)
+	}
+
+	out, err := host.run("sudo ss -Hltnp")
+	if err != nil {
+		return Result{Status: Fail, Message: fmt.Sprintf("failed to list the ports in use: %s", err)}
+	}
+
+	var used []string
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		local := fields[3]
+		port, err := strconv.Atoi(local[strings.LastIndex(local, ":")+1:])
+		if err != nil || !slices.Contains(ports, port) || isK0sProcess(line) {
+			continue
+		}
+		process := "another process"
+		if len(fields) > 5 {
+			process = fields[5]
+		}
+		used = append(used, fmt.Sprintf("%d (%s)", port, process))
+	}
+	if len(used) > 0 {
+		return Result{
+			Status:      Fail,
+			Message:     fmt.Sprintf("ports %s are in use", strings.Join(used, ", ")),
+			Remediation: "Stop the processes that listen on the ports, e.g. another Kubernetes distribution, or use another host.",
+		}
+	}
+	return Result{Status: Pass, Message: fmt.Sprintf("ports %s are free", joinInts(ports))}
+}
+
+// isK0sProcess returns true if the listening socket of the ss output belongs to k0s
+func isK0sProcess(line string) bool {
+	for _, process := range k0sProcesses {
+		if strings.Contains(line, `(("`+process) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkResources(ctx context.Context, host *Host) Result {
+	required := requirements[host.Role]
+
+	out, err := host.run("grep MemTotal /proc/meminfo")
+	if err != nil {
+		return Result{Status: Fail, Message: fmt.Sprintf("failed to get the memory: %s", err)}
+	}
+	fields := strings.Fields(out)
+	if len(fields) < 2 {
+		return Result{Status: Fail, Message: fmt.Sprintf("unexpected memory info: %q", out)}
+	}
+	memoryKiB, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return Result{Status: Fail, Message: fmt.Sprintf("unexpected memory info: %q", out)}
+	}
+
+	// k0s keeps its data and the images in /var/lib
+	out, err = host.run("df -Pk /var/lib | tail -n 1")
+	if err != nil {
+		return Result{Status: Fail, Message: fmt.Sprintf("failed to get the free disk space: %s", err)}
+	}
+	fields = strings.Fields(out)
+	if len(fields) < 4 {
+		return Result{Status: Fail, Message: fmt.Sprintf("unexpected output of df: %q", out)}
+	}
+	diskKiB, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return Result{Status: Fail, Message: fmt.Sprintf("unexpected output of df: %q", out)}
+	}
+
+	memory, disk := memoryKiB*1024, diskKiB*1024
+	message := fmt.Sprintf("%s of memory, %s free in /var/lib", formatBytes(memory), formatBytes(disk))
+	if memory < required.memory || disk < required.disk {
+		return Result{
+			Status:      Fail,
+			Message:     message,
+			Remediation: fmt.Sprintf("A %s needs at least %s of memory and %s of free disk space.", host.Role, formatBytes(required.memory), formatBytes(required.disk)),
+		}
+	}
+	return Result{Status: Pass, Message: message}
+}
+
+func checkTimeSync(ctx context.Context, host *Host) Result {
+	before := time.Now()
+	out, err := host.run("date +%s")
+	if err != nil {
+		return Result{Status: Fail, Message: fmt.Sprintf("failed to get the time: %s", err)}
+	}
+	seconds, err := strconv.ParseInt(out, 10, 64)
+	if err != nil {
+		return Result{Status: Fail, Message: fmt.Sprintf("unexpected output of date: %q", out)}
+	}
+	// the command took some time, compare with the middle of the round trip
+	local := before.Add(time.Since(before) / 2)
+	skew := time.Unix(seconds, 0).Sub(local).Round(time.Second)
+	if skew.Abs() > maxClockSkew {
+		return Result{
+			Status:      Fail,
+			Message:     fmt.Sprintf("clock is off by %s", skew),
+			Remediation: "Synchronize the clock of the host with NTP, e.g. with chrony or systemd-timesyncd.",
+		}
+	}
+
+	out, err = host.run("timedatectl show -p NTPSynchronized --value")
+	if err != nil {
+		return Result{Status: Warn, Message: fmt.Sprintf("clock is off by %s, but the time synchronization could not be checked", skew)}
+	}
+	if out != "yes" {
+		return Result{
+			Status:      Warn,
+			Message:     fmt.Sprintf("clock is off by %s and is not synchronized", skew),
+			Remediation: "Enable NTP on the host, e.g. with timedatectl set-ntp true, so that the clocks of the nodes do not drift apart.",
+		}
+	}
+	return Result{Status: Pass, Message: fmt.Sprintf("clock is synchronized, off by %s", skew)}
+}
+
+func checkControllerConnectivity(ctx context.Context, host *Host) Result {
+	var unreachable []string
+	for _, controller := range host.Controllers {
+		for _, port := range peerPorts {
+			address := fmt.Sprintf("%s:%d", controller.SSH.Address, port)
+			// the exit code is printed last; nothing listens on the ports yet, so a refused connection is enough
+			out, _ := host.run(fmt.Sprintf("timeout %d bash -c '</dev/tcp/%s/%d' 2>&1; echo $?", connectTimeout, controller.SSH.Address, port))
+			lines := strings.Split(out, "\n")
+			code := lines[len(lines)-1]
+			switch {
+			case code == "0" || strings.Contains(out, "refused"):
+			case code == "124":
+				unreachable = append(unreachable, address+" (timed out)")
+			default:
+				unreachable = append(unreachable, address)
+			}
+		}
+	}
+	if len(unreachable) > 0 {
+		return Result{
+			Status:      Fail,
+			Message:     fmt.Sprintf("cannot connect to %s", strings.Join(unreachable, ", ")),
+			Remediation: fmt.Sprintf("Allow the controllers to connect to each other on ports %s in the firewalls and security groups.", joinInts(peerPorts)),
+		}
+	}
+	return Result{Status: Pass, Message: fmt.Sprintf("%d other controllers are reachable", len(host.Controllers))}
+}
+
+func formatBytes(bytes int64) string {
+	return fmt.Sprintf("%.1fGiB", float64(bytes)/gib)
+}
+
+func joinInts(values []int) string {
+	var s []string
+	for _, value := range values {
+		s = append(s, strconv.Itoa(value))
+	}
+	return strings.Join(s, ", ")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
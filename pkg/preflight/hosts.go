@@ -0,0 +1,186 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+// Runner runs commands on the hosts, e.g. an *ssh.Pool
+type Runner interface {
+	Run(host types.SSHHost, command string) (string, error)
+}
+
+// Host is a host the host checks run on
+type Host struct {
+	types.Host
+	Runner Runner
+	// Controllers are the other controllers of the cluster
+	Controllers []types.Host
+}
+
+// run runs the command on the host
+func (h *Host) run(command string) (string, error) {
+	return h.Runner.Run(*h.SSH, command)
+}
+
+// isController returns true if the host runs a k0s controller
+func (h *Host) isController() bool {
+	return isControllerRole(h.Role)
+}
+
+// isWorker returns true if the host runs the workloads
+func (h *Host) isWorker() bool {
+	return h.Role != "controller"
+}
+
+func isControllerRole(role string) bool {
+	return strings.Contains(role, "controller") || role == "single"
+}
+
+// HostCheck is a single check of a host
+type HostCheck struct {
+	Name string
+	// Applies returns false for the hosts the check does not apply to, e.g. the workers for the controller checks
+	Applies func(host *Host) bool
+	Run     func(ctx context.Context, host *Host) Result
+}
+
+// HostReport are the results of the host checks, by host address
+type HostReport struct {
+	// Checks are the names of the checks, in the order they ran
+	Checks []string
+	// Hosts are the addresses of the hosts, in the order of the blueprint
+	Hosts   []string
+	Results map[string][]Result
+}
+
+// RunHosts runs the checks on all the hosts in parallel and returns their results
+// The checks of a host run one after the other; a host that cannot be reached fails all its checks.
+func RunHosts(ctx context.Context, runner Runner, hosts []types.Host, checks []HostCheck) HostReport {
+	report := HostReport{Results: make(map[string][]Result)}
+	for _, check := range checks {
+		report.Checks = append(report.Checks, check.Name)
+	}
+
+	var controllers []types.Host
+	for _, host := range hosts {
+		if host.SSH != nil && isControllerRole(host.Role) {
+			controllers = append(controllers, host)
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, host := range hosts {
+		if host.SSH == nil {
+			continue
+		}
+		report.Hosts = append(report.Hosts, host.SSH.Address)
+
+		target := &Host{Host: host, Runner: runner}
+		for _, controller := range controllers {
+			if controller.SSH.Address != host.SSH.Address {
+				target.Controllers = append(target.Controllers, controller)
+			}
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results := runHost(ctx, target, checks)
+			mu.Lock()
+			report.Results[host.SSH.Address] = results
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
+
+// runHost runs the checks on the host
+func runHost(ctx context.Context, host *Host, checks []HostCheck) []Result {
+	var results []Result
+	if _, err := host.run("true"); err != nil {
+		for _, check := range checks {
+			results = append(results, Result{
+				Check:       check.Name,
+				Status:      Fail,
+				Message:     fmt.Sprintf("host is not reachable: %s", err),
+				Remediation: "Check the address, port, user and key of the host, and that it accepts SSH connections from this machine.",
+			})
+		}
+		return results
+	}
+
+	for _, check := range checks {
+		if check.Applies != nil && !check.Applies(host) {
+			continue
+		}
+		log.Debug().Msgf("Running host check %q on %s", check.Name, host.SSH.Address)
+		result := check.Run(ctx, host)
+		result.Check = check.Name
+		results = append(results, result)
+	}
+	return results
+}
+
+// Failed returns the results of the checks that failed, by host address
+func (r HostReport) Failed() map[string][]Result {
+	failed := make(map[string][]Result)
+	for host, results := range r.Results {
+		for _, result := range results {
+			if result.Status == Fail {
+				failed[host] = append(failed[host], result)
+			}
+		}
+	}
+	return failed
+}
+
+// Print writes the results in a matrix of the hosts and the checks, followed by the details of the checks that did not pass
+// The checks that do not apply to a host are shown as "-".
+func (r HostReport) Print(w io.Writer) {
+	width := 16
+	for _, host := range r.Hosts {
+		width = max(width, len(host)+2)
+	}
+
+	fmt.Fprintf(w, "%-*s", width, "HOST")
+	for _, check := range r.Checks {
+		fmt.Fprintf(w, " %-*s", len(check), strings.ToUpper(check))
+	}
+	fmt.Fprintln(w)
+
+	for _, host := range r.Hosts {
+		fmt.Fprintf(w, "%-*s", width, host)
+		for _, check := range r.Checks {
+			status := "-"
+			if i := slices.IndexFunc(r.Results[host], func(result Result) bool { return result.Check == check }); i >= 0 {
+				status = strings.ToUpper(string(r.Results[host][i].Status))
+			}
+			fmt.Fprintf(w, " %-*s", len(check), status)
+		}
+		fmt.Fprintln(w)
+	}
+
+	for _, host := range r.Hosts {
+		for _, result := range r.Results[host] {
+			if result.Status == Pass {
+				continue
+			}
+			fmt.Fprintf(w, "\n%s %s: %s\n", host, result.Check, result.Message)
+			if result.Remediation != "" {
+				fmt.Fprintf(w, "  %s\n", result.Remediation)
+			}
+		}
+	}
+}
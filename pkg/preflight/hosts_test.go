@@ -0,0 +1,167 @@
+package preflight
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+// fakeRunner returns the output of the commands by host address, and fails the commands it has no output for
+type fakeRunner struct {
+	outputs     map[string]map[string]string
+	unreachable map[string]bool
+}
+
+func (r *fakeRunner) Run(host types.SSHHost, command string) (string, error) {
+	if r.unreachable[host.Address] {
+		return "", errors.New("connection refused")
+	}
+	if command == "true" {
+		return "", nil
+	}
+	out, ok := r.outputs[host.Address][command]
+	if !ok {
+		return "", fmt.Errorf("%q failed", command)
+	}
+	return out, nil
+}
+
+// healthyHost returns the outputs of the commands of a host that passes all the checks
+func healthyHost() map[string]string {
+	return map[string]string{
+		"uname -sm": "Linux x86_64",
+		"grep -q '^overlay ' /proc/modules || modinfo overlay":           "",
+		"grep -q '^br_netfilter ' /proc/modules || modinfo br_netfilter": "",
+		"cat /proc/sys/net/ipv4/ip_forward":                              "1",
+		"cat /proc/sys/net/bridge/bridge-nf-call-iptables":               "1",
+		"cat /proc/sys/net/bridge/bridge-nf-call-ip6tables":              "1",
+		"sudo ss -Hltnp":                                      `LISTEN 0 128 0.0.0.0:22 0.0.0.0:* users:(("sshd",pid=712,fd=3))`,
+		"grep MemTotal /proc/meminfo":                         "MemTotal:        4026524 kB",
+		"df -Pk /var/lib | tail -n 1":                         "/dev/sda1 41152736 5217364 35935372 13% /",
+		"date +%s":                                            fmt.Sprint(time.Now().Unix()),
+		"timedatectl show -p NTPSynchronized --value":         "yes",
+		"timeout 3 bash -c '</dev/tcp/c2/6443' 2>&1; echo $?": "bash: connect: Connection refused\nbash: line 1: /dev/tcp/c2/6443: Connection refused\n1",
+		"timeout 3 bash -c '</dev/tcp/c2/9443' 2>&1; echo $?": "0",
+		"timeout 3 bash -c '</dev/tcp/c2/2380' 2>&1; echo $?": "0",
+	}
+}
+
+func checkHost(role string, outputs map[string]string) *Host {
+	host := &Host{
+		Host:   types.Host{SSH: &types.SSHHost{Address: "c1"}, Role: role},
+		Runner: &fakeRunner{outputs: map[string]map[string]string{"c1": outputs}},
+	}
+	if isControllerRole(role) {
+		host.Controllers = []types.Host{{SSH: &types.SSHHost{Address: "c2"}, Role: "controller"}}
+	}
+	return host
+}
+
+// TestHostChecks tests the checks of a host against the outputs of its commands
+func TestHostChecks(t *testing.T) {
+	checks := make(map[string]HostCheck)
+	for _, check := range HostChecks() {
+		checks[check.Name] = check
+	}
+
+	tests := map[string]struct {
+		check   string
+		role    string
+		outputs map[string]string
+		want    Status
+		message string
+	}{
+		"supported arch":        {check: "os-arch", want: Pass},
+		"unsupported arch":      {check: "os-arch", outputs: map[string]string{"uname -sm": "Linux ppc64le"}, want: Fail, message: "architecture ppc64le"},
+		"not linux":             {check: "os-arch", outputs: map[string]string{"uname -sm": "Darwin arm64"}, want: Fail, message: "operating system Darwin"},
+		"modules available":     {check: "kernel-modules", want: Pass},
+		"missing module":        {check: "kernel-modules", outputs: map[string]string{"grep -q '^br_netfilter ' /proc/modules || modinfo br_netfilter": "-"}, want: Fail, message: "br_netfilter"},
+		"sysctls set":           {check: "sysctls", want: Pass},
+		"forwarding disabled":   {check: "sysctls", outputs: map[string]string{"cat /proc/sys/net/ipv4/ip_forward": "0"}, want: Warn, message: "net.ipv4.ip_forward is 0"},
+		"free ports":            {check: "ports", want: Pass, message: "6443, 8132, 9443, 2380, 10250"},
+		"worker ports":          {check: "ports", role: "worker", want: Pass, message: "ports 10250 are free"},
+		"port in use":           {check: "ports", outputs: map[string]string{"sudo ss -Hltnp": `LISTEN 0 4096 *:6443 *:* users:(("kube-apiserver",pid=9,fd=3))` + "\n" + `LISTEN 0 4096 [::]:10250 [::]:* users:(("kubelet",pid=1,fd=3))` + "\n" + `LISTEN 0 4096 0.0.0.0:2380 0.0.0.0:* users:(("docker-proxy",pid=8,fd=3))`}, want: Fail, message: `ports 2380 (users:(("docker-proxy",pid=8,fd=3))) are in use`},
+		"enough resources":      {check: "resources", want: Pass, message: "3.8GiB of memory, 34.3GiB free in /var/lib"},
+		"not enough memory":     {check: "resources", outputs: map[string]string{"grep MemTotal /proc/meminfo": "MemTotal: 786432 kB"}, want: Fail},
+		"worker memory":         {check: "resources", role: "worker", outputs: map[string]string{"grep MemTotal /proc/meminfo": "MemTotal: 786432 kB"}, want: Pass},
+		"not enough disk":       {check: "resources", outputs: map[string]string{"df -Pk /var/lib | tail -n 1": "/dev/sda1 41152736 40152736 1000000 98% /"}, want: Fail},
+		"synchronized":          {check: "time-sync", want: Pass},
+		"clock skew":            {check: "time-sync", outputs: map[string]string{"date +%s": fmt.Sprint(time.Now().Add(-2 * time.Minute).Unix())}, want: Fail, message: "clock is off by -2m"},
+		"not synchronized":      {check: "time-sync", outputs: map[string]string{"timedatectl show -p NTPSynchronized --value": "no"}, want: Warn},
+		"controllers reachable": {check: "controller-connectivity", want: Pass},
+		"controller filtered":   {check: "controller-connectivity", outputs: map[string]string{"timeout 3 bash -c '</dev/tcp/c2/2380' 2>&1; echo $?": "124"}, want: Fail, message: "c2:2380 (timed out)"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			outputs := healthyHost()
+			for command, out := range tc.outputs {
+				if out == "-" {
+					delete(outputs, command)
+				} else {
+					outputs[command] = out
+				}
+			}
+			role := tc.role
+			if role == "" {
+				role = "controller+worker"
+			}
+
+			result := checks[tc.check].Run(context.Background(), checkHost(role, outputs))
+			g.Expect(result.Status).To(Equal(tc.want), result.Message)
+			g.Expect(result.Message).To(ContainSubstring(tc.message))
+		})
+	}
+}
+
+// TestRunHosts tests that the checks run on every host, only where they apply, and that an unreachable host fails them all
+func TestRunHosts(t *testing.T) {
+	g := NewWithT(t)
+
+	hosts := []types.Host{
+		{SSH: &types.SSHHost{Address: "c1"}, Role: "controller"},
+		{SSH: &types.SSHHost{Address: "c2"}, Role: "controller"},
+		{SSH: &types.SSHHost{Address: "w1"}, Role: "worker"},
+		{SSH: &types.SSHHost{Address: "w2"}, Role: "worker"},
+	}
+	c1 := healthyHost()
+	c1["timeout 3 bash -c '</dev/tcp/c2/6443' 2>&1; echo $?"] = "0"
+	c2 := healthyHost()
+	for _, port := range peerPorts {
+		c2[fmt.Sprintf("timeout 3 bash -c '</dev/tcp/c1/%d' 2>&1; echo $?", port)] = "0"
+	}
+	w1 := healthyHost()
+	w1["uname -sm"] = "Linux s390x"
+	runner := &fakeRunner{
+		outputs:     map[string]map[string]string{"c1": c1, "c2": c2, "w1": w1},
+		unreachable: map[string]bool{"w2": true},
+	}
+
+	report := RunHosts(context.Background(), runner, hosts, HostChecks())
+	g.Expect(report.Hosts).To(Equal([]string{"c1", "c2", "w1", "w2"}))
+
+	failed := report.Failed()
+	g.Expect(failed).To(HaveLen(2))
+	g.Expect(failed["w1"]).To(HaveLen(1))
+	g.Expect(failed["w1"][0].Check).To(Equal("os-arch"))
+	g.Expect(failed["w2"]).To(HaveLen(len(HostChecks())))
+	g.Expect(failed["w2"][0].Message).To(ContainSubstring("host is not reachable"))
+
+	var out bytes.Buffer
+	report.Print(&out)
+	lines := strings.Split(out.String(), "\n")
+	g.Expect(strings.Fields(lines[0])).To(Equal([]string{"HOST", "OS-ARCH", "KERNEL-MODULES", "SYSCTLS", "PORTS", "RESOURCES", "TIME-SYNC", "CONTROLLER-CONNECTIVITY"}))
+	g.Expect(strings.Fields(lines[1])).To(Equal([]string{"c1", "PASS", "-", "-", "PASS", "PASS", "PASS", "PASS"}))
+	g.Expect(strings.Fields(lines[3])).To(Equal([]string{"w1", "FAIL", "PASS", "PASS", "PASS", "PASS", "PASS", "-"}))
+	g.Expect(out.String()).To(ContainSubstring("w1 os-arch: architecture s390x is not supported"))
+}
@@ -0,0 +1,111 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/rest"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/k8s"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+// Status is the outcome of a preflight check
+type Status string
+
+const (
+	// Pass means the cluster meets the requirement
+	Pass Status = "pass"
+	// Warn means the blueprint can be applied, but something may not work as expected
+	Warn Status = "warn"
+	// Fail means the blueprint cannot be applied until the problem is fixed
+	Fail Status = "fail"
+)
+
+// Result is the outcome of a preflight check with a human-readable explanation
+type Result struct {
+	Check       string
+	Status      Status
+	Message     string
+	Remediation string
+}
+
+// Cluster is the cluster the checks run against
+type Cluster struct {
+	Clients   *k8s.Clients
+	Config    *rest.Config
+	Blueprint *types.Blueprint
+	// Manifest are the objects of the Blueprint Operator manifest that will be applied
+	Manifest []unstructured.Unstructured
+}
+
+// Check is a single preflight check
+type Check struct {
+	Name string
+	// Critical checks stop the suite when they fail, because the checks after them depend on them
+	Critical bool
+	Run      func(ctx context.Context, cluster *Cluster) Result
+}
+
+// Checks returns the preflight checks run before a blueprint is applied, in the order they run
+func Checks() []Check {
+	return []Check{
+		{Name: "api-server", Critical: true, Run: checkAPIServer},
+		{Name: "server-version", Run: checkServerVersion},
+		{Name: "rbac", Run: checkRBAC},
+		{Name: "default-storage-class", Run: checkDefaultStorageClass},
+		{Name: "coredns", Run: checkCoreDNS},
+		{Name: "cert-manager-crds", Run: checkCertManager},
+		{Name: "blueprint-namespace", Run: checkBlueprintNamespace},
+	}
+}
+
+// Report are the results of the preflight checks
+type Report []Result
+
+// Run runs the checks in order and returns their results
+func Run(ctx context.Context, cluster *Cluster, checks []Check) Report {
+	var report Report
+	for _, check := range checks {
+		log.Debug().Msgf("Running preflight check %q", check.Name)
+		result := check.Run(ctx, cluster)
+		result.Check = check.Name
+		report = append(report, result)
+
+		if result.Status == Fail && check.Critical {
+			log.Debug().Msgf("Skipping the remaining preflight checks because %q failed", check.Name)
+			break
+		}
+	}
+	return report
+}
+
+// Failed returns the results of the checks that failed
+func (r Report) Failed() []Result {
+	var failed []Result
+	for _, result := range r {
+		if result.Status == Fail {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Print writes the results in a table, followed by the remediation of the checks that did not pass
+func (r Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "%-6s %-24s %s\n", "STATUS", "CHECK", "MESSAGE")
+	for _, result := range r {
+		fmt.Fprintf(w, "%-6s %-24s %s\n", strings.ToUpper(string(result.Status)), result.Check, result.Message)
+	}
+
+	for _, result := range r {
+		if result.Status == Pass || result.Remediation == "" {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s: %s\n", result.Check, result.Remediation)
+	}
+}
@@ -0,0 +1,202 @@
+package preflight
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/discovery/cached/memory"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	fakekubernetes "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/k8s"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+// TestServerVersion tests that the server version is checked against the supported range
+func TestServerVersion(t *testing.T) {
+	tests := map[string]struct {
+		gitVersion string
+		want       Status
+	}{
+		"supported":            {gitVersion: "v1.30.4+k0s", want: Pass},
+		"managed distribution": {gitVersion: "v1.29.3-eks-adc7111", want: Pass},
+		"too old":              {gitVersion: "v1.25.0", want: Fail},
+		"not tested yet":       {gitVersion: "v1.32.0", want: Warn},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			cluster, client := testCluster()
+			client.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: tc.gitVersion}
+
+			result := checkServerVersion(context.Background(), cluster)
+			g.Expect(result.Status).To(Equal(tc.want), result.Message)
+		})
+	}
+}
+
+// TestRBAC tests that the verbs needed on the manifest objects are looked up in the rules review
+func TestRBAC(t *testing.T) {
+	tests := map[string]struct {
+		rules      []authorizationv1.ResourceRule
+		incomplete bool
+		want       Status
+	}{
+		"cluster admin": {
+			rules: []authorizationv1.ResourceRule{{Verbs: []string{"*"}, APIGroups: []string{"*"}, Resources: []string{"*"}}},
+			want:  Pass,
+		},
+		"missing cluster role permissions": {
+			rules: []authorizationv1.ResourceRule{{Verbs: []string{"*"}, APIGroups: []string{""}, Resources: []string{"configmaps"}}},
+			want:  Fail,
+		},
+		"create restricted by name": {
+			rules: []authorizationv1.ResourceRule{
+				{Verbs: []string{"*"}, APIGroups: []string{""}, Resources: []string{"configmaps"}},
+				{Verbs: []string{"*"}, APIGroups: []string{"rbac.authorization.k8s.io"}, Resources: []string{"clusterroles"}, ResourceNames: []string{"manager"}},
+			},
+			want: Fail,
+		},
+		"incomplete review": {
+			rules:      []authorizationv1.ResourceRule{{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"configmaps"}}},
+			incomplete: true,
+			want:       Warn,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			cluster, client := testCluster()
+			client.PrependReactor("create", "selfsubjectrulesreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				review := &authorizationv1.SelfSubjectRulesReview{Status: authorizationv1.SubjectRulesReviewStatus{ResourceRules: tc.rules, Incomplete: tc.incomplete}}
+				return true, review, nil
+			})
+			cluster.Manifest = []unstructured.Unstructured{
+				object("v1", "ConfigMap", "blueprint-system", "config"),
+				object("rbac.authorization.k8s.io/v1", "ClusterRole", "", "manager"),
+				object("blueprint.mirantis.com/v1alpha1", "Blueprint", "blueprint-system", "defined-by-a-crd"),
+			}
+
+			result := checkRBAC(context.Background(), cluster)
+			g.Expect(result.Status).To(Equal(tc.want), result.Message)
+		})
+	}
+}
+
+// TestDefaultStorageClass tests that exactly one default storage class is expected
+func TestDefaultStorageClass(t *testing.T) {
+	tests := map[string]struct {
+		defaults []bool
+		want     Status
+	}{
+		"no storage class":  {want: Warn},
+		"no default":        {defaults: []bool{false, false}, want: Warn},
+		"one default":       {defaults: []bool{false, true}, want: Pass},
+		"multiple defaults": {defaults: []bool{true, true}, want: Warn},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			cluster, client := testCluster()
+			for i, isDefault := range tc.defaults {
+				class := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: string(rune('a' + i))}}
+				if isDefault {
+					class.Annotations = map[string]string{defaultStorageClassAnnotation: "true"}
+				}
+				g.Expect(client.Tracker().Add(class)).To(Succeed())
+			}
+
+			result := checkDefaultStorageClass(context.Background(), cluster)
+			g.Expect(result.Status).To(Equal(tc.want), result.Message)
+		})
+	}
+}
+
+// TestBlueprintNamespace tests that a terminating blueprint namespace fails the check
+func TestBlueprintNamespace(t *testing.T) {
+	g := NewWithT(t)
+
+	cluster, client := testCluster()
+	g.Expect(checkBlueprintNamespace(context.Background(), cluster).Status).To(Equal(Pass))
+
+	namespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "blueprint-system"},
+		Status: corev1.NamespaceStatus{
+			Phase: corev1.NamespaceTerminating,
+			Conditions: []corev1.NamespaceCondition{
+				{Type: corev1.NamespaceFinalizersRemaining, Status: corev1.ConditionTrue, Message: "Some content in the namespace has finalizers remaining"},
+			},
+		},
+	}
+	g.Expect(client.Tracker().Add(namespace)).To(Succeed())
+
+	result := checkBlueprintNamespace(context.Background(), cluster)
+	g.Expect(result.Status).To(Equal(Fail))
+	g.Expect(result.Message).To(ContainSubstring("finalizers remaining"))
+	g.Expect(result.Remediation).ToNot(BeEmpty())
+}
+
+// TestRun tests that the checks stop after a critical check fails
+func TestRun(t *testing.T) {
+	g := NewWithT(t)
+
+	fail := func(ctx context.Context, cluster *Cluster) Result { return Result{Status: Fail} }
+	pass := func(ctx context.Context, cluster *Cluster) Result { return Result{Status: Pass} }
+
+	report := Run(context.Background(), &Cluster{}, []Check{
+		{Name: "optional", Run: fail},
+		{Name: "critical", Critical: true, Run: fail},
+		{Name: "skipped", Run: pass},
+	})
+	g.Expect(report).To(HaveLen(2))
+	g.Expect(report[1].Check).To(Equal("critical"))
+	g.Expect(report.Failed()).To(HaveLen(2))
+}
+
+func testCluster() (*Cluster, *fakekubernetes.Clientset) {
+	client := fakekubernetes.NewSimpleClientset()
+	discovery := client.Discovery().(*fakediscovery.FakeDiscovery)
+	discovery.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap", Namespaced: true}},
+		},
+		{
+			GroupVersion: "rbac.authorization.k8s.io/v1",
+			APIResources: []metav1.APIResource{{Name: "clusterroles", Kind: "ClusterRole", Namespaced: false}},
+		},
+	}
+
+	cluster := &Cluster{
+		Clients:   k8s.NewClients(client, fakedynamic.NewSimpleDynamicClient(runtime.NewScheme()), memory.NewMemCacheClient(discovery)),
+		Config:    &rest.Config{Host: "https://127.0.0.1:6443"},
+		Blueprint: &types.Blueprint{},
+	}
+	return cluster, client
+}
+
+func object(apiVersion string, kind string, namespace string, name string) unstructured.Unstructured {
+	obj := unstructured.Unstructured{}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
@@ -0,0 +1,134 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// manifestVerbs are the verbs bctl uses on the objects of the operator manifest to apply and remove them
+var manifestVerbs = []string{"get", "create", "update", "delete"}
+
+// permission is a resource bctl needs access to
+type permission struct {
+	group    string
+	resource string
+	name     string
+}
+
+func (p permission) String() string {
+	if p.group == "" {
+		return p.resource
+	}
+	return p.resource + "." + p.group
+}
+
+func checkRBAC(ctx context.Context, cluster *Cluster) Result {
+	required, err := requiredPermissions(cluster)
+	if err != nil {
+		return Result{Status: Warn, Message: err.Error()}
+	}
+
+	namespaces := make([]string, 0, len(required))
+	for namespace := range required {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	var missing []string
+	var incomplete []string
+	for _, namespace := range namespaces {
+		review := &authorizationv1.SelfSubjectRulesReview{Spec: authorizationv1.SelfSubjectRulesReviewSpec{Namespace: namespace}}
+		review, err = cluster.Clients.Client.AuthorizationV1().SelfSubjectRulesReviews().Create(ctx, review, metav1.CreateOptions{})
+		if err != nil {
+			return Result{Status: Warn, Message: fmt.Sprintf("failed to review the rules in namespace %q: %s", namespace, err)}
+		}
+		if review.Status.Incomplete {
+			incomplete = append(incomplete, namespace)
+		}
+
+		for _, p := range required[namespace] {
+			var denied []string
+			for _, verb := range manifestVerbs {
+				if !allowed(review.Status.ResourceRules, verb, p) {
+					denied = append(denied, verb)
+				}
+			}
+			if len(denied) > 0 {
+				missing = append(missing, fmt.Sprintf("%s %s", strings.Join(denied, ","), p))
+			}
+		}
+	}
+	missing = slices.Compact(missing)
+
+	switch {
+	case len(missing) > 0 && len(incomplete) == 0:
+		return Result{
+			Status:      Fail,
+			Message:     fmt.Sprintf("missing permissions: %s", strings.Join(missing, "; ")),
+			Remediation: "Use a kubeconfig user that is bound to the cluster-admin cluster role, or grant the missing permissions to the user.",
+		}
+	case len(missing) > 0:
+		return Result{
+			Status:      Warn,
+			Message:     fmt.Sprintf("permissions not found in the rules review: %s", strings.Join(missing, "; ")),
+			Remediation: "The rules review is incomplete because an authorizer of the cluster cannot list its rules. Check the permissions with: kubectl auth can-i",
+		}
+	case len(incomplete) > 0:
+		return Result{
+			Status:      Warn,
+			Message:     fmt.Sprintf("all permissions found, but the rules review is incomplete in namespaces: %s", strings.Join(incomplete, ", ")),
+			Remediation: "Some authorizers of the cluster cannot list their rules, so denials may not be reported.",
+		}
+	}
+	return Result{Status: Pass, Message: fmt.Sprintf("%s allowed on all objects of the operator manifest", strings.Join(manifestVerbs, ", "))}
+}
+
+// requiredPermissions returns the permissions needed on the objects of the manifest by the namespace they are in
+// Cluster-scoped objects are reviewed in the default namespace, since a rules review always includes the cluster-wide rules
+func requiredPermissions(cluster *Cluster) (map[string][]permission, error) {
+	required := map[string][]permission{}
+	for _, obj := range cluster.Manifest {
+		gvk := obj.GroupVersionKind()
+		mapping, err := cluster.Clients.Mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if meta.IsNoMatchError(err) {
+			// the kind is defined by a CRD of the manifest
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the resource of %s %q: %w", obj.GetKind(), obj.GetName(), err)
+		}
+
+		namespace := metav1.NamespaceDefault
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace && obj.GetNamespace() != "" {
+			namespace = obj.GetNamespace()
+		}
+		p := permission{group: mapping.Resource.Group, resource: mapping.Resource.Resource, name: obj.GetName()}
+		required[namespace] = append(required[namespace], p)
+	}
+	return required, nil
+}
+
+// allowed returns true if a rule allows the verb on the resource
+func allowed(rules []authorizationv1.ResourceRule, verb string, p permission) bool {
+	for _, rule := range rules {
+		if !matches(rule.Verbs, verb) || !matches(rule.APIGroups, p.group) || !matches(rule.Resources, p.resource) {
+			continue
+		}
+		// objects cannot be created by name, so create is only allowed by rules without names
+		if len(rule.ResourceNames) == 0 || (verb != "create" && slices.Contains(rule.ResourceNames, p.name)) {
+			return true
+		}
+	}
+	return false
+}
+
+func matches(values []string, value string) bool {
+	return slices.Contains(values, "*") || slices.Contains(values, value)
+}
@@ -0,0 +1,131 @@
+package readiness
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// DefaultFailureGracePeriod is how long the containers of a resource may stay in a failure state before it fails
+// The kubelet retries the image pulls, and a container may crash a few times until the services it needs are up.
+const DefaultFailureGracePeriod = 2 * time.Minute
+
+// podOwnerKinds are the kinds whose pods are checked for containers that cannot start
+var podOwnerKinds = []schema.GroupKind{
+	{Group: "apps", Kind: "Deployment"},
+	{Group: "apps", Kind: "StatefulSet"},
+	{Group: "apps", Kind: "DaemonSet"},
+}
+
+// containerFailure is a container waiting for one of the PodFailureReasons
+type containerFailure struct {
+	pod     string
+	name    string
+	reason  string
+	message string
+}
+
+// containerFailures returns the containers of the pod that are waiting for one of the PodFailureReasons
+func containerFailures(pod *unstructured.Unstructured) []containerFailure {
+	var failures []containerFailure
+	statuses, _, _ := unstructured.NestedSlice(pod.Object, "status", "containerStatuses")
+	for _, s := range statuses {
+		status, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		reason, _, _ := unstructured.NestedString(status, "state", "waiting", "reason")
+		if !slices.Contains(PodFailureReasons, reason) {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(status, "name")
+		message, _, _ := unstructured.NestedString(status, "state", "waiting", "message")
+		failures = append(failures, containerFailure{
+			pod:     pod.GetNamespace() + "/" + pod.GetName(),
+			name:    name,
+			reason:  reason,
+			message: message,
+		})
+	}
+	return failures
+}
+
+// failureTracker records since when the containers of the pods of the resources are in a failure state
+type failureTracker struct {
+	grace time.Duration
+	since map[string]time.Time
+}
+
+func newFailureTracker(grace time.Duration) *failureTracker {
+	return &failureTracker{grace: grace, since: map[string]time.Time{}}
+}
+
+// check records the failing containers of the pods, and forgets the ones that recovered
+// It returns the first container that has been failing for the grace period.
+func (t *failureTracker) check(pods []unstructured.Unstructured, now time.Time) (Result, bool) {
+	failing := map[string]bool{}
+	var failed *Result
+	for i := range pods {
+		for _, f := range containerFailures(&pods[i]) {
+			// ErrImagePull and ImagePullBackOff alternate while the kubelet retries, so the reason is not part of the key
+			key := f.pod + "/" + f.name
+			failing[key] = true
+			since, ok := t.since[key]
+			if !ok {
+				t.since[key] = now
+				continue
+			}
+			if failed == nil && now.Sub(since) >= t.grace {
+				failed = &Result{Status: Failed, Message: fmt.Sprintf("container %q of pod %s is in %s for %s: %s",
+					f.name, f.pod, f.reason, now.Sub(since).Round(time.Second), f.message)}
+			}
+		}
+	}
+
+	for key := range t.since {
+		if !failing[key] {
+			delete(t.since, key)
+		}
+	}
+	if failed != nil {
+		return *failed, true
+	}
+	return Result{}, false
+}
+
+// podsOf returns the pods of the object: the pod itself, or the pods selected by a workload
+// Other kinds have no pods.
+func (w *Waiter) podsOf(ctx context.Context, obj *unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+	gk := obj.GroupVersionKind().GroupKind()
+	if gk == (schema.GroupKind{Kind: "Pod"}) {
+		return []unstructured.Unstructured{*obj}, nil
+	}
+	if !slices.Contains(podOwnerKinds, gk) {
+		return nil, nil
+	}
+
+	content, found, err := unstructured.NestedMap(obj.Object, "spec", "selector")
+	if err != nil || !found {
+		return nil, err
+	}
+	var labelSelector metav1.LabelSelector
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, &labelSelector); err != nil {
+		return nil, fmt.Errorf("invalid selector: %w", err)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(&labelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %w", err)
+	}
+
+	pods, err := w.client.Resource(podsResource).Namespace(obj.GetNamespace()).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	return pods.Items, nil
+}
@@ -0,0 +1,114 @@
+package readiness
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+)
+
+func rolloutDeployment(available int64) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: object("apps/v1", "Deployment", 2,
+		map[string]interface{}{
+			"replicas": int64(1),
+			"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "operator"}},
+		},
+		map[string]interface{}{"observedGeneration": int64(2), "replicas": int64(1), "updatedReplicas": int64(1), "availableReplicas": available})}
+	obj.SetNamespace("blueprint-system")
+	obj.SetName("controller-manager")
+	return obj
+}
+
+func waitingPod(name string, app string, reason string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: object("v1", "Pod", 0, nil,
+		map[string]interface{}{"phase": "Pending", "containerStatuses": []interface{}{
+			map[string]interface{}{"name": "manager", "state": map[string]interface{}{"waiting": map[string]interface{}{"reason": reason}}},
+		}})}
+	obj.SetNamespace("blueprint-system")
+	obj.SetName(name)
+	obj.SetLabels(map[string]string{"app": app})
+	return obj
+}
+
+// TestWaitForDeployment tests that a rollout fails once its pods are stuck for the grace period
+func TestWaitForDeployment(t *testing.T) {
+	tests := map[string]struct {
+		objs    []runtime.Object
+		grace   time.Duration
+		wantErr string
+	}{
+		"available": {
+			objs: []runtime.Object{rolloutDeployment(1)},
+		},
+		"crash loop for the grace period": {
+			objs:    []runtime.Object{rolloutDeployment(0), waitingPod("controller-manager-1", "operator", "CrashLoopBackOff")},
+			grace:   50 * time.Millisecond,
+			wantErr: `deployments/blueprint-system/controller-manager failed: container "manager" of pod blueprint-system/controller-manager-1 is in CrashLoopBackOff`,
+		},
+		"image pull error within the grace period": {
+			objs:    []runtime.Object{rolloutDeployment(0), waitingPod("controller-manager-1", "operator", "ErrImagePull")},
+			grace:   time.Hour,
+			wantErr: "timed out waiting for deployments/blueprint-system/controller-manager: InProgress (0/1 replicas available)",
+		},
+		"crash loop of another deployment": {
+			objs:    []runtime.Object{rolloutDeployment(0), waitingPod("webhook-1", "webhook", "CrashLoopBackOff")},
+			grace:   50 * time.Millisecond,
+			wantErr: "timed out waiting",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			client := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+				podsResource: "PodList",
+			}, tc.objs...)
+			waiter := NewWaiter(client)
+			waiter.Interval = 10 * time.Millisecond
+			waiter.FailureGracePeriod = tc.grace
+
+			err := waiter.Wait(context.Background(), Deployment("blueprint-system", "controller-manager", 300*time.Millisecond))
+			if tc.wantErr == "" {
+				g.Expect(err).ToNot(HaveOccurred())
+			} else {
+				g.Expect(err).To(MatchError(ContainSubstring(tc.wantErr)))
+			}
+		})
+	}
+}
+
+// TestFailureTracker tests that the failures of the containers are tracked until they recover
+func TestFailureTracker(t *testing.T) {
+	g := NewWithT(t)
+
+	pod := func(reason string) []unstructured.Unstructured {
+		return []unstructured.Unstructured{*waitingPod("controller-manager-1", "operator", reason)}
+	}
+
+	start := time.Now()
+	tracker := newFailureTracker(time.Minute)
+	_, failed := tracker.check(pod("ErrImagePull"), start)
+	g.Expect(failed).To(BeFalse())
+	_, failed = tracker.check(pod("ImagePullBackOff"), start.Add(30*time.Second))
+	g.Expect(failed).To(BeFalse())
+
+	// the image was pulled, so the failure starts over
+	_, failed = tracker.check(pod("ContainerCreating"), start.Add(40*time.Second))
+	g.Expect(failed).To(BeFalse())
+	g.Expect(tracker.since).To(BeEmpty())
+	_, failed = tracker.check(pod("CrashLoopBackOff"), start.Add(50*time.Second))
+	g.Expect(failed).To(BeFalse())
+	_, failed = tracker.check(pod("CrashLoopBackOff"), start.Add(100*time.Second))
+	g.Expect(failed).To(BeFalse())
+
+	result, failed := tracker.check(pod("CrashLoopBackOff"), start.Add(110*time.Second))
+	g.Expect(failed).To(BeTrue())
+	g.Expect(result.Status).To(Equal(Failed))
+	g.Expect(result.Message).To(ContainSubstring("is in CrashLoopBackOff for 1m0s"))
+}
@@ -0,0 +1,287 @@
+package readiness
+
+import (
+	"fmt"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Status is the readiness of a resource
+type Status string
+
+const (
+	// Current means the resource is fully reconciled and ready
+	Current Status = "Current"
+	// InProgress means the resource is still being reconciled
+	InProgress Status = "InProgress"
+	// Failed means the resource will not become ready without intervention
+	Failed Status = "Failed"
+	// NotFound means the resource does not exist
+	NotFound Status = "NotFound"
+)
+
+// Result is the computed readiness of a resource with a human-readable explanation
+type Result struct {
+	Status  Status
+	Message string
+}
+
+// PodFailureReasons are the container waiting reasons that mean a pod will not become ready without intervention
+// when the container stays in them
+var PodFailureReasons = []string{"CrashLoopBackOff", "ImagePullBackOff", "ErrImagePull", "InvalidImageName", "CreateContainerConfigError"}
+
+// Compute returns the readiness of the object
+// Well-known kinds are checked using their status fields; other kinds, including custom resources,
+// are checked using the observed generation and the standard Ready, Available, Reconciling and Stalled conditions
+func Compute(obj *unstructured.Unstructured) Result {
+	if result, ok := checkGeneration(obj); !ok {
+		return result
+	}
+
+	switch obj.GroupVersionKind().GroupKind().String() {
+	case "Deployment.apps":
+		return deploymentStatus(obj)
+	case "StatefulSet.apps":
+		return statefulSetStatus(obj)
+	case "DaemonSet.apps":
+		return daemonSetStatus(obj)
+	case "ReplicaSet.apps":
+		return replicaSetStatus(obj)
+	case "Job.batch":
+		return jobStatus(obj)
+	case "Pod":
+		return podStatus(obj)
+	case "Node":
+		return nodeStatus(obj)
+	case "PersistentVolumeClaim":
+		return pvcStatus(obj)
+	case "Namespace":
+		return namespaceStatus(obj)
+	case "CustomResourceDefinition.apiextensions.k8s.io":
+		return crdStatus(obj)
+	default:
+		return genericStatus(obj)
+	}
+}
+
+// checkGeneration returns false if the controller has not observed the latest generation of the object
+func checkGeneration(obj *unstructured.Unstructured) (Result, bool) {
+	observed, found, err := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	if err != nil || !found {
+		return Result{}, true
+	}
+	if observed < obj.GetGeneration() {
+		return Result{Status: InProgress, Message: fmt.Sprintf("generation %d is not observed yet (observed %d)", obj.GetGeneration(), observed)}, false
+	}
+	return Result{}, true
+}
+
+func deploymentStatus(obj *unstructured.Unstructured) Result {
+	if c := condition(obj, "Progressing"); c != nil && c.reason == "ProgressDeadlineExceeded" {
+		return Result{Status: Failed, Message: fmt.Sprintf("progress deadline exceeded: %s", c.message)}
+	}
+
+	replicas := specReplicas(obj)
+	updated := statusInt(obj, "updatedReplicas")
+	available := statusInt(obj, "availableReplicas")
+	total := statusInt(obj, "replicas")
+
+	switch {
+	case updated < replicas:
+		return Result{Status: InProgress, Message: fmt.Sprintf("%d/%d replicas updated", updated, replicas)}
+	case total > updated:
+		return Result{Status: InProgress, Message: fmt.Sprintf("%d old replicas pending termination", total-updated)}
+	case available < replicas:
+		return Result{Status: InProgress, Message: fmt.Sprintf("%d/%d replicas available", available, replicas)}
+	}
+	return Result{Status: Current, Message: fmt.Sprintf("%d/%d replicas available", available, replicas)}
+}
+
+func statefulSetStatus(obj *unstructured.Unstructured) Result {
+	replicas := specReplicas(obj)
+	ready := statusInt(obj, "readyReplicas")
+
+	strategy, _, _ := unstructured.NestedString(obj.Object, "spec", "updateStrategy", "type")
+	if strategy != "OnDelete" {
+		updated := statusInt(obj, "updatedReplicas")
+		current, _, _ := unstructured.NestedString(obj.Object, "status", "currentRevision")
+		update, _, _ := unstructured.NestedString(obj.Object, "status", "updateRevision")
+		if updated < replicas || current != update {
+			return Result{Status: InProgress, Message: fmt.Sprintf("%d/%d replicas updated", updated, replicas)}
+		}
+	}
+
+	if ready < replicas {
+		return Result{Status: InProgress, Message: fmt.Sprintf("%d/%d replicas ready", ready, replicas)}
+	}
+	return Result{Status: Current, Message: fmt.Sprintf("%d/%d replicas ready", ready, replicas)}
+}
+
+func daemonSetStatus(obj *unstructured.Unstructured) Result {
+	desired := statusInt(obj, "desiredNumberScheduled")
+	updated := statusInt(obj, "updatedNumberScheduled")
+	available := statusInt(obj, "numberAvailable")
+
+	if updated < desired {
+		return Result{Status: InProgress, Message: fmt.Sprintf("%d/%d pods updated", updated, desired)}
+	}
+	if available < desired {
+		return Result{Status: InProgress, Message: fmt.Sprintf("%d/%d pods available", available, desired)}
+	}
+	return Result{Status: Current, Message: fmt.Sprintf("%d/%d pods available", available, desired)}
+}
+
+func replicaSetStatus(obj *unstructured.Unstructured) Result {
+	replicas := specReplicas(obj)
+	available := statusInt(obj, "availableReplicas")
+
+	if available < replicas {
+		return Result{Status: InProgress, Message: fmt.Sprintf("%d/%d replicas available", available, replicas)}
+	}
+	return Result{Status: Current, Message: fmt.Sprintf("%d/%d replicas available", available, replicas)}
+}
+
+func jobStatus(obj *unstructured.Unstructured) Result {
+	if c := condition(obj, "Failed"); c != nil && c.status == "True" {
+		return Result{Status: Failed, Message: fmt.Sprintf("job failed: %s", c.message)}
+	}
+	if c := condition(obj, "Complete"); c != nil && c.status == "True" {
+		return Result{Status: Current, Message: "job completed"}
+	}
+
+	succeeded := statusInt(obj, "succeeded")
+	completions, found, _ := unstructured.NestedInt64(obj.Object, "spec", "completions")
+	if !found {
+		completions = 1
+	}
+	return Result{Status: InProgress, Message: fmt.Sprintf("%d/%d completions", succeeded, completions)}
+}
+
+func podStatus(obj *unstructured.Unstructured) Result {
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	switch phase {
+	case "Succeeded":
+		return Result{Status: Current, Message: "pod succeeded"}
+	case "Failed":
+		return Result{Status: Failed, Message: "pod failed"}
+	}
+
+	statuses, _, _ := unstructured.NestedSlice(obj.Object, "status", "containerStatuses")
+	for _, s := range statuses {
+		status, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		reason, _, _ := unstructured.NestedString(status, "state", "waiting", "reason")
+		if slices.Contains(PodFailureReasons, reason) {
+			// the kubelet keeps retrying, the Waiter fails the pod when the container does not recover
+			message, _, _ := unstructured.NestedString(status, "state", "waiting", "message")
+			return Result{Status: InProgress, Message: fmt.Sprintf("container %q is in %s: %s", status["name"], reason, message)}
+		}
+	}
+
+	if c := condition(obj, "Ready"); c != nil && c.status == "True" {
+		return Result{Status: Current, Message: "pod is ready"}
+	}
+	return Result{Status: InProgress, Message: fmt.Sprintf("pod is %s and not ready", phaseOrUnknown(phase))}
+}
+
+func nodeStatus(obj *unstructured.Unstructured) Result {
+	if c := condition(obj, "Ready"); c != nil && c.status == "True" {
+		return Result{Status: Current, Message: "node is ready"}
+	}
+	return Result{Status: InProgress, Message: "node is not ready"}
+}
+
+func pvcStatus(obj *unstructured.Unstructured) Result {
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	switch phase {
+	case "Bound":
+		return Result{Status: Current, Message: "claim is bound"}
+	case "Lost":
+		return Result{Status: Failed, Message: "claim lost its volume"}
+	}
+	return Result{Status: InProgress, Message: fmt.Sprintf("claim is %s", phaseOrUnknown(phase))}
+}
+
+func namespaceStatus(obj *unstructured.Unstructured) Result {
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	if phase == "Terminating" {
+		return Result{Status: InProgress, Message: "namespace is terminating"}
+	}
+	return Result{Status: Current, Message: "namespace is active"}
+}
+
+func crdStatus(obj *unstructured.Unstructured) Result {
+	if c := condition(obj, "NamesAccepted"); c != nil && c.status == "False" {
+		return Result{Status: Failed, Message: fmt.Sprintf("names not accepted: %s", c.message)}
+	}
+	if c := condition(obj, "Established"); c != nil && c.status == "True" {
+		return Result{Status: Current, Message: "CRD is established"}
+	}
+	return Result{Status: InProgress, Message: "CRD is not established yet"}
+}
+
+// genericStatus checks the conditions commonly used by controllers of custom resources
+func genericStatus(obj *unstructured.Unstructured) Result {
+	if c := condition(obj, "Stalled"); c != nil && c.status == "True" {
+		return Result{Status: Failed, Message: fmt.Sprintf("stalled: %s", c.message)}
+	}
+	if c := condition(obj, "Reconciling"); c != nil && c.status == "True" {
+		return Result{Status: InProgress, Message: fmt.Sprintf("reconciling: %s", c.message)}
+	}
+	for _, t := range []string{"Ready", "Available"} {
+		if c := condition(obj, t); c != nil {
+			if c.status == "True" {
+				return Result{Status: Current, Message: fmt.Sprintf("%s condition is true", t)}
+			}
+			return Result{Status: InProgress, Message: fmt.Sprintf("%s condition is %s: %s", t, c.status, c.message)}
+		}
+	}
+
+	return Result{Status: Current, Message: "resource has no readiness conditions"}
+}
+
+type statusCondition struct {
+	status  string
+	reason  string
+	message string
+}
+
+// condition returns the status condition of the given type, or nil if the object does not have it
+func condition(obj *unstructured.Unstructured, conditionType string) *statusCondition {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok || cond["type"] != conditionType {
+			continue
+		}
+		status, _, _ := unstructured.NestedString(cond, "status")
+		reason, _, _ := unstructured.NestedString(cond, "reason")
+		message, _, _ := unstructured.NestedString(cond, "message")
+		return &statusCondition{status: status, reason: reason, message: message}
+	}
+	return nil
+}
+
+// specReplicas returns the desired replicas of a workload, which default to 1
+func specReplicas(obj *unstructured.Unstructured) int64 {
+	replicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	if !found {
+		return 1
+	}
+	return replicas
+}
+
+func statusInt(obj *unstructured.Unstructured, field string) int64 {
+	value, _, _ := unstructured.NestedInt64(obj.Object, "status", field)
+	return value
+}
+
+func phaseOrUnknown(phase string) string {
+	if phase == "" {
+		return "Unknown"
+	}
+	return phase
+}
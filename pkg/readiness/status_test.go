@@ -0,0 +1,113 @@
+package readiness
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// TestCompute tests the readiness computed for objects of different kinds
+func TestCompute(t *testing.T) {
+	tests := map[string]struct {
+		obj  map[string]interface{}
+		want Status
+	}{
+		"deployment available": {
+			obj: object("apps/v1", "Deployment", 2,
+				map[string]interface{}{"replicas": int64(3)},
+				map[string]interface{}{"observedGeneration": int64(2), "replicas": int64(3), "updatedReplicas": int64(3), "availableReplicas": int64(3)}),
+			want: Current,
+		},
+		"deployment generation not observed": {
+			obj: object("apps/v1", "Deployment", 3,
+				map[string]interface{}{"replicas": int64(3)},
+				map[string]interface{}{"observedGeneration": int64(2), "replicas": int64(3), "updatedReplicas": int64(3), "availableReplicas": int64(3)}),
+			want: InProgress,
+		},
+		"deployment rolling out": {
+			obj: object("apps/v1", "Deployment", 1,
+				map[string]interface{}{"replicas": int64(3)},
+				map[string]interface{}{"observedGeneration": int64(1), "replicas": int64(4), "updatedReplicas": int64(3), "availableReplicas": int64(3)}),
+			want: InProgress,
+		},
+		"deployment progress deadline exceeded": {
+			obj: object("apps/v1", "Deployment", 1, nil,
+				map[string]interface{}{"conditions": []interface{}{
+					map[string]interface{}{"type": "Progressing", "status": "False", "reason": "ProgressDeadlineExceeded"},
+				}}),
+			want: Failed,
+		},
+		"statefulset revision pending": {
+			obj: object("apps/v1", "StatefulSet", 1,
+				map[string]interface{}{"replicas": int64(1)},
+				map[string]interface{}{"readyReplicas": int64(1), "updatedReplicas": int64(1), "currentRevision": "a", "updateRevision": "b"}),
+			want: InProgress,
+		},
+		"daemonset available": {
+			obj: object("apps/v1", "DaemonSet", 1, nil,
+				map[string]interface{}{"desiredNumberScheduled": int64(2), "updatedNumberScheduled": int64(2), "numberAvailable": int64(2)}),
+			want: Current,
+		},
+		"job complete": {
+			obj: object("batch/v1", "Job", 1, nil,
+				map[string]interface{}{"conditions": []interface{}{map[string]interface{}{"type": "Complete", "status": "True"}}}),
+			want: Current,
+		},
+		"job failed": {
+			obj: object("batch/v1", "Job", 1, nil,
+				map[string]interface{}{"conditions": []interface{}{map[string]interface{}{"type": "Failed", "status": "True"}}}),
+			want: Failed,
+		},
+		"pod running but not ready": {
+			obj: object("v1", "Pod", 0, nil,
+				map[string]interface{}{"phase": "Running", "conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "False"}}}),
+			want: InProgress,
+		},
+		"pod crash looping, which may recover": {
+			obj: object("v1", "Pod", 0, nil,
+				map[string]interface{}{"phase": "Running", "containerStatuses": []interface{}{
+					map[string]interface{}{"name": "manager", "state": map[string]interface{}{"waiting": map[string]interface{}{"reason": "CrashLoopBackOff"}}},
+				}}),
+			want: InProgress,
+		},
+		"crd established": {
+			obj: object("apiextensions.k8s.io/v1", "CustomResourceDefinition", 1, nil,
+				map[string]interface{}{"conditions": []interface{}{map[string]interface{}{"type": "Established", "status": "True"}}}),
+			want: Current,
+		},
+		"custom resource not ready": {
+			obj: object("blueprint.mirantis.com/v1alpha1", "Addon", 1, nil,
+				map[string]interface{}{"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "False"}}}),
+			want: InProgress,
+		},
+		"custom resource without conditions": {
+			obj:  object("blueprint.mirantis.com/v1alpha1", "Addon", 1, nil, nil),
+			want: Current,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			result := Compute(&unstructured.Unstructured{Object: tc.obj})
+			g.Expect(result.Status).To(Equal(tc.want), result.Message)
+		})
+	}
+}
+
+func object(apiVersion string, kind string, generation int64, spec map[string]interface{}, status map[string]interface{}) map[string]interface{} {
+	obj := map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata":   map[string]interface{}{"name": "test", "generation": generation},
+	}
+	if spec != nil {
+		obj["spec"] = spec
+	}
+	if status != nil {
+		obj["status"] = status
+	}
+	return obj
+}
@@ -0,0 +1,228 @@
+package readiness
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// DefaultTimeout is the timeout of the resources that do not set their own
+	DefaultTimeout = 5 * time.Minute
+	// DefaultInterval is the interval between two status checks
+	DefaultInterval = 5 * time.Second
+)
+
+var (
+	nodesResource       = schema.GroupVersionResource{Version: "v1", Resource: "nodes"}
+	podsResource        = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	deploymentsResource = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+
+	// workloadResources are the resources checked when waiting for a namespace
+	workloadResources = []schema.GroupVersionResource{
+		deploymentsResource,
+		{Group: "apps", Version: "v1", Resource: "statefulsets"},
+		{Group: "apps", Version: "v1", Resource: "daemonsets"},
+		{Group: "batch", Version: "v1", Resource: "jobs"},
+	}
+)
+
+// Resource identifies an object to wait for
+type Resource struct {
+	GVR       schema.GroupVersionResource
+	Namespace string
+	Name      string
+	// Timeout overrides the timeout of the waiter for this resource
+	Timeout time.Duration
+}
+
+// Deployment returns the resource of the deployment, which waits for its latest rollout
+func Deployment(namespace string, name string, timeout time.Duration) Resource {
+	return Resource{GVR: deploymentsResource, Namespace: namespace, Name: name, Timeout: timeout}
+}
+
+// String returns a short human-readable reference to the resource, e.g. "deployments/kube-system/coredns"
+func (r Resource) String() string {
+	if r.Namespace == "" {
+		return fmt.Sprintf("%s/%s", r.GVR.Resource, r.Name)
+	}
+	return fmt.Sprintf("%s/%s/%s", r.GVR.Resource, r.Namespace, r.Name)
+}
+
+// Waiter waits for resources to become ready
+type Waiter struct {
+	client dynamic.Interface
+
+	// Timeout is the default timeout for each resource
+	Timeout time.Duration
+	// Interval is the interval between two status checks
+	Interval time.Duration
+	// FailureGracePeriod is how long the containers of a resource may stay in a failure state, e.g. CrashLoopBackOff,
+	// before the resource fails
+	FailureGracePeriod time.Duration
+}
+
+// NewWaiter returns a waiter with the default timeout and interval
+func NewWaiter(client dynamic.Interface) *Waiter {
+	return &Waiter{
+		client:             client,
+		Timeout:            DefaultTimeout,
+		Interval:           DefaultInterval,
+		FailureGracePeriod: DefaultFailureGracePeriod,
+	}
+}
+
+// Wait waits until all resources are Current
+// Progress is logged whenever the status of a resource changes. It returns as soon as a resource fails,
+// when the containers of a resource stay in a failure state for the grace period,
+// or when a resource is not ready by the end of its timeout
+func (w *Waiter) Wait(ctx context.Context, resources ...Resource) error {
+	start := time.Now()
+	last := make(map[Resource]Result, len(resources))
+	failures := make(map[Resource]*failureTracker, len(resources))
+	for _, r := range resources {
+		failures[r] = newFailureTracker(w.FailureGracePeriod)
+	}
+	pending := resources
+
+	return wait.PollUntilContextCancel(ctx, w.Interval, true, func(ctx context.Context) (bool, error) {
+		var stillPending []Resource
+		for _, r := range pending {
+			result, err := w.check(ctx, r, failures[r])
+			if err != nil {
+				log.Warn().Msgf("failed to get %s: %s", r, err)
+			} else if result != last[r] {
+				last[r] = result
+				log.Debug().Msgf("%s: %s (%s)", r, result.Status, result.Message)
+			}
+
+			switch {
+			case last[r].Status == Current:
+				continue
+			case last[r].Status == Failed:
+				return false, fmt.Errorf("%s failed: %s", r, last[r].Message)
+			case time.Since(start) > w.timeout(r):
+				return false, fmt.Errorf("timed out waiting for %s: %s", r, describe(last[r]))
+			}
+			stillPending = append(stillPending, r)
+		}
+		pending = stillPending
+
+		if len(pending) > 0 {
+			log.Info().Msgf("%d/%d resources ready, waiting for %s", len(resources)-len(pending), len(resources), pending[0])
+		}
+		return len(pending) == 0, nil
+	})
+}
+
+// WaitForNamespace waits for the workloads and standalone pods in the namespace to become ready
+// An empty namespace is ready straight away
+func (w *Waiter) WaitForNamespace(ctx context.Context, namespace string) error {
+	var resources []Resource
+	for _, gvr := range workloadResources {
+		list, err := w.client.Resource(gvr).Namespace(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to list %s in namespace %q: %w", gvr.Resource, namespace, err)
+		}
+		resources = append(resources, resourcesOf(gvr, list.Items)...)
+	}
+
+	pods, err := w.client.Resource(podsResource).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list pods in namespace %q: %w", namespace, err)
+	}
+	for _, pod := range pods.Items {
+		// pods of workloads are covered by the status of their owner
+		if len(pod.GetOwnerReferences()) == 0 {
+			resources = append(resources, resourcesOf(podsResource, []unstructured.Unstructured{pod})...)
+		}
+	}
+
+	if len(resources) == 0 {
+		log.Debug().Msgf("No workloads found in namespace %q", namespace)
+		return nil
+	}
+
+	log.Info().Msgf("Waiting for %d workloads in namespace %q to be ready", len(resources), namespace)
+	return w.Wait(ctx, resources...)
+}
+
+// WaitForNodes waits until the cluster has at least one node and all nodes are ready
+func (w *Waiter) WaitForNodes(ctx context.Context) error {
+	log.Info().Msgf("Waiting for nodes to be ready")
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, w.Timeout)
+	defer cancel()
+
+	var nodes []unstructured.Unstructured
+	err := wait.PollUntilContextCancel(timeoutCtx, w.Interval, true, func(ctx context.Context) (bool, error) {
+		list, err := w.client.Resource(nodesResource).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			log.Warn().Msgf("failed to list nodes: %s", err)
+			return false, nil
+		}
+		nodes = list.Items
+		return len(nodes) > 0, nil
+	})
+	if err != nil {
+		return fmt.Errorf("no nodes registered in the cluster: %w", err)
+	}
+
+	return w.Wait(ctx, resourcesOf(nodesResource, nodes)...)
+}
+
+// check returns the readiness of the resource
+// A resource in progress fails when the containers of its pods are in a failure state for the grace period.
+func (w *Waiter) check(ctx context.Context, r Resource, failures *failureTracker) (Result, error) {
+	obj, err := w.client.Resource(r.GVR).Namespace(r.Namespace).Get(ctx, r.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return Result{Status: NotFound, Message: "resource does not exist"}, nil
+	}
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Compute(obj)
+	if result.Status != InProgress {
+		return result, nil
+	}
+	pods, err := w.podsOf(ctx, obj)
+	if err != nil {
+		log.Warn().Msgf("failed to get the pods of %s: %s", r, err)
+		return result, nil
+	}
+	if failed, ok := failures.check(pods, time.Now()); ok {
+		return failed, nil
+	}
+	return result, nil
+}
+
+func (w *Waiter) timeout(r Resource) time.Duration {
+	if r.Timeout > 0 {
+		return r.Timeout
+	}
+	return w.Timeout
+}
+
+func resourcesOf(gvr schema.GroupVersionResource, objs []unstructured.Unstructured) []Resource {
+	resources := make([]Resource, 0, len(objs))
+	for _, o := range objs {
+		resources = append(resources, Resource{GVR: gvr, Namespace: o.GetNamespace(), Name: o.GetName()})
+	}
+	return resources
+}
+
+func describe(result Result) string {
+	if result.Status == "" {
+		return "status unknown"
+	}
+	return fmt.Sprintf("%s (%s)", result.Status, result.Message)
+}
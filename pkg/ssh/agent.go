@@ -0,0 +1,179 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+var errReadOnlyAgent = errors.New("the ssh-agent of bctl is read only")
+
+// Agent is an ssh-agent that serves the keys of the hosts that k0sctl cannot use by itself,
+// i.e. the decrypted keys and the OpenSSH certificates, followed by the keys of the ssh-agent of the user
+type Agent struct {
+	// Socket is the path of the unix socket to set SSH_AUTH_SOCK to
+	Socket   string
+	dir      string
+	listener net.Listener
+}
+
+// ServeAgent serves the keys of the hosts, and of their bastions, that need an ssh-agent
+// It returns nil if no host needs one.
+func ServeAgent(hosts []types.Host) (*Agent, error) {
+	keyring := &signersAgent{}
+	for _, host := range hosts {
+		for ssh := host.SSH; ssh != nil; ssh = ssh.Bastion {
+			if !ssh.NeedsAgent() {
+				continue
+			}
+			if err := keyring.add(*ssh); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(keyring.signers) == 0 {
+		return nil, nil
+	}
+	if os.Getenv("SSH_AUTH_SOCK") != "" {
+		next, err := sshAgent()
+		if err != nil {
+			return nil, err
+		}
+		keyring.next = next
+	}
+
+	dir, err := os.MkdirTemp("", "bctl-agent-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the directory of the ssh-agent: %w", err)
+	}
+	socket := filepath.Join(dir, "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to listen on the socket of the ssh-agent: %w", err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = agent.ServeAgent(keyring, conn)
+			}()
+		}
+	}()
+
+	return &Agent{Socket: socket, dir: dir, listener: listener}, nil
+}
+
+// Close stops serving the keys and removes the socket
+func (a *Agent) Close() {
+	a.listener.Close()
+	os.RemoveAll(a.dir)
+}
+
+// signersAgent signs with the keys of the hosts, then with the keys of the next ssh-agent if any
+type signersAgent struct {
+	signers []gossh.Signer
+	next    agent.ExtendedAgent
+}
+
+// add adds the key of the host, with its certificate first if it has one
+func (s *signersAgent) add(host types.SSHHost) error {
+	key, err := loadKey(host.KeyPath)
+	if err != nil {
+		return err
+	}
+	cert, err := loadCertificate(host.KeyPath, key)
+	if err != nil {
+		return err
+	}
+	for _, signer := range []gossh.Signer{cert, key} {
+		if signer != nil && s.signer(signer.PublicKey()) == nil {
+			s.signers = append(s.signers, signer)
+		}
+	}
+	return nil
+}
+
+// signer returns the signer of the public key, or nil if it is not one of the keys of the hosts
+func (s *signersAgent) signer(key gossh.PublicKey) gossh.Signer {
+	for _, signer := range s.signers {
+		if bytes.Equal(signer.PublicKey().Marshal(), key.Marshal()) {
+			return signer
+		}
+	}
+	return nil
+}
+
+func (s *signersAgent) List() ([]*agent.Key, error) {
+	var keys []*agent.Key
+	for _, signer := range s.signers {
+		public := signer.PublicKey()
+		keys = append(keys, &agent.Key{Format: public.Type(), Blob: public.Marshal()})
+	}
+	if s.next == nil {
+		return keys, nil
+	}
+	next, err := s.next.List()
+	if err != nil {
+		return nil, err
+	}
+	return append(keys, next...), nil
+}
+
+func (s *signersAgent) Sign(key gossh.PublicKey, data []byte) (*gossh.Signature, error) {
+	return s.SignWithFlags(key, data, 0)
+}
+
+func (s *signersAgent) SignWithFlags(key gossh.PublicKey, data []byte, flags agent.SignatureFlags) (*gossh.Signature, error) {
+	signer := s.signer(key)
+	if signer == nil {
+		if s.next == nil {
+			return nil, fmt.Errorf("the ssh-agent of bctl has no key %s", gossh.FingerprintSHA256(key))
+		}
+		return s.next.SignWithFlags(key, data, flags)
+	}
+
+	algorithmSigner, ok := signer.(gossh.AlgorithmSigner)
+	switch {
+	case ok && flags&agent.SignatureFlagRsaSha256 != 0:
+		return algorithmSigner.SignWithAlgorithm(rand.Reader, data, gossh.KeyAlgoRSASHA256)
+	case ok && flags&agent.SignatureFlagRsaSha512 != 0:
+		return algorithmSigner.SignWithAlgorithm(rand.Reader, data, gossh.KeyAlgoRSASHA512)
+	default:
+		return signer.Sign(rand.Reader, data)
+	}
+}
+
+func (s *signersAgent) Signers() ([]gossh.Signer, error) {
+	if s.next == nil {
+		return s.signers, nil
+	}
+	next, err := s.next.Signers()
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]gossh.Signer{}, s.signers...), next...), nil
+}
+
+func (s *signersAgent) Extension(string, []byte) ([]byte, error) {
+	return nil, agent.ErrExtensionUnsupported
+}
+
+func (s *signersAgent) Add(agent.AddedKey) error     { return errReadOnlyAgent }
+func (s *signersAgent) Remove(gossh.PublicKey) error { return errReadOnlyAgent }
+func (s *signersAgent) RemoveAll() error             { return errReadOnlyAgent }
+func (s *signersAgent) Lock([]byte) error            { return errReadOnlyAgent }
+func (s *signersAgent) Unlock([]byte) error          { return errReadOnlyAgent }
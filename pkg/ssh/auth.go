@@ -0,0 +1,153 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/utils"
+)
+
+var (
+	// keys caches the keys by path, so that the passphrase of a key shared by the hosts is only asked once
+	keys   = make(map[string]gossh.Signer)
+	keysMu sync.Mutex
+
+	// agents are the clients of the ssh-agents by socket
+	agents   = make(map[string]agent.ExtendedAgent)
+	agentsMu sync.Mutex
+)
+
+// authMethods returns the methods to authenticate to the host with
+// The key of the host is offered first, with its OpenSSH certificate if there is one next to it, then the keys of the ssh-agent.
+func authMethods(host types.SSHHost) ([]gossh.AuthMethod, error) {
+	var signers []gossh.Signer
+	if host.KeyPath != "" {
+		key, err := loadKey(host.KeyPath)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := loadCertificate(host.KeyPath, key)
+		if err != nil {
+			return nil, err
+		}
+		if cert != nil {
+			signers = append(signers, cert)
+		}
+		signers = append(signers, key)
+	}
+
+	if os.Getenv("SSH_AUTH_SOCK") == "" {
+		if len(signers) == 0 {
+			return nil, fmt.Errorf("host %s has no keyPath and SSH_AUTH_SOCK is not set to use an ssh-agent", host.Address)
+		}
+		return []gossh.AuthMethod{gossh.PublicKeys(signers...)}, nil
+	}
+
+	return []gossh.AuthMethod{gossh.PublicKeysCallback(func() ([]gossh.Signer, error) {
+		client, err := sshAgent()
+		if err != nil {
+			if len(signers) > 0 {
+				return signers, nil
+			}
+			return nil, err
+		}
+		agentSigners, err := client.Signers()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the keys of the ssh-agent: %w", err)
+		}
+		return append(signers, agentSigners...), nil
+	})}, nil
+}
+
+// loadKey reads the private key at the path
+// An encrypted key is decrypted with the passphrase of the environment, or the one the user enters.
+func loadKey(path string) (gossh.Signer, error) {
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	if key, ok := keys[path]; ok {
+		return key, nil
+	}
+
+	data, err := os.ReadFile(expandHome(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the SSH key %s: %w", path, err)
+	}
+	key, err := gossh.ParsePrivateKey(data)
+	var missing *gossh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		var passphrase string
+		if passphrase, err = keyPassphrase(path); err != nil {
+			return nil, err
+		}
+		key, err = gossh.ParsePrivateKeyWithPassphrase(data, []byte(passphrase))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the SSH key %s: %w", path, err)
+	}
+
+	keys[path] = key
+	return key, nil
+}
+
+// keyPassphrase returns the passphrase of the encrypted key
+func keyPassphrase(path string) (string, error) {
+	if passphrase, ok := os.LookupEnv(constants.SSHKeyPassphraseEnv); ok {
+		return passphrase, nil
+	}
+	if !isTerminal(os.Stdin) {
+		return "", fmt.Errorf("the SSH key %s is encrypted; set %s to its passphrase or load it into an ssh-agent", path, constants.SSHKeyPassphraseEnv)
+	}
+	return utils.PromptPassword(fmt.Sprintf("Enter passphrase for key %s: ", path))
+}
+
+// loadCertificate returns the key signed with the OpenSSH certificate next to it, e.g. id_ed25519-cert.pub,
+// or nil if the key has no certificate
+func loadCertificate(path string, key gossh.Signer) (gossh.Signer, error) {
+	certPath := expandHome(path) + "-cert.pub"
+	data, err := os.ReadFile(certPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the SSH certificate %s: %w", certPath, err)
+	}
+
+	public, _, _, _, err := gossh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the SSH certificate %s: %w", certPath, err)
+	}
+	cert, ok := public.(*gossh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an SSH certificate", certPath)
+	}
+	signer, err := gossh.NewCertSigner(cert, key)
+	if err != nil {
+		return nil, fmt.Errorf("the SSH certificate %s does not match the key %s: %w", certPath, path, err)
+	}
+	return signer, nil
+}
+
+// sshAgent returns the client of the ssh-agent of SSH_AUTH_SOCK
+func sshAgent() (agent.ExtendedAgent, error) {
+	agentsMu.Lock()
+	defer agentsMu.Unlock()
+
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if client, ok := agents[socket]; ok {
+		return client, nil
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the ssh-agent: %w", err)
+	}
+	agents[socket] = agent.NewClient(conn)
+	return agents[socket], nil
+}
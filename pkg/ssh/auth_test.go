@@ -0,0 +1,131 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+// TestEncryptedKey tests that an encrypted key is decrypted with the passphrase of the environment
+func TestEncryptedKey(t *testing.T) {
+	g := NewWithT(t)
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	keyPath, public := writeKey(t, "secret")
+	server := newTestServer(t, public)
+	host := server.host(t, keyPath)
+
+	t.Setenv(constants.SSHKeyPassphraseEnv, "secret")
+	client, err := Dial(host, CallbackHostKeys(gossh.FixedHostKey(server.key.PublicKey())))
+	g.Expect(err).ToNot(HaveOccurred())
+	client.Close()
+}
+
+// TestCertificate tests that the OpenSSH certificate next to the key is used to authenticate
+func TestCertificate(t *testing.T) {
+	g := NewWithT(t)
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	keyPath, public := clientKey(t)
+	// the server only accepts the certificates of the CA
+	server := newTestServer(t, nil)
+	host := server.host(t, keyPath)
+
+	_, err := Dial(host, CallbackHostKeys(gossh.FixedHostKey(server.key.PublicKey())))
+	g.Expect(err).To(MatchError(ContainSubstring("unable to authenticate")))
+
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	g.Expect(err).ToNot(HaveOccurred())
+	ca, err := gossh.NewSignerFromKey(caKey)
+	g.Expect(err).ToNot(HaveOccurred())
+	server.userCA = ca.PublicKey()
+
+	cert := &gossh.Certificate{Key: public, CertType: gossh.UserCert, ValidPrincipals: []string{"root"}, ValidBefore: gossh.CertTimeInfinity}
+	g.Expect(cert.SignCert(rand.Reader, ca)).To(Succeed())
+	g.Expect(os.WriteFile(keyPath+"-cert.pub", gossh.MarshalAuthorizedKey(cert), 0o600)).To(Succeed())
+
+	client, err := Dial(host, CallbackHostKeys(gossh.FixedHostKey(server.key.PublicKey())))
+	g.Expect(err).ToNot(HaveOccurred())
+	client.Close()
+}
+
+// TestAgent tests that a host without a key is authenticated with the keys of the ssh-agent
+func TestAgent(t *testing.T) {
+	g := NewWithT(t)
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	g.Expect(err).ToNot(HaveOccurred())
+	keyring := agent.NewKeyring()
+	g.Expect(keyring.Add(agent.AddedKey{PrivateKey: private})).To(Succeed())
+	signers, err := keyring.Signers()
+	g.Expect(err).ToNot(HaveOccurred())
+
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	g.Expect(err).ToNot(HaveOccurred())
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(keyring, conn)
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", socket)
+
+	server := newTestServer(t, signers[0].PublicKey())
+	host := server.host(t, "")
+
+	client, err := Dial(host, CallbackHostKeys(gossh.FixedHostKey(server.key.PublicKey())))
+	g.Expect(err).ToNot(HaveOccurred())
+	client.Close()
+}
+
+// TestServeAgent tests that a host without a key is authenticated with the decrypted key and the certificate
+// of another host served by the ssh-agent of bctl, as k0sctl is
+func TestServeAgent(t *testing.T) {
+	g := NewWithT(t)
+	t.Setenv("SSH_AUTH_SOCK", "")
+	t.Setenv(constants.SSHKeyPassphraseEnv, "secret")
+
+	keyPath, public := writeKey(t, "secret")
+	// the server only accepts the certificates of the CA
+	server := newTestServer(t, nil)
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	g.Expect(err).ToNot(HaveOccurred())
+	ca, err := gossh.NewSignerFromKey(caKey)
+	g.Expect(err).ToNot(HaveOccurred())
+	server.userCA = ca.PublicKey()
+	cert := &gossh.Certificate{Key: public, CertType: gossh.UserCert, ValidPrincipals: []string{"root"}, ValidBefore: gossh.CertTimeInfinity}
+	g.Expect(cert.SignCert(rand.Reader, ca)).To(Succeed())
+	g.Expect(os.WriteFile(keyPath+"-cert.pub", gossh.MarshalAuthorizedKey(cert), 0o600)).To(Succeed())
+
+	plainPath, _ := clientKey(t)
+	plain := server.host(t, plainPath)
+	served := server.host(t, keyPath)
+
+	agentServer, err := ServeAgent([]types.Host{{SSH: &plain}})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(agentServer).To(BeNil())
+
+	agentServer, err = ServeAgent([]types.Host{{SSH: &plain}, {SSH: &served}})
+	g.Expect(err).ToNot(HaveOccurred())
+	defer agentServer.Close()
+
+	t.Setenv("SSH_AUTH_SOCK", agentServer.Socket)
+	client, err := Dial(server.host(t, ""), CallbackHostKeys(gossh.FixedHostKey(server.key.PublicKey())))
+	g.Expect(err).ToNot(HaveOccurred())
+	client.Close()
+}
@@ -0,0 +1,306 @@
+// Package ssh runs commands on the hosts of the blueprint over SSH
+package ssh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	gossh "golang.org/x/crypto/ssh"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+// DefaultPort is the port used for the hosts that do not set one
+const DefaultPort = 22
+
+// dialTimeout is the time a host has to accept the connection
+var dialTimeout = 30 * time.Second
+
+// CommandError is returned when a command fails on a host
+type CommandError struct {
+	// Host is the address of the host
+	Host string
+	// Command is the command that failed
+	Command string
+	// Stderr is the error output of the command
+	Stderr string
+	// Err is the error of the command, usually an *ssh.ExitError
+	Err error
+}
+
+func (e *CommandError) Error() string {
+	if e.Stderr == "" {
+		return fmt.Sprintf("%q failed on host %s: %s", e.Command, e.Host, e.Err)
+	}
+	return fmt.Sprintf("%q failed on host %s: %s: %s", e.Command, e.Host, e.Stderr, e.Err)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// Client runs commands on a host over a single SSH connection
+type Client struct {
+	host   types.SSHHost
+	client *gossh.Client
+	// bastion is the connection to the jump host the host is reached through
+	bastion *Client
+}
+
+// Dial connects to the host, verifying its key with the host keys
+// A host with a bastion is reached through a connection to the bastion, like the ProxyJump option of OpenSSH;
+// the bastion may itself be reached through another bastion.
+func Dial(host types.SSHHost, hostKeys HostKeys) (*Client, error) {
+	auth, err := authMethods(host)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &gossh.ClientConfig{
+		User:              host.User,
+		Auth:              auth,
+		HostKeyCallback:   hostKeys.HostKeyCallback,
+		HostKeyAlgorithms: hostKeys.HostKeyAlgorithms(Address(host)),
+		Timeout:           dialTimeout,
+	}
+	if host.Bastion == nil {
+		client, err := gossh.Dial("tcp", Address(host), config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to host %s: %w", Address(host), err)
+		}
+		return &Client{host: host, client: client}, nil
+	}
+
+	bastion, err := Dial(*host.Bastion, hostKeys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the bastion of host %s: %w", host.Address, err)
+	}
+	conn, err := bastion.client.Dial("tcp", Address(host))
+	if err != nil {
+		bastion.Close()
+		return nil, fmt.Errorf("failed to connect to host %s through bastion %s: %w", Address(host), Address(*host.Bastion), err)
+	}
+	clientConn, channels, requests, err := gossh.NewClientConn(conn, Address(host), config)
+	if err != nil {
+		conn.Close()
+		bastion.Close()
+		return nil, fmt.Errorf("failed to connect to host %s through bastion %s: %w", Address(host), Address(*host.Bastion), err)
+	}
+	return &Client{host: host, client: gossh.NewClient(clientConn, channels, requests), bastion: bastion}, nil
+}
+
+// Run runs the command on the host and returns its output
+// A command that fails returns a *CommandError with the error output of the command
+func (c *Client) Run(command string) (string, error) {
+	var stdout bytes.Buffer
+	err := c.Stream(command, &stdout)
+	return clean(stdout.String()), err
+}
+
+// Stream runs the command on the host and copies its output to stdout as is, e.g. to download a file with cat
+// A command that fails returns a *CommandError with the error output of the command
+func (c *Client) Stream(command string, stdout io.Writer) error {
+	return c.run(command, nil, stdout)
+}
+
+// run runs the command on the host with the input, if any, and copies its output to stdout
+func (c *Client) run(command string, stdin io.Reader, stdout io.Writer) error {
+	session, err := c.client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	var stderr bytes.Buffer
+	session.Stdin = stdin
+	session.Stdout = stdout
+	session.Stderr = &stderr
+
+	if err := session.Run(command); err != nil {
+		return &CommandError{Host: c.host.Address, Command: command, Stderr: clean(stderr.String()), Err: err}
+	}
+	return nil
+}
+
+// Close closes the connection, and the one to the bastion if any
+func (c *Client) Close() error {
+	err := c.client.Close()
+	if c.bastion != nil {
+		c.bastion.Close()
+	}
+	return err
+}
+
+// Address returns the address to dial the host at, with the default port if the host does not set one
+func Address(host types.SSHHost) string {
+	port := host.Port
+	if port == 0 {
+		port = DefaultPort
+	}
+	return net.JoinHostPort(host.Address, strconv.Itoa(port))
+}
+
+// expandHome replaces a leading ~ with the home directory of the user
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+// clean removes the non-printable characters around the output of a command
+func clean(output string) string {
+	return strings.TrimFunc(output, func(r rune) bool {
+		return !unicode.IsGraphic(r)
+	})
+}
+
+// Pool keeps one connection per host, so that the commands run on a host do not reconnect every time
+// It is safe for concurrent use.
+type Pool struct {
+	hostKeys HostKeys
+
+	mu    sync.Mutex
+	conns map[string]*pooledConn
+}
+
+type pooledConn struct {
+	mu     sync.Mutex
+	client *Client
+}
+
+// NewPool returns a pool that verifies the keys of the hosts with the host keys
+func NewPool(hostKeys HostKeys) *Pool {
+	return &Pool{hostKeys: hostKeys, conns: make(map[string]*pooledConn)}
+}
+
+// Run runs the command on the host, connecting to it if the pool has no connection to it yet
+// A broken connection, e.g. to a host that rebooted, is replaced once.
+func (p *Pool) Run(host types.SSHHost, command string) (string, error) {
+	var out string
+	err := p.do(host, func(client *Client) error {
+		var err error
+		out, err = client.Run(command)
+		return err
+	})
+	return out, err
+}
+
+// Stream runs the command on the host and copies its output to stdout as is, see Client.Stream
+func (p *Pool) Stream(host types.SSHHost, command string, stdout io.Writer) error {
+	return p.do(host, func(client *Client) error {
+		return client.Stream(command, stdout)
+	})
+}
+
+// do calls f with the connection to the host, replacing a broken connection once
+func (p *Pool) do(host types.SSHHost, f func(client *Client) error) error {
+	conn := p.conn(host)
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	for attempt := 0; ; attempt++ {
+		if conn.client == nil {
+			client, err := Dial(host, p.hostKeys)
+			if err != nil {
+				return err
+			}
+			conn.client = client
+		}
+
+		err := f(conn.client)
+		var commandErr *CommandError
+		if err == nil || errors.As(err, &commandErr) || attempt > 0 {
+			return err
+		}
+		// the session could not be opened, the connection is gone
+		conn.client.Close()
+		conn.client = nil
+	}
+}
+
+// Close closes all the connections of the pool
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, conn := range p.conns {
+		conn.mu.Lock()
+		if conn.client != nil {
+			conn.client.Close()
+		}
+		conn.mu.Unlock()
+		delete(p.conns, key)
+	}
+}
+
+func (p *Pool) conn(host types.SSHHost) *pooledConn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := host.User + "@" + Address(host)
+	conn, ok := p.conns[key]
+	if !ok {
+		conn = &pooledConn{}
+		p.conns[key] = conn
+	}
+	return conn
+}
+
+var (
+	defaultPool     *Pool
+	defaultPoolOnce sync.Once
+)
+
+// DefaultPool returns the default pool, shared by the commands of bctl
+// The default pool verifies the host keys with ~/.ssh/known_hosts, see KnownHosts.
+func DefaultPool() *Pool {
+	defaultPoolOnce.Do(func() {
+		defaultPool = NewPool(DefaultKnownHosts())
+	})
+	return defaultPool
+}
+
+// Upload copies the local file to the path on the host, see Client.Upload
+func (p *Pool) Upload(host types.SSHHost, src string, dst string, mode os.FileMode) error {
+	return p.do(host, func(client *Client) error {
+		return client.Upload(src, dst, mode)
+	})
+}
+
+// Run runs the command on the host with the default pool
+func Run(host types.SSHHost, command string) (string, error) {
+	return DefaultPool().Run(host, command)
+}
+
+// Stream runs the command on the host with the default pool and copies its output to stdout as is
+func Stream(host types.SSHHost, command string, stdout io.Writer) error {
+	return DefaultPool().Stream(host, command, stdout)
+}
+
+// Upload copies the local file to the path on the host with the default pool
+func Upload(host types.SSHHost, src string, dst string, mode os.FileMode) error {
+	return DefaultPool().Upload(host, src, dst, mode)
+}
+
+// CheckReachable checks that bctl can connect and authenticate to the host
+func CheckReachable(host types.SSHHost) error {
+	client, err := Dial(host, DefaultPool().hostKeys)
+	if err != nil {
+		return err
+	}
+	return client.Close()
+}
@@ -0,0 +1,351 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+// testServer is an SSH server that echoes the commands it runs, and fails the "false" command
+// The input of the tee commands is kept in uploads.
+type testServer struct {
+	listener    net.Listener
+	key         gossh.Signer
+	connections atomic.Int32
+	forwarded   atomic.Int32
+	// userCA signs the certificates of the users, when set
+	userCA gossh.PublicKey
+
+	mu      sync.Mutex
+	uploads []string
+}
+
+// The server presents its ed25519 key, or one of the other host keys if the client prefers their algorithm.
+func newTestServer(t *testing.T, clientKey gossh.PublicKey, otherHostKeys ...gossh.Signer) *testServer {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostKey, err := gossh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	server := &testServer{listener: listener, key: hostKey}
+
+	// the client key is accepted, as well as the certificates signed by the user CA
+	checker := &gossh.CertChecker{
+		IsUserAuthority: func(auth gossh.PublicKey) bool {
+			return server.userCA != nil && string(auth.Marshal()) == string(server.userCA.Marshal())
+		},
+		UserKeyFallback: func(conn gossh.ConnMetadata, key gossh.PublicKey) (*gossh.Permissions, error) {
+			if clientKey == nil || string(key.Marshal()) != string(clientKey.Marshal()) {
+				return nil, os.ErrPermission
+			}
+			return nil, nil
+		},
+	}
+	config := &gossh.ServerConfig{PublicKeyCallback: checker.Authenticate}
+	config.AddHostKey(hostKey)
+	for _, key := range otherHostKeys {
+		config.AddHostKey(key)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			server.connections.Add(1)
+			go server.serve(conn, config)
+		}
+	}()
+	return server
+}
+
+func (s *testServer) serve(conn net.Conn, config *gossh.ServerConfig) {
+	_, channels, requests, err := gossh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go gossh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() == "direct-tcpip" {
+			go s.forward(newChannel)
+			continue
+		}
+
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer channel.Close()
+			for request := range channelRequests {
+				if request.Type != "exec" {
+					request.Reply(false, nil)
+					continue
+				}
+				request.Reply(true, nil)
+
+				command := string(request.Payload[4:])
+				status := make([]byte, 4)
+				if command == "false" {
+					channel.Stderr().Write([]byte("failed\n"))
+					binary.BigEndian.PutUint32(status, 1)
+				} else if strings.Contains(command, " tee ") {
+					data, _ := io.ReadAll(channel)
+					s.mu.Lock()
+					s.uploads = append(s.uploads, string(data))
+					s.mu.Unlock()
+				} else {
+					channel.Write([]byte(command + "\n"))
+				}
+				channel.SendRequest("exit-status", false, status)
+				return
+			}
+		}()
+	}
+}
+
+// forward connects a channel opened by a client that uses the server as a bastion to its destination
+func (s *testServer) forward(newChannel gossh.NewChannel) {
+	s.forwarded.Add(1)
+
+	var destination struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+	if err := gossh.Unmarshal(newChannel.ExtraData(), &destination); err != nil {
+		newChannel.Reject(gossh.ConnectionFailed, err.Error())
+		return
+	}
+	conn, err := net.Dial("tcp", net.JoinHostPort(destination.Host, strconv.Itoa(int(destination.Port))))
+	if err != nil {
+		newChannel.Reject(gossh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go gossh.DiscardRequests(requests)
+
+	go func() {
+		io.Copy(channel, conn)
+		channel.CloseWrite()
+	}()
+	io.Copy(conn, channel)
+	conn.Close()
+}
+
+func (s *testServer) host(t *testing.T, keyPath string) types.SSHHost {
+	address, port, err := net.SplitHostPort(s.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	portNumber, _ := strconv.Atoi(port)
+	return types.SSHHost{Address: address, Port: portNumber, User: "root", KeyPath: keyPath}
+}
+
+// clientKey writes a private key for the client and returns its path
+func clientKey(t *testing.T) (string, gossh.PublicKey) {
+	return writeKey(t, "")
+}
+
+// writeKey writes a private key, encrypted with the passphrase if any, and returns its path
+func writeKey(t *testing.T, passphrase string) (string, gossh.PublicKey) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var block *pem.Block
+	if passphrase == "" {
+		block, err = gossh.MarshalPrivateKey(private, "")
+	} else {
+		block, err = gossh.MarshalPrivateKeyWithPassphrase(private, "", []byte(passphrase))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sshPublic, err := gossh.NewPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path, sshPublic
+}
+
+// TestPool tests that the commands run on the port of the host over a single connection
+func TestPool(t *testing.T) {
+	g := NewWithT(t)
+
+	keyPath, public := clientKey(t)
+	server := newTestServer(t, public)
+	host := server.host(t, keyPath)
+
+	pool := NewPool(CallbackHostKeys(gossh.FixedHostKey(server.key.PublicKey())))
+	defer pool.Close()
+
+	out, err := pool.Run(host, "k0s version")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(out).To(Equal("k0s version"))
+
+	_, err = pool.Run(host, "false")
+	var commandErr *CommandError
+	g.Expect(err).To(BeAssignableToTypeOf(commandErr))
+	g.Expect(err).To(MatchError(ContainSubstring("failed on host 127.0.0.1: failed")))
+
+	// the output is streamed as is
+	var stream bytes.Buffer
+	g.Expect(pool.Stream(host, "cat backup.tar.gz", &stream)).To(Succeed())
+	g.Expect(stream.String()).To(Equal("cat backup.tar.gz\n"))
+
+	// the file is streamed to the host
+	src := filepath.Join(t.TempDir(), "k0s")
+	g.Expect(os.WriteFile(src, []byte("k0s binary"), 0o755)).To(Succeed())
+	g.Expect(pool.Upload(host, src, "/usr/local/bin/k0s", 0o755)).To(Succeed())
+	g.Expect(server.uploads).To(Equal([]string{"k0s binary"}))
+
+	_, err = pool.Run(host, "true")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(server.connections.Load()).To(BeEquivalentTo(1))
+}
+
+// TestKnownHosts tests that an unknown host is trusted once confirmed and that a changed host key is rejected
+func TestKnownHosts(t *testing.T) {
+	g := NewWithT(t)
+
+	keyPath, public := clientKey(t)
+	server := newTestServer(t, public)
+	host := server.host(t, keyPath)
+	knownHostsPath := filepath.Join(t.TempDir(), ".ssh", "known_hosts")
+
+	// without a prompt, unknown hosts are rejected
+	_, err := Dial(host, &KnownHosts{Path: knownHostsPath})
+	g.Expect(err).To(MatchError(ContainSubstring("is not a known host")))
+
+	refuse := &KnownHosts{Path: knownHostsPath, Confirm: func(string) (bool, error) { return false, nil }}
+	_, err = Dial(host, refuse)
+	g.Expect(err).To(MatchError(ContainSubstring("was not trusted")))
+
+	var prompts int
+	trust := &KnownHosts{Path: knownHostsPath, Confirm: func(string) (bool, error) { prompts++; return true, nil }}
+	for range 2 {
+		client, err := Dial(host, trust)
+		g.Expect(err).ToNot(HaveOccurred())
+		client.Close()
+	}
+	g.Expect(prompts).To(Equal(1))
+
+	data, err := os.ReadFile(knownHostsPath)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(data)).To(HavePrefix("[127.0.0.1]:" + strconv.Itoa(host.Port) + " ssh-ed25519 "))
+
+	// another server on the same address presents a different key
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	g.Expect(err).ToNot(HaveOccurred())
+	signer, err := gossh.NewSignerFromKey(otherKey)
+	g.Expect(err).ToNot(HaveOccurred())
+	err = trust.HostKeyCallback(Address(host), server.listener.Addr(), signer.PublicKey())
+	g.Expect(err).To(MatchError(ContainSubstring("does not match")))
+}
+
+// TestKnownHostsAlgorithms tests that a host is asked for the key of the known hosts file
+// rather than for a key of an algorithm the client prefers, which would not match
+func TestKnownHostsAlgorithms(t *testing.T) {
+	g := NewWithT(t)
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).ToNot(HaveOccurred())
+	ecdsaSigner, err := gossh.NewSignerFromKey(ecdsaKey)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	keyPath, public := clientKey(t)
+	server := newTestServer(t, public, ecdsaSigner)
+	host := server.host(t, keyPath)
+
+	knownHostsPath := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(Address(host))}, server.key.PublicKey())
+	g.Expect(os.WriteFile(knownHostsPath, []byte(line+"\n"), 0o600)).To(Succeed())
+
+	known := &KnownHosts{Path: knownHostsPath}
+	g.Expect(known.HostKeyAlgorithms(Address(host))).To(Equal([]string{gossh.KeyAlgoED25519}))
+	g.Expect(known.HostKeyAlgorithms("127.0.0.2:22")).To(BeNil())
+
+	client, err := Dial(host, known)
+	g.Expect(err).ToNot(HaveOccurred())
+	client.Close()
+}
+
+// TestDialBastion tests that a host is reached through its bastion
+func TestDialBastion(t *testing.T) {
+	g := NewWithT(t)
+
+	keyPath, public := clientKey(t)
+	bastionServer := newTestServer(t, public)
+	server := newTestServer(t, public)
+
+	host := server.host(t, keyPath)
+	bastion := bastionServer.host(t, keyPath)
+	host.Bastion = &bastion
+
+	hostKeys := CallbackHostKeys(func(hostname string, remote net.Addr, key gossh.PublicKey) error {
+		for _, known := range []gossh.PublicKey{server.key.PublicKey(), bastionServer.key.PublicKey()} {
+			if string(key.Marshal()) == string(known.Marshal()) {
+				return nil
+			}
+		}
+		return os.ErrPermission
+	})
+
+	client, err := Dial(host, hostKeys)
+	g.Expect(err).ToNot(HaveOccurred())
+	defer client.Close()
+
+	out, err := client.Run("hostname")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(out).To(Equal("hostname"))
+	g.Expect(bastionServer.forwarded.Load()).To(BeEquivalentTo(1))
+	g.Expect(server.connections.Load()).To(BeEquivalentTo(1))
+
+	// the bastion cannot reach a host that is down
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	g.Expect(err).ToNot(HaveOccurred())
+	host.Port = closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+	_, err = Dial(host, hostKeys)
+	g.Expect(err).To(MatchError(ContainSubstring("through bastion " + Address(bastion))))
+}
@@ -0,0 +1,177 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/utils"
+)
+
+// HostKeys verifies the keys of the hosts bctl connects to
+type HostKeys interface {
+	// HostKeyCallback verifies the key of the host, see gossh.HostKeyCallback
+	HostKeyCallback(hostname string, remote net.Addr, key gossh.PublicKey) error
+	// HostKeyAlgorithms returns the algorithms of the keys known for the host:port address, so that the host
+	// presents one of them rather than the key of another algorithm it prefers; nil lets the host present any key
+	HostKeyAlgorithms(address string) []string
+}
+
+// callbackHostKeys verifies the keys of the hosts with a callback, whatever their algorithm
+type callbackHostKeys gossh.HostKeyCallback
+
+// CallbackHostKeys returns the host keys verified by the callback, e.g. gossh.FixedHostKey
+func CallbackHostKeys(callback gossh.HostKeyCallback) HostKeys {
+	return callbackHostKeys(callback)
+}
+
+func (c callbackHostKeys) HostKeyCallback(hostname string, remote net.Addr, key gossh.PublicKey) error {
+	return c(hostname, remote, key)
+}
+
+func (c callbackHostKeys) HostKeyAlgorithms(string) []string {
+	return nil
+}
+
+// KnownHosts verifies the host keys with a known_hosts file
+// The key of a host that is not in the file is trusted on first use, once the user confirms it, and added to the file.
+// A key that does not match the one in the file is always rejected.
+type KnownHosts struct {
+	// Path is the known_hosts file
+	Path string
+	// Confirm asks the user to trust the key of an unknown host; unknown hosts are rejected when it is nil
+	Confirm func(prompt string) (bool, error)
+
+	mu sync.Mutex
+}
+
+// DefaultKnownHosts returns the known hosts of ~/.ssh/known_hosts
+// The unknown hosts are only trusted when bctl runs in a terminal, so that the user can confirm them.
+func DefaultKnownHosts() *KnownHosts {
+	known := &KnownHosts{Path: expandHome("~/.ssh/known_hosts")}
+	if isTerminal(os.Stdin) {
+		known.Confirm = utils.Confirm
+	}
+	return known
+}
+
+// HostKeyCallback verifies the key of the host, see gossh.HostKeyCallback
+func (k *KnownHosts) HostKeyCallback(hostname string, remote net.Addr, key gossh.PublicKey) error {
+	// the file is written by concurrent connections to unknown hosts
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if err := ensureFile(k.Path); err != nil {
+		return err
+	}
+	callback, err := knownhosts.New(k.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read known hosts %s: %w", k.Path, err)
+	}
+
+	err = callback(hostname, remote, key)
+	var keyErr *knownhosts.KeyError
+	if !errors.As(err, &keyErr) {
+		return err
+	}
+	if len(keyErr.Want) > 0 {
+		return fmt.Errorf("the %s key of host %s does not match the one in %s; if the host was reinstalled, remove its entry from the file: %w",
+			key.Type(), hostname, k.Path, err)
+	}
+
+	return k.trust(hostname, key)
+}
+
+// HostKeyAlgorithms returns the algorithms of the keys of the host in the known hosts file
+// An unknown host returns nil, so that it presents the key it prefers to be trusted.
+func (k *KnownHosts) HostKeyAlgorithms(address string) []string {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	callback, err := knownhosts.New(k.Path)
+	if err != nil {
+		return nil
+	}
+	// the known keys of the host are reported as the ones wanted instead of a key that matches none of them
+	var keyErr *knownhosts.KeyError
+	if !errors.As(callback(address, &net.TCPAddr{}, unknownKey{}), &keyErr) {
+		return nil
+	}
+
+	var algorithms []string
+	for _, known := range keyErr.Want {
+		for _, algorithm := range keyAlgorithms(known.Key.Type()) {
+			if !slices.Contains(algorithms, algorithm) {
+				algorithms = append(algorithms, algorithm)
+			}
+		}
+	}
+	return algorithms
+}
+
+// keyAlgorithms returns the host key algorithms a key of the type is presented with
+// RSA keys are signed with SHA-2 by current servers, and with SHA-1 by older ones
+func keyAlgorithms(keyType string) []string {
+	if keyType == gossh.KeyAlgoRSA {
+		return []string{gossh.KeyAlgoRSASHA512, gossh.KeyAlgoRSASHA256, gossh.KeyAlgoRSA}
+	}
+	return []string{keyType}
+}
+
+// unknownKey is a key that matches no known key
+type unknownKey struct{}
+
+func (unknownKey) Type() string                                   { return "unknown" }
+func (unknownKey) Marshal() []byte                                { return []byte("unknown") }
+func (unknownKey) Verify(data []byte, sig *gossh.Signature) error { return errors.New("unknown key") }
+
+// trust adds the key of an unknown host to the known hosts once the user confirms it
+func (k *KnownHosts) trust(hostname string, key gossh.PublicKey) error {
+	if k.Confirm == nil {
+		return fmt.Errorf("host %s is not a known host; add its key to %s, e.g. with ssh-keyscan", hostname, k.Path)
+	}
+
+	ok, err := k.Confirm(fmt.Sprintf("The authenticity of host %s can't be established.\n%s key fingerprint is %s.\nTrust it and add it to %s?",
+		hostname, key.Type(), gossh.FingerprintSHA256(key), k.Path))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("the key of host %s was not trusted", hostname)
+	}
+
+	file, err := os.OpenFile(k.Path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to add host %s to known hosts: %w", hostname, err)
+	}
+	defer file.Close()
+	if _, err := fmt.Fprintln(file, knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key)); err != nil {
+		return fmt.Errorf("failed to add host %s to known hosts: %w", hostname, err)
+	}
+	return nil
+}
+
+// ensureFile creates the known hosts file if it does not exist yet
+func ensureFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create known hosts: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create known hosts: %w", err)
+	}
+	return file.Close()
+}
+
+// isTerminal returns true if the file is a terminal
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,83 @@
+package ssh
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Upload copies the local file to the path on the host, with the mode
+// The file is streamed to sudo tee rather than copied with SFTP, so that it can be written to the directories of root,
+// e.g. /usr/local/bin. It is written next to the path and only moved there once its checksum matches, so that
+// an interrupted upload never leaves a truncated file behind. A host that already has the file is skipped.
+func (c *Client) Upload(src string, dst string, mode os.FileMode) error {
+	sum, err := fileChecksum(src)
+	if err != nil {
+		return err
+	}
+
+	if out, err := c.Run("sudo sha256sum " + quote(dst)); err == nil && strings.HasPrefix(out, sum+" ") {
+		return nil
+	}
+
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	partial := dst + ".partial"
+	write := fmt.Sprintf("sudo mkdir -p %s && sudo tee %s > /dev/null", quote(path.Dir(dst)), quote(partial))
+	if err := c.run(write, file, io.Discard); err != nil {
+		return fmt.Errorf("failed to upload %s: %w", src, err)
+	}
+
+	install := fmt.Sprintf("echo %s | sudo sha256sum -c --status - && sudo chmod %o %s && sudo mv -f %s %s",
+		quote(sum+"  "+partial), mode.Perm(), quote(partial), quote(partial), quote(dst))
+	if _, err := c.Run(install); err != nil {
+		_, _ = c.Run("sudo rm -f " + quote(partial))
+		return fmt.Errorf("failed to install %s at %s, the upload may be corrupt: %w", src, dst, err)
+	}
+	return nil
+}
+
+// fileChecksum returns the hex sha256 checksum of the file
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// quote quotes the argument for the shell of the host
+func quote(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// EnvAssignments returns the environment variables as shell assignments sorted by name, followed by a space,
+// to set them for a command of the host, e.g. "HTTPS_PROXY='http://proxy:3128' curl ..." or "sudo HTTPS_PROXY=... sh"
+func EnvAssignments(env map[string]string) string {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s=%s ", name, quote(env[name]))
+	}
+	return b.String()
+}
@@ -14,6 +14,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/k0sconfig"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 )
 
@@ -59,6 +60,9 @@ type BlueprintSpec struct {
 	Kubernetes *Kubernetes `yaml:"kubernetes,omitempty" json:"kubernetes,omitempty"`
 	Components Components  `yaml:"components" json:"components"`
 	Resources  *Resources  `yaml:"resources,omitempty" json:"resources,omitempty"`
+	Registry   *Registry   `yaml:"registry,omitempty" json:"registry,omitempty"`
+	Operator   *Operator   `yaml:"operator,omitempty" json:"operator,omitempty"`
+	Proxy      *Proxy      `yaml:"proxy,omitempty" json:"proxy,omitempty"`
 }
 
 // Validate checks the BlueprintSpec structure and its children
@@ -83,16 +87,46 @@ func (bs *BlueprintSpec) Validate() error {
 		}
 	}
 
+	// Registry checks
+	if bs.Registry != nil {
+		if err := bs.Registry.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Operator checks
+	if bs.Operator != nil {
+		if err := bs.Operator.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Proxy checks
+	if bs.Proxy != nil {
+		if err := bs.Proxy.Validate(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 type Infra struct {
 	Hosts []Host `yaml:"hosts" json:"hosts"`
+	// HostsFrom imports more hosts from the output of a provisioning tool; they are added to the hosts when the blueprint is loaded
+	HostsFrom *HostsFrom `yaml:"hostsFrom,omitempty" json:"hostsFrom,omitempty"`
 }
 
 // Validate checks the Infra structure and its children
 func (i *Infra) Validate() error {
 
+	// HostsFrom checks
+	if i.HostsFrom != nil {
+		if err := i.HostsFrom.Validate(); err != nil {
+			return err
+		}
+	}
+
 	// Host checks
 	for _, host := range i.Hosts {
 		if err := host.Validate(); err != nil {
@@ -109,6 +143,7 @@ type Kubernetes struct {
 	Config     dig.Mapping `yaml:"config,omitempty" json:"config,omitempty"`
 	Infra      *Infra      `yaml:"infra,omitempty" json:"infra,omitempty"`
 	KubeConfig string      `yaml:"kubeconfig,omitempty" json:"kubeConfig,omitempty"`
+	Airgap     *Airgap     `yaml:"airgap,omitempty" json:"airgap,omitempty"`
 }
 
 var providerKinds = []string{constants.ProviderExisting, constants.ProviderKind, constants.ProviderK0s}
@@ -132,6 +167,14 @@ func (k *Kubernetes) Validate() error {
 		}
 	}
 
+	// Config checks
+	// The k0s config is checked offline, before it is sent to the hosts with k0sctl
+	if k.Provider == constants.ProviderK0s && len(k.Config) > 0 {
+		if err := k0sconfig.Validate(k.Config, k.Version); err != nil {
+			return err
+		}
+	}
+
 	// Infra checks
 	if k.Infra != nil {
 		if err := k.Infra.Validate(); err != nil {
@@ -139,6 +182,19 @@ func (k *Kubernetes) Validate() error {
 		}
 	}
 
+	// Airgap checks
+	if k.Airgap != nil {
+		if k.Provider != constants.ProviderK0s {
+			return fmt.Errorf("kubernetes.airgap is only supported with the %s provider", constants.ProviderK0s)
+		}
+		if k.Version == "" {
+			return fmt.Errorf("kubernetes.version must be set for an airgap install")
+		}
+		if err := k.Airgap.Validate(); err != nil {
+			return err
+		}
+	}
+
 	// KubeConfig checks
 	if k.KubeConfig != "" {
 		if _, err := os.Stat(k.KubeConfig); errors.Is(err, os.ErrNotExist) {
@@ -149,6 +205,45 @@ func (k *Kubernetes) Validate() error {
 	return nil
 }
 
+// Airgap are the files of a k0s install on hosts that cannot reach the internet
+type Airgap struct {
+	// K0sBinary is the path of the k0s binary of kubernetes.version for the architecture of the hosts
+	K0sBinary string `yaml:"k0sBinary,omitempty" json:"k0sBinary,omitempty"`
+	// ImageBundle is the path of the k0s airgap image bundle of kubernetes.version for the architecture of the hosts
+	ImageBundle string `yaml:"imageBundle,omitempty" json:"imageBundle,omitempty"`
+	// Fetch downloads the files that are not set from the k0s releases, while bctl is online, and caches them
+	Fetch bool `yaml:"fetch,omitempty" json:"fetch,omitempty"`
+	// Arch is the architecture of the hosts the files are fetched for, amd64 by default
+	Arch string `yaml:"arch,omitempty" json:"arch,omitempty"`
+}
+
+var airgapArchs = []string{"amd64", "arm64", "arm"}
+
+// Validate checks the Airgap structure
+func (a *Airgap) Validate() error {
+	files := []struct{ field, path string }{
+		{field: "k0sBinary", path: a.K0sBinary},
+		{field: "imageBundle", path: a.ImageBundle},
+	}
+	for _, file := range files {
+		if file.path == "" {
+			if !a.Fetch {
+				return fmt.Errorf("kubernetes.airgap.%s must be set unless kubernetes.airgap.fetch is true", file.field)
+			}
+			continue
+		}
+		if _, err := os.Stat(file.path); errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("kubernetes.airgap.%s file %q does not exist: %s", file.field, file.path, err)
+		}
+	}
+
+	if a.Arch != "" && !slices.Contains(airgapArchs, a.Arch) {
+		return fmt.Errorf("invalid kubernetes.airgap.arch: %s\nValid kubernetes.airgap.arch values: %s", a.Arch, airgapArchs)
+	}
+
+	return nil
+}
+
 type Components struct {
 	Addons []Addon `yaml:"addons,omitempty" json:"addons,omitempty"`
 }
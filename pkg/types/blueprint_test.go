@@ -2,8 +2,11 @@ package types
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/k0sproject/dig"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/types"
 )
@@ -128,6 +131,87 @@ func TestKubernetesValidateVersion(t *testing.T) {
 	}
 }
 
+// TestKubernetesValidateConfig tests that the k0s config is validated, and that a non-bool dynamicConfig is reported
+func TestKubernetesValidateConfig(t *testing.T) {
+	tests := map[string]struct {
+		provider string
+		config   dig.Mapping
+		want     types.GomegaMatcher
+	}{
+		"valid k0s config":       {provider: "k0s", config: dig.Mapping{"dynamicConfig": true, "spec": map[string]interface{}{"network": map[string]interface{}{"provider": "calico"}}}, want: BeNil()},
+		"unknown field":          {provider: "k0s", config: dig.Mapping{"spec": map[string]interface{}{"netwerk": map[string]interface{}{}}}, want: BeNil()},
+		"non-bool dynamicConfig": {provider: "k0s", config: dig.Mapping{"dynamicConfig": "true"}, want: MatchError("invalid kubernetes.config: dynamicConfig: expected true or false, got true")},
+		"other provider":         {provider: "kind", config: dig.Mapping{"anything": "goes"}, want: BeNil()},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			kubernetes := Kubernetes{Provider: tc.provider, Version: "1.31.1+k0s.0", Config: tc.config}
+			g.Expect(kubernetes.Validate()).Should(tc.want)
+
+			// converting the config does not panic, whatever the type of dynamicConfig
+			blueprint := &Blueprint{Spec: BlueprintSpec{Kubernetes: &kubernetes}}
+			kubernetes.Infra = &Infra{}
+			g.Expect(func() { ConvertToK0s(blueprint) }).ToNot(Panic())
+		})
+	}
+}
+
+// TestDigToString tests that a value that is not a string is read as an empty string
+func TestDigToString(t *testing.T) {
+	tests := map[string]struct {
+		config dig.Mapping
+		want   string
+	}{
+		"string":     {config: dig.Mapping{"spec": dig.Mapping{"storage": dig.Mapping{"type": "etcd"}}}, want: "etcd"},
+		"not set":    {config: dig.Mapping{"spec": dig.Mapping{}}, want: ""},
+		"not string": {config: dig.Mapping{"spec": dig.Mapping{"storage": dig.Mapping{"type": 1}}}, want: ""},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(func() { DigToString(tc.config, "spec", "storage", "type") }).ToNot(Panic())
+			g.Expect(DigToString(tc.config, "spec", "storage", "type")).To(Equal(tc.want))
+		})
+	}
+}
+
+// TestKubernetesValidateAirgap tests the validation of the files of an airgap install
+func TestKubernetesValidateAirgap(t *testing.T) {
+	binary := filepath.Join(t.TempDir(), "k0s")
+	if err := os.WriteFile(binary, []byte("k0s"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		provider string
+		version  string
+		airgap   Airgap
+		want     types.GomegaMatcher
+	}{
+		"local files":      {provider: "k0s", version: "1.31.1+k0s.0", airgap: Airgap{K0sBinary: binary, ImageBundle: binary}, want: BeNil()},
+		"fetched files":    {provider: "k0s", version: "1.31.1+k0s.0", airgap: Airgap{Fetch: true, Arch: "arm64"}, want: BeNil()},
+		"fetched bundle":   {provider: "k0s", version: "1.31.1+k0s.0", airgap: Airgap{K0sBinary: binary, Fetch: true}, want: BeNil()},
+		"missing bundle":   {provider: "k0s", version: "1.31.1+k0s.0", airgap: Airgap{K0sBinary: binary}, want: MatchError("kubernetes.airgap.imageBundle must be set unless kubernetes.airgap.fetch is true")},
+		"no such binary":   {provider: "k0s", version: "1.31.1+k0s.0", airgap: Airgap{K0sBinary: "/nonexistent/k0s", Fetch: true}, want: MatchError(ContainSubstring(`kubernetes.airgap.k0sBinary file "/nonexistent/k0s" does not exist`))},
+		"invalid arch":     {provider: "k0s", version: "1.31.1+k0s.0", airgap: Airgap{Fetch: true, Arch: "x86_64"}, want: MatchError(ContainSubstring("invalid kubernetes.airgap.arch: x86_64"))},
+		"no version":       {provider: "k0s", airgap: Airgap{Fetch: true}, want: MatchError("kubernetes.version must be set for an airgap install")},
+		"another provider": {provider: "kind", airgap: Airgap{Fetch: true}, want: MatchError("kubernetes.airgap is only supported with the k0s provider")},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			kubernetes := Kubernetes{Provider: tc.provider, Version: tc.version, Airgap: &tc.airgap}
+			g.Expect(kubernetes.Validate()).Should(tc.want)
+		})
+	}
+}
+
 // TestAddonValidateName tests the validation of a Addon's name
 func TestAddonValidateName(t *testing.T) {
 	tests := map[string]struct {
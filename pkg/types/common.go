@@ -6,6 +6,8 @@ import (
 	"os"
 	"regexp"
 	"slices"
+
+	"golang.org/x/crypto/ssh"
 )
 
 type Metadata struct {
@@ -24,6 +26,12 @@ type Host struct {
 	LocalHost    *LocalHost `yaml:"localhost,omitempty" json:"localHost,omitempty"`
 	Role         string     `yaml:"role" json:"role"`
 	InstallFlags []string   `yaml:"installFlags,omitempty" json:"installFlags,omitempty"`
+	// Environment are the environment variables set on the host for k0s
+	Environment map[string]string `yaml:"environment,omitempty" json:"environment,omitempty"`
+	// UploadBinary and K0sBinaryPath make k0sctl upload the local k0s binary of an airgap install to the host
+	// They are set from spec.kubernetes.airgap, and are not part of the blueprint
+	UploadBinary  bool   `yaml:"uploadBinary,omitempty" json:"-"`
+	K0sBinaryPath string `yaml:"k0sBinaryPath,omitempty" json:"-"`
 }
 
 var nodeRoles = []string{"single", "controller", "worker", "controller+worker"}
@@ -58,40 +66,106 @@ func (h *Host) Validate() error {
 
 type SSHHost struct {
 	Address string `yaml:"address" json:"address"`
-	KeyPath string `yaml:"keyPath" json:"keyPath"`
+	// KeyPath is the private key to authenticate with; it can be left out to use the keys of the ssh-agent
+	// An OpenSSH certificate next to the key, e.g. id_ed25519-cert.pub, is used with it.
+	KeyPath string `yaml:"keyPath,omitempty" json:"keyPath,omitempty"`
 	Port    int    `yaml:"port" json:"port"`
 	User    string `yaml:"user" json:"user"`
+	// Bastion is the jump host the host is reached through
+	Bastion *SSHHost `yaml:"bastion,omitempty" json:"bastion,omitempty"`
 }
 
 // Validate checks the SSHHost structure and its children
 func (sh *SSHHost) Validate() error {
+	return sh.validate("hosts.ssh")
+}
+
+// validate checks the fields of the SSHHost, reporting them under the field prefix
+func (sh *SSHHost) validate(field string) error {
 
 	// Address checks
 	if sh.Address == "" {
-		return fmt.Errorf("hosts.ssh.address field cannot be left empty")
+		return fmt.Errorf("%s.address field cannot be left empty", field)
 	}
 	// This regex is for either valid hostnames or ip addresses
 	re, _ := regexp.Compile(`^(([a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9\-]*[a-zA-Z0-9])\.)*([A-Za-z0-9]|[A-Za-z0-9][A-Za-z0-9\-]*[A-Za-z0-9])$`)
 	if !re.MatchString(sh.Address) {
-		return fmt.Errorf("invalid hosts.ssh.address: %s", sh.Address)
+		return fmt.Errorf("invalid %s.address: %s", field, sh.Address)
 	}
 
 	// KeyPath checks
 	if sh.KeyPath == "" {
-		return fmt.Errorf("hosts.ssh.keypath field cannot be left empty")
-	}
-	if _, err := os.Stat(sh.KeyPath); errors.Is(err, os.ErrNotExist) {
-		return fmt.Errorf("hosts.ssh.keypath does not exist: %s", sh.KeyPath)
+		if os.Getenv("SSH_AUTH_SOCK") == "" {
+			return fmt.Errorf("%s.keypath field cannot be left empty unless SSH_AUTH_SOCK is set to use an ssh-agent", field)
+		}
+	} else if _, err := os.Stat(sh.KeyPath); errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s.keypath does not exist: %s", field, sh.KeyPath)
 	}
 
 	// Port checks
 	if sh.Port <= 0 || sh.Port > 65535 {
-		return fmt.Errorf("hosts.ssh.port outside of valid range 0-65535")
+		return fmt.Errorf("%s.port outside of valid range 0-65535", field)
 	}
 
 	// User checks
 	if sh.User == "" {
-		return fmt.Errorf("hosts.ssh.user cannot be left empty")
+		return fmt.Errorf("%s.user cannot be left empty", field)
+	}
+
+	// Bastion checks
+	if sh.Bastion != nil {
+		if err := sh.Bastion.validate(field + ".bastion"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NeedsAgent tells whether k0sctl has to get the key of the host from the ssh-agent of bctl
+// k0sctl cannot use an encrypted key or the OpenSSH certificate next to the key by itself.
+func (sh *SSHHost) NeedsAgent() bool {
+	if sh.KeyPath == "" {
+		return false
+	}
+	if _, err := os.Stat(sh.KeyPath + "-cert.pub"); err == nil {
+		return true
+	}
+	data, err := os.ReadFile(sh.KeyPath)
+	if err != nil {
+		return false
+	}
+	_, err = ssh.ParseRawPrivateKey(data)
+	var missing *ssh.PassphraseMissingError
+	return errors.As(err, &missing)
+}
+
+// HostsFrom imports the hosts from a Terraform state or output, or from an Ansible inventory
+type HostsFrom struct {
+	// Terraform is a Terraform state file, or the JSON of terraform output -json; each output is a group of hosts
+	Terraform string `yaml:"terraform,omitempty" json:"terraform,omitempty"`
+	// Ansible is an Ansible inventory, in the INI or the YAML format
+	Ansible string `yaml:"ansible,omitempty" json:"ansible,omitempty"`
+	// Roles are the roles of the hosts by group; the hosts of the other groups are not imported
+	Roles map[string]string `yaml:"roles" json:"roles"`
+	// SSH are the SSH settings of the imported hosts that the inventory does not set, e.g. the user and the key
+	SSH *SSHHost `yaml:"ssh,omitempty" json:"ssh,omitempty"`
+}
+
+// Validate checks the HostsFrom structure
+// The imported hosts are checked with Host.Validate once they are imported.
+func (hf *HostsFrom) Validate() error {
+	if (hf.Terraform == "") == (hf.Ansible == "") {
+		return fmt.Errorf("exactly one of hostsFrom.terraform and hostsFrom.ansible must be set")
+	}
+
+	if len(hf.Roles) == 0 {
+		return fmt.Errorf("hostsFrom.roles field cannot be left empty")
+	}
+	for group, role := range hf.Roles {
+		if !slices.Contains(nodeRoles, role) {
+			return fmt.Errorf("invalid hostsFrom.roles role for group %s: %s\nValid hosts.role values: %s", group, role, nodeRoles)
+		}
 	}
 
 	return nil
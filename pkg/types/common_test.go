@@ -1,12 +1,20 @@
 package types
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
 	"fmt"
+	"os"
+	"path/filepath"
 	"runtime"
 	"testing"
 
+	"github.com/k0sproject/dig"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/types"
+	"golang.org/x/crypto/ssh"
+	"gopkg.in/yaml.v2"
 )
 
 var (
@@ -78,18 +86,21 @@ func TestSSHHostValidateAddress(t *testing.T) {
 // TestSSHHostValidateKeypath tests the validation of a SSHHost's keypath
 func TestSSHHostValidateKeypath(t *testing.T) {
 	tests := map[string]struct {
-		keypath string
-		want    types.GomegaMatcher
+		keypath   string
+		agentSock string
+		want      types.GomegaMatcher
 	}{
-		"valid filepath":   {keypath: thisFile, want: BeNil()},
-		"invalid filepath": {keypath: "/tmp/keypath", want: Equal(fmt.Errorf("hosts.ssh.keypath does not exist: /tmp/keypath"))},
-		"no keypath":       {keypath: "", want: Equal(fmt.Errorf("hosts.ssh.keypath field cannot be left empty"))},
+		"valid filepath":       {keypath: thisFile, want: BeNil()},
+		"invalid filepath":     {keypath: "/tmp/keypath", want: Equal(fmt.Errorf("hosts.ssh.keypath does not exist: /tmp/keypath"))},
+		"no keypath":           {keypath: "", want: Equal(fmt.Errorf("hosts.ssh.keypath field cannot be left empty unless SSH_AUTH_SOCK is set to use an ssh-agent"))},
+		"no keypath and agent": {keypath: "", agentSock: "/tmp/ssh-agent.sock", want: BeNil()},
 	}
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
 			// Set up the test environment
 			g := NewWithT(t)
+			t.Setenv("SSH_AUTH_SOCK", tc.agentSock)
 
 			// Run the method under test
 			sshHost := SSHHost{
@@ -169,3 +180,179 @@ func TestSSHHostValidateUser(t *testing.T) {
 		})
 	}
 }
+
+// TestSSHHostValidateBastion tests the validation of a SSHHost's bastion
+func TestSSHHostValidateBastion(t *testing.T) {
+	tests := map[string]struct {
+		bastion *SSHHost
+		want    types.GomegaMatcher
+	}{
+		"no bastion":    {bastion: nil, want: BeNil()},
+		"valid bastion": {bastion: &SSHHost{Address: "bastion", KeyPath: thisFile, Port: 2222, User: "jump"}, want: BeNil()},
+		"no address":    {bastion: &SSHHost{KeyPath: thisFile, Port: 22, User: "jump"}, want: Equal(fmt.Errorf("hosts.ssh.bastion.address field cannot be left empty"))},
+		"invalid port":  {bastion: &SSHHost{Address: "bastion", KeyPath: thisFile, User: "jump"}, want: Equal(fmt.Errorf("hosts.ssh.bastion.port outside of valid range 0-65535"))},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Set up the test environment
+			g := NewWithT(t)
+
+			// Run the method under test
+			sshHost := SSHHost{
+				Address: "localhost", // This is required for Validate() to work but not tested here
+				KeyPath: thisFile,    // This is required for Validate() to work but not tested here
+				Port:    22,          // This is required for Validate() to work but not tested here
+				User:    "Bob",       // This is required for Validate() to work but not tested here
+				Bastion: tc.bastion,
+			}
+			actual := sshHost.Validate()
+
+			// Check the results
+			g.Expect(actual).Should(tc.want)
+
+		})
+	}
+}
+
+// TestHostsFromValidate tests the validation of the source and the roles of the imported hosts
+func TestHostsFromValidate(t *testing.T) {
+	tests := map[string]struct {
+		hostsFrom HostsFrom
+		want      types.GomegaMatcher
+	}{
+		"terraform": {hostsFrom: HostsFrom{Terraform: "tf.json", Roles: map[string]string{"controllers": "controller"}}, want: BeNil()},
+		"ansible":   {hostsFrom: HostsFrom{Ansible: "hosts.ini", Roles: map[string]string{"workers": "worker"}}, want: BeNil()},
+		"no source": {hostsFrom: HostsFrom{Roles: map[string]string{"workers": "worker"}}, want: Equal(fmt.Errorf("exactly one of hostsFrom.terraform and hostsFrom.ansible must be set"))},
+		"both sources": {
+			hostsFrom: HostsFrom{Terraform: "tf.json", Ansible: "hosts.ini", Roles: map[string]string{"workers": "worker"}},
+			want:      Equal(fmt.Errorf("exactly one of hostsFrom.terraform and hostsFrom.ansible must be set")),
+		},
+		"no roles": {hostsFrom: HostsFrom{Ansible: "hosts.ini"}, want: Equal(fmt.Errorf("hostsFrom.roles field cannot be left empty"))},
+		"wrong role": {
+			hostsFrom: HostsFrom{Ansible: "hosts.ini", Roles: map[string]string{"db": "database"}},
+			want:      Equal(fmt.Errorf("invalid hostsFrom.roles role for group db: database\nValid hosts.role values: [single controller worker controller+worker]")),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(tc.hostsFrom.Validate()).Should(tc.want)
+		})
+	}
+}
+
+// TestConvertToK0sBastion tests that the bastion of a host is written into the k0sctl config
+func TestConvertToK0sBastion(t *testing.T) {
+	g := NewWithT(t)
+
+	blueprint := &Blueprint{Spec: BlueprintSpec{
+		Kubernetes: &Kubernetes{Provider: "k0s", Infra: &Infra{Hosts: []Host{{
+			Role: "controller",
+			SSH: &SSHHost{
+				Address: "10.0.0.1", KeyPath: "~/.ssh/id_rsa", Port: 22, User: "root",
+				Bastion: &SSHHost{Address: "bastion.example.com", KeyPath: "~/.ssh/bastion", Port: 2222, User: "jump"},
+			},
+		}}}},
+	}}
+
+	data, err := yaml.Marshal(ConvertToK0s(blueprint))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	var config dig.Mapping
+	g.Expect(yaml.Unmarshal(data, &config)).To(Succeed())
+	host := config.Dig("spec", "hosts").([]interface{})[0].(dig.Mapping)
+	bastion := host.Dig("ssh", "bastion")
+	g.Expect(bastion).To(HaveKeyWithValue("address", "bastion.example.com"))
+	g.Expect(bastion).To(HaveKeyWithValue("port", 2222))
+	g.Expect(bastion).To(HaveKeyWithValue("user", "jump"))
+	g.Expect(bastion).To(HaveKeyWithValue("keyPath", "~/.ssh/bastion"))
+}
+
+// TestConvertToK0sAgentKeys tests that the keys k0sctl cannot use by itself are left out of the k0sctl config,
+// so that k0sctl gets them from the ssh-agent of bctl
+func TestConvertToK0sAgentKeys(t *testing.T) {
+	g := NewWithT(t)
+
+	plain := writeTestKey(t, "id_plain", "")
+	encrypted := writeTestKey(t, "id_encrypted", "secret")
+	certified := writeTestKey(t, "id_certified", "")
+	g.Expect(os.WriteFile(certified+"-cert.pub", []byte("certificate"), 0o600)).To(Succeed())
+
+	blueprint := &Blueprint{Spec: BlueprintSpec{
+		Kubernetes: &Kubernetes{Provider: "k0s", Infra: &Infra{Hosts: []Host{
+			{Role: "controller", SSH: &SSHHost{Address: "10.0.0.1", KeyPath: plain, Port: 22, User: "root"}},
+			{Role: "worker", SSH: &SSHHost{
+				Address: "10.0.0.2", KeyPath: encrypted, Port: 22, User: "root",
+				Bastion: &SSHHost{Address: "bastion.example.com", KeyPath: certified, Port: 22, User: "jump"},
+			}},
+			{Role: "worker", SSH: &SSHHost{Address: "10.0.0.3", Port: 22, User: "root"}},
+		}}},
+	}}
+
+	data, err := yaml.Marshal(ConvertToK0s(blueprint))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	var config dig.Mapping
+	g.Expect(yaml.Unmarshal(data, &config)).To(Succeed())
+	hosts := config.Dig("spec", "hosts").([]interface{})
+	plainHost, encryptedHost, agentHost := hosts[0].(dig.Mapping), hosts[1].(dig.Mapping), hosts[2].(dig.Mapping)
+	g.Expect(plainHost.Dig("ssh")).To(HaveKeyWithValue("keyPath", plain))
+	g.Expect(encryptedHost.Dig("ssh")).ToNot(HaveKey("keyPath"))
+	g.Expect(encryptedHost.Dig("ssh", "bastion")).ToNot(HaveKey("keyPath"))
+	g.Expect(agentHost.Dig("ssh")).ToNot(HaveKey("keyPath"))
+	// the blueprint hosts are left as they are
+	g.Expect(blueprint.Spec.Kubernetes.Infra.Hosts[1].SSH.KeyPath).To(Equal(encrypted))
+	g.Expect(blueprint.Spec.Kubernetes.Infra.Hosts[1].SSH.Bastion.KeyPath).To(Equal(certified))
+}
+
+// writeTestKey writes a private key, encrypted with the passphrase if any, and returns its path
+func writeTestKey(t *testing.T, name string, passphrase string) string {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var block *pem.Block
+	if passphrase == "" {
+		block, err = ssh.MarshalPrivateKey(private, "")
+	} else {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(private, "", []byte(passphrase))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestConvertToK0sAirgap tests that k0sctl uploads the k0s binary of an airgap install to every host
+func TestConvertToK0sAirgap(t *testing.T) {
+	g := NewWithT(t)
+
+	blueprint := &Blueprint{Spec: BlueprintSpec{
+		Kubernetes: &Kubernetes{
+			Provider: "k0s",
+			Airgap:   &Airgap{K0sBinary: "/airgap/k0s", ImageBundle: "/airgap/bundle"},
+			Infra: &Infra{Hosts: []Host{
+				{Role: "controller", SSH: &SSHHost{Address: "10.0.0.1", KeyPath: "~/.ssh/id_rsa", Port: 22, User: "root"}},
+				{Role: "worker", SSH: &SSHHost{Address: "10.0.0.2", KeyPath: "~/.ssh/id_rsa", Port: 22, User: "root"}},
+			}},
+		},
+	}}
+
+	data, err := yaml.Marshal(ConvertToK0s(blueprint))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	var config dig.Mapping
+	g.Expect(yaml.Unmarshal(data, &config)).To(Succeed())
+	for _, host := range config.Dig("spec", "hosts").([]interface{}) {
+		g.Expect(host).To(HaveKeyWithValue("uploadBinary", true))
+		g.Expect(host).To(HaveKeyWithValue("k0sBinaryPath", "/airgap/k0s"))
+	}
+	// the blueprint hosts are left as they are
+	g.Expect(blueprint.Spec.Kubernetes.Infra.Hosts[0].UploadBinary).To(BeFalse())
+}
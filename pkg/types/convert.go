@@ -46,7 +46,7 @@ func ConvertToK0s(cluster *Blueprint) K0sCluster {
 			Name: cluster.Metadata.Name,
 		},
 		Spec: K0sClusterSpec{
-			Hosts: cluster.Spec.Kubernetes.Infra.Hosts,
+			Hosts: k0sHosts(cluster),
 			K0S: K0s{
 				Version:       cluster.Spec.Kubernetes.Version,
 				DynamicConfig: digBool(cluster.Spec.Kubernetes.Config, "dynamicConfig"),
@@ -56,6 +56,43 @@ func ConvertToK0s(cluster *Blueprint) K0sCluster {
 	}
 }
 
+// k0sHosts returns the hosts of the blueprint with the proxy environment of the blueprint,
+// and with the k0s binary of the airgap install to upload
+// Environment variables set on a host take precedence over the proxy ones.
+// The keys k0sctl cannot use by itself are left out, so that it gets them from the ssh-agent of bctl.
+func k0sHosts(cluster *Blueprint) []Host {
+	proxyEnv := cluster.Spec.ProxyEnv()
+	airgap := cluster.Spec.Kubernetes.Airgap
+
+	hosts := make([]Host, len(cluster.Spec.Kubernetes.Infra.Hosts))
+	for i, host := range cluster.Spec.Kubernetes.Infra.Hosts {
+		if proxyEnv != nil {
+			host.Environment = cluster.Spec.HostEnvironment(host)
+		}
+		if airgap != nil && airgap.K0sBinary != "" {
+			host.UploadBinary = true
+			host.K0sBinaryPath = airgap.K0sBinary
+		}
+		if host.SSH != nil {
+			host.SSH = k0sSSHHost(host.SSH)
+		}
+		hosts[i] = host
+	}
+	return hosts
+}
+
+// k0sSSHHost returns the SSH host, and its bastion, without the key when k0sctl needs the ssh-agent of bctl for it
+func k0sSSHHost(host *SSHHost) *SSHHost {
+	ssh := *host
+	if ssh.NeedsAgent() {
+		ssh.KeyPath = ""
+	}
+	if ssh.Bastion != nil {
+		ssh.Bastion = k0sSSHHost(ssh.Bastion)
+	}
+	return &ssh
+}
+
 func ConvertToClusterWithK0s(k0s K0sCluster, components Components) Blueprint {
 	return Blueprint{
 		APIVersion: apiVersion,
@@ -95,18 +132,15 @@ func ConvertToClusterWithKind(name string, components Components) Blueprint {
 	}
 }
 
+// DigToString returns the string at the keys, or an empty string when it is not set or not a string
 func DigToString(m dig.Mapping, keys ...string) string {
-	val := m.Dig(keys...)
-	if val == nil {
-		return ""
-	}
-	return val.(string)
+	val, _ := m.Dig(keys...).(string)
+	return val
 }
 
+// digBool returns the boolean at the keys, or false when it is not set or not a boolean
+// Values of other types are reported when the config is validated.
 func digBool(m dig.Mapping, keys ...string) bool {
-	val := m.Dig(keys...)
-	if val == nil {
-		return false
-	}
-	return val.(bool)
+	val, _ := m.Dig(keys...).(bool)
+	return val
 }
@@ -0,0 +1,193 @@
+package types
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/k0sproject/dig"
+)
+
+var fleetKinds = []string{"Fleet"}
+
+// Fleet lists the clusters a blueprint is applied to
+type Fleet struct {
+	APIVersion string    `yaml:"apiVersion" json:"apiVersion"`
+	Kind       string    `yaml:"kind" json:"kind"`
+	Metadata   Metadata  `yaml:"metadata" json:"metadata"`
+	Spec       FleetSpec `yaml:"spec" json:"spec"`
+}
+
+// Validate checks the Fleet structure and its children
+func (f *Fleet) Validate() error {
+	if f.APIVersion == "" {
+		return fmt.Errorf("apiVersion field cannot be left blank")
+	}
+	if f.Kind == "" {
+		return fmt.Errorf("kind field cannot be left blank")
+	}
+	if !slices.Contains(fleetKinds, f.Kind) {
+		return fmt.Errorf("invalid fleet kind: %s", f.Kind)
+	}
+
+	return f.Spec.Validate()
+}
+
+type FleetSpec struct {
+	// Blueprint is the path to the blueprint shared by the clusters, relative to the fleet file
+	Blueprint string `yaml:"blueprint" json:"blueprint"`
+	// Concurrency is the number of clusters changed at the same time; 0 means all the clusters of a wave
+	Concurrency int `yaml:"concurrency,omitempty" json:"concurrency,omitempty"`
+	// Waves is the order in which the waves of clusters are rolled out
+	// Clusters without a wave are rolled out after all the waves
+	Waves    []string       `yaml:"waves,omitempty" json:"waves,omitempty"`
+	Clusters []FleetCluster `yaml:"clusters" json:"clusters"`
+}
+
+// Validate checks the FleetSpec structure and its children
+func (fs *FleetSpec) Validate() error {
+	if fs.Blueprint == "" {
+		return fmt.Errorf("fleet blueprint field cannot be left blank")
+	}
+	if fs.Concurrency < 0 {
+		return fmt.Errorf("invalid fleet concurrency %d: must not be negative", fs.Concurrency)
+	}
+	if len(fs.Clusters) == 0 {
+		return fmt.Errorf("fleet must have at least one cluster")
+	}
+
+	for i, wave := range fs.Waves {
+		if wave == "" {
+			return fmt.Errorf("fleet wave names cannot be left blank")
+		}
+		if slices.Contains(fs.Waves[:i], wave) {
+			return fmt.Errorf("duplicate fleet wave %q", wave)
+		}
+	}
+
+	var names []string
+	for _, cluster := range fs.Clusters {
+		if err := cluster.Validate(); err != nil {
+			return err
+		}
+		if slices.Contains(names, cluster.Name) {
+			return fmt.Errorf("duplicate fleet cluster %q", cluster.Name)
+		}
+		names = append(names, cluster.Name)
+
+		if cluster.Wave != "" && !slices.Contains(fs.Waves, cluster.Wave) {
+			return fmt.Errorf("cluster %q uses unknown wave %q (valid values: %v)", cluster.Name, cluster.Wave, fs.Waves)
+		}
+	}
+
+	return nil
+}
+
+// FleetCluster is a cluster of the fleet
+type FleetCluster struct {
+	Name string `yaml:"name" json:"name"`
+	// KubeConfig is the path to the kubeconfig of the cluster, relative to the fleet file
+	// The kubeconfig of the blueprint is used if it is not set
+	KubeConfig string `yaml:"kubeconfig,omitempty" json:"kubeConfig,omitempty"`
+	// Context is the kubeconfig context of the cluster
+	Context string `yaml:"context,omitempty" json:"context,omitempty"`
+	// Wave is the wave the cluster is rolled out in
+	Wave string `yaml:"wave,omitempty" json:"wave,omitempty"`
+	// Overrides are merged into the blueprint for this cluster, see MergeOverrides
+	Overrides dig.Mapping `yaml:"overrides,omitempty" json:"overrides,omitempty"`
+}
+
+// Validate checks the FleetCluster structure
+func (fc *FleetCluster) Validate() error {
+	if fc.Name == "" {
+		return fmt.Errorf("fleet cluster name cannot be left blank")
+	}
+	if err := validateNoOverride(fc.Overrides, "apiVersion"); err != nil {
+		return fmt.Errorf("invalid overrides of cluster %q: %w", fc.Name, err)
+	}
+	if err := validateNoOverride(fc.Overrides, "kind"); err != nil {
+		return fmt.Errorf("invalid overrides of cluster %q: %w", fc.Name, err)
+	}
+	return nil
+}
+
+func validateNoOverride(overrides dig.Mapping, key string) error {
+	if _, ok := overrides[key]; ok {
+		return fmt.Errorf("%s cannot be overridden", key)
+	}
+	return nil
+}
+
+// MergeOverrides merges the overrides into the blueprint document and returns the result
+// Mappings are merged recursively and a null value removes the key, as in a JSON merge patch.
+// Lists of named objects, like the addons, are merged by name, so that a single addon can be overridden;
+// an override item with a name that is not in the blueprint is appended. Other lists are replaced.
+func MergeOverrides(document dig.Mapping, overrides dig.Mapping) dig.Mapping {
+	merged := document.Dup()
+	for key, value := range overrides {
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+		merged[key] = mergeValue(merged[key], value)
+	}
+	return merged
+}
+
+func mergeValue(current any, override any) any {
+	if overrideMapping, ok := asMapping(override); ok {
+		if currentMapping, ok := asMapping(current); ok {
+			return MergeOverrides(currentMapping, overrideMapping)
+		}
+		return override
+	}
+
+	overrideList, ok := override.([]any)
+	if !ok {
+		return override
+	}
+	currentList, ok := current.([]any)
+	if !ok || !namedItems(currentList) || !namedItems(overrideList) {
+		return override
+	}
+
+	merged := slices.Clone(currentList)
+	for _, item := range overrideList {
+		overrideItem, _ := asMapping(item)
+		i := slices.IndexFunc(merged, func(existing any) bool {
+			existingItem, _ := asMapping(existing)
+			return existingItem["name"] == overrideItem["name"]
+		})
+		if i < 0 {
+			merged = append(merged, overrideItem)
+			continue
+		}
+		existingItem, _ := asMapping(merged[i])
+		merged[i] = MergeOverrides(existingItem, overrideItem)
+	}
+	return merged
+}
+
+// namedItems returns true if all the items of the list are mappings with a name
+func namedItems(list []any) bool {
+	for _, item := range list {
+		m, ok := asMapping(item)
+		if !ok {
+			return false
+		}
+		if _, ok := m["name"].(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func asMapping(value any) (dig.Mapping, bool) {
+	switch v := value.(type) {
+	case dig.Mapping:
+		return v, true
+	case map[string]any:
+		return v, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,109 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/k0sproject/dig"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+)
+
+// TestFleetSpecValidate tests the validation of the fleet clusters and waves
+func TestFleetSpecValidate(t *testing.T) {
+	tests := map[string]struct {
+		spec    FleetSpec
+		wantErr string
+	}{
+		"valid": {
+			spec: FleetSpec{Blueprint: "blueprint.yaml", Waves: []string{"canary"}, Clusters: []FleetCluster{{Name: "a", Wave: "canary"}, {Name: "b"}}},
+		},
+		"no blueprint": {
+			spec:    FleetSpec{Clusters: []FleetCluster{{Name: "a"}}},
+			wantErr: "fleet blueprint field cannot be left blank",
+		},
+		"no clusters": {
+			spec:    FleetSpec{Blueprint: "blueprint.yaml"},
+			wantErr: "fleet must have at least one cluster",
+		},
+		"duplicate cluster": {
+			spec:    FleetSpec{Blueprint: "blueprint.yaml", Clusters: []FleetCluster{{Name: "a"}, {Name: "a"}}},
+			wantErr: `duplicate fleet cluster "a"`,
+		},
+		"unknown wave": {
+			spec:    FleetSpec{Blueprint: "blueprint.yaml", Clusters: []FleetCluster{{Name: "a", Wave: "canary"}}},
+			wantErr: `cluster "a" uses unknown wave "canary"`,
+		},
+		"negative concurrency": {
+			spec:    FleetSpec{Blueprint: "blueprint.yaml", Concurrency: -1, Clusters: []FleetCluster{{Name: "a"}}},
+			wantErr: "invalid fleet concurrency -1",
+		},
+		"kind override": {
+			spec:    FleetSpec{Blueprint: "blueprint.yaml", Clusters: []FleetCluster{{Name: "a", Overrides: dig.Mapping{"kind": "Other"}}}},
+			wantErr: "kind cannot be overridden",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := tc.spec.Validate()
+			if tc.wantErr == "" {
+				g.Expect(err).ToNot(HaveOccurred())
+			} else {
+				g.Expect(err).To(MatchError(ContainSubstring(tc.wantErr)))
+			}
+		})
+	}
+}
+
+// TestMergeOverrides tests that the overrides are merged like a JSON merge patch, with named list items merged by name
+func TestMergeOverrides(t *testing.T) {
+	tests := map[string]struct {
+		overrides string
+		want      string
+	}{
+		"mapping merged": {
+			overrides: `{"spec": {"kubernetes": {"kubeConfig": "eu-1.yaml"}}}`,
+			want:      `{"spec": {"kubernetes": {"provider": "existing", "kubeConfig": "eu-1.yaml"}, "components": {"addons": [{"name": "ingress", "enabled": true, "chart": {"version": "1.0.0"}}, {"name": "monitoring", "enabled": true}]}}}`,
+		},
+		"key removed": {
+			overrides: `{"spec": {"kubernetes": null}}`,
+			want:      `{"spec": {"components": {"addons": [{"name": "ingress", "enabled": true, "chart": {"version": "1.0.0"}}, {"name": "monitoring", "enabled": true}]}}}`,
+		},
+		"addon merged by name": {
+			overrides: `{"spec": {"components": {"addons": [{"name": "ingress", "chart": {"version": "2.0.0"}}, {"name": "logging", "enabled": true}]}}}`,
+			want:      `{"spec": {"kubernetes": {"provider": "existing"}, "components": {"addons": [{"name": "ingress", "enabled": true, "chart": {"version": "2.0.0"}}, {"name": "monitoring", "enabled": true}, {"name": "logging", "enabled": true}]}}}`,
+		},
+		"unnamed list replaced": {
+			overrides: `{"spec": {"components": {"addons": ["none"]}}}`,
+			want:      `{"spec": {"kubernetes": {"provider": "existing"}, "components": {"addons": ["none"]}}}`,
+		},
+	}
+
+	document := `{"spec": {"kubernetes": {"provider": "existing"}, "components": {"addons": [{"name": "ingress", "enabled": true, "chart": {"version": "1.0.0"}}, {"name": "monitoring", "enabled": true}]}}}`
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			var base, overrides, want dig.Mapping
+			g.Expect(yaml.Unmarshal([]byte(document), &base)).To(Succeed())
+			g.Expect(yaml.Unmarshal([]byte(tc.overrides), &overrides)).To(Succeed())
+			g.Expect(yaml.Unmarshal([]byte(tc.want), &want)).To(Succeed())
+
+			merged := MergeOverrides(base, overrides)
+
+			actual, err := yaml.Marshal(merged)
+			g.Expect(err).ToNot(HaveOccurred())
+			expected, err := yaml.Marshal(want)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(string(actual)).To(Equal(string(expected)))
+
+			// the blueprint document is left unchanged
+			original, err := yaml.Marshal(base)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(string(original)).To(MatchYAML(document))
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package types
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Operator defines customizations of the Blueprint Operator deployment
+type Operator struct {
+	Replicas          *int32                       `yaml:"replicas,omitempty" json:"replicas,omitempty"`
+	Resources         *corev1.ResourceRequirements `yaml:"resources,omitempty" json:"resources,omitempty"`
+	NodeSelector      map[string]string            `yaml:"nodeSelector,omitempty" json:"nodeSelector,omitempty"`
+	Tolerations       []corev1.Toleration          `yaml:"tolerations,omitempty" json:"tolerations,omitempty"`
+	Affinity          *corev1.Affinity             `yaml:"affinity,omitempty" json:"affinity,omitempty"`
+	PriorityClassName string                       `yaml:"priorityClassName,omitempty" json:"priorityClassName,omitempty"`
+	ExtraArgs         []string                     `yaml:"extraArgs,omitempty" json:"extraArgs,omitempty"`
+	Env               []corev1.EnvVar              `yaml:"env,omitempty" json:"env,omitempty"`
+}
+
+// Validate checks the Operator structure and its children
+func (o *Operator) Validate() error {
+	// Replicas checks
+	if o.Replicas != nil && *o.Replicas < 0 {
+		return fmt.Errorf("operator.replicas cannot be negative")
+	}
+
+	// Env checks
+	for _, env := range o.Env {
+		if env.Name == "" {
+			return fmt.Errorf("operator.env.name field cannot be left blank")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package types
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/k0sproject/dig"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
+)
+
+// defaultNoProxy are the addresses that are never reached through the proxy
+var defaultNoProxy = []string{"localhost", "127.0.0.1", ".svc", ".cluster.local"}
+
+// Proxy defines the HTTP proxy used by bctl, the Blueprint Operator and the k0s hosts
+type Proxy struct {
+	// HTTP is the proxy URL used for http requests
+	HTTP string `yaml:"http,omitempty" json:"http,omitempty"`
+	// HTTPS is the proxy URL used for https requests
+	HTTPS string `yaml:"https,omitempty" json:"https,omitempty"`
+	// NoProxy are the hosts, domains and CIDRs that are reached without the proxy
+	// The service and pod CIDRs of the cluster are added to it automatically
+	NoProxy []string `yaml:"noProxy,omitempty" json:"noProxy,omitempty"`
+}
+
+// Validate checks the Proxy structure and its children
+func (p *Proxy) Validate() error {
+	if p.HTTP == "" && p.HTTPS == "" {
+		return fmt.Errorf("proxy must specify at least one of http or https")
+	}
+	if err := validateProxyURL("http", p.HTTP); err != nil {
+		return err
+	}
+	if err := validateProxyURL("https", p.HTTPS); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func validateProxyURL(field string, proxy string) error {
+	if proxy == "" {
+		return nil
+	}
+
+	u, err := url.Parse(proxy)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid proxy.%s: %s", field, proxy)
+	}
+	if !slices.Contains([]string{"http", "https", "socks5"}, u.Scheme) {
+		return fmt.Errorf("invalid proxy.%s scheme %q: must be one of http, https or socks5", field, u.Scheme)
+	}
+
+	return nil
+}
+
+// Env returns the proxy environment variables, in upper and lower case since tools read either of them
+func (p *Proxy) Env(noProxy []string) map[string]string {
+	env := map[string]string{}
+	set := func(name string, value string) {
+		if value != "" {
+			env[name] = value
+			env[strings.ToLower(name)] = value
+		}
+	}
+
+	set("HTTP_PROXY", p.HTTP)
+	set("HTTPS_PROXY", p.HTTPS)
+	set("NO_PROXY", strings.Join(noProxy, ","))
+
+	return env
+}
+
+// NoProxy returns the addresses that are reached without the proxy: the ones of the blueprint,
+// the cluster service and pod CIDRs, the internal cluster domains and the addresses of the hosts
+func (bs *BlueprintSpec) NoProxy() []string {
+	if bs.Proxy == nil {
+		return nil
+	}
+
+	noProxy := slices.Clone(bs.Proxy.NoProxy)
+	noProxy = append(noProxy, defaultNoProxy...)
+	if bs.Kubernetes != nil {
+		noProxy = append(noProxy, bs.Kubernetes.ClusterCIDRs()...)
+		if bs.Kubernetes.Infra != nil {
+			for _, host := range bs.Kubernetes.Infra.Hosts {
+				if host.SSH != nil {
+					noProxy = append(noProxy, host.SSH.Address)
+				}
+			}
+		}
+	}
+
+	// keep the first occurrence of every address
+	var unique []string
+	for _, address := range noProxy {
+		if !slices.Contains(unique, address) {
+			unique = append(unique, address)
+		}
+	}
+	return unique
+}
+
+// ProxyEnv returns the proxy environment variables of the blueprint, or nil if no proxy is used
+func (bs *BlueprintSpec) ProxyEnv() map[string]string {
+	if bs.Proxy == nil {
+		return nil
+	}
+	return bs.Proxy.Env(bs.NoProxy())
+}
+
+// HostEnvironment returns the environment of the k0s host: the proxy environment of the blueprint, overridden by the one of the host
+func (bs *BlueprintSpec) HostEnvironment(host Host) map[string]string {
+	proxyEnv := bs.ProxyEnv()
+	if proxyEnv == nil {
+		return host.Environment
+	}
+	maps.Copy(proxyEnv, host.Environment)
+	return proxyEnv
+}
+
+// ClusterCIDRs returns the pod and service CIDRs of the cluster
+// They are read from the provider config, falling back to the defaults of the provider
+// The CIDRs of existing clusters are unknown, so they have to be added to the proxy noProxy explicitly
+func (k *Kubernetes) ClusterCIDRs() []string {
+	switch k.Provider {
+	case constants.ProviderK0s:
+		return []string{
+			digStringOr(k.Config, constants.DefaultPodCIDR, "spec", "network", "podCIDR"),
+			digStringOr(k.Config, constants.DefaultServiceCIDR, "spec", "network", "serviceCIDR"),
+		}
+	case constants.ProviderKind:
+		return []string{
+			digStringOr(k.Config, constants.DefaultPodCIDR, "networking", "podSubnet"),
+			digStringOr(k.Config, constants.DefaultServiceCIDR, "networking", "serviceSubnet"),
+		}
+	default:
+		return nil
+	}
+}
+
+// digStringOr returns the string at the keys of the mapping, or the fallback if it is not set
+func digStringOr(m dig.Mapping, fallback string, keys ...string) string {
+	if s, ok := m.Dig(keys...).(string); ok && s != "" {
+		return s
+	}
+	return fallback
+}
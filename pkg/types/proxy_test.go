@@ -0,0 +1,92 @@
+package types
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/k0sproject/dig"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/types"
+)
+
+// TestProxyValidate tests the validation of the proxy URLs
+func TestProxyValidate(t *testing.T) {
+	tests := map[string]struct {
+		http  string
+		https string
+		want  types.GomegaMatcher
+	}{
+		"valid proxies":   {http: "http://proxy:3128", https: "http://proxy:3128", want: BeNil()},
+		"https only":      {https: "https://proxy.example.com", want: BeNil()},
+		"no proxy":        {want: Equal(fmt.Errorf("proxy must specify at least one of http or https"))},
+		"missing scheme":  {http: "proxy:3128", want: Equal(fmt.Errorf("invalid proxy.http: proxy:3128"))},
+		"wrong scheme":    {https: "ftp://proxy:21", want: Equal(fmt.Errorf("invalid proxy.https scheme \"ftp\": must be one of http, https or socks5"))},
+		"host is missing": {https: "http://", want: Equal(fmt.Errorf("invalid proxy.https: http://"))},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Set up the test environment
+			g := NewWithT(t)
+
+			// Run the method under test
+			proxy := Proxy{HTTP: tc.http, HTTPS: tc.https}
+			actual := proxy.Validate()
+
+			// Check the results
+			g.Expect(actual).Should(tc.want)
+		})
+	}
+}
+
+// TestBlueprintSpecNoProxy tests that the cluster CIDRs and host addresses are added to the noProxy addresses
+func TestBlueprintSpecNoProxy(t *testing.T) {
+	tests := map[string]struct {
+		kubernetes *Kubernetes
+		want       []string
+	}{
+		"k0s with default CIDRs": {
+			kubernetes: &Kubernetes{Provider: "k0s", Infra: &Infra{Hosts: []Host{{SSH: &SSHHost{Address: "10.0.0.1"}}, {LocalHost: &LocalHost{Enabled: true}}}}},
+			want:       []string{"example.com", "localhost", "127.0.0.1", ".svc", ".cluster.local", "10.244.0.0/16", "10.96.0.0/12", "10.0.0.1"},
+		},
+		"k0s with custom CIDRs": {
+			kubernetes: &Kubernetes{Provider: "k0s", Config: dig.Mapping{"spec": dig.Mapping{"network": dig.Mapping{"podCIDR": "10.10.0.0/16", "serviceCIDR": "10.20.0.0/16"}}}},
+			want:       []string{"example.com", "localhost", "127.0.0.1", ".svc", ".cluster.local", "10.10.0.0/16", "10.20.0.0/16"},
+		},
+		"kind with custom pod subnet": {
+			kubernetes: &Kubernetes{Provider: "kind", Config: dig.Mapping{"networking": dig.Mapping{"podSubnet": "10.30.0.0/16"}}},
+			want:       []string{"example.com", "localhost", "127.0.0.1", ".svc", ".cluster.local", "10.30.0.0/16", "10.96.0.0/12"},
+		},
+		"existing cluster": {
+			kubernetes: &Kubernetes{Provider: "existing"},
+			want:       []string{"example.com", "localhost", "127.0.0.1", ".svc", ".cluster.local"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			spec := BlueprintSpec{Kubernetes: tc.kubernetes, Proxy: &Proxy{HTTP: "http://proxy:3128", NoProxy: []string{"example.com", "localhost"}}}
+			g.Expect(spec.NoProxy()).To(Equal(tc.want))
+		})
+	}
+}
+
+// TestConvertToK0sProxyEnvironment tests that the proxy is passed to the k0s hosts without overriding their environment
+func TestConvertToK0sProxyEnvironment(t *testing.T) {
+	g := NewWithT(t)
+
+	blueprint := &Blueprint{Spec: BlueprintSpec{
+		Kubernetes: &Kubernetes{Provider: "k0s", Infra: &Infra{Hosts: []Host{
+			{Role: "controller", Environment: map[string]string{"HTTP_PROXY": "http://local:3128"}},
+		}}},
+		Proxy: &Proxy{HTTP: "http://proxy:3128"},
+	}}
+
+	hosts := ConvertToK0s(blueprint).Spec.Hosts
+	g.Expect(hosts[0].Environment).To(HaveKeyWithValue("HTTP_PROXY", "http://local:3128"))
+	g.Expect(hosts[0].Environment).To(HaveKeyWithValue("http_proxy", "http://proxy:3128"))
+	g.Expect(hosts[0].Environment).To(HaveKey("NO_PROXY"))
+	g.Expect(blueprint.Spec.Kubernetes.Infra.Hosts[0].Environment).To(HaveLen(1))
+}
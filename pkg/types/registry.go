@@ -0,0 +1,69 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Registry defines the image registry settings used for the Blueprint Operator and addon images
+type Registry struct {
+	Credentials *RegistryCredentials `yaml:"credentials,omitempty" json:"credentials,omitempty"`
+}
+
+// Validate checks the Registry structure and its children
+func (r *Registry) Validate() error {
+	if r.Credentials != nil {
+		if err := r.Credentials.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RegistryCredentials defines how to authenticate against a private image registry
+// Either a docker config.json file or a pair of environment variables holding the username and password can be used
+type RegistryCredentials struct {
+	// DockerConfig is the path to a docker config.json file containing the registry auths
+	DockerConfig string `yaml:"dockerConfig,omitempty" json:"dockerConfig,omitempty"`
+	// Server is the registry host used with username/password credentials; defaults to the host of the image registry
+	Server string `yaml:"server,omitempty" json:"server,omitempty"`
+	// UsernameEnv is the name of the environment variable holding the registry username
+	UsernameEnv string `yaml:"usernameEnv,omitempty" json:"usernameEnv,omitempty"`
+	// PasswordEnv is the name of the environment variable holding the registry password
+	PasswordEnv string `yaml:"passwordEnv,omitempty" json:"passwordEnv,omitempty"`
+	// CopyToAddonNamespaces copies the pull secret into the namespace of every addon, and adds it to its service accounts
+	CopyToAddonNamespaces bool `yaml:"copyToAddonNamespaces,omitempty" json:"copyToAddonNamespaces,omitempty"`
+}
+
+// Validate checks the RegistryCredentials structure and its children
+func (rc *RegistryCredentials) Validate() error {
+	usesEnv := rc.UsernameEnv != "" || rc.PasswordEnv != ""
+
+	if rc.DockerConfig == "" && !usesEnv {
+		return fmt.Errorf("registry.credentials must specify either dockerConfig or usernameEnv and passwordEnv")
+	}
+	if rc.DockerConfig != "" && usesEnv {
+		return fmt.Errorf("registry.credentials cannot contain both dockerConfig and usernameEnv/passwordEnv")
+	}
+
+	// DockerConfig checks
+	if rc.DockerConfig != "" {
+		if _, err := os.Stat(rc.DockerConfig); errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("registry.credentials.dockerConfig does not exist: %s", rc.DockerConfig)
+		}
+	}
+
+	// Env checks
+	if usesEnv {
+		if rc.UsernameEnv == "" {
+			return fmt.Errorf("registry.credentials.usernameEnv cannot be left empty")
+		}
+		if rc.PasswordEnv == "" {
+			return fmt.Errorf("registry.credentials.passwordEnv cannot be left empty")
+		}
+	}
+
+	return nil
+}
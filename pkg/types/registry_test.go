@@ -0,0 +1,46 @@
+package types
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/types"
+)
+
+// TestRegistryCredentialsValidate tests the validation of a RegistryCredentials' sources
+func TestRegistryCredentialsValidate(t *testing.T) {
+	tests := map[string]struct {
+		dockerConfig string
+		usernameEnv  string
+		passwordEnv  string
+		want         types.GomegaMatcher
+	}{
+		"valid docker config":     {dockerConfig: thisFile, want: BeNil()},
+		"valid env credentials":   {usernameEnv: "REGISTRY_USER", passwordEnv: "REGISTRY_PASSWORD", want: BeNil()},
+		"no credentials":          {want: Equal(fmt.Errorf("registry.credentials must specify either dockerConfig or usernameEnv and passwordEnv"))},
+		"both credential sources": {dockerConfig: thisFile, usernameEnv: "REGISTRY_USER", want: Equal(fmt.Errorf("registry.credentials cannot contain both dockerConfig and usernameEnv/passwordEnv"))},
+		"missing docker config":   {dockerConfig: "/tmp/config.json", want: Equal(fmt.Errorf("registry.credentials.dockerConfig does not exist: /tmp/config.json"))},
+		"missing username env":    {passwordEnv: "REGISTRY_PASSWORD", want: Equal(fmt.Errorf("registry.credentials.usernameEnv cannot be left empty"))},
+		"missing password env":    {usernameEnv: "REGISTRY_USER", want: Equal(fmt.Errorf("registry.credentials.passwordEnv cannot be left empty"))},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Set up the test environment
+			g := NewWithT(t)
+
+			// Run the method under test
+			creds := RegistryCredentials{
+				DockerConfig: tc.dockerConfig,
+				UsernameEnv:  tc.usernameEnv,
+				PasswordEnv:  tc.passwordEnv,
+			}
+			actual := creds.Validate()
+
+			// Check the results
+			g.Expect(actual).Should(tc.want)
+
+		})
+	}
+}
@@ -1,7 +1,11 @@
 package utils
 
 import (
+	"fmt"
+	"path/filepath"
+
 	"github.com/a8m/envsubst"
+	"github.com/mirantiscontainers/blueprint-cli/pkg/inventory"
 	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
 	"github.com/rs/zerolog/log"
 )
@@ -29,5 +33,29 @@ func LoadBlueprint(path string) (types.Blueprint, error) {
 		return types.Blueprint{}, err
 	}
 
+	if err := ImportHosts(&cfg, filepath.Dir(path)); err != nil {
+		return types.Blueprint{}, err
+	}
+
 	return cfg, nil
 }
+
+// ImportHosts adds the hosts of spec.kubernetes.infra.hostsFrom to the hosts of the blueprint
+// The path of the inventory is relative to dir, the directory of the blueprint file.
+func ImportHosts(blueprint *types.Blueprint, dir string) error {
+	if blueprint.Spec.Kubernetes == nil || blueprint.Spec.Kubernetes.Infra == nil || blueprint.Spec.Kubernetes.Infra.HostsFrom == nil {
+		return nil
+	}
+
+	infra := blueprint.Spec.Kubernetes.Infra
+	if err := infra.HostsFrom.Validate(); err != nil {
+		return err
+	}
+	hosts, err := inventory.Import(infra.HostsFrom, dir)
+	if err != nil {
+		return fmt.Errorf("invalid spec.kubernetes.infra.hostsFrom: %w", err)
+	}
+	log.Debug().Msgf("Imported %d hosts", len(hosts))
+	infra.Hosts = inventory.Merge(infra.Hosts, hosts)
+	return nil
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/a8m/envsubst"
+	"github.com/k0sproject/dig"
+	"github.com/rs/zerolog/log"
+	"sigs.k8s.io/yaml"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+const DefaultFleetPath = "fleet.yaml"
+
+// LoadFleet loads and validates the fleet file
+// The paths of the blueprint and of the cluster kubeconfigs are resolved relative to the fleet file
+func LoadFleet(path string) (types.Fleet, error) {
+	if path == "" {
+		path = DefaultFleetPath
+	}
+
+	content, err := ReadFile(path)
+	if err != nil {
+		return types.Fleet{}, err
+	}
+
+	subst, err := envsubst.Bytes(content)
+	if err != nil {
+		return types.Fleet{}, err
+	}
+
+	var fleet types.Fleet
+	if err := yaml.Unmarshal(subst, &fleet); err != nil {
+		return types.Fleet{}, err
+	}
+	if err := fleet.Validate(); err != nil {
+		return types.Fleet{}, err
+	}
+
+	dir := filepath.Dir(path)
+	fleet.Spec.Blueprint = relativeTo(dir, fleet.Spec.Blueprint)
+	for i := range fleet.Spec.Clusters {
+		fleet.Spec.Clusters[i].KubeConfig = relativeTo(dir, fleet.Spec.Clusters[i].KubeConfig)
+	}
+
+	return fleet, nil
+}
+
+// LoadFleetBlueprint loads the blueprint of the fleet with the overrides of the cluster merged into it
+func LoadFleetBlueprint(fleet *types.Fleet, cluster *types.FleetCluster) (types.Blueprint, error) {
+	content, err := ReadFile(fleet.Spec.Blueprint)
+	if err != nil {
+		return types.Blueprint{}, err
+	}
+
+	subst, err := envsubst.Bytes(content)
+	if err != nil {
+		return types.Blueprint{}, err
+	}
+
+	var document dig.Mapping
+	if err := yaml.Unmarshal(subst, &document); err != nil {
+		return types.Blueprint{}, err
+	}
+
+	data, err := json.Marshal(types.MergeOverrides(document, cluster.Overrides))
+	if err != nil {
+		return types.Blueprint{}, fmt.Errorf("failed to apply the overrides of cluster %q: %w", cluster.Name, err)
+	}
+	log.Debug().Msgf("Blueprint of cluster %q:\n%s", cluster.Name, data)
+
+	blueprint, err := types.ParseBoundlessCluster(data)
+	if err != nil {
+		return types.Blueprint{}, err
+	}
+	if err := ImportHosts(&blueprint, filepath.Dir(fleet.Spec.Blueprint)); err != nil {
+		return types.Blueprint{}, fmt.Errorf("invalid blueprint for cluster %q: %w", cluster.Name, err)
+	}
+	if err := blueprint.Validate(); err != nil {
+		return types.Blueprint{}, fmt.Errorf("invalid blueprint for cluster %q: %w", cluster.Name, err)
+	}
+
+	return blueprint, nil
+}
+
+// relativeTo returns the path joined to the directory, unless it is empty or absolute
+func relativeTo(dir string, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
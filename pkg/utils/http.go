@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/types"
+)
+
+// NewHTTPClient returns a client that goes through the proxy, except for the noProxy addresses
+// Each blueprint has its own client, so that the clusters of a fleet can each have their own proxy.
+// Without a proxy, the proxy environment variables are used like with the default client
+func NewHTTPClient(proxy *types.Proxy, noProxy []string) *http.Client {
+	if proxy == nil {
+		return http.DefaultClient
+	}
+
+	config := httpproxy.Config{
+		HTTPProxy:  proxy.HTTP,
+		HTTPSProxy: proxy.HTTPS,
+		NoProxy:    strings.Join(noProxy, ","),
+	}
+	proxyFunc := config.ProxyFunc()
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}
+	return &http.Client{Transport: transport}
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/fatih/color"
+	"golang.org/x/term"
+)
+
+// promptMu serializes the prompts of commands that run against several clusters or hosts at once
+var promptMu sync.Mutex
+
+// Confirm asks the user to confirm the action and returns true if the answer is yes
+func Confirm(prompt string) (bool, error) {
+	promptMu.Lock()
+	defer promptMu.Unlock()
+
+	color.Red("%s (N/y)", prompt)
+	reader := bufio.NewReader(os.Stdin)
+	answer, err := reader.ReadString('\n')
+	if err != nil {
+		return false, fmt.Errorf("failed to read input: %w", err)
+	}
+	return answer == "y\n", nil
+}
+
+// PromptPassword asks the user for a secret without echoing it
+func PromptPassword(prompt string) (string, error) {
+	promptMu.Lock()
+	defer promptMu.Unlock()
+
+	fmt.Fprint(os.Stderr, prompt)
+	password, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read input: %w", err)
+	}
+	return string(password), nil
+}
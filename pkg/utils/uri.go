@@ -10,7 +10,8 @@ import (
 )
 
 // ReadURI reads the content of a URI.
-// The URI argument can be a file path or a URL.
+// The URI argument can be a file path or a URL. A URL is read with the default client, without the proxy of a blueprint:
+// the manifests of a blueprint are downloaded with its own client first, see NewHTTPClient.
 // @TODO: Make this function testable by injecting a reader for file and http requests.
 func ReadURI(uri string) ([]byte, error) {
 	u, err := url.Parse(uri)
@@ -0,0 +1,102 @@
+package versions
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/k0sproject/version"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
+)
+
+// compatibility is the range of Kubernetes versions supported by the releases of the Blueprint Operator
+type compatibility struct {
+	// operator is the constraint on the operator releases of the range
+	operator string
+	// minKubernetes is the oldest supported Kubernetes version
+	minKubernetes string
+	// maxKubernetes is the newest supported Kubernetes minor version
+	maxKubernetes string
+}
+
+// compatibilities are the Kubernetes ranges of the operator releases, the newest releases first
+// The first range an operator release satisfies applies to it. The newest range is the one the preflight
+// checks of the cluster use, and each of its Kubernetes minor versions has a k0s release in k0s-releases.txt.
+var compatibilities = []compatibility{
+	// the 1.x releases, which bctl installs by default
+	{operator: ">= 1.0.0", minKubernetes: constants.MinKubernetesVersion, maxKubernetes: constants.MaxKubernetesVersion},
+	// the 0.x releases, which bctl installs when spec.version pins one
+	{operator: ">= 0.0.0, < 1.0.0", minKubernetes: "1.25.0", maxKubernetes: "1.29"},
+}
+
+// KubernetesRange returns the oldest and the newest Kubernetes versions supported by the operator version,
+// which is a release, or latest or empty for the latest release
+// It returns false when the operator version is not a release, like the URI of a manifest.
+func KubernetesRange(operator string) (string, string, bool) {
+	c, ok := compatibilityOf(operator)
+	if !ok {
+		return "", "", false
+	}
+	return c.minKubernetes, c.maxKubernetes, true
+}
+
+// Supports tells whether the operator version supports the Kubernetes version of the k0s release
+// It returns false when the operator version is not a release or the k0s version is invalid.
+func Supports(operator string, k0s string) bool {
+	c, ok := compatibilityOf(operator)
+	if !ok {
+		return false
+	}
+	v, err := version.NewVersion(k0s)
+	if err != nil {
+		return false
+	}
+	kubernetes := minorVersion(v)
+	return !kubernetes.LessThan(minorVersion(version.MustParse(c.minKubernetes))) &&
+		!kubernetes.GreaterThan(minorVersion(version.MustParse(c.maxKubernetes)))
+}
+
+// Check returns why the combination of the operator and k0s versions of a blueprint is not supported,
+// or an empty string when it is supported or cannot be checked
+func Check(operator string, k0s string) string {
+	c, ok := compatibilityOf(operator)
+	if !ok || k0s == "" {
+		return ""
+	}
+	if _, err := version.NewVersion(k0s); err != nil {
+		return ""
+	}
+	if Supports(operator, k0s) {
+		return ""
+	}
+	if operator == "" {
+		operator = "latest"
+	}
+	return fmt.Sprintf("k0s %s is not supported by the Blueprint Operator %s, which supports Kubernetes %s to %s",
+		k0s, operator, c.minKubernetes, c.maxKubernetes)
+}
+
+// compatibilityOf returns the Kubernetes range of the operator version
+func compatibilityOf(operator string) (compatibility, bool) {
+	if operator == "" || operator == "latest" {
+		return compatibilities[0], true
+	}
+	if strings.Contains(operator, "/") {
+		return compatibility{}, false
+	}
+	v, err := version.NewVersion(operator)
+	if err != nil {
+		return compatibility{}, false
+	}
+	for _, c := range compatibilities {
+		if version.MustConstraint(c.operator).Check(v) {
+			return c, true
+		}
+	}
+	return compatibility{}, false
+}
+
+// minorVersion returns the major and minor version of a version, without the patch and the k0s build
+func minorVersion(v *version.Version) *version.Version {
+	return version.MustParse(Minor(v))
+}
@@ -0,0 +1,220 @@
+// Package versions lists the releases of k0s and of the Blueprint Operator, and tells which combinations of them are supported
+// The releases are read from the GitHub releases of the projects and cached, so that bctl also works offline.
+package versions
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/k0sproject/version"
+	"github.com/rs/zerolog/log"
+)
+
+// Origin tells where a list of releases comes from
+type Origin string
+
+const (
+	// FromIndex is a list read from the release index
+	FromIndex Origin = "release index"
+	// FromCache is a list read from the cache of the release index
+	FromCache Origin = "cache"
+	// FromFallback is the list embedded in bctl, used when the index cannot be reached and nothing is cached
+	FromFallback Origin = "list embedded in bctl"
+	// Unavailable is an empty list, when the index cannot be reached, nothing is cached, and nothing is embedded
+	Unavailable Origin = "release index unavailable"
+)
+
+// cacheTTL is how long a cached release index is used before it is read again
+const cacheTTL = 24 * time.Hour
+
+// indexTimeout is the time the release index has to answer
+const indexTimeout = 10 * time.Second
+
+//go:embed k0s-releases.txt
+var k0sFallback string
+
+var (
+	// k0sIndex and operatorIndex are the release indexes of k0s and of the Blueprint Operator
+	k0sIndex      = "https://api.github.com/repos/k0sproject/k0s/releases?per_page=100"
+	operatorIndex = "https://api.github.com/repos/mirantiscontainers/blueprint/releases?per_page=100"
+
+	// cacheDir returns the directory the release indexes are cached in
+	cacheDir = func() (string, error) {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to determine the bctl cache directory: %w", err)
+		}
+		return filepath.Join(dir, "bctl", "releases"), nil
+	}
+)
+
+// Releases are the releases of a project, the newest first
+type Releases struct {
+	Versions []*version.Version
+	Origin   Origin
+}
+
+// K0s returns the stable releases of k0s, read with the client
+func K0s(client *http.Client) (Releases, error) {
+	return releases(client, "k0s", k0sIndex, k0sFallback)
+}
+
+// Operator returns the stable releases of the Blueprint Operator, read with the client
+// There is no embedded list of the operator releases: without the index or a cache, the list is empty.
+func Operator(client *http.Client) (Releases, error) {
+	return releases(client, "blueprint-operator", operatorIndex, "")
+}
+
+// cachedIndex is the cache of a release index
+type cachedIndex struct {
+	Fetched  time.Time `json:"fetched"`
+	Versions []string  `json:"versions"`
+}
+
+// releases returns the releases of the index: from the cache while it is fresh, then from the index,
+// and when the index cannot be reached, from the outdated cache or from the fallback list
+func releases(client *http.Client, name string, index string, fallback string) (Releases, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return Releases{}, err
+	}
+	path := filepath.Join(dir, name+".json")
+
+	cached, cacheErr := readCache(path)
+	if cacheErr == nil && time.Since(cached.Fetched) < cacheTTL {
+		return newReleases(cached.Versions, FromCache), nil
+	}
+
+	tags, err := fetchIndex(client, index)
+	if err == nil {
+		if err := writeCache(path, cachedIndex{Fetched: time.Now(), Versions: tags}); err != nil {
+			log.Debug().Msgf("Failed to cache the releases of %s: %s", name, err)
+		}
+		return newReleases(tags, FromIndex), nil
+	}
+
+	log.Warn().Msgf("Failed to read the releases of %s: %s", name, err)
+	if cacheErr == nil {
+		return newReleases(cached.Versions, FromCache), nil
+	}
+	if fallback == "" {
+		return Releases{Origin: Unavailable}, nil
+	}
+	return newReleases(parseList(fallback), FromFallback), nil
+}
+
+// fetchIndex returns the tags of the stable releases of a GitHub release index
+func fetchIndex(client *http.Client, index string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), indexTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, index, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to read %s: %s", index, resp.Status)
+	}
+
+	var releases []struct {
+		TagName    string `json:"tag_name"`
+		Draft      bool   `json:"draft"`
+		Prerelease bool   `json:"prerelease"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", index, err)
+	}
+
+	var tags []string
+	for _, release := range releases {
+		if !release.Draft && !release.Prerelease {
+			tags = append(tags, release.TagName)
+		}
+	}
+	return tags, nil
+}
+
+func readCache(path string) (cachedIndex, error) {
+	var cached cachedIndex
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cached, err
+	}
+	err = json.Unmarshal(data, &cached)
+	return cached, err
+}
+
+func writeCache(path string, cached cachedIndex) error {
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// newReleases returns the releases of the tags, the newest first, skipping the tags that are not versions
+func newReleases(tags []string, origin Origin) Releases {
+	releases := Releases{Origin: origin}
+	for _, tag := range tags {
+		v, err := version.NewVersion(tag)
+		if err != nil {
+			log.Debug().Msgf("Skipping release %q: %s", tag, err)
+			continue
+		}
+		releases.Versions = append(releases.Versions, v)
+	}
+	sort.Slice(releases.Versions, func(i, j int) bool {
+		return releases.Versions[i].GreaterThan(releases.Versions[j])
+	})
+	return releases
+}
+
+// parseList returns the versions of a list with one version per line and # comments
+func parseList(list string) []string {
+	var versions []string
+	scanner := bufio.NewScanner(strings.NewReader(list))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			versions = append(versions, line)
+		}
+	}
+	return versions
+}
+
+// LatestPatches returns the newest release of each minor version
+func (r Releases) LatestPatches() []*version.Version {
+	var latest []*version.Version
+	seen := make(map[string]bool)
+	for _, v := range r.Versions {
+		if minor := Minor(v); !seen[minor] {
+			seen[minor] = true
+			latest = append(latest, v)
+		}
+	}
+	return latest
+}
+
+// Minor returns the major and minor version, e.g. 1.31, which is also the Kubernetes minor version of a k0s release
+func Minor(v *version.Version) string {
+	segments := v.Segments()
+	return fmt.Sprintf("%d.%d", segments[0], segments[1])
+}
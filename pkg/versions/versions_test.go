@@ -0,0 +1,214 @@
+package versions
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/k0sproject/version"
+	. "github.com/onsi/gomega"
+
+	"github.com/mirantiscontainers/blueprint-cli/pkg/constants"
+)
+
+// useIndex points the release indexes at a test server answering with the body, and the cache at a temporary directory
+func useIndex(t *testing.T, status int, body string) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	oldK0s, oldOperator, oldCacheDir := k0sIndex, operatorIndex, cacheDir
+	k0sIndex, operatorIndex = server.URL, server.URL
+	cacheDir = func() (string, error) { return dir, nil }
+	t.Cleanup(func() { k0sIndex, operatorIndex, cacheDir = oldK0s, oldOperator, oldCacheDir })
+	return dir
+}
+
+func writeTestCache(t *testing.T, dir string, name string, fetched time.Time, versions ...string) {
+	if err := writeCache(filepath.Join(dir, name+".json"), cachedIndex{Fetched: fetched, Versions: versions}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func tags(releases Releases) []string {
+	var tags []string
+	for _, v := range releases.Versions {
+		tags = append(tags, v.String())
+	}
+	return tags
+}
+
+// TestK0s tests that the k0s releases are read from the index, the cache, and the embedded list
+func TestK0s(t *testing.T) {
+	const index = `[
+		{"tag_name": "v1.30.5+k0s.0"},
+		{"tag_name": "v1.31.1+k0s.0"},
+		{"tag_name": "v1.31.0+k0s.0"},
+		{"tag_name": "v1.32.0-rc.1+k0s.0", "prerelease": true},
+		{"tag_name": "v1.32.0+k0s.0", "draft": true},
+		{"tag_name": "nightly"}
+	]`
+
+	t.Run("index", func(t *testing.T) {
+		g := NewWithT(t)
+		dir := useIndex(t, http.StatusOK, index)
+
+		releases, err := K0s(http.DefaultClient)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(releases.Origin).To(Equal(FromIndex))
+		g.Expect(tags(releases)).To(Equal([]string{"v1.31.1+k0s.0", "v1.31.0+k0s.0", "v1.30.5+k0s.0"}))
+		g.Expect(filepath.Join(dir, "k0s.json")).To(BeARegularFile())
+	})
+
+	t.Run("fresh cache", func(t *testing.T) {
+		g := NewWithT(t)
+		dir := useIndex(t, http.StatusOK, index)
+		writeTestCache(t, dir, "k0s", time.Now(), "v1.29.9+k0s.0")
+
+		releases, err := K0s(http.DefaultClient)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(releases.Origin).To(Equal(FromCache))
+		g.Expect(tags(releases)).To(Equal([]string{"v1.29.9+k0s.0"}))
+	})
+
+	t.Run("outdated cache", func(t *testing.T) {
+		g := NewWithT(t)
+		dir := useIndex(t, http.StatusOK, index)
+		writeTestCache(t, dir, "k0s", time.Now().Add(-2*cacheTTL), "v1.29.9+k0s.0")
+
+		releases, err := K0s(http.DefaultClient)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(releases.Origin).To(Equal(FromIndex))
+	})
+
+	t.Run("outdated cache and unreachable index", func(t *testing.T) {
+		g := NewWithT(t)
+		dir := useIndex(t, http.StatusForbidden, "rate limited")
+		writeTestCache(t, dir, "k0s", time.Now().Add(-2*cacheTTL), "v1.29.9+k0s.0")
+
+		releases, err := K0s(http.DefaultClient)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(releases.Origin).To(Equal(FromCache))
+		g.Expect(tags(releases)).To(Equal([]string{"v1.29.9+k0s.0"}))
+	})
+
+	t.Run("embedded list", func(t *testing.T) {
+		g := NewWithT(t)
+		dir := useIndex(t, http.StatusForbidden, "rate limited")
+
+		releases, err := K0s(http.DefaultClient)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(releases.Origin).To(Equal(FromFallback))
+		g.Expect(releases.Versions).ToNot(BeEmpty())
+		_, err = os.Stat(filepath.Join(dir, "k0s.json"))
+		g.Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	t.Run("no operator releases without index", func(t *testing.T) {
+		g := NewWithT(t)
+		useIndex(t, http.StatusForbidden, "rate limited")
+
+		releases, err := Operator(http.DefaultClient)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(releases.Origin).To(Equal(Unavailable))
+		g.Expect(releases.Versions).To(BeEmpty())
+	})
+}
+
+// TestLatestPatches tests that the newest release of each minor version is kept
+func TestLatestPatches(t *testing.T) {
+	g := NewWithT(t)
+
+	releases := newReleases([]string{"v1.30.4+k0s.0", "v1.31.0+k0s.0", "v1.30.5+k0s.0", "v1.31.1+k0s.0"}, FromIndex)
+	latest := Releases{Versions: releases.LatestPatches()}
+	g.Expect(tags(latest)).To(Equal([]string{"v1.31.1+k0s.0", "v1.30.5+k0s.0"}))
+}
+
+// TestSupports tests the combinations of operator and k0s versions
+func TestSupports(t *testing.T) {
+	tests := map[string]struct {
+		operator string
+		k0s      string
+		want     bool
+	}{
+		"latest operator":          {operator: "latest", k0s: "v1.31.1+k0s.0", want: true},
+		"no operator version":      {operator: "", k0s: "1.27.16+k0s.0", want: true},
+		"operator release":         {operator: "v1.0.3", k0s: "v1.29.9+k0s.0", want: true},
+		"operator 1.x too old":     {operator: "v1.0.3", k0s: "v1.26.15+k0s.0", want: false},
+		"operator 0.x release":     {operator: "v0.9.2", k0s: "v1.25.16+k0s.0", want: true},
+		"operator 0.x too new":     {operator: "v0.9.2", k0s: "v1.30.5+k0s.0", want: false},
+		"operator without v":       {operator: "1.0.3", k0s: "v1.29.9+k0s.0", want: true},
+		"Kubernetes minor":         {operator: "latest", k0s: "1.30", want: true},
+		"too old":                  {operator: "latest", k0s: "v1.26.15+k0s.0", want: false},
+		"too new":                  {operator: "latest", k0s: "v1.32.0+k0s.0", want: false},
+		"operator manifest":        {operator: "https://example.com/blueprint-operator.yaml", k0s: "v1.31.1+k0s.0", want: false},
+		"invalid k0s version":      {operator: "latest", k0s: "nope", want: false},
+		"invalid operator version": {operator: "nope", k0s: "v1.31.1+k0s.0", want: false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(Supports(tc.operator, tc.k0s)).To(Equal(tc.want))
+		})
+	}
+}
+
+// TestCheck tests the warnings about the combinations of a blueprint
+func TestCheck(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(Check("latest", "1.31.1+k0s.0")).To(BeEmpty())
+	g.Expect(Check("latest", "")).To(BeEmpty())
+	g.Expect(Check("https://example.com/blueprint-operator.yaml", "1.20.0+k0s.0")).To(BeEmpty())
+	g.Expect(Check("", "1.26.15+k0s.0")).To(Equal("k0s 1.26.15+k0s.0 is not supported by the Blueprint Operator latest, which supports Kubernetes 1.27.0 to 1.31"))
+	g.Expect(Check("v0.9.2", "1.26.15+k0s.0")).To(BeEmpty())
+	g.Expect(Check("v0.9.2", "1.31.1+k0s.0")).To(Equal("k0s 1.31.1+k0s.0 is not supported by the Blueprint Operator v0.9.2, which supports Kubernetes 1.25.0 to 1.29"))
+}
+
+// TestCompatibilities checks the Kubernetes ranges of the operator releases against each other and the k0s releases
+func TestCompatibilities(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(compatibilities[0].minKubernetes).To(Equal(constants.MinKubernetesVersion), "the newest range is the one of the preflight checks")
+	g.Expect(compatibilities[0].maxKubernetes).To(Equal(constants.MaxKubernetesVersion), "the newest range is the one of the preflight checks")
+
+	var minors []string
+	for _, v := range newReleases(parseList(k0sFallback), FromFallback).LatestPatches() {
+		minors = append(minors, Minor(v))
+	}
+
+	for i, c := range compatibilities {
+		constraint, err := version.NewConstraint(c.operator)
+		g.Expect(err).ToNot(HaveOccurred(), c.operator)
+		minimum := version.MustParse(c.minKubernetes)
+		maximum := version.MustParse(c.maxKubernetes)
+		g.Expect(minimum.GreaterThan(maximum)).To(BeFalse(), "%s: %s is newer than %s", c.operator, c.minKubernetes, c.maxKubernetes)
+
+		// every Kubernetes minor of the range has a k0s release to offer offline
+		for minor := minorVersion(minimum); !minor.GreaterThan(minorVersion(maximum)); minor = nextMinor(minor) {
+			g.Expect(minors).To(ContainElement(Minor(minor)), "%s: no k0s release for Kubernetes %s", c.operator, Minor(minor))
+		}
+
+		// the newer ranges are for newer releases
+		for _, newer := range compatibilities[:i] {
+			g.Expect(version.MustParse(newer.maxKubernetes).LessThan(maximum)).To(BeFalse(), "%s supports a newer Kubernetes than %s", c.operator, newer.operator)
+			lowest, _, _ := strings.Cut(strings.TrimPrefix(newer.operator, ">= "), ",")
+			g.Expect(constraint.Check(version.MustParse(lowest))).To(BeFalse(), "%s overlaps with %s", c.operator, newer.operator)
+		}
+	}
+}
+
+// nextMinor returns the next Kubernetes minor version
+func nextMinor(v *version.Version) *version.Version {
+	segments := v.Segments()
+	return version.MustParse(fmt.Sprintf("%d.%d", segments[0], segments[1]+1))
+}